@@ -4,8 +4,10 @@ import (
 	"crypto/ecdsa"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/openshift/library-go/pkg/certs/cert-inspection/certgraphapi"
 	"k8s.io/apimachinery/pkg/util/duration"
@@ -105,7 +107,10 @@ func toCertKeyMetadata(certificate *x509.Certificate) certgraphapi.CertKeyMetada
 		CertIdentifier: certgraphapi.CertIdentifier{
 			CommonName:   certificate.Subject.CommonName,
 			SerialNumber: certificate.SerialNumber.String(),
+			SubjectKeyID: hex.EncodeToString(certificate.SubjectKeyId),
 		},
+		NotBefore:          certificate.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:           certificate.NotAfter.UTC().Format(time.RFC3339),
 		SignatureAlgorithm: certificate.SignatureAlgorithm.String(),
 		PublicKeyAlgorithm: certificate.PublicKeyAlgorithm.String(),
 		ValidityDuration:   duration.HumanDuration(certificate.NotAfter.Sub(certificate.NotBefore)),
@@ -124,6 +129,7 @@ func toCertKeyMetadata(certificate *x509.Certificate) certgraphapi.CertKeyMetada
 	ret.CertIdentifier.Issuer = &certgraphapi.CertIdentifier{
 		CommonName:   signerHumanName,
 		SerialNumber: certificate.Issuer.SerialNumber,
+		SubjectKeyID: hex.EncodeToString(certificate.AuthorityKeyId),
 	}
 
 	humanUsages := []string{}
@@ -0,0 +1,186 @@
+package certgraphanalysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/library-go/pkg/certs/cert-inspection/certgraphapi"
+)
+
+type PKIViolationType string
+
+const (
+	// PKIViolationUnregistered is a secret or config map holding certificates that is not in the registry.
+	PKIViolationUnregistered PKIViolationType = "Unregistered"
+	// PKIViolationMissing is a registered secret or config map that was not found.
+	PKIViolationMissing PKIViolationType = "Missing"
+	// PKIViolationMissingOwner is a registered secret or config map without an owning component.
+	PKIViolationMissingOwner PKIViolationType = "MissingOwner"
+	// PKIViolationMissingDescription is a registered secret or config map without a description.
+	PKIViolationMissingDescription PKIViolationType = "MissingDescription"
+	// PKIViolationLifetime is a certificate whose lifetime exceeds the policy.
+	PKIViolationLifetime PKIViolationType = "Lifetime"
+	// PKIViolationKeySize is a certificate whose key is smaller than the policy allows.
+	PKIViolationKeySize PKIViolationType = "KeySize"
+)
+
+// PKIViolation is a violation of the registry or the policy found by CheckPKIRegistry.
+type PKIViolation struct {
+	Type PKIViolationType `json:"type"`
+	// Location is the violating secret or config map, formatted as secrets/<namespace>/<name> or
+	// configmaps/<namespace>/<name>.
+	Location string `json:"location"`
+	// Certificate is the violating certificate as CommonName::SerialNumber, if any.
+	Certificate string `json:"certificate,omitempty"`
+	Message     string `json:"message"`
+}
+
+func (v PKIViolation) String() string {
+	return fmt.Sprintf("%s: %s: %s", v.Location, v.Type, v.Message)
+}
+
+// PKIPolicy limits the lifetime and key sizes of the certificates checked by CheckPKIRegistry. Zero values disable the
+// respective check.
+type PKIPolicy struct {
+	// MaxLifetime is the maximum lifetime of certificates that are not signers.
+	MaxLifetime metav1.Duration `json:"maxLifetime,omitempty"`
+	// MaxSignerLifetime is the maximum lifetime of signer certificates.
+	MaxSignerLifetime metav1.Duration `json:"maxSignerLifetime,omitempty"`
+	// MinRSAKeySize is the minimum size of RSA keys in bits.
+	MinRSAKeySize int `json:"minRSAKeySize,omitempty"`
+	// MinECDSAKeySize is the minimum size of ECDSA keys in bits.
+	MinECDSAKeySize int `json:"minECDSAKeySize,omitempty"`
+}
+
+// CheckPKIRegistry evaluates the in-cluster certificates and CA bundles of the PKIList against the registry and the
+// policy. It reports
+//   - secrets and config maps that are not registered,
+//   - registered secrets and config maps that were not found,
+//   - registered secrets and config maps without an owner or description,
+//   - certificates in secrets violating the lifetime or key size policy.
+//
+// The violations are sorted by location.
+func CheckPKIRegistry(pkiList *certgraphapi.PKIList, registry *certgraphapi.PKIRegistryInfo, policy PKIPolicy) []PKIViolation {
+	violations := []PKIViolation{}
+
+	registeredSecrets := map[certgraphapi.InClusterSecretLocation]certgraphapi.PKIRegistryCertKeyPairInfo{}
+	for _, curr := range registry.CertKeyPairs {
+		registeredSecrets[curr.SecretLocation] = curr.CertKeyInfo
+	}
+	registeredConfigMaps := map[certgraphapi.InClusterConfigMapLocation]certgraphapi.PKIRegistryCertificateAuthorityInfo{}
+	for _, curr := range registry.CertificateAuthorityBundles {
+		registeredConfigMaps[curr.ConfigMapLocation] = curr.CABundleInfo
+	}
+
+	foundSecrets := map[certgraphapi.InClusterSecretLocation]bool{}
+	for _, certKeyPair := range pkiList.CertKeyPairs.Items {
+		for _, location := range certKeyPair.Spec.SecretLocations {
+			foundSecrets[location] = true
+			formattedLocation := fmt.Sprintf("secrets/%s/%s", location.Namespace, location.Name)
+			if _, ok := registeredSecrets[location]; !ok {
+				violations = append(violations, PKIViolation{
+					Type:        PKIViolationUnregistered,
+					Location:    formattedLocation,
+					Certificate: certKeyPair.Name,
+					Message:     "secret is not in the registry",
+				})
+			}
+			for _, violation := range checkCertificatePolicy(certKeyPair.Spec.CertMetadata, policy) {
+				violation.Location = formattedLocation
+				violation.Certificate = certKeyPair.Name
+				violations = append(violations, violation)
+			}
+		}
+	}
+
+	foundConfigMaps := map[certgraphapi.InClusterConfigMapLocation]bool{}
+	for _, caBundle := range pkiList.CertificateAuthorityBundles.Items {
+		for _, location := range caBundle.Spec.ConfigMapLocations {
+			foundConfigMaps[location] = true
+			if _, ok := registeredConfigMaps[location]; !ok {
+				violations = append(violations, PKIViolation{
+					Type:     PKIViolationUnregistered,
+					Location: fmt.Sprintf("configmaps/%s/%s", location.Namespace, location.Name),
+					Message:  "config map is not in the registry",
+				})
+			}
+		}
+	}
+
+	for location, info := range registeredSecrets {
+		violations = append(violations, checkRegistration(
+			fmt.Sprintf("secrets/%s/%s", location.Namespace, location.Name), foundSecrets[location], info.OwningJiraComponent, info.Description)...)
+	}
+	for location, info := range registeredConfigMaps {
+		violations = append(violations, checkRegistration(
+			fmt.Sprintf("configmaps/%s/%s", location.Namespace, location.Name), foundConfigMaps[location], info.OwningJiraComponent, info.Description)...)
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Location != violations[j].Location {
+			return violations[i].Location < violations[j].Location
+		}
+		return violations[i].Type < violations[j].Type
+	})
+	return violations
+}
+
+func checkRegistration(location string, found bool, owner, description string) []PKIViolation {
+	violations := []PKIViolation{}
+	if !found {
+		violations = append(violations, PKIViolation{Type: PKIViolationMissing, Location: location, Message: "registered, but not found"})
+	}
+	if len(owner) == 0 {
+		violations = append(violations, PKIViolation{Type: PKIViolationMissingOwner, Location: location, Message: "no owning component is registered"})
+	}
+	if len(description) == 0 {
+		violations = append(violations, PKIViolation{Type: PKIViolationMissingDescription, Location: location, Message: "no description is registered"})
+	}
+	return violations
+}
+
+func checkCertificatePolicy(metadata certgraphapi.CertKeyMetadata, policy PKIPolicy) []PKIViolation {
+	violations := []PKIViolation{}
+
+	maxLifetime := policy.MaxLifetime.Duration
+	if isSigner(&metadata) {
+		maxLifetime = policy.MaxSignerLifetime.Duration
+	}
+	if maxLifetime > 0 {
+		notBefore, notBeforeErr := time.Parse(time.RFC3339, metadata.NotBefore)
+		notAfter, notAfterErr := time.Parse(time.RFC3339, metadata.NotAfter)
+		switch {
+		case notBeforeErr != nil || notAfterErr != nil:
+			violations = append(violations, PKIViolation{Type: PKIViolationLifetime, Message: "unknown lifetime"})
+		case notAfter.Sub(notBefore) > maxLifetime:
+			violations = append(violations, PKIViolation{
+				Type:    PKIViolationLifetime,
+				Message: fmt.Sprintf("lifetime %v exceeds %v", notAfter.Sub(notBefore), maxLifetime),
+			})
+		}
+	}
+
+	minKeySize := 0
+	switch metadata.PublicKeyAlgorithm {
+	case "RSA":
+		minKeySize = policy.MinRSAKeySize
+	case "ECDSA":
+		minKeySize = policy.MinECDSAKeySize
+	}
+	if minKeySize > 0 {
+		keySize := 0
+		if _, err := fmt.Sscanf(metadata.PublicKeyBitSize, "%d bit", &keySize); err != nil {
+			violations = append(violations, PKIViolation{Type: PKIViolationKeySize, Message: "unknown key size"})
+		} else if keySize < minKeySize {
+			violations = append(violations, PKIViolation{
+				Type:    PKIViolationKeySize,
+				Message: fmt.Sprintf("%s key size %d is smaller than %d", metadata.PublicKeyAlgorithm, keySize, minKeySize),
+			})
+		}
+	}
+
+	return violations
+}
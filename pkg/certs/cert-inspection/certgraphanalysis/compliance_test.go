@@ -0,0 +1,70 @@
+package certgraphanalysis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/library-go/pkg/certs/cert-inspection/certgraphapi"
+)
+
+func TestCheckPKIRegistry(t *testing.T) {
+	pkiList := newTestPKIList(t, newTestCA(t, "signer", 2*time.Hour))
+	registry := &certgraphapi.PKIRegistryInfo{
+		CertKeyPairs: []certgraphapi.PKIRegistryInClusterCertKeyPair{
+			{
+				SecretLocation: certgraphapi.InClusterSecretLocation{Namespace: "ns", Name: "signer"},
+				CertKeyInfo:    certgraphapi.PKIRegistryCertKeyPairInfo{OwningJiraComponent: "component", Description: "signer"},
+			},
+			{
+				SecretLocation: certgraphapi.InClusterSecretLocation{Namespace: "ns", Name: "removed"},
+				CertKeyInfo:    certgraphapi.PKIRegistryCertKeyPairInfo{OwningJiraComponent: "component", Description: "removed"},
+			},
+		},
+		CertificateAuthorityBundles: []certgraphapi.PKIRegistryInClusterCABundle{
+			{
+				ConfigMapLocation: certgraphapi.InClusterConfigMapLocation{Namespace: "ns", Name: "ca-bundle"},
+				CABundleInfo:      certgraphapi.PKIRegistryCertificateAuthorityInfo{OwningJiraComponent: "component"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		policy   PKIPolicy
+		expected []PKIViolationType
+	}{
+		{
+			name:     "registry",
+			expected: []PKIViolationType{PKIViolationMissingDescription, PKIViolationMissing, PKIViolationUnregistered},
+		},
+		{
+			name: "policy",
+			policy: PKIPolicy{
+				MaxLifetime:       metav1.Duration{Duration: time.Hour},
+				MaxSignerLifetime: metav1.Duration{Duration: 3 * time.Hour},
+				MinRSAKeySize:     3072,
+			},
+			expected: []PKIViolationType{
+				PKIViolationMissingDescription,
+				PKIViolationMissing,
+				PKIViolationKeySize, PKIViolationLifetime, PKIViolationUnregistered,
+				PKIViolationKeySize,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			violations := CheckPKIRegistry(pkiList, registry, test.policy)
+			actual := []PKIViolationType{}
+			for _, violation := range violations {
+				actual = append(actual, violation.Type)
+			}
+			if !reflect.DeepEqual(test.expected, actual) {
+				t.Errorf("expected %v, got %v", test.expected, violations)
+			}
+		})
+	}
+}
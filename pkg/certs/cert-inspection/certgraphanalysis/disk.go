@@ -0,0 +1,476 @@
+package certgraphanalysis
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift/api/annotations"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/errors"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/cert"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/library-go/pkg/certs/cert-inspection/certgraphapi"
+)
+
+// maxInspectedFileSize skips files that are too large to hold certificates, like binaries and logs.
+const maxInspectedFileSize = 10 * 1024 * 1024
+
+// GatherCertsFromDisk walks the given directory trees, like /etc/kubernetes on a node or an extracted must-gather, and
+// collects
+//   - PEM encoded certificates and private keys, a certificate is paired with the key file holding its private key,
+//   - PEM encoded CA bundles, which are files holding only CA certificates,
+//   - embedded certificates and keys of kubeconfigs,
+//   - ConfigMaps, Secrets and Nodes serialized as YAML or JSON, alone, in lists or in multi-document YAML files, as found
+//     in must-gathers.
+//
+// Symlinks are followed, every directory is walked once even if it is reachable through multiple paths or symlink loops.
+// Files that cannot be read or parsed are reported as errors, the remaining files are still collected.
+func GatherCertsFromDisk(ctx context.Context, dirs ...string) (*certgraphapi.PKIList, error) {
+	c := &diskCollector{nodes: map[string]int{}, walkedDirs: map[string]bool{}}
+	for _, dir := range dirs {
+		if err := c.walk(ctx, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	certs := c.certKeyPairs()
+	pkiList := PKIListFromParts(ctx, &c.inClusterResourceData, certs, c.caBundles, c.nodes)
+	return pkiList, errors.NewAggregate(c.errs)
+}
+
+// MergePKILists merges PKI lists gathered from different sources, e.g. the live cluster and the disks of its nodes,
+// into one. Certificates found in multiple sources are merged into one item with all locations.
+func MergePKILists(ctx context.Context, nodes map[string]int, lists ...*certgraphapi.PKIList) *certgraphapi.PKIList {
+	inClusterResourceData := &certgraphapi.PerInClusterResourceData{}
+	certs := []*certgraphapi.CertKeyPair{}
+	caBundles := []*certgraphapi.CertificateAuthorityBundle{}
+	for _, list := range lists {
+		inClusterResourceData.CertificateAuthorityBundles = append(inClusterResourceData.CertificateAuthorityBundles, list.InClusterResourceData.CertificateAuthorityBundles...)
+		inClusterResourceData.CertKeyPairs = append(inClusterResourceData.CertKeyPairs, list.InClusterResourceData.CertKeyPairs...)
+		for i := range list.CertKeyPairs.Items {
+			certs = append(certs, &list.CertKeyPairs.Items[i])
+		}
+		for i := range list.CertificateAuthorityBundles.Items {
+			caBundles = append(caBundles, &list.CertificateAuthorityBundles.Items[i])
+		}
+	}
+	return PKIListFromParts(ctx, inClusterResourceData, certs, caBundles, nodes)
+}
+
+type diskCollector struct {
+	inClusterResourceData certgraphapi.PerInClusterResourceData
+	certs                 []*certgraphapi.CertKeyPair
+	caBundles             []*certgraphapi.CertificateAuthorityBundle
+	nodes                 map[string]int
+
+	// onDiskCerts are paired with onDiskKeys once all files were inspected
+	onDiskCerts []onDiskCert
+	onDiskKeys  []onDiskKey
+
+	// walkedDirs holds the paths of the walked directories with all symlinks resolved
+	walkedDirs map[string]bool
+
+	errs []error
+}
+
+type onDiskCert struct {
+	certificate *x509.Certificate
+	location    certgraphapi.OnDiskLocation
+	// caBundleNoKey is set for files holding a single CA certificate without its key
+	caBundleNoKey bool
+}
+
+type onDiskKey struct {
+	publicKey crypto.PublicKey
+	location  certgraphapi.OnDiskLocation
+}
+
+// walk inspects the files in the directory tree of dir. Symlinked directories are walked by their resolved path.
+func (c *diskCollector) walk(ctx context.Context, dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			c.errs = append(c.errs, err)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() {
+			if !c.firstWalk(path) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		mode := entry.Type()
+		if mode&fs.ModeSymlink != 0 {
+			info, err := os.Stat(path)
+			if err != nil {
+				c.errs = append(c.errs, err)
+				return nil
+			}
+			if info.IsDir() {
+				resolved, err := filepath.EvalSymlinks(path)
+				if err != nil {
+					c.errs = append(c.errs, err)
+					return nil
+				}
+				return c.walk(ctx, resolved)
+			}
+			mode = info.Mode()
+		}
+		if !mode.IsRegular() {
+			return nil
+		}
+		if err := c.inspectFile(path); err != nil {
+			c.errs = append(c.errs, fmt.Errorf("%s: %w", path, err))
+		}
+		return nil
+	})
+}
+
+// firstWalk returns true if the directory was not walked yet, and records it as walked.
+func (c *diskCollector) firstWalk(dir string) bool {
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		resolved = filepath.Clean(dir)
+	}
+	if c.walkedDirs[resolved] {
+		return false
+	}
+	c.walkedDirs[resolved] = true
+	return true
+}
+
+func (c *diskCollector) inspectFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() > maxInspectedFileSize {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	location := toOnDiskLocation(path, info)
+
+	// manifests embed PEM data, so they are recognized first. Kubeconfigs and other manifests are often named without
+	// a YAML extension, e.g. admin.kubeconfig, files of any other extension are recognized by their content.
+	switch ext := strings.ToLower(filepath.Ext(path)); {
+	case ext == ".yaml" || ext == ".yml" || ext == ".json":
+		return c.inspectManifest(data, location)
+	case isManifest(data):
+		return c.inspectManifest(data, location)
+	case bytes.Contains(data, []byte("-----BEGIN ")):
+		return c.inspectPEM(data, location)
+	}
+	return nil
+}
+
+// isManifest returns true if the first non-empty document of data is a YAML or JSON object with a kind.
+func isManifest(data []byte) bool {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		document, err := reader.Read()
+		if err != nil {
+			return false
+		}
+		obj := map[string]interface{}{}
+		if err := yaml.Unmarshal(document, &obj); err != nil {
+			return false
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		_, ok := obj["kind"].(string)
+		return ok
+	}
+}
+
+// inspectPEM collects the certificates and keys of a PEM file. A file holding a leaf certificate, optionally followed by
+// its chain, is a certificate, a file holding multiple CA certificates is a CA bundle, unless it also holds a private key.
+func (c *diskCollector) inspectPEM(data []byte, location certgraphapi.OnDiskLocation) error {
+	certificates := []*x509.Certificate{}
+	keys := []crypto.PublicKey{}
+	var errs []error
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		switch {
+		case block.Type == "CERTIFICATE":
+			certificate, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			certificates = append(certificates, certificate)
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			publicKey, err := publicKeyOfPEMBlock(block)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			keys = append(keys, publicKey)
+		}
+	}
+	for _, key := range keys {
+		c.onDiskKeys = append(c.onDiskKeys, onDiskKey{publicKey: key, location: location})
+	}
+	if len(certificates) == 0 {
+		return errors.NewAggregate(errs)
+	}
+
+	switch {
+	case len(certificates) > 1 && allCAs(certificates) && len(keys) == 0:
+		if err := c.addOnDiskCABundle(certificates, location); err != nil {
+			errs = append(errs, err)
+		}
+	default:
+		// a single CA certificate is a signer when its key is found next to it, and a CA bundle otherwise
+		c.onDiskCerts = append(c.onDiskCerts, onDiskCert{
+			certificate:   certificates[0],
+			location:      location,
+			caBundleNoKey: len(certificates) == 1 && certificates[0].IsCA && len(keys) == 0,
+		})
+	}
+	return errors.NewAggregate(errs)
+}
+
+func (c *diskCollector) addOnDiskCABundle(certificates []*x509.Certificate, location certgraphapi.OnDiskLocation) error {
+	caBundle, err := toCABundle(certificates)
+	if err != nil {
+		return err
+	}
+	caBundle.Spec.OnDiskLocations = append(caBundle.Spec.OnDiskLocations, location)
+	c.caBundles = append(c.caBundles, caBundle)
+	return nil
+}
+
+// inspectManifest collects kubeconfigs and the ConfigMaps, Secrets and Nodes of Kubernetes manifests, including all
+// documents of multi-document YAML files. Other files are ignored.
+func (c *diskCollector) inspectManifest(data []byte, location certgraphapi.OnDiskLocation) error {
+	var errs []error
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		document, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// not a manifest
+			break
+		}
+		obj := map[string]interface{}{}
+		if err := yaml.Unmarshal(document, &obj); err != nil || len(obj) == 0 {
+			// not a manifest
+			continue
+		}
+		if kind, _ := obj["kind"].(string); kind == "Config" {
+			if err := c.inspectKubeconfig(document, location); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := c.inspectObject(&unstructured.Unstructured{Object: obj}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
+func (c *diskCollector) inspectObject(obj *unstructured.Unstructured) error {
+	if obj.IsList() {
+		var errs []error
+		err := obj.EachListItem(func(item runtime.Object) error {
+			if err := c.inspectObject(item.(*unstructured.Unstructured)); err != nil {
+				errs = append(errs, err)
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return errors.NewAggregate(errs)
+	}
+	if obj.GetAPIVersion() != "v1" {
+		return nil
+	}
+
+	switch obj.GetKind() {
+	case "ConfigMap":
+		configMap := &corev1.ConfigMap{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, configMap); err != nil {
+			return err
+		}
+		details, err := InspectConfigMap(configMap)
+		if details != nil {
+			c.caBundles = append(c.caBundles, details)
+			c.inClusterResourceData.CertificateAuthorityBundles = append(c.inClusterResourceData.CertificateAuthorityBundles,
+				certgraphapi.PKIRegistryInClusterCABundle{
+					ConfigMapLocation: certgraphapi.InClusterConfigMapLocation{
+						Namespace: configMap.Namespace,
+						Name:      configMap.Name,
+					},
+					CABundleInfo: certgraphapi.PKIRegistryCertificateAuthorityInfo{
+						OwningJiraComponent: configMap.Annotations[annotations.OpenShiftComponent],
+						Description:         configMap.Annotations[annotations.OpenShiftDescription],
+					},
+				})
+		}
+		return err
+	case "Secret":
+		secret := &corev1.Secret{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, secret); err != nil {
+			return err
+		}
+		details, err := InspectSecret(secret)
+		if details != nil {
+			c.certs = append(c.certs, details)
+			c.inClusterResourceData.CertKeyPairs = append(c.inClusterResourceData.CertKeyPairs,
+				certgraphapi.PKIRegistryInClusterCertKeyPair{
+					SecretLocation: certgraphapi.InClusterSecretLocation{
+						Namespace: secret.Namespace,
+						Name:      secret.Name,
+					},
+					CertKeyInfo: certgraphapi.PKIRegistryCertKeyPairInfo{
+						OwningJiraComponent: secret.Annotations[annotations.OpenShiftComponent],
+						Description:         secret.Annotations[annotations.OpenShiftDescription],
+					},
+				})
+		}
+		return err
+	case "Node":
+		if _, ok := c.nodes[obj.GetName()]; !ok {
+			c.nodes[obj.GetName()] = len(c.nodes)
+		}
+	}
+	return nil
+}
+
+// inspectKubeconfig collects the embedded CA bundles and client certificates of a kubeconfig. Referenced files are
+// collected when they are part of the inspected directories.
+func (c *diskCollector) inspectKubeconfig(data []byte, location certgraphapi.OnDiskLocation) error {
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, cluster := range config.Clusters {
+		if len(cluster.CertificateAuthorityData) == 0 {
+			continue
+		}
+		certificates, err := cert.ParseCertsPEM(cluster.CertificateAuthorityData)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := c.addOnDiskCABundle(certificates, location); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, authInfo := range config.AuthInfos {
+		if len(authInfo.ClientCertificateData) == 0 {
+			continue
+		}
+		if err := c.inspectPEM(append(append([]byte{}, authInfo.ClientCertificateData...), authInfo.ClientKeyData...), location); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
+// certKeyPairs returns the certificates of the inspected manifests and files. The files are paired with the files
+// holding their private key, CA certificates without a key in the same directory are added to the CA bundles instead.
+func (c *diskCollector) certKeyPairs() []*certgraphapi.CertKeyPair {
+	certs := append([]*certgraphapi.CertKeyPair{}, c.certs...)
+	for _, onDisk := range c.onDiskCerts {
+		location := certgraphapi.OnDiskCertKeyPairLocation{Cert: onDisk.location}
+		for _, key := range c.onDiskKeys {
+			if publicKeysEqual(onDisk.certificate.PublicKey, key.publicKey) {
+				location.Key = key.location
+				// prefer a key in the same file
+				if key.location.Path == onDisk.location.Path {
+					break
+				}
+			}
+		}
+		if onDisk.caBundleNoKey && filepath.Dir(location.Key.Path) != filepath.Dir(onDisk.location.Path) {
+			if err := c.addOnDiskCABundle([]*x509.Certificate{onDisk.certificate}, onDisk.location); err != nil {
+				c.errs = append(c.errs, fmt.Errorf("%s: %w", onDisk.location.Path, err))
+			}
+			continue
+		}
+
+		details, err := toCertKeyPair(onDisk.certificate)
+		if err != nil {
+			c.errs = append(c.errs, fmt.Errorf("%s: %w", onDisk.location.Path, err))
+			continue
+		}
+		details.Spec.OnDiskLocations = append(details.Spec.OnDiskLocations, location)
+		certs = append(certs, details)
+	}
+	return certs
+}
+
+func toOnDiskLocation(path string, info os.FileInfo) certgraphapi.OnDiskLocation {
+	user, group, seLinuxOptions := fileOwnership(path, info)
+	return certgraphapi.OnDiskLocation{
+		Path:           path,
+		User:           user,
+		Group:          group,
+		Permissions:    fmt.Sprintf("%#o", info.Mode().Perm()),
+		SELinuxOptions: seLinuxOptions,
+	}
+}
+
+func publicKeyOfPEMBlock(block *pem.Block) (crypto.PublicKey, error) {
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported private key type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key %T", key)
+	}
+	return signer.Public(), nil
+}
+
+func publicKeysEqual(lhs, rhs crypto.PublicKey) bool {
+	comparable, ok := lhs.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && comparable.Equal(rhs)
+}
+
+func allCAs(certificates []*x509.Certificate) bool {
+	for _, certificate := range certificates {
+		if !certificate.IsCA {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,26 @@
+//go:build linux
+// +build linux
+
+package certgraphanalysis
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileOwnership returns the numeric owner, group and the SELinux label of the file.
+func fileOwnership(path string, info os.FileInfo) (user, group, seLinuxOptions string) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		user = strconv.FormatUint(uint64(stat.Uid), 10)
+		group = strconv.FormatUint(uint64(stat.Gid), 10)
+	}
+	label := make([]byte, 256)
+	if size, err := unix.Lgetxattr(path, "security.selinux", label); err == nil && size > 0 {
+		seLinuxOptions = string(bytes.TrimRight(label[:size], "\x00"))
+	}
+	return user, group, seLinuxOptions
+}
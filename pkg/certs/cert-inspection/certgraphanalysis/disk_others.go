@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package certgraphanalysis
+
+import (
+	"os"
+)
+
+// fileOwnership is not supported on this platform.
+func fileOwnership(path string, info os.FileInfo) (user, group, seLinuxOptions string) {
+	return "", "", ""
+}
@@ -0,0 +1,144 @@
+package certgraphanalysis
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/openshift/library-go/pkg/crypto"
+)
+
+func TestGatherCertsFromDisk(t *testing.T) {
+	caConfig, err := crypto.MakeSelfSignedCAConfigForDuration("disk-ca", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &crypto.CA{Config: caConfig, SerialGenerator: &crypto.RandomSerialGenerator{}}
+	serving, err := ca.MakeServerCertForDuration(sets.NewString("localhost"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := ca.Config.WriteCertConfigFile(filepath.Join(dir, "pki", "ca.crt"), filepath.Join(dir, "pki", "ca.key")); err != nil {
+		t.Fatal(err)
+	}
+	if err := serving.WriteCertConfigFile(filepath.Join(dir, "serving", "tls.crt"), filepath.Join(dir, "serving", "tls.key")); err != nil {
+		t.Fatal(err)
+	}
+	caBundle, err := crypto.EncodeCertificates(ca.Config.Certs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ca-bundle.crt"), caBundle, 0644); err != nil {
+		t.Fatal(err)
+	}
+	servingCert, servingKey, err := serving.GetPEMBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters["cluster"] = &clientcmdapi.Cluster{Server: "https://localhost:6443", CertificateAuthorityData: caBundle}
+	kubeconfig.AuthInfos["user"] = &clientcmdapi.AuthInfo{ClientCertificateData: servingCert, ClientKeyData: servingKey}
+	if err := clientcmd.WriteToFile(*kubeconfig, filepath.Join(dir, "kubeconfig")); err != nil {
+		t.Fatal(err)
+	}
+	// kubeconfigs are often named by their user, without a YAML extension
+	if err := clientcmd.WriteToFile(*kubeconfig, filepath.Join(dir, "auth", "admin.kubeconfig")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "configmaps.yaml"), []byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    namespace: ns
+    name: ca-bundle
+  data:
+    ca-bundle.crt: |
+`+indent(string(caBundle), "      ")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// symlinked files and directories are followed, loops are walked once
+	externalDir := t.TempDir()
+	configMap := func(namespace string) string {
+		return `apiVersion: v1
+kind: ConfigMap
+metadata:
+  namespace: ` + namespace + `
+  name: ca-bundle
+data:
+  ca-bundle.crt: |
+` + indent(string(caBundle), "    ")
+	}
+	if err := os.WriteFile(filepath.Join(externalDir, "configmaps"), []byte(configMap("external-1")+"---\n"+configMap("external-2")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{
+		"external":           externalDir,
+		"loop":               dir,
+		"ca-bundle-link.crt": filepath.Join(dir, "ca-bundle.crt"),
+	} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pkiList, err := GatherCertsFromDisk(context.TODO(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the CA is paired with its key file, the serving cert is found in its file and the kubeconfigs
+	if len(pkiList.CertKeyPairs.Items) != 2 {
+		t.Fatalf("expected 2 certificates, got %#v", pkiList.CertKeyPairs.Items)
+	}
+	for _, certKeyPair := range pkiList.CertKeyPairs.Items {
+		expectedKeys := map[string]bool{filepath.Join(dir, "pki", "ca.key"): true}
+		if certKeyPair.Spec.Details.SignerDetails == nil {
+			expectedKeys = map[string]bool{
+				filepath.Join(dir, "serving", "tls.key"):       true,
+				filepath.Join(dir, "kubeconfig"):               true,
+				filepath.Join(dir, "auth", "admin.kubeconfig"): true,
+			}
+		}
+		if len(certKeyPair.Spec.OnDiskLocations) != len(expectedKeys) {
+			t.Errorf("expected %d locations of %s, got %#v", len(expectedKeys), certKeyPair.Name, certKeyPair.Spec.OnDiskLocations)
+		}
+		for _, location := range certKeyPair.Spec.OnDiskLocations {
+			if !expectedKeys[location.Key.Path] {
+				t.Errorf("unexpected key of %s in %#v", certKeyPair.Name, location)
+			}
+			if len(location.Cert.Permissions) == 0 {
+				t.Errorf("missing permissions of %s", location.Cert.Path)
+			}
+		}
+	}
+	if len(pkiList.CertificateAuthorityBundles.Items) != 1 {
+		t.Fatalf("expected 1 CA bundle, got %#v", pkiList.CertificateAuthorityBundles.Items)
+	}
+	bundle := pkiList.CertificateAuthorityBundles.Items[0]
+	if len(bundle.Spec.OnDiskLocations) != 4 || len(bundle.Spec.ConfigMapLocations) != 3 {
+		t.Errorf("expected the CA bundle in the file, its symlink, the kubeconfigs and the config maps, got %#v", bundle.Spec)
+	}
+	if len(pkiList.InClusterResourceData.CertificateAuthorityBundles) != 3 {
+		t.Errorf("expected the in-cluster data of the config map, got %#v", pkiList.InClusterResourceData)
+	}
+}
+
+func indent(s, prefix string) string {
+	out := ""
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		out += prefix + line + "\n"
+	}
+	return out
+}
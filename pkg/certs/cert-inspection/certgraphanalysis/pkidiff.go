@@ -0,0 +1,180 @@
+package certgraphanalysis
+
+import (
+	"sort"
+
+	"github.com/openshift/library-go/pkg/certs/cert-inspection/certgraphapi"
+)
+
+// PKIDiff describes the changes between two PKILists, e.g. collected before and after an upgrade.
+type PKIDiff struct {
+	// NewSigners are the IDs of the signer certificates that only exist in the new PKIList.
+	NewSigners []string `json:"newSigners,omitempty"`
+	// ChangedValidities are the locations holding a certificate with a different validity.
+	ChangedValidities []PKIValidityChange `json:"changedValidities,omitempty"`
+	// MissingIssuers are the certificates whose issuer is missing from every CA bundle that contained the issuer before,
+	// and the certificates without such a bundle, e.g. new ones, whose issuer is in no CA bundle at all. Self-signed
+	// certificates are not reported.
+	MissingIssuers []PKIMissingIssuer `json:"missingIssuers,omitempty"`
+}
+
+type PKIValidityChange struct {
+	// Location is the secret or file holding the certificate, formatted like PKIGraphNode.Locations.
+	Location string `json:"location"`
+
+	OldCertificate string `json:"oldCertificate"`
+	OldNotBefore   string `json:"oldNotBefore"`
+	OldNotAfter    string `json:"oldNotAfter"`
+
+	NewCertificate string `json:"newCertificate"`
+	NewNotBefore   string `json:"newNotBefore"`
+	NewNotAfter    string `json:"newNotAfter"`
+}
+
+type PKIMissingIssuer struct {
+	// Certificate is the ID of the certificate in the new PKIList.
+	Certificate string   `json:"certificate"`
+	Locations   []string `json:"locations"`
+	// Issuer is the common name of the issuer of the certificate.
+	Issuer string `json:"issuer"`
+	// CABundles are the locations of the CA bundles that contained the issuer of the certificate in the old PKIList.
+	// It is empty when no CA bundle contained the issuer before.
+	CABundles []string `json:"caBundles"`
+}
+
+// Empty returns true if there are no changes.
+func (d *PKIDiff) Empty() bool {
+	return len(d.NewSigners) == 0 && len(d.ChangedValidities) == 0 && len(d.MissingIssuers) == 0
+}
+
+// DiffPKILists compares two PKILists. Certificates are matched by their locations, so a rotated certificate is
+// reported as a changed validity of its secret or file.
+func DiffPKILists(oldPKIList, newPKIList *certgraphapi.PKIList) *PKIDiff {
+	oldGraph := NewPKIGraph(oldPKIList)
+	newGraph := NewPKIGraph(newPKIList)
+	diff := &PKIDiff{}
+
+	for _, node := range newGraph.Nodes {
+		if node.Certificate == nil || !isSigner(node.Certificate) || oldGraph.Node(node.ID) != nil {
+			continue
+		}
+		diff.NewSigners = append(diff.NewSigners, node.ID)
+	}
+	sort.Strings(diff.NewSigners)
+
+	oldLocations := certificatesByLocation(oldGraph)
+	newLocations := certificatesByLocation(newGraph)
+	locations := []string{}
+	for location := range newLocations {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+	for _, location := range locations {
+		newNode := newLocations[location]
+		oldNode, ok := oldLocations[location]
+		if !ok {
+			continue
+		}
+		if oldNode.Certificate.NotBefore == newNode.Certificate.NotBefore && oldNode.Certificate.NotAfter == newNode.Certificate.NotAfter {
+			continue
+		}
+		diff.ChangedValidities = append(diff.ChangedValidities, PKIValidityChange{
+			Location:       location,
+			OldCertificate: oldNode.ID,
+			OldNotBefore:   oldNode.Certificate.NotBefore,
+			OldNotAfter:    oldNode.Certificate.NotAfter,
+			NewCertificate: newNode.ID,
+			NewNotBefore:   newNode.Certificate.NotBefore,
+			NewNotAfter:    newNode.Certificate.NotAfter,
+		})
+	}
+
+	for _, newNode := range newGraph.Nodes {
+		if newNode.Certificate == nil || len(newNode.Locations) == 0 {
+			continue
+		}
+		// the bundles that trusted the certificate at the same location before
+		referencingBundles := map[string]bool{}
+		for _, location := range newNode.Locations {
+			oldNode, ok := oldLocations[location]
+			if !ok {
+				continue
+			}
+			for _, bundleLocation := range issuerBundleLocations(oldGraph, oldNode) {
+				referencingBundles[bundleLocation] = true
+			}
+		}
+		// without bundles that trusted the certificate before, any bundle containing its issuer will do
+		if len(referencingBundles) == 0 && isSelfSigned(newNode.Certificate) {
+			continue
+		}
+		found := false
+		for _, bundleLocation := range issuerBundleLocations(newGraph, newNode) {
+			if len(referencingBundles) == 0 || referencingBundles[bundleLocation] {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		issuer := ""
+		if newNode.Certificate.CertIdentifier.Issuer != nil {
+			issuer = newNode.Certificate.CertIdentifier.Issuer.CommonName
+		}
+		diff.MissingIssuers = append(diff.MissingIssuers, PKIMissingIssuer{
+			Certificate: newNode.ID,
+			Locations:   newNode.Locations,
+			Issuer:      issuer,
+			CABundles:   sortedKeys(referencingBundles),
+		})
+	}
+
+	return diff
+}
+
+// isSelfSigned returns true if the certificate is its own issuer.
+func isSelfSigned(certificate *certgraphapi.CertKeyMetadata) bool {
+	issuer := certificate.CertIdentifier.Issuer
+	if issuer == nil {
+		return true
+	}
+	if len(issuer.SubjectKeyID) > 0 && len(certificate.CertIdentifier.SubjectKeyID) > 0 {
+		return issuer.SubjectKeyID == certificate.CertIdentifier.SubjectKeyID
+	}
+	return issuer.CommonName == certificate.CertIdentifier.CommonName
+}
+
+// certificatesByLocation returns the certificates of the graph stored in secrets or files by their location.
+func certificatesByLocation(g *PKIGraph) map[string]*PKIGraphNode {
+	ret := map[string]*PKIGraphNode{}
+	for _, node := range g.Nodes {
+		if node.Certificate == nil {
+			continue
+		}
+		for _, location := range node.Locations {
+			ret[location] = node
+		}
+	}
+	return ret
+}
+
+// issuerBundleLocations returns the locations of the CA bundles containing an issuer of the certificate.
+func issuerBundleLocations(g *PKIGraph, node *PKIGraphNode) []string {
+	ret := []string{}
+	for _, issuer := range g.Issuers(node.ID) {
+		for _, bundle := range g.CABundles(issuer.ID) {
+			ret = append(ret, bundle.Locations...)
+		}
+	}
+	return ret
+}
+
+func sortedKeys(in map[string]bool) []string {
+	ret := make([]string, 0, len(in))
+	for key := range in {
+		ret = append(ret, key)
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,264 @@
+package certgraphanalysis
+
+import (
+	"fmt"
+
+	"github.com/gonum/graph"
+	"github.com/gonum/graph/encoding/dot"
+	"github.com/gonum/graph/simple"
+
+	"github.com/openshift/library-go/pkg/certs/cert-inspection/certgraphapi"
+)
+
+type PKIGraphNodeKind string
+
+const (
+	// PKIGraphCertKeyPair is a certificate stored with its key in a secret or on disk.
+	PKIGraphCertKeyPair PKIGraphNodeKind = "CertKeyPair"
+	// PKIGraphCertificate is a certificate only found in CA bundles.
+	PKIGraphCertificate PKIGraphNodeKind = "Certificate"
+	// PKIGraphCABundle is a CA bundle stored in a config map or on disk.
+	PKIGraphCABundle PKIGraphNodeKind = "CABundle"
+)
+
+type PKIGraphEdgeKind string
+
+const (
+	// PKIGraphIssuedBy links a certificate to the certificate of its issuer.
+	PKIGraphIssuedBy PKIGraphEdgeKind = "IssuedBy"
+	// PKIGraphContainedIn links a certificate to the CA bundles containing it.
+	PKIGraphContainedIn PKIGraphEdgeKind = "ContainedIn"
+)
+
+// PKIGraph links the certificates of a PKIList to their issuers and to the CA bundles they are contained in.
+type PKIGraph struct {
+	Nodes []*PKIGraphNode `json:"nodes"`
+	Edges []PKIGraphEdge  `json:"edges"`
+
+	nodes map[string]*PKIGraphNode
+}
+
+type PKIGraphNode struct {
+	// ID is CommonName::SerialNumber for certificates. CA bundles are identified by their first location.
+	ID          string           `json:"id"`
+	Kind        PKIGraphNodeKind `json:"kind"`
+	LogicalName string           `json:"logicalName,omitempty"`
+	// Locations are the secrets, config maps and files holding the node, formatted as secrets/<namespace>/<name>,
+	// configmaps/<namespace>/<name> and the path of the file.
+	Locations []string `json:"locations,omitempty"`
+	// Certificate is set for certificates.
+	Certificate *certgraphapi.CertKeyMetadata `json:"certificate,omitempty"`
+}
+
+type PKIGraphEdge struct {
+	From string           `json:"from"`
+	To   string           `json:"to"`
+	Kind PKIGraphEdgeKind `json:"kind"`
+}
+
+// NewPKIGraph builds the graph of the given PKIList. Certificates are linked to their issuers by the authority and
+// subject key identifiers, or by the common name of the issuer when the certificate has no authority key identifier.
+func NewPKIGraph(pkiList *certgraphapi.PKIList) *PKIGraph {
+	g := &PKIGraph{nodes: map[string]*PKIGraphNode{}}
+
+	for i := range pkiList.CertKeyPairs.Items {
+		certKeyPair := &pkiList.CertKeyPairs.Items[i]
+		locations := []string{}
+		for _, location := range certKeyPair.Spec.SecretLocations {
+			locations = append(locations, fmt.Sprintf("secrets/%s/%s", location.Namespace, location.Name))
+		}
+		for _, location := range certKeyPair.Spec.OnDiskLocations {
+			locations = append(locations, location.Cert.Path)
+		}
+		node := g.certificateNode(certKeyPair.Spec.CertMetadata)
+		node.Kind = PKIGraphCertKeyPair
+		node.LogicalName = certKeyPair.LogicalName
+		node.Locations = append(node.Locations, locations...)
+	}
+
+	for i := range pkiList.CertificateAuthorityBundles.Items {
+		caBundle := &pkiList.CertificateAuthorityBundles.Items[i]
+		locations := []string{}
+		for _, location := range caBundle.Spec.ConfigMapLocations {
+			locations = append(locations, fmt.Sprintf("configmaps/%s/%s", location.Namespace, location.Name))
+		}
+		for _, location := range caBundle.Spec.OnDiskLocations {
+			locations = append(locations, location.Path)
+		}
+		id := caBundle.Name
+		if len(locations) > 0 {
+			id = locations[0]
+		}
+		bundleNode := g.addNode(&PKIGraphNode{ID: id, Kind: PKIGraphCABundle, LogicalName: caBundle.LogicalName, Locations: locations})
+
+		for _, metadata := range caBundle.Spec.CertificateMetadata {
+			node := g.certificateNode(metadata)
+			g.addEdge(node, bundleNode, PKIGraphContainedIn)
+		}
+	}
+
+	bySubjectKeyID := map[string][]*PKIGraphNode{}
+	byCommonName := map[string][]*PKIGraphNode{}
+	for _, node := range g.Nodes {
+		if node.Certificate == nil || !isSigner(node.Certificate) {
+			continue
+		}
+		if len(node.Certificate.CertIdentifier.SubjectKeyID) > 0 {
+			bySubjectKeyID[node.Certificate.CertIdentifier.SubjectKeyID] = append(bySubjectKeyID[node.Certificate.CertIdentifier.SubjectKeyID], node)
+		}
+		byCommonName[node.Certificate.CertIdentifier.CommonName] = append(byCommonName[node.Certificate.CertIdentifier.CommonName], node)
+	}
+	for _, node := range g.Nodes {
+		if node.Certificate == nil || node.Certificate.CertIdentifier.Issuer == nil {
+			continue
+		}
+		issuer := node.Certificate.CertIdentifier.Issuer
+		issuers := byCommonName[issuer.CommonName]
+		if len(issuer.SubjectKeyID) > 0 {
+			issuers = bySubjectKeyID[issuer.SubjectKeyID]
+		}
+		for _, issuerNode := range issuers {
+			// self-signed
+			if issuerNode == node {
+				continue
+			}
+			g.addEdge(node, issuerNode, PKIGraphIssuedBy)
+		}
+	}
+
+	return g
+}
+
+func (g *PKIGraph) certificateNode(metadata certgraphapi.CertKeyMetadata) *PKIGraphNode {
+	id := fmt.Sprintf("%v::%v", metadata.CertIdentifier.CommonName, metadata.CertIdentifier.SerialNumber)
+	if node, ok := g.nodes[id]; ok {
+		return node
+	}
+	certificate := metadata
+	return g.addNode(&PKIGraphNode{ID: id, Kind: PKIGraphCertificate, Certificate: &certificate})
+}
+
+func (g *PKIGraph) addNode(node *PKIGraphNode) *PKIGraphNode {
+	if existing, ok := g.nodes[node.ID]; ok {
+		existing.Locations = append(existing.Locations, node.Locations...)
+		return existing
+	}
+	g.nodes[node.ID] = node
+	g.Nodes = append(g.Nodes, node)
+	return node
+}
+
+func (g *PKIGraph) addEdge(from, to *PKIGraphNode, kind PKIGraphEdgeKind) {
+	edge := PKIGraphEdge{From: from.ID, To: to.ID, Kind: kind}
+	for _, existing := range g.Edges {
+		if existing == edge {
+			return
+		}
+	}
+	g.Edges = append(g.Edges, edge)
+}
+
+// Node returns the node with the given ID, or nil.
+func (g *PKIGraph) Node(id string) *PKIGraphNode {
+	if g.nodes == nil {
+		g.nodes = map[string]*PKIGraphNode{}
+		for _, node := range g.Nodes {
+			g.nodes[node.ID] = node
+		}
+	}
+	return g.nodes[id]
+}
+
+// Issuers returns the certificates that may have issued the certificate with the given ID.
+func (g *PKIGraph) Issuers(id string) []*PKIGraphNode {
+	return g.targets(id, PKIGraphIssuedBy)
+}
+
+// CABundles returns the CA bundles containing the certificate with the given ID.
+func (g *PKIGraph) CABundles(id string) []*PKIGraphNode {
+	return g.targets(id, PKIGraphContainedIn)
+}
+
+func (g *PKIGraph) targets(id string, kind PKIGraphEdgeKind) []*PKIGraphNode {
+	ret := []*PKIGraphNode{}
+	for _, edge := range g.Edges {
+		if edge.From == id && edge.Kind == kind {
+			ret = append(ret, g.Node(edge.To))
+		}
+	}
+	return ret
+}
+
+type pkiGraphNode struct {
+	simple.Node
+	PKIGraphNode *PKIGraphNode
+}
+
+// DOTAttributes implements an attribute getter for the DOT encoding
+func (n pkiGraphNode) DOTAttributes() []dot.Attribute {
+	color := "white"
+	label := n.PKIGraphNode.ID
+	switch {
+	case n.PKIGraphNode.Kind == PKIGraphCABundle:
+		color = `"#bdebfd"` // blue
+	case n.PKIGraphNode.Certificate != nil && isSigner(n.PKIGraphNode.Certificate):
+		color = `"#c8fbcd"` // green
+	case n.PKIGraphNode.Kind == PKIGraphCertKeyPair:
+		color = `"#fffdb8"` // yellow
+	}
+	if n.PKIGraphNode.Certificate != nil {
+		label = fmt.Sprintf("%s\n%s\n%s", label, n.PKIGraphNode.Certificate.NotBefore, n.PKIGraphNode.Certificate.NotAfter)
+	}
+	if len(n.PKIGraphNode.LogicalName) > 0 {
+		label = n.PKIGraphNode.LogicalName + "\n" + label
+	}
+	return []dot.Attribute{
+		{Key: "label", Value: fmt.Sprintf("%q", label)},
+		{Key: "style", Value: "filled"},
+		{Key: "fillcolor", Value: color},
+	}
+}
+
+type pkiGraphEdge struct {
+	simple.Edge
+	Kind PKIGraphEdgeKind
+}
+
+// DOTAttributes implements an attribute getter for the DOT encoding
+func (e pkiGraphEdge) DOTAttributes() []dot.Attribute {
+	if e.Kind == PKIGraphContainedIn {
+		return []dot.Attribute{{Key: "style", Value: "dashed"}}
+	}
+	return nil
+}
+
+// NewGraph returns the graph with an edge from every certificate to its issuers and the CA bundles containing it.
+func (g *PKIGraph) NewGraph() graph.Directed {
+	directed := simple.NewDirectedGraph(1.0, 0.0)
+
+	idToNode := map[string]graph.Node{}
+	for _, node := range g.Nodes {
+		graphNode := pkiGraphNode{Node: simple.Node(directed.NewNodeID()), PKIGraphNode: node}
+		idToNode[node.ID] = graphNode
+		directed.AddNode(graphNode)
+	}
+	for _, edge := range g.Edges {
+		directed.SetEdge(pkiGraphEdge{Edge: simple.Edge{F: idToNode[edge.From], T: idToNode[edge.To]}, Kind: edge.Kind})
+	}
+
+	return directed
+}
+
+// DOT returns the graph in the DOT format of graphviz.
+func (g *PKIGraph) DOT() ([]byte, error) {
+	return dot.Marshal(g.NewGraph(), "pki", "", "  ", false)
+}
+
+func isSigner(metadata *certgraphapi.CertKeyMetadata) bool {
+	for _, usage := range metadata.Usages {
+		if usage == "KeyUsageCertSign" {
+			return true
+		}
+	}
+	return false
+}
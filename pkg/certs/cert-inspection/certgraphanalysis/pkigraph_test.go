@@ -0,0 +1,141 @@
+package certgraphanalysis
+
+import (
+	"crypto/x509"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"github.com/openshift/library-go/pkg/certs/cert-inspection/certgraphapi"
+	"github.com/openshift/library-go/pkg/crypto"
+)
+
+func newTestCA(t *testing.T, name string, lifetime time.Duration) *crypto.CA {
+	t.Helper()
+	caConfig, err := crypto.MakeSelfSignedCAConfigForDuration(name, lifetime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &crypto.CA{Config: caConfig, SerialGenerator: &crypto.RandomSerialGenerator{}}
+}
+
+// newTestPKIList returns the signer in secrets/ns/signer, a serving cert signed by it with the same lifetime in
+// secrets/ns/serving and the bundle in configmaps/ns/ca-bundle.
+func newTestPKIList(t *testing.T, signer *crypto.CA, bundle ...*crypto.CA) *certgraphapi.PKIList {
+	t.Helper()
+	lifetime := signer.Config.Certs[0].NotAfter.Sub(signer.Config.Certs[0].NotBefore)
+	serving, err := signer.MakeServerCertForDuration(sets.NewString("localhost"), lifetime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	servingCertKeyPair, err := toCertKeyPair(serving.Certs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	servingCertKeyPair = addSecretLocation(servingCertKeyPair, "ns", "serving")
+	signerCertKeyPair, err := toCertKeyPair(signer.Config.Certs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signerCertKeyPair = addSecretLocation(signerCertKeyPair, "ns", "signer")
+
+	bundleCerts := []*x509.Certificate{}
+	for _, ca := range bundle {
+		bundleCerts = append(bundleCerts, ca.Config.Certs[0])
+	}
+	caBundle, err := toCABundle(bundleCerts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caBundle = addConfigMapLocation(caBundle, "ns", "ca-bundle")
+
+	return &certgraphapi.PKIList{
+		CertKeyPairs:                certgraphapi.CertKeyPairList{Items: []certgraphapi.CertKeyPair{*signerCertKeyPair, *servingCertKeyPair}},
+		CertificateAuthorityBundles: certgraphapi.CertificateAuthorityBundleList{Items: []certgraphapi.CertificateAuthorityBundle{*caBundle}},
+	}
+}
+
+func TestPKIGraph(t *testing.T) {
+	signer := newTestCA(t, "signer", time.Hour)
+	g := NewPKIGraph(newTestPKIList(t, signer, signer))
+
+	if len(g.Nodes) != 3 {
+		t.Fatalf("expected the signer, serving cert and CA bundle, got %#v", g.Nodes)
+	}
+	var serving *PKIGraphNode
+	for _, node := range g.Nodes {
+		if node.Certificate != nil && !isSigner(node.Certificate) {
+			serving = node
+		}
+	}
+	if serving == nil || len(serving.Locations) != 1 || serving.Locations[0] != "secrets/ns/serving" {
+		t.Fatalf("unexpected serving cert %#v", serving)
+	}
+	issuers := g.Issuers(serving.ID)
+	if len(issuers) != 1 || issuers[0].Kind != PKIGraphCertKeyPair || issuers[0].Locations[0] != "secrets/ns/signer" {
+		t.Fatalf("expected the signer to issue the serving cert, got %#v", issuers)
+	}
+	if len(g.Issuers(issuers[0].ID)) != 0 {
+		t.Errorf("expected no issuer of the self-signed signer")
+	}
+	bundles := g.CABundles(issuers[0].ID)
+	if len(bundles) != 1 || bundles[0].ID != "configmaps/ns/ca-bundle" {
+		t.Errorf("expected the signer in the CA bundle, got %#v", bundles)
+	}
+
+	dot, err := g.DOT()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(dot), "digraph pki {") || !strings.Contains(string(dot), "configmaps/ns/ca-bundle") {
+		t.Errorf("unexpected DOT graph:\n%s", dot)
+	}
+}
+
+func TestDiffPKILists(t *testing.T) {
+	oldSigner := newTestCA(t, "signer", time.Hour)
+	newSigner := newTestCA(t, "signer", 2*time.Hour)
+	oldPKIList := newTestPKIList(t, oldSigner, oldSigner)
+
+	if diff := DiffPKILists(oldPKIList, oldPKIList); !diff.Empty() {
+		t.Errorf("expected no changes, got %#v", diff)
+	}
+
+	// the signer and the serving cert are rotated, but the CA bundle misses the new signer
+	diff := DiffPKILists(oldPKIList, newTestPKIList(t, newSigner, oldSigner))
+	if len(diff.NewSigners) != 1 || !strings.HasPrefix(diff.NewSigners[0], "signer::") {
+		t.Errorf("expected the new signer, got %v", diff.NewSigners)
+	}
+	if len(diff.ChangedValidities) != 2 || diff.ChangedValidities[0].Location != "secrets/ns/serving" || diff.ChangedValidities[1].Location != "secrets/ns/signer" {
+		t.Errorf("expected the validity of the rotated certificates to change, got %#v", diff.ChangedValidities)
+	}
+	if len(diff.MissingIssuers) != 1 || diff.MissingIssuers[0].Locations[0] != "secrets/ns/serving" || diff.MissingIssuers[0].CABundles[0] != "configmaps/ns/ca-bundle" {
+		t.Errorf("expected the issuer of the serving cert to be missing from the CA bundle, got %#v", diff.MissingIssuers)
+	}
+
+	// the new signer is added to the CA bundle
+	diff = DiffPKILists(oldPKIList, newTestPKIList(t, newSigner, oldSigner, newSigner))
+	if len(diff.MissingIssuers) != 0 {
+		t.Errorf("expected no missing issuers, got %#v", diff.MissingIssuers)
+	}
+
+	// a new certificate is issued by a signer that is in no CA bundle
+	unbundledSigner := newTestCA(t, "unbundled-signer", time.Hour)
+	client, err := unbundledSigner.MakeClientCertificateForDuration(&user.DefaultInfo{Name: "client"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCertKeyPair, err := toCertKeyPair(client.Certs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPKIList := newTestPKIList(t, newSigner, oldSigner, newSigner)
+	newPKIList.CertKeyPairs.Items = append(newPKIList.CertKeyPairs.Items, *addSecretLocation(clientCertKeyPair, "ns", "client"))
+	diff = DiffPKILists(oldPKIList, newPKIList)
+	if len(diff.MissingIssuers) != 1 || diff.MissingIssuers[0].Locations[0] != "secrets/ns/client" || diff.MissingIssuers[0].Issuer != "unbundled-signer" || len(diff.MissingIssuers[0].CABundles) != 0 {
+		t.Errorf("expected the issuer of the new client cert to be missing, got %#v", diff.MissingIssuers)
+	}
+}
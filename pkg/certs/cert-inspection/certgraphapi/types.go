@@ -132,12 +132,18 @@ type ClientCertDetails struct {
 type CertIdentifier struct {
 	CommonName   string
 	SerialNumber string
+	// SubjectKeyID is the hex encoded subject key identifier. For the Issuer it is the authority key identifier of the
+	// certificate.  It may be empty.
+	SubjectKeyID string
 
 	Issuer *CertIdentifier
 }
 
 type CertKeyMetadata struct {
-	CertIdentifier     CertIdentifier
+	CertIdentifier CertIdentifier
+	// NotBefore and NotAfter are the validity of the certificate in RFC3339.
+	NotBefore          string
+	NotAfter           string
 	SignatureAlgorithm string
 	PublicKeyAlgorithm string
 	PublicKeyBitSize   string
@@ -57,6 +57,10 @@ type ControllerContext struct {
 
 	// Namespace where the operator runs. Either specified on the command line or autodetected.
 	OperatorNamespace string
+
+	// Shard assigns queue keys to this replica. It is only set in the context passed to the sharded StartFunc of
+	// WithSharding, pass it to factory.Factory.WithSharding for the controllers started there.
+	Shard *Shard
 }
 
 // defaultObserverInterval specifies the default interval that file observer will do rehash the files it watches and react to any changes
@@ -94,6 +98,14 @@ type ControllerBuilder struct {
 	// Keep track if we defaulted leader election, used to make sure we don't stomp on the users intent for leader election
 	// We use this flag to determine at runtime if we can alter leader election for SNO configurations
 	userExplicitlySetLeaderElectionValues bool
+
+	// leaderElectionRecontention keeps the process running when the leadership is lost, see WithLeaderElectionRecontention.
+	leaderElectionRecontention bool
+
+	// shardGroup, shardLeaseDuration and shardedStartFunc configure sharding, see WithSharding.
+	shardGroup         string
+	shardLeaseDuration time.Duration
+	shardedStartFunc   StartFunc
 }
 
 // NewController returns a builder struct for constructing the command you want to run
@@ -157,6 +169,20 @@ func (b *ControllerBuilder) WithLeaderElection(leaderElection configv1.LeaderEle
 	return b
 }
 
+// WithSharding runs shardedStartFunc in every replica, next to the StartFunc, which keeps running only in the
+// elected leader when leader election is configured. The replicas coordinate via Leases in the component namespace
+// and ControllerContext.Shard assigns every queue key to exactly one of them. Keys are rebalanced when replicas join
+// or leave, a replica that stopped renewing its Lease for leaseDuration is considered gone.
+//
+// The controllers started by shardedStartFunc must pass ControllerContext.Shard to factory.Factory.WithSharding,
+// otherwise they run in every replica.
+func (b *ControllerBuilder) WithSharding(group string, leaseDuration time.Duration, shardedStartFunc StartFunc) *ControllerBuilder {
+	b.shardGroup = group
+	b.shardLeaseDuration = leaseDuration
+	b.shardedStartFunc = shardedStartFunc
+	return b
+}
+
 // WithVersion accepts a getting that provide binary version information that is used to report build_info information to prometheus
 func (b *ControllerBuilder) WithVersion(info version.Info) *ControllerBuilder {
 	b.versionInfo = &info
@@ -307,6 +333,10 @@ func (b *ControllerBuilder) Run(ctx context.Context, config *unstructured.Unstru
 		OperatorNamespace: namespace,
 	}
 
+	if len(b.shardGroup) > 0 {
+		b.startSharded(ctx, controllerContext, NewShard(kubeClient.CoordinationV1(), namespace, b.shardGroup, shardIdentity(b.instanceIdentity), b.shardLeaseDuration))
+	}
+
 	if b.leaderElection == nil {
 		if err := b.startFunc(ctx, controllerContext); err != nil {
 			return err
@@ -337,12 +367,28 @@ func (b *ControllerBuilder) Run(ctx context.Context, config *unstructured.Unstru
 	// 10s is the graceful termination time we give the controllers to finish their workers.
 	// when this time pass, we exit with non-zero code, killing all controller workers.
 	// NOTE: The pod must set the termination graceful time.
+	if b.leaderElectionRecontention {
+		return b.runWithLeaderElectionRecontention(ctx, leaderElection, controllerContext, 10*time.Second)
+	}
 	leaderElection.Callbacks.OnStartedLeading = b.getOnStartedLeadingFunc(controllerContext, 10*time.Second)
 
 	leaderelection.RunOrDie(ctx, leaderElection)
 	return nil
 }
 
+// startSharded joins the shard group and runs the sharded StartFunc in the background, independent of leader election.
+func (b ControllerBuilder) startSharded(ctx context.Context, controllerContext *ControllerContext, shard *Shard) {
+	go shard.Run(ctx)
+
+	shardedContext := *controllerContext
+	shardedContext.Shard = shard
+	go func() {
+		if err := b.shardedStartFunc(ctx, &shardedContext); err != nil {
+			b.nonZeroExitFn(fmt.Sprintf("sharded controllers failed with error: %v", err))
+		}
+	}()
+}
+
 func (b ControllerBuilder) getOnStartedLeadingFunc(controllerContext *ControllerContext, gracefulTerminationDuration time.Duration) func(ctx context.Context) {
 	return func(ctx context.Context) {
 		stoppedCh := make(chan struct{})
@@ -63,6 +63,14 @@ type ControllerCommandConfig struct {
 
 	ComponentOwnerReference *corev1.ObjectReference
 	healthChecks            []healthz.HealthChecker
+
+	// leaderElectionRecontention keeps the process running when it loses the leadership, see WithLeaderElectionRecontention.
+	leaderElectionRecontention bool
+
+	// shardGroup, shardLeaseDuration and shardedStartFunc configure sharding, see WithSharding.
+	shardGroup         string
+	shardLeaseDuration time.Duration
+	shardedStartFunc   StartFunc
 }
 
 // NewControllerConfig returns a new ControllerCommandConfig which can be used to wire up all the boiler plate of a controller
@@ -91,6 +99,22 @@ func (c *ControllerCommandConfig) WithHealthChecks(healthChecks ...healthz.Healt
 	return c
 }
 
+// WithLeaderElectionRecontention makes the process contend for the lease again when it loses the leadership instead
+// of exiting, see ControllerBuilder.WithLeaderElectionRecontention.
+func (c *ControllerCommandConfig) WithLeaderElectionRecontention() *ControllerCommandConfig {
+	c.leaderElectionRecontention = true
+	return c
+}
+
+// WithSharding runs shardedStartFunc in every replica and shares the queue keys of its controllers between them,
+// see ControllerBuilder.WithSharding.
+func (c *ControllerCommandConfig) WithSharding(group string, leaseDuration time.Duration, shardedStartFunc StartFunc) *ControllerCommandConfig {
+	c.shardGroup = group
+	c.shardLeaseDuration = leaseDuration
+	c.shardedStartFunc = shardedStartFunc
+	return c
+}
+
 // NewCommand returns a new command that a caller must set the Use and Descriptions on.  It wires default log, profiling,
 // leader election and other "normal" behaviors.
 // Deprecated: Use the NewCommandWithContext instead, this is here to be less disturbing for existing usages.
@@ -315,6 +339,12 @@ func (c *ControllerCommandConfig) StartController(ctx context.Context) error {
 	if !c.DisableServing {
 		builder = builder.WithServer(config.ServingInfo, config.Authentication, config.Authorization)
 	}
+	if c.leaderElectionRecontention {
+		builder = builder.WithLeaderElectionRecontention()
+	}
+	if len(c.shardGroup) > 0 {
+		builder = builder.WithSharding(c.shardGroup, c.shardLeaseDuration, c.shardedStartFunc)
+	}
 
 	return builder.Run(controllerCtx, unstructuredConfig)
 }
@@ -0,0 +1,138 @@
+package controllercmd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/klog/v2"
+)
+
+// WithLeaderElectionRecontention makes the process survive the loss of leadership. Instead of exiting, the context
+// passed to the StartFunc is cancelled and, once the StartFunc returned, the process contends for the lease again
+// while it keeps serving health checks and metrics. The StartFunc is called again on every acquired leadership, so it
+// must not leave global state behind.
+//
+// On shutdown the controllers are stopped first and only then the lease is released, so the next replica can take over
+// after a single retry period instead of waiting for the lease to expire.
+func (b *ControllerBuilder) WithLeaderElectionRecontention() *ControllerBuilder {
+	b.leaderElectionRecontention = true
+	return b
+}
+
+// runWithLeaderElectionRecontention leads with the given config until ctx is cancelled, see WithLeaderElectionRecontention.
+func (b *ControllerBuilder) runWithLeaderElectionRecontention(ctx context.Context, config leaderelection.LeaderElectionConfig, controllerContext *ControllerContext, gracefulTerminationDuration time.Duration) error {
+	for {
+		if err := b.leadOnce(ctx, config, controllerContext, gracefulTerminationDuration); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			controllerContext.EventRecorder.Shutdown()
+			return nil
+		}
+		klog.Warningf("%s lost leadership, contending for the lease again", b.componentName)
+	}
+}
+
+// leadOnce acquires the lease and runs the controllers until the lease is lost or ctx is cancelled. It returns after
+// the controllers stopped and, when ctx was cancelled, the lease was released.
+func (b *ControllerBuilder) leadOnce(ctx context.Context, config leaderelection.LeaderElectionConfig, controllerContext *ControllerContext, gracefulTerminationDuration time.Duration) error {
+	// the elector is not stopped by ctx directly, so that the lease is released only after the controllers stopped
+	electionCtx, cancelElection := context.WithCancel(context.Background())
+	defer cancelElection()
+
+	var lock sync.Mutex
+	started := false
+	leadingDone := make(chan struct{})
+
+	config.ReleaseOnCancel = true
+	config.Callbacks = leaderelection.LeaderCallbacks{
+		OnStartedLeading: func(leaderCtx context.Context) {
+			defer close(leadingDone)
+			lock.Lock()
+			if ctx.Err() != nil {
+				lock.Unlock()
+				cancelElection()
+				return
+			}
+			started = true
+			lock.Unlock()
+
+			controllersCtx, cancelControllers := context.WithCancel(leaderCtx)
+			defer cancelControllers()
+			go func() {
+				select {
+				case <-ctx.Done():
+					cancelControllers()
+				case <-controllersCtx.Done():
+				}
+			}()
+
+			b.runControllersUntilStopped(controllersCtx, controllerContext, gracefulTerminationDuration)
+			// releases the lease when the process is shutting down, when the leadership was lost it was released already
+			cancelElection()
+		},
+		OnStoppedLeading: func() {
+			klog.Infof("%s stopped leading", b.componentName)
+		},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(config)
+	if err != nil {
+		return fmt.Errorf("unable to create leader elector: %w", err)
+	}
+
+	// stop contending for the lease when ctx is cancelled before the leadership was acquired
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-electionCtx.Done():
+			return
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		if !started {
+			cancelElection()
+		}
+	}()
+
+	elector.Run(electionCtx)
+
+	lock.Lock()
+	wasStarted := started
+	lock.Unlock()
+	if wasStarted {
+		// the elector returns as soon as the leadership is lost, wait for the controllers to stop
+		<-leadingDone
+	}
+	return nil
+}
+
+// runControllersUntilStopped runs the StartFunc until ctx is cancelled and waits up to gracefulTerminationDuration for
+// it to return afterwards. Controllers that fail or terminate prematurely exit the process with non-zero code, as do
+// controllers that do not stop in time, because contending again while they are running could result in two leaders.
+func (b *ControllerBuilder) runControllersUntilStopped(ctx context.Context, controllerContext *ControllerContext, gracefulTerminationDuration time.Duration) {
+	stoppedCh := make(chan struct{})
+	go func() {
+		defer close(stoppedCh)
+		if err := b.startFunc(ctx, controllerContext); err != nil {
+			b.nonZeroExitFn(fmt.Sprintf("graceful termination failed, controllers failed with error: %v", err))
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+	case <-stoppedCh:
+		if ctx.Err() == nil {
+			b.nonZeroExitFn("graceful termination failed, controllers terminated prematurely")
+		}
+	}
+
+	select {
+	case <-time.After(gracefulTerminationDuration):
+		b.nonZeroExitFn(fmt.Sprintf("graceful termination failed, some controllers failed to shutdown in %s", gracefulTerminationDuration))
+	case <-stoppedCh:
+	}
+}
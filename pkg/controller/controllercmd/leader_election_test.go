@@ -0,0 +1,104 @@
+package controllercmd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+)
+
+func TestControllerBuilder_LeaderElectionRecontention(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	var failRenewals atomic.Bool
+	kubeClient.PrependReactor("update", "leases", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if failRenewals.Load() {
+			return true, nil, errors.New("apiserver unavailable")
+		}
+		return false, nil, nil
+	})
+
+	config := leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: "test", Name: "test-lock"},
+			Client:     kubeClient.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: "test-identity"},
+		},
+		LeaseDuration: 2 * time.Second,
+		RenewDeadline: time.Second,
+		RetryPeriod:   200 * time.Millisecond,
+	}
+
+	var lock sync.Mutex
+	nonZeroExits := []string{}
+	startedCh := make(chan struct{}, 10)
+	b := &ControllerBuilder{
+		componentName: "test",
+		nonZeroExitFn: func(args ...interface{}) {
+			lock.Lock()
+			defer lock.Unlock()
+			nonZeroExits = append(nonZeroExits, args[0].(string))
+		},
+		startFunc: func(ctx context.Context, controllerContext *ControllerContext) error {
+			startedCh <- struct{}{}
+			<-ctx.Done()
+			return nil
+		},
+	}
+
+	ctx, shutdown := context.WithCancel(context.Background())
+	stoppedCh := make(chan error)
+	go func() {
+		stoppedCh <- b.runWithLeaderElectionRecontention(ctx, config, &ControllerContext{EventRecorder: eventstesting.NewTestingEventRecorder(t)}, 5*time.Second)
+	}()
+
+	waitForStart := func(reason string) {
+		select {
+		case <-startedCh:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("controllers were not started %s", reason)
+		}
+	}
+
+	waitForStart("on the first leadership")
+
+	// lose the leadership, the controllers must be restarted once the lease can be acquired again
+	failRenewals.Store(true)
+	time.Sleep(2 * time.Second)
+	failRenewals.Store(false)
+	waitForStart("after the leadership was lost")
+
+	shutdown()
+	select {
+	case err := <-stoppedCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("unexpected timeout while terminating")
+	}
+
+	lease, err := kubeClient.CoordinationV1().Leases("test").Get(context.TODO(), "test-lock", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.Spec.HolderIdentity != nil && len(*lease.Spec.HolderIdentity) > 0 {
+		t.Errorf("expected the lease to be released on shutdown, got holder %q", *lease.Spec.HolderIdentity)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(nonZeroExits) > 0 {
+		t.Errorf("unexpected non-zero exits: %v", nonZeroExits)
+	}
+}
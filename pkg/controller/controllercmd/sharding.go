@@ -0,0 +1,221 @@
+package controllercmd
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coordinationclientv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+)
+
+// ShardGroupLabel is set on the Leases of all replicas that share the queue keys of a shard group.
+const ShardGroupLabel = "controllercmd.openshift.io/shard-group"
+
+// Shard tracks the members of a shard group and assigns queue keys to them. Every replica maintains its own Lease
+// labeled with ShardGroupLabel. Members whose Lease was not renewed within the lease duration are considered gone.
+// Keys are assigned using rendezvous hashing, so a membership change only moves the keys of the joined or left member.
+type Shard struct {
+	client        coordinationclientv1.LeasesGetter
+	namespace     string
+	group         string
+	identity      string
+	leaseDuration time.Duration
+
+	lock          sync.RWMutex
+	members       []string
+	handlers      []membershipChangeHandler
+	nextHandlerID int
+
+	now func() time.Time
+}
+
+var _ factory.ShardFilter = &Shard{}
+
+// NewShard returns a member of the given shard group. Run must be called to join the group, until then no key is owned.
+func NewShard(client coordinationclientv1.LeasesGetter, namespace, group, identity string, leaseDuration time.Duration) *Shard {
+	return &Shard{
+		client:        client,
+		namespace:     namespace,
+		group:         group,
+		identity:      identity,
+		leaseDuration: leaseDuration,
+		now:           time.Now,
+	}
+}
+
+// Run joins the shard group and keeps the Lease of this member renewed until ctx is cancelled. The Lease is deleted on
+// shutdown, so the other members take over the keys of this member immediately.
+func (s *Shard) Run(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := s.heartbeat(ctx); err != nil {
+			klog.Warningf("Failed to update membership of shard group %q: %v", s.group, err)
+		}
+	}, s.leaseDuration/4)
+
+	deleteCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.client.Leases(s.namespace).Delete(deleteCtx, s.leaseName(), metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		klog.Warningf("Failed to leave shard group %q: %v", s.group, err)
+	}
+}
+
+// OwnsKey returns true when the queue key is assigned to this member.
+func (s *Shard) OwnsKey(key string) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return ownerOf(s.members, key) == s.identity
+}
+
+// Members returns the identities of the live members of the shard group, sorted.
+func (s *Shard) Members() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return append([]string{}, s.members...)
+}
+
+// membershipChangeHandler is a handler registered with AddMembershipChangeHandler.
+type membershipChangeHandler struct {
+	id     int
+	handle func()
+}
+
+// AddMembershipChangeHandler registers a function that is called whenever members join or leave the shard group.
+// The returned function removes the handler again.
+func (s *Shard) AddMembershipChangeHandler(handler func()) func() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	id := s.nextHandlerID
+	s.nextHandlerID++
+	s.handlers = append(s.handlers, membershipChangeHandler{id: id, handle: handler})
+
+	return func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		for i := range s.handlers {
+			if s.handlers[i].id == id {
+				s.handlers = append(s.handlers[:i:i], s.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// heartbeat renews the Lease of this member and refreshes the list of live members.
+func (s *Shard) heartbeat(ctx context.Context) error {
+	if err := s.renew(ctx); err != nil {
+		return err
+	}
+
+	leases, err := s.client.Leases(s.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{ShardGroupLabel: s.group}).String(),
+	})
+	if err != nil {
+		return err
+	}
+	members := []string{}
+	for _, lease := range leases.Items {
+		if lease.Spec.HolderIdentity == nil || lease.Spec.RenewTime == nil {
+			continue
+		}
+		if lease.Spec.RenewTime.Add(s.leaseDuration).Before(s.now()) {
+			continue
+		}
+		members = append(members, *lease.Spec.HolderIdentity)
+	}
+	sort.Strings(members)
+
+	s.lock.Lock()
+	changed := !reflect.DeepEqual(s.members, members)
+	s.members = members
+	handlers := append([]membershipChangeHandler{}, s.handlers...)
+	s.lock.Unlock()
+
+	if changed {
+		klog.Infof("Members of shard group %q changed to %v", s.group, members)
+		for _, handler := range handlers {
+			handler.handle()
+		}
+	}
+	return nil
+}
+
+func (s *Shard) renew(ctx context.Context) error {
+	renewTime := metav1.NewMicroTime(s.now())
+	leaseDurationSeconds := int32(s.leaseDuration.Seconds())
+	existing, err := s.client.Leases(s.namespace).Get(ctx, s.leaseName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = s.client.Leases(s.namespace).Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: s.namespace,
+				Name:      s.leaseName(),
+				Labels:    map[string]string{ShardGroupLabel: s.group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &s.identity,
+				LeaseDurationSeconds: &leaseDurationSeconds,
+				AcquireTime:          &renewTime,
+				RenewTime:            &renewTime,
+			},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	required := existing.DeepCopy()
+	required.Spec.HolderIdentity = &s.identity
+	required.Spec.LeaseDurationSeconds = &leaseDurationSeconds
+	required.Spec.RenewTime = &renewTime
+	_, err = s.client.Leases(s.namespace).Update(ctx, required, metav1.UpdateOptions{})
+	return err
+}
+
+// leaseName returns the name of the Lease of this member. The identity is hashed, it does not need to be a valid name.
+func (s *Shard) leaseName() string {
+	return fmt.Sprintf("%s-%x", s.group, hashOf(s.identity))
+}
+
+// shardIdentity returns the identity if set, or a unique identity for this process otherwise.
+func shardIdentity(identity string) string {
+	if len(identity) > 0 {
+		return identity
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		// add a uniquifier so that two processes on the same host don't accidentally share a shard
+		return hostname + "_" + string(uuid.NewUUID())
+	}
+	return string(uuid.NewUUID())
+}
+
+// ownerOf returns the member with the highest rendezvous hash for the key, or an empty string when there are no members.
+func ownerOf(members []string, key string) string {
+	owner := ""
+	var ownerHash uint64
+	for _, member := range members {
+		if memberHash := hashOf(member + "/" + key); len(owner) == 0 || memberHash > ownerHash {
+			owner, ownerHash = member, memberHash
+		}
+	}
+	return owner
+}
+
+func hashOf(value string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(value))
+	return hash.Sum64()
+}
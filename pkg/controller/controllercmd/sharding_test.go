@@ -0,0 +1,111 @@
+package controllercmd
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestShard(t *testing.T) {
+	ctx := context.TODO()
+	client := fake.NewSimpleClientset()
+	now := time.Now()
+	newTestShard := func(identity string) *Shard {
+		s := NewShard(client.CoordinationV1(), "test", "group", identity, time.Minute)
+		s.now = func() time.Time { return now }
+		return s
+	}
+	first, second := newTestShard("first"), newTestShard("second")
+
+	firstChanges, removedChanges := 0, 0
+	first.AddMembershipChangeHandler(func() { firstChanges++ })
+	removeHandler := first.AddMembershipChangeHandler(func() { removedChanges++ })
+	removeHandler()
+
+	if first.OwnsKey("ns/foo") {
+		t.Errorf("expected no keys to be owned before joining the group")
+	}
+
+	for _, s := range []*Shard{first, second, first} {
+		if err := s.heartbeat(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if members := first.Members(); !reflect.DeepEqual(members, []string{"first", "second"}) {
+		t.Fatalf("unexpected members %v", members)
+	}
+	if firstChanges != 2 {
+		t.Errorf("expected two membership changes, got %d", firstChanges)
+	}
+	if removedChanges != 0 {
+		t.Errorf("expected a removed handler not to be called, got %d calls", removedChanges)
+	}
+
+	owned := map[string]int{}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("ns-%d/foo", i)
+		switch {
+		case first.OwnsKey(key) && second.OwnsKey(key):
+			t.Errorf("key %q is owned by both shards", key)
+		case first.OwnsKey(key):
+			owned["first"]++
+		case second.OwnsKey(key):
+			owned["second"]++
+		default:
+			t.Errorf("key %q is not owned by any shard", key)
+		}
+	}
+	if owned["first"] == 0 || owned["second"] == 0 {
+		t.Errorf("expected keys to be spread across shards, got %v", owned)
+	}
+
+	// the second shard stops renewing its lease and all keys move to the first one
+	now = now.Add(2 * time.Minute)
+	if err := first.heartbeat(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if members := first.Members(); !reflect.DeepEqual(members, []string{"first"}) {
+		t.Fatalf("unexpected members %v", members)
+	}
+	if firstChanges != 3 {
+		t.Errorf("expected three membership changes, got %d", firstChanges)
+	}
+	for i := 0; i < 100; i++ {
+		if key := fmt.Sprintf("ns-%d/foo", i); !first.OwnsKey(key) {
+			t.Errorf("expected key %q to move to the remaining shard", key)
+		}
+	}
+}
+
+func TestShard_RunLeavesGroup(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	s := NewShard(client.CoordinationV1(), "test", "group", "first", time.Minute)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		s.Run(ctx)
+	}()
+	for i := 0; len(s.Members()) == 0; i++ {
+		if i > 100 {
+			t.Fatal("shard did not join the group")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-stopped
+
+	leases, err := client.CoordinationV1().Leases("test").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leases.Items) != 0 {
+		t.Errorf("expected the lease to be deleted on shutdown, got %d leases", len(leases.Items))
+	}
+}
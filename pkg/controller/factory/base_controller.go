@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/robfig/cron"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
@@ -34,15 +37,28 @@ type baseController struct {
 	sync               func(ctx context.Context, controllerContext SyncContext) error
 	syncContext        SyncContext
 	syncDegradedClient operatorv1helpers.OperatorClient
-	resyncEvery        time.Duration
-	resyncSchedules    []cron.Schedule
-	postStartHooks     []PostStartHook
-	cacheSyncTimeout   time.Duration
+	// failingKeys tracks the queue keys whose last sync failed, the degraded condition is cleared only when no key is
+	// failing. When nil, the last sync of any key sets the condition.
+	failingKeys      *failingKeys
+	resyncEvery      time.Duration
+	resyncSchedules  []cron.Schedule
+	postStartHooks   []PostStartHook
+	cacheSyncTimeout time.Duration
+
+	// syncContextForKey returns the SyncContext passed to sync() for a key taken from the queue. When nil, only
+	// string keys are accepted, see TypedFactory for other key types.
+	syncContextForKey func(key interface{}) (SyncContext, error)
+
+	// shard filters the queue keys processed by this replica, see Factory.WithSharding.
+	shard ShardFilter
+	// shardKeys returns the queue keys of all objects in the informer caches, they are queued again when the shard
+	// membership changes.
+	shardKeys func() []interface{}
 }
 
 var _ Controller = &baseController{}
 
-func (c baseController) Name() string {
+func (c *baseController) Name() string {
 	return c.name
 }
 
@@ -97,6 +113,12 @@ func (c *baseController) Run(ctx context.Context, workers int) {
 		}
 	}
 
+	if c.shard != nil {
+		// the controller can be started again, the handler of this run must not requeue keys after it stopped
+		removeHandler := c.shard.AddMembershipChangeHandler(c.requeueShardKeys)
+		defer removeHandler()
+	}
+
 	var workerWg sync.WaitGroup
 	defer func() {
 		defer klog.Infof("All %s workers have been terminated", c.name)
@@ -198,8 +220,10 @@ func (c *baseController) runWorker(queueCtx context.Context) {
 
 // reconcile wraps the sync() call and if operator client is set, it handle the degraded condition if sync() returns an error.
 func (c *baseController) reconcile(ctx context.Context, syncCtx SyncContext) error {
+	start := time.Now()
 	err := c.sync(ctx, syncCtx)
-	degradedErr := c.reportDegraded(ctx, err)
+	controllerMetrics.ObserveSync(c.name, start, err)
+	degradedErr := c.reportDegraded(ctx, syncCtx.QueueKey(), err)
 	if apierrors.IsNotFound(degradedErr) && management.IsOperatorRemovable() {
 		// The operator tolerates missing CR, therefore don't report it up.
 		return err
@@ -213,25 +237,39 @@ func (c *baseController) degradedPanicHandler(panicVal interface{}) {
 		// if we don't have a client for reporting degraded condition, then let the existing panic handler do the work
 		return
 	}
-	_ = c.reportDegraded(context.TODO(), fmt.Errorf("panic caught:\n%v", panicVal))
+	// the key that panicked is not known, the next successful sync clears the condition
+	_ = c.setDegradedCondition(context.TODO(), fmt.Errorf("panic caught:\n%v", panicVal))
 }
 
 // reportDegraded updates status with an indication of degraded-ness
-func (c *baseController) reportDegraded(ctx context.Context, reportedError error) error {
+func (c *baseController) reportDegraded(ctx context.Context, queueKey string, reportedError error) error {
 	if c.syncDegradedClient == nil {
 		return reportedError
 	}
+	conditionErr := reportedError
+	if c.failingKeys != nil {
+		conditionErr = c.failingKeys.observe(queueKey, reportedError)
+	}
+	if err := c.setDegradedCondition(ctx, conditionErr); reportedError == nil {
+		return err
+	}
+	return reportedError
+}
+
+// setDegradedCondition sets the degraded condition to True with the given error, or to False when it is nil.
+// Failures to set the condition to True are only logged, the sync error is reported instead.
+func (c *baseController) setDegradedCondition(ctx context.Context, reportedError error) error {
 	if reportedError != nil {
 		_, _, updateErr := v1helpers.UpdateStatus(ctx, c.syncDegradedClient, v1helpers.UpdateConditionFn(operatorv1.OperatorCondition{
 			Type:    c.name + "Degraded",
 			Status:  operatorv1.ConditionTrue,
-			Reason:  "SyncError",
+			Reason:  degradedReason(reportedError),
 			Message: reportedError.Error(),
 		}))
 		if updateErr != nil {
 			klog.Warningf("Updating status of %q failed: %v", c.Name(), updateErr)
 		}
-		return reportedError
+		return nil
 	}
 	_, _, updateErr := v1helpers.UpdateStatus(ctx, c.syncDegradedClient,
 		v1helpers.UpdateConditionFn(operatorv1.OperatorCondition{
@@ -242,21 +280,140 @@ func (c *baseController) reportDegraded(ctx context.Context, reportedError error
 	return updateErr
 }
 
-func (c *baseController) processNextWorkItem(queueCtx context.Context) {
-	key, quit := c.syncContext.Queue().Get()
-	if quit {
+// requeueShardKeys queues the keys of all objects known to the informers, so that keys which moved to this shard are
+// synced. Keys of deleted objects are not queued again, their deletion was synced by the shard owning them.
+func (c *baseController) requeueShardKeys() {
+	if c.shardKeys == nil {
 		return
 	}
-	defer c.syncContext.Queue().Done(key)
+	for _, key := range c.shardKeys() {
+		c.syncContext.Queue().Add(key)
+	}
+}
 
+// storeInformer is implemented by shared informers, their cache is listed to find the keys to requeue on shard
+// membership changes.
+type storeInformer interface {
+	GetStore() cache.Store
+}
+
+// informerQueueKeys returns the queue keys of all objects in the caches of the informers that pass the filter.
+// Informers that do not expose their cache are skipped.
+func informerQueueKeys[K comparable](informers []Informer, filter EventFilterFunc, queueKeysFn func(runtime.Object) []K) []interface{} {
+	keys := []interface{}{}
+	for _, informer := range informers {
+		withStore, ok := informer.(storeInformer)
+		if !ok {
+			klog.V(4).Infof("Informer %T does not expose its cache, its keys are not queued on shard membership changes", informer)
+			continue
+		}
+		for _, obj := range withStore.GetStore().List() {
+			runtimeObj, ok := obj.(runtime.Object)
+			if !ok || (filter != nil && !filter(obj)) {
+				continue
+			}
+			for _, key := range queueKeysFn(runtimeObj) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// syncContextFor returns the SyncContext for a single sync() of the given queue key.
+func (c *baseController) syncContextFor(key interface{}) (SyncContext, error) {
+	if c.syncContextForKey != nil {
+		return c.syncContextForKey(key)
+	}
 	syncCtx := c.syncContext.(syncContext)
 	var ok bool
 	syncCtx.queueKey, ok = key.(string)
 	if !ok {
-		utilruntime.HandleError(fmt.Errorf("%q controller failed to process key %q (not a string)", c.name, key))
+		return nil, fmt.Errorf("%q controller failed to process key %q (not a string)", c.name, key)
+	}
+	return syncCtx, nil
+}
+
+// maxReportedFailingKeys limits the number of failing keys listed in the degraded condition message.
+const maxReportedFailingKeys = 5
+
+// failingKeys tracks the last error of every failing queue key of a controller.
+type failingKeys struct {
+	lock sync.Mutex
+	errs map[string]error
+}
+
+func newFailingKeys() *failingKeys {
+	return &failingKeys{errs: map[string]error{}}
+}
+
+// observe records the outcome of the sync of key and returns an error describing all failing keys, or nil if no key
+// is failing. The reason of the returned error is the reason of the first failing key.
+func (k *failingKeys) observe(key string, err error) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if err == nil {
+		delete(k.errs, key)
+	} else {
+		k.errs[key] = err
+	}
+	if len(k.errs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(k.errs))
+	for key := range k.errs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if len(keys) == 1 {
+		return NewSyncError(degradedReason(k.errs[keys[0]]), fmt.Errorf("%s: %w", keys[0], k.errs[keys[0]]))
+	}
+	messages := []string{fmt.Sprintf("%d keys failed to sync", len(keys))}
+	for _, key := range keys {
+		if len(messages) > maxReportedFailingKeys {
+			messages = append(messages, fmt.Sprintf("and %d more", len(keys)-maxReportedFailingKeys))
+			break
+		}
+		messages = append(messages, fmt.Sprintf("%s: %v", key, k.errs[key]))
+	}
+	return NewSyncError(degradedReason(k.errs[keys[0]]), errors.New(strings.Join(messages, "\n")))
+}
+
+// degradedReason returns the reason passed to NewSyncError, or "SyncError" for any other error.
+func degradedReason(err error) string {
+	var reasonErr *syncError
+	if errors.As(err, &reasonErr) {
+		return reasonErr.reason
+	}
+	return "SyncError"
+}
+
+func (c *baseController) processNextWorkItem(queueCtx context.Context) {
+	key, quit := c.syncContext.Queue().Get()
+	if quit {
+		return
+	}
+	defer c.syncContext.Queue().Done(key)
+
+	if c.syncContext.Queue().NumRequeues(key) > 0 {
+		controllerMetrics.ObserveRetry(c.name)
+	}
+
+	syncCtx, err := c.syncContextFor(key)
+	if err != nil {
+		utilruntime.HandleError(err)
 		return
 	}
 
+	if c.shard != nil {
+		if !c.shard.OwnsKey(syncCtx.QueueKey()) {
+			klog.V(5).Infof("%q controller skipped key %q owned by another shard", c.name, key)
+			c.syncContext.Queue().Forget(key)
+			return
+		}
+	}
+
 	if err := c.reconcile(queueCtx, syncCtx); err != nil {
 		if err == SyntheticRequeueError {
 			// logging this helps detecting wedged controllers with missing pre-requirements
@@ -9,6 +9,12 @@ import (
 	"testing"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 
 	operatorv1 "github.com/openshift/api/operator/v1"
@@ -184,3 +190,127 @@ func TestBaseController_Run(t *testing.T) {
 		t.Errorf("expected the post start hook to be terminated when context is cancelled")
 	}
 }
+
+type fakeShard struct {
+	sync.Mutex
+	owned    map[string]bool
+	handlers map[int]func()
+	nextID   int
+}
+
+func (s *fakeShard) OwnsKey(key string) bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.owned[key]
+}
+
+func (s *fakeShard) AddMembershipChangeHandler(handler func()) func() {
+	s.Lock()
+	defer s.Unlock()
+	if s.handlers == nil {
+		s.handlers = map[int]func(){}
+	}
+	id := s.nextID
+	s.nextID++
+	s.handlers[id] = handler
+	return func() {
+		s.Lock()
+		defer s.Unlock()
+		delete(s.handlers, id)
+	}
+}
+
+func (s *fakeShard) handlerCount() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.handlers)
+}
+
+// fakeStoreInformer is a fakeInformer with a cache.
+type fakeStoreInformer struct {
+	fakeInformer
+	store cache.Store
+}
+
+func (f *fakeStoreInformer) GetStore() cache.Store {
+	return f.store
+}
+
+func TestBaseController_Sharding(t *testing.T) {
+	shard := &fakeShard{owned: map[string]bool{"mine": true}}
+	informer := &fakeStoreInformer{store: cache.NewStore(cache.MetaNamespaceKeyFunc)}
+	for _, name := range []string{"mine", "theirs"} {
+		informer.store.Add(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	var syncedLock sync.Mutex
+	synced := []string{}
+	c := New().
+		WithSync(func(ctx context.Context, syncCtx SyncContext) error {
+			syncedLock.Lock()
+			defer syncedLock.Unlock()
+			synced = append(synced, syncCtx.QueueKey())
+			return nil
+		}).
+		WithInformersQueueKeysFunc(func(obj runtime.Object) []string {
+			metaObj, _ := meta.Accessor(obj)
+			return []string{metaObj.GetName()}
+		}, informer).
+		WithSharding(shard).
+		ToController("test", eventstesting.NewTestingEventRecorder(t)).(*baseController)
+	shard.AddMembershipChangeHandler(c.requeueShardKeys)
+
+	c.syncContext.Queue().Add("mine")
+	c.syncContext.Queue().Add("theirs")
+	c.syncContext.Queue().Add("deleted")
+	for c.syncContext.Queue().Len() > 0 {
+		c.processNextWorkItem(context.TODO())
+	}
+	if len(synced) != 1 || synced[0] != "mine" {
+		t.Fatalf("expected only the owned key to be synced, got %v", synced)
+	}
+
+	// membership changed and the other keys moved to this shard
+	shard.Lock()
+	shard.owned["theirs"] = true
+	shard.owned["deleted"] = true
+	shard.Unlock()
+	for _, handler := range shard.handlers {
+		handler()
+	}
+	for c.syncContext.Queue().Len() > 0 {
+		c.processNextWorkItem(context.TODO())
+	}
+	if !sets.NewString(synced...).Has("theirs") {
+		t.Errorf("expected the moved key to be synced, got %v", synced)
+	}
+	if sets.NewString(synced...).Has("deleted") {
+		t.Errorf("expected the key of the deleted object not to be queued again, got %v", synced)
+	}
+}
+
+func TestBaseController_RunRemovesMembershipChangeHandler(t *testing.T) {
+	shard := &fakeShard{owned: map[string]bool{}}
+	c := New().
+		WithSync(func(ctx context.Context, syncCtx SyncContext) error { return nil }).
+		WithInformers(&fakeInformer{}).
+		WithSharding(shard).
+		ToController("test", eventstesting.NewTestingEventRecorder(t))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(ctx, 1)
+	}()
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return shard.handlerCount() == 1, nil
+	}); err != nil {
+		t.Fatalf("expected the controller to register a membership change handler: %v", err)
+	}
+	cancel()
+	<-done
+
+	if count := shard.handlerCount(); count != 0 {
+		t.Errorf("expected the membership change handler to be removed after the controller stopped, got %d handlers", count)
+	}
+}
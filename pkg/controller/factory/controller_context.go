@@ -46,6 +46,16 @@ func (c syncContext) Recorder() events.Recorder {
 
 // eventHandler provides default event handler that is added to an informers passed to controller factory.
 func (c syncContext) eventHandler(queueKeysFunc ObjectQueueKeysFunc, filter EventFilterFunc) cache.ResourceEventHandler {
+	return newEventHandler[string](c.queue, queueKeysFunc, filter)
+}
+
+// newEventHandler returns an event handler that adds the keys returned by queueKeysFunc for every observed object to queue.
+func newEventHandler[K comparable](queue workqueue.Interface, queueKeysFunc func(runtime.Object) []K, filter EventFilterFunc) cache.ResourceEventHandler {
+	enqueueKeys := func(keys ...K) {
+		for _, qKey := range keys {
+			queue.Add(qKey)
+		}
+	}
 	resourceEventHandler := cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			runtimeObj, ok := obj.(runtime.Object)
@@ -53,7 +63,7 @@ func (c syncContext) eventHandler(queueKeysFunc ObjectQueueKeysFunc, filter Even
 				utilruntime.HandleError(fmt.Errorf("added object %+v is not runtime Object", obj))
 				return
 			}
-			c.enqueueKeys(queueKeysFunc(runtimeObj)...)
+			enqueueKeys(queueKeysFunc(runtimeObj)...)
 		},
 		UpdateFunc: func(old, new interface{}) {
 			runtimeObj, ok := new.(runtime.Object)
@@ -61,20 +71,20 @@ func (c syncContext) eventHandler(queueKeysFunc ObjectQueueKeysFunc, filter Even
 				utilruntime.HandleError(fmt.Errorf("updated object %+v is not runtime Object", runtimeObj))
 				return
 			}
-			c.enqueueKeys(queueKeysFunc(runtimeObj)...)
+			enqueueKeys(queueKeysFunc(runtimeObj)...)
 		},
 		DeleteFunc: func(obj interface{}) {
 			runtimeObj, ok := obj.(runtime.Object)
 			if !ok {
 				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
-					c.enqueueKeys(queueKeysFunc(tombstone.Obj.(runtime.Object))...)
+					enqueueKeys(queueKeysFunc(tombstone.Obj.(runtime.Object))...)
 
 					return
 				}
 				utilruntime.HandleError(fmt.Errorf("updated object %+v is not runtime Object", runtimeObj))
 				return
 			}
-			c.enqueueKeys(queueKeysFunc(runtimeObj)...)
+			enqueueKeys(queueKeysFunc(runtimeObj)...)
 		},
 	}
 	if filter == nil {
@@ -86,12 +96,6 @@ func (c syncContext) eventHandler(queueKeysFunc ObjectQueueKeysFunc, filter Even
 	}
 }
 
-func (c syncContext) enqueueKeys(keys ...string) {
-	for _, qKey := range keys {
-		c.queue.Add(qKey)
-	}
-}
-
 // namespaceChecker returns a function which returns true if an inpuut obj
 // (or its tombstone) is a namespace  and it matches a name of any namespaces
 // that we are interested in
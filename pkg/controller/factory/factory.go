@@ -38,6 +38,7 @@ type Factory struct {
 	namespaceInformers    []*namespaceInformer
 	cachesToSync          []cache.InformerSynced
 	interestingNamespaces sets.String
+	shard                 ShardFilter
 }
 
 // Informer represents any structure that allow to register event handlers and informs if caches are synced.
@@ -239,6 +240,16 @@ func (f *Factory) WithSyncDegradedOnError(operatorClient operatorv1helpers.Opera
 	return f
 }
 
+// WithSharding makes the controller process only the queue keys owned by this replica. Keys owned by other replicas
+// are dropped from the queue. When the shard membership changes, the keys of all objects in the caches of the
+// informers are queued again, so that keys moved to this replica are synced without waiting for the next informer
+// event. Informers added WithBareInformers and informers that do not expose their cache via GetStore are not listed.
+// Controllers using only the DefaultQueueKey are not worth sharding, all their work happens in a single replica.
+func (f *Factory) WithSharding(shard ShardFilter) *Factory {
+	f.shard = shard
+	return f
+}
+
 // Controller produce a runnable controller.
 func (f *Factory) ToController(name string, eventRecorder events.Recorder) Controller {
 	if f.sync == nil {
@@ -277,6 +288,7 @@ func (f *Factory) ToController(name string, eventRecorder events.Recorder) Contr
 		syncContext:        ctx,
 		postStartHooks:     f.postStartHooks,
 		cacheSyncTimeout:   defaultCacheSyncTimeout,
+		shard:              f.shard,
 	}
 
 	for i := range f.informerQueueKeys {
@@ -305,5 +317,18 @@ func (f *Factory) ToController(name string, eventRecorder events.Recorder) Contr
 		c.cachesToSync = append(c.cachesToSync, f.namespaceInformers[i].informer.HasSynced)
 	}
 
+	if f.shard != nil {
+		c.shardKeys = func() []interface{} {
+			keys := []interface{}{}
+			for _, informers := range f.informerQueueKeys {
+				keys = append(keys, informerQueueKeys(informers.informers, informers.filter, informers.queueKeyFn)...)
+			}
+			if len(f.informers) > 0 || len(f.namespaceInformers) > 0 || f.resyncInterval > 0 || len(f.resyncSchedules) > 0 {
+				keys = append(keys, DefaultQueueKey)
+			}
+			return keys
+		}
+	}
+
 	return c
 }
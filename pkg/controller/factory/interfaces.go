@@ -45,3 +45,14 @@ type SyncContext interface {
 // The syncContext.syncContext passed is the main controller syncContext, when cancelled it means the controller is being shut down.
 // The syncContext provides access to controller name, queue and event recorder.
 type SyncFunc func(ctx context.Context, controllerContext SyncContext) error
+
+// ShardFilter decides which queue keys are processed by this replica when a controller runs in multiple replicas.
+// See controllercmd.ControllerBuilder.WithSharding for an implementation based on Leases.
+type ShardFilter interface {
+	// OwnsKey returns true when the queue key is assigned to this replica.
+	OwnsKey(key string) bool
+
+	// AddMembershipChangeHandler registers a function that is called whenever the assignment of keys to replicas
+	// may have changed. The returned function removes the handler again.
+	AddMembershipChangeHandler(handler func()) (remove func())
+}
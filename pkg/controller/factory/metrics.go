@@ -0,0 +1,129 @@
+package factory
+
+import (
+	"errors"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	// registers the workqueue metrics provider, it reports the depth and latency of the controller queues
+	_ "k8s.io/component-base/metrics/prometheus/workqueue"
+)
+
+const controllerSubsystem = "openshift_controller"
+
+// controllerMetrics provides access to the metrics of all factory based controllers, labeled by controller name.
+var controllerMetrics = newBaseControllerMetrics(legacyregistry.Register)
+
+// baseControllerMetrics instruments the baseController sync loop with prometheus metrics. The queue of every
+// controller is named after the controller, its depth and latency are reported as workqueue_depth and the other
+// workqueue metrics labeled with the controller name.
+type baseControllerMetrics struct {
+	syncDuration       *k8smetrics.HistogramVec
+	syncErrors         *k8smetrics.CounterVec
+	syncRetries        *k8smetrics.CounterVec
+	lastSuccessfulSync *k8smetrics.GaugeVec
+}
+
+func newBaseControllerMetrics(registerFunc func(k8smetrics.Registerable) error) *baseControllerMetrics {
+	syncDuration := k8smetrics.NewHistogramVec(
+		&k8smetrics.HistogramOpts{
+			Subsystem:      controllerSubsystem,
+			Name:           "sync_duration_seconds",
+			Help:           "How long a sync of a controller takes in seconds, labeled with the controller name",
+			Buckets:        k8smetrics.ExponentialBuckets(0.001, 2, 16),
+			StabilityLevel: k8smetrics.ALPHA,
+		}, []string{"name"})
+	registerFunc(syncDuration)
+
+	syncErrors := k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Subsystem:      controllerSubsystem,
+			Name:           "sync_errors_total",
+			Help:           "The total number of failed syncs of a controller, labeled with the controller name and the error reason",
+			StabilityLevel: k8smetrics.ALPHA,
+		}, []string{"name", "reason"})
+	registerFunc(syncErrors)
+
+	syncRetries := k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Subsystem:      controllerSubsystem,
+			Name:           "sync_retries_total",
+			Help:           "The total number of syncs of a controller that retried a previously failed queue key, labeled with the controller name",
+			StabilityLevel: k8smetrics.ALPHA,
+		}, []string{"name"})
+	registerFunc(syncRetries)
+
+	lastSuccessfulSync := k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Subsystem:      controllerSubsystem,
+			Name:           "last_successful_sync_timestamp_seconds",
+			Help:           "The unix timestamp of the last successful sync of a controller, labeled with the controller name",
+			StabilityLevel: k8smetrics.ALPHA,
+		}, []string{"name"})
+	registerFunc(lastSuccessfulSync)
+
+	return &baseControllerMetrics{
+		syncDuration:       syncDuration,
+		syncErrors:         syncErrors,
+		syncRetries:        syncRetries,
+		lastSuccessfulSync: lastSuccessfulSync,
+	}
+}
+
+// ObserveRetry increments the number of syncs of previously failed queue keys.
+func (m *baseControllerMetrics) ObserveRetry(name string) {
+	m.syncRetries.WithLabelValues(name).Inc()
+}
+
+// ObserveSync records the duration and the outcome of a single sync. A SyntheticRequeueError is neither a failed
+// nor a successful sync.
+func (m *baseControllerMetrics) ObserveSync(name string, start time.Time, err error) {
+	m.syncDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	if errors.Is(err, SyntheticRequeueError) {
+		return
+	}
+	if err != nil {
+		m.syncErrors.WithLabelValues(name, SyncErrorReason(err)).Inc()
+		return
+	}
+	m.lastSuccessfulSync.WithLabelValues(name).Set(float64(time.Now().Unix()))
+}
+
+// syncError is an error that carries a machine-readable reason.
+type syncError struct {
+	reason string
+	err    error
+}
+
+func (e *syncError) Error() string {
+	return e.err.Error()
+}
+
+func (e *syncError) Unwrap() error {
+	return e.err
+}
+
+// NewSyncError wraps the error returned from sync() with a CamelCase reason. The reason is used to label the
+// sync_errors_total metric and, if WithSyncDegradedOnError is used, as the reason of the Degraded condition.
+// Keep the set of reasons small, every reason creates a new metric series.
+func NewSyncError(reason string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &syncError{reason: reason, err: err}
+}
+
+// SyncErrorReason returns the reason of an error returned from sync(). It is the reason passed to NewSyncError,
+// the reason of an API status error, or "SyncError" for any other error.
+func SyncErrorReason(err error) string {
+	var reasonErr *syncError
+	if errors.As(err, &reasonErr) {
+		return reasonErr.reason
+	}
+	if reason := apierrors.ReasonForError(err); len(reason) > 0 {
+		return string(reason)
+	}
+	return "SyncError"
+}
@@ -0,0 +1,135 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+)
+
+func TestSyncErrorReason(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedReason string
+	}{
+		{
+			name:           "plain error",
+			err:            errors.New("boom"),
+			expectedReason: "SyncError",
+		},
+		{
+			name:           "sync error",
+			err:            NewSyncError("MissingSecret", errors.New("secret foo not found")),
+			expectedReason: "MissingSecret",
+		},
+		{
+			name:           "wrapped sync error",
+			err:            fmt.Errorf("sync failed: %w", NewSyncError("MissingSecret", errors.New("secret foo not found"))),
+			expectedReason: "MissingSecret",
+		},
+		{
+			name:           "api error",
+			err:            apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "foo"),
+			expectedReason: "NotFound",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if reason := SyncErrorReason(test.err); reason != test.expectedReason {
+				t.Errorf("expected reason %q, got %q", test.expectedReason, reason)
+			}
+		})
+	}
+
+	if NewSyncError("Foo", nil) != nil {
+		t.Errorf("expected nil error to stay nil")
+	}
+}
+
+func TestBaseControllerMetrics(t *testing.T) {
+	registry := testutil.NewFakeKubeRegistry("1.28.0")
+	m := newBaseControllerMetrics(registry.Register)
+
+	m.ObserveSync("test", time.Now(), nil)
+	m.ObserveSync("test", time.Now(), NewSyncError("MissingSecret", errors.New("boom")))
+	m.ObserveSync("test", time.Now(), NewSyncError("MissingSecret", errors.New("boom")))
+	m.ObserveSync("test", time.Now(), SyntheticRequeueError)
+	m.ObserveRetry("test")
+
+	syncCount, err := testutil.GetHistogramMetricCount(m.syncDuration.WithLabelValues("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if syncCount != 4 {
+		t.Errorf("expected 4 observed syncs, got %d", syncCount)
+	}
+
+	for _, counter := range []struct {
+		name     string
+		value    func() (float64, error)
+		expected float64
+	}{
+		{"errors", func() (float64, error) {
+			return testutil.GetCounterMetricValue(m.syncErrors.WithLabelValues("test", "MissingSecret"))
+		}, 2},
+		{"synthetic requeues", func() (float64, error) {
+			return testutil.GetCounterMetricValue(m.syncErrors.WithLabelValues("test", "SyncError"))
+		}, 0},
+		{"retries", func() (float64, error) { return testutil.GetCounterMetricValue(m.syncRetries.WithLabelValues("test")) }, 1},
+	} {
+		value, err := counter.value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != counter.expected {
+			t.Errorf("expected %s %v, got %v", counter.name, counter.expected, value)
+		}
+	}
+
+	lastSync, err := testutil.GetGaugeMetricValue(m.lastSuccessfulSync.WithLabelValues("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastSync == 0 {
+		t.Errorf("expected last successful sync timestamp to be set")
+	}
+}
+
+func TestControllerQueueMetrics(t *testing.T) {
+	c := New().
+		WithSync(func(ctx context.Context, syncCtx SyncContext) error { return nil }).
+		ToController("queue-metrics-test", eventstesting.NewTestingEventRecorder(t)).(*baseController)
+	c.syncContext.Queue().Add("foo")
+	c.syncContext.Queue().Add("bar")
+
+	families, err := legacyregistry.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// other tests of this package create queues too, only look at the queue of this controller
+	var depth *float64
+	for _, family := range families {
+		if family.GetName() != "workqueue_depth" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == "queue-metrics-test" {
+					depth = metric.GetGauge().Value
+				}
+			}
+		}
+	}
+	if depth == nil || *depth != 2 {
+		t.Errorf("expected the workqueue depth of the controller to be 2, got %v", depth)
+	}
+}
@@ -0,0 +1,220 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	operatorv1helpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+)
+
+// TypedSyncContext is the SyncContext given to the Sync() function of controllers produced by TypedFactory.
+type TypedSyncContext[K comparable] interface {
+	SyncContext
+
+	// Key is the typed queue key passed to the Sync function.
+	// QueueKey returns the same key formatted with "%v".
+	Key() K
+}
+
+// TypedSyncFunc is a function that contains the main controller logic for a single typed queue key.
+type TypedSyncFunc[K comparable] func(ctx context.Context, controllerContext TypedSyncContext[K]) error
+
+// TypedObjectQueueKeysFunc is used to make typed work queue keys out of the runtime object that is passed to it.
+type TypedObjectQueueKeysFunc[K comparable] func(runtime.Object) []K
+
+// NamespacedNameQueueKeysFunc returns the namespace and name of the object as the only queue key.
+func NamespacedNameQueueKeysFunc(obj runtime.Object) []types.NamespacedName {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		return nil
+	}
+	return []types.NamespacedName{{Namespace: metaObj.GetNamespace(), Name: metaObj.GetName()}}
+}
+
+// TypedFactory is a generator of controllers that reconcile individual objects. Unlike Factory, where most controllers
+// use the single DefaultQueueKey, the work queue holds keys of type K, typically a struct identifying one object.
+// Equal keys are deduplicated by the queue and failed keys are retried with per-key rate limiting, so a broken
+// object does not delay the reconciliation of the others.
+//
+// Periodic resyncs are not supported as there is no key to enqueue, use the resync period of the informers instead.
+type TypedFactory[K comparable] struct {
+	sync               TypedSyncFunc[K]
+	syncDegradedClient operatorv1helpers.OperatorClient
+	informerQueueKeys  []typedInformersWithQueueKey[K]
+	bareInformers      []Informer
+	postStartHooks     []PostStartHook
+	shard              ShardFilter
+}
+
+type typedInformersWithQueueKey[K comparable] struct {
+	informers  []Informer
+	filter     EventFilterFunc
+	queueKeyFn TypedObjectQueueKeysFunc[K]
+}
+
+// NewTyped returns new typed factory instance.
+func NewTyped[K comparable]() *TypedFactory[K] {
+	return &TypedFactory[K]{}
+}
+
+// WithSync is used to set the controller synchronization function. It is called once for every key taken from the queue.
+func (f *TypedFactory[K]) WithSync(syncFn TypedSyncFunc[K]) *TypedFactory[K] {
+	f.sync = syncFn
+	return f
+}
+
+// WithInformersQueueKeysFunc is used to register event handlers and get the caches synchronized functions.
+// Pass the queueKeyFn you want to use to transform the informer runtime.Object into typed keys used by work queue.
+func (f *TypedFactory[K]) WithInformersQueueKeysFunc(queueKeyFn TypedObjectQueueKeysFunc[K], informers ...Informer) *TypedFactory[K] {
+	return f.WithFilteredEventsInformersQueueKeysFunc(queueKeyFn, nil, informers...)
+}
+
+// WithFilteredEventsInformersQueueKeysFunc is used to register event handlers and get the caches synchronized functions.
+// Pass the queueKeyFn you want to use to transform the informer runtime.Object into typed keys used by work queue.
+// Pass filter to filter out events that should not trigger Sync() call.
+func (f *TypedFactory[K]) WithFilteredEventsInformersQueueKeysFunc(queueKeyFn TypedObjectQueueKeysFunc[K], filter EventFilterFunc, informers ...Informer) *TypedFactory[K] {
+	f.informerQueueKeys = append(f.informerQueueKeys, typedInformersWithQueueKey[K]{
+		informers:  informers,
+		filter:     filter,
+		queueKeyFn: queueKeyFn,
+	})
+	return f
+}
+
+// WithBareInformers allow to register informer that already has custom event handlers registered and no additional
+// event handlers will be added to this informer.
+// The controller will wait for the cache of this informer to be synced.
+func (f *TypedFactory[K]) WithBareInformers(informers ...Informer) *TypedFactory[K] {
+	f.bareInformers = append(f.bareInformers, informers...)
+	return f
+}
+
+// WithPostStartHooks allows to register functions that will run asynchronously after the controller is started via Run command.
+// The queue of the sync context accepts keys of type K.
+func (f *TypedFactory[K]) WithPostStartHooks(hooks ...PostStartHook) *TypedFactory[K] {
+	f.postStartHooks = append(f.postStartHooks, hooks...)
+	return f
+}
+
+// WithSyncDegradedOnError encapsulate the controller sync() function, so when this function return an error, the operator client
+// is used to set the degraded condition to (eg. "ControllerFooDegraded"). The degraded condition name is set based on the controller name.
+// A failure of any key sets the condition, it is cleared once the last sync of every failed key succeeded. The message
+// lists the failing keys.
+func (f *TypedFactory[K]) WithSyncDegradedOnError(operatorClient operatorv1helpers.OperatorClient) *TypedFactory[K] {
+	f.syncDegradedClient = operatorClient
+	return f
+}
+
+// WithSharding makes the controller process only the queue keys owned by this replica, see Factory.WithSharding.
+// Keys are passed to the ShardFilter formatted with "%v".
+func (f *TypedFactory[K]) WithSharding(shard ShardFilter) *TypedFactory[K] {
+	f.shard = shard
+	return f
+}
+
+// ToController produce a runnable controller.
+func (f *TypedFactory[K]) ToController(name string, eventRecorder events.Recorder) Controller {
+	if f.sync == nil {
+		panic(fmt.Errorf("WithSync() must be used before calling ToController() in %q", name))
+	}
+
+	syncCtx := newTypedSyncContext[K](name, eventRecorder)
+	c := &baseController{
+		name:               name,
+		syncDegradedClient: f.syncDegradedClient,
+		failingKeys:        newFailingKeys(),
+		sync: func(ctx context.Context, controllerContext SyncContext) error {
+			typedCtx, ok := controllerContext.(TypedSyncContext[K])
+			if !ok {
+				return fmt.Errorf("%q controller requires a TypedSyncContext, got %T", name, controllerContext)
+			}
+			return f.sync(ctx, typedCtx)
+		},
+		syncContext:      syncCtx,
+		postStartHooks:   f.postStartHooks,
+		cacheSyncTimeout: defaultCacheSyncTimeout,
+		shard:            f.shard,
+		syncContextForKey: func(key interface{}) (SyncContext, error) {
+			typedKey, ok := key.(K)
+			if !ok {
+				return nil, fmt.Errorf("%q controller failed to process key %v (not a %T)", name, key, *new(K))
+			}
+			return syncCtx.withKey(typedKey), nil
+		},
+	}
+
+	for i := range f.informerQueueKeys {
+		for d := range f.informerQueueKeys[i].informers {
+			informer := f.informerQueueKeys[i].informers[d]
+			informer.AddEventHandler(newEventHandler[K](syncCtx.queue, f.informerQueueKeys[i].queueKeyFn, f.informerQueueKeys[i].filter))
+			c.cachesToSync = append(c.cachesToSync, informer.HasSynced)
+		}
+	}
+
+	for i := range f.bareInformers {
+		c.cachesToSync = append(c.cachesToSync, f.bareInformers[i].HasSynced)
+	}
+
+	if f.shard != nil {
+		c.shardKeys = func() []interface{} {
+			keys := []interface{}{}
+			for _, informers := range f.informerQueueKeys {
+				keys = append(keys, informerQueueKeys(informers.informers, informers.filter, informers.queueKeyFn)...)
+			}
+			return keys
+		}
+	}
+
+	return c
+}
+
+// typedSyncContext implements TypedSyncContext.
+type typedSyncContext[K comparable] struct {
+	eventRecorder events.Recorder
+	queue         workqueue.RateLimitingInterface
+	key           K
+}
+
+var _ TypedSyncContext[types.NamespacedName] = typedSyncContext[types.NamespacedName]{}
+
+// NewTypedSyncContext gives new typed sync context for the given key. Its queue holds keys of type K.
+// This is useful during unit testing to exercise the sync of a single key.
+func NewTypedSyncContext[K comparable](name string, recorder events.Recorder, key K) TypedSyncContext[K] {
+	return newTypedSyncContext[K](name, recorder).withKey(key)
+}
+
+func newTypedSyncContext[K comparable](name string, recorder events.Recorder) typedSyncContext[K] {
+	return typedSyncContext[K]{
+		queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), name),
+		eventRecorder: recorder.WithComponentSuffix(strings.ToLower(name)),
+	}
+}
+
+func (c typedSyncContext[K]) Queue() workqueue.RateLimitingInterface {
+	return c.queue
+}
+
+func (c typedSyncContext[K]) QueueKey() string {
+	return fmt.Sprintf("%v", c.key)
+}
+
+func (c typedSyncContext[K]) Key() K {
+	return c.key
+}
+
+func (c typedSyncContext[K]) Recorder() events.Recorder {
+	return c.eventRecorder
+}
+
+// withKey returns a copy of the sync context for the given key.
+func (c typedSyncContext[K]) withKey(key K) typedSyncContext[K] {
+	c.key = key
+	return c
+}
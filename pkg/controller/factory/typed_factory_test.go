@@ -0,0 +1,181 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+)
+
+func TestTypedFactory_ToController(t *testing.T) {
+	informer := &fakeInformer{}
+	c := NewTyped[types.NamespacedName]().
+		WithSync(func(ctx context.Context, controllerContext TypedSyncContext[types.NamespacedName]) error { return nil }).
+		WithInformersQueueKeysFunc(NamespacedNameQueueKeysFunc, informer).
+		ToController("test", eventstesting.NewTestingEventRecorder(t))
+	b := c.(*baseController)
+	if b.Name() != "test" {
+		t.Errorf("expected controller name to be test, got %q", b.name)
+	}
+	for _, cache := range b.cachesToSync {
+		cache()
+	}
+	if informer.hasSyncedCount == 0 {
+		t.Errorf("expected the informer to be registered")
+	}
+
+	// duplicate events for the same object are deduplicated by the queue
+	informer.eventHandler.OnAdd(makeFakeSecret(), false /* isInInitialList */)
+	informer.eventHandler.OnUpdate(makeFakeSecret(), makeFakeSecret())
+	if queueLen := b.syncContext.Queue().Len(); queueLen != 1 {
+		t.Errorf("expected exactly one queued key, got %d", queueLen)
+	}
+	key, _ := b.syncContext.Queue().Get()
+	if key != (types.NamespacedName{Namespace: "test", Name: "test-secret"}) {
+		t.Errorf("unexpected queue key %#v", key)
+	}
+}
+
+func TestTypedController_PerKeySync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	failing := types.NamespacedName{Namespace: "test", Name: "failing"}
+	healthy := types.NamespacedName{Namespace: "test", Name: "healthy"}
+
+	var lock sync.Mutex
+	syncCalls := map[types.NamespacedName]int{}
+	done := make(chan struct{})
+	informer := &fakeInformer{}
+	controller := NewTyped[types.NamespacedName]().
+		WithSync(func(ctx context.Context, syncCtx TypedSyncContext[types.NamespacedName]) error {
+			lock.Lock()
+			defer lock.Unlock()
+			syncCalls[syncCtx.Key()]++
+			if syncCtx.Key() != failing {
+				return nil
+			}
+			if syncCalls[failing] == 3 {
+				close(done)
+			}
+			if syncCalls[failing] >= 3 {
+				return nil
+			}
+			return errors.New("boom")
+		}).
+		WithInformersQueueKeysFunc(NamespacedNameQueueKeysFunc, informer).
+		ToController("TypedController", events.NewInMemoryRecorder("typed-controller"))
+
+	go controller.Run(ctx, 1)
+	for _, key := range []types.NamespacedName{failing, healthy} {
+		informer.eventHandler.OnAdd(&v1.Secret{ObjectMeta: meta.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}, false /* isInInitialList */)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("failing key was not retried")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if syncCalls[healthy] != 1 {
+		t.Errorf("expected healthy key to be synced once, got %d", syncCalls[healthy])
+	}
+}
+
+func TestTypedController_Sync(t *testing.T) {
+	key := types.NamespacedName{Namespace: "test", Name: "foo"}
+	var observed types.NamespacedName
+	controller := NewTyped[types.NamespacedName]().
+		WithSync(func(ctx context.Context, syncCtx TypedSyncContext[types.NamespacedName]) error {
+			observed = syncCtx.Key()
+			return nil
+		}).
+		ToController("test", eventstesting.NewTestingEventRecorder(t))
+
+	if err := controller.Sync(context.TODO(), NewTypedSyncContext("test", eventstesting.NewTestingEventRecorder(t), key)); err != nil {
+		t.Fatal(err)
+	}
+	if observed != key {
+		t.Errorf("expected key %v, got %v", key, observed)
+	}
+	if err := controller.Sync(context.TODO(), NewSyncContext("test", eventstesting.NewTestingEventRecorder(t))); err == nil {
+		t.Errorf("expected untyped sync context to be rejected")
+	}
+}
+
+func TestTypedController_SyncDegradedOnError(t *testing.T) {
+	operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{}, &operatorv1.OperatorStatus{}, nil)
+	failing := map[types.NamespacedName]bool{}
+	controller := NewTyped[types.NamespacedName]().
+		WithSync(func(ctx context.Context, syncCtx TypedSyncContext[types.NamespacedName]) error {
+			if failing[syncCtx.Key()] {
+				return NewSyncError("Boom", errors.New("boom"))
+			}
+			return nil
+		}).
+		WithSyncDegradedOnError(operatorClient).
+		ToController("TypedController", eventstesting.NewTestingEventRecorder(t)).(*baseController)
+
+	first := types.NamespacedName{Namespace: "test", Name: "first"}
+	second := types.NamespacedName{Namespace: "test", Name: "second"}
+	healthy := types.NamespacedName{Namespace: "test", Name: "healthy"}
+	failing[first], failing[second] = true, true
+
+	tests := []struct {
+		name              string
+		key               types.NamespacedName
+		fixed             bool
+		expectedStatus    operatorv1.ConditionStatus
+		expectedInMessage []string
+	}{
+		{name: "first key fails", key: first, expectedStatus: operatorv1.ConditionTrue, expectedInMessage: []string{"test/first: boom"}},
+		{name: "second key fails", key: second, expectedStatus: operatorv1.ConditionTrue, expectedInMessage: []string{"2 keys failed", "test/first: boom", "test/second: boom"}},
+		{name: "healthy key does not clear the condition", key: healthy, expectedStatus: operatorv1.ConditionTrue, expectedInMessage: []string{"2 keys failed"}},
+		{name: "first key recovers", key: first, fixed: true, expectedStatus: operatorv1.ConditionTrue, expectedInMessage: []string{"test/second: boom"}},
+		{name: "second key recovers", key: second, fixed: true, expectedStatus: operatorv1.ConditionFalse},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.fixed {
+				failing[test.key] = false
+			}
+			syncCtx := NewTypedSyncContext("TypedController", eventstesting.NewTestingEventRecorder(t), test.key)
+			if err := controller.reconcile(context.TODO(), syncCtx); (err != nil) != failing[test.key] {
+				t.Errorf("unexpected error %v", err)
+			}
+
+			_, status, _, err := operatorClient.GetOperatorState()
+			if err != nil {
+				t.Fatal(err)
+			}
+			condition := v1helpers.FindOperatorCondition(status.Conditions, "TypedControllerDegraded")
+			if condition == nil || condition.Status != test.expectedStatus {
+				t.Fatalf("expected TypedControllerDegraded to be %s, got %#v", test.expectedStatus, condition)
+			}
+			if test.expectedStatus == operatorv1.ConditionTrue && condition.Reason != "Boom" {
+				t.Errorf("expected reason Boom, got %q", condition.Reason)
+			}
+			for _, expected := range test.expectedInMessage {
+				if !strings.Contains(condition.Message, expected) {
+					t.Errorf("expected %q in message %q", expected, condition.Message)
+				}
+			}
+			if test.key == first && test.fixed && strings.Contains(condition.Message, "test/first") {
+				t.Errorf("expected the recovered key not to be reported, got %q", condition.Message)
+			}
+		})
+	}
+}
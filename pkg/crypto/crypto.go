@@ -4,9 +4,9 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha1"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -434,15 +434,19 @@ func GetTLSCertificateConfigFromBytes(certBytes, keyBytes []byte) (*TLSCertifica
 const (
 	DefaultCertificateLifetimeInDays   = 365 * 2 // 2 years
 	DefaultCACertificateLifetimeInDays = 365 * 5 // 5 years
-
-	// Default keys are 2048 bits
-	keyBits = 2048
 )
 
 type CA struct {
 	Config *TLSCertificateConfig
 
 	SerialGenerator SerialGenerator
+
+	// KeyAlgorithm is the algorithm of the keys generated for certificates issued by this CA.
+	// It defaults to DefaultKeyAlgorithm.
+	KeyAlgorithm KeyAlgorithm
+
+	// Index records the certificates issued by this CA, if set. It is required to revoke certificates.
+	Index CertificateIndex
 }
 
 // SerialGenerator is an interface for getting a serial number for the cert.  It MUST be thread-safe.
@@ -644,17 +648,22 @@ func MakeSelfSignedCAConfigForSubject(subject pkix.Name, expireDays int) (*TLSCe
 	}
 
 	caLifetime := time.Duration(caLifetimeInDays) * 24 * time.Hour
-	return makeSelfSignedCAConfigForSubjectAndDuration(subject, caLifetime)
+	return makeSelfSignedCAConfigForSubjectAndDuration(subject, caLifetime, DefaultKeyAlgorithm)
 }
 
 func MakeSelfSignedCAConfigForDuration(name string, caLifetime time.Duration) (*TLSCertificateConfig, error) {
+	return MakeSelfSignedCAConfigForDurationWithKeyAlgorithm(name, caLifetime, DefaultKeyAlgorithm)
+}
+
+// MakeSelfSignedCAConfigForDurationWithKeyAlgorithm returns a self-signed CA with a key of the given algorithm.
+func MakeSelfSignedCAConfigForDurationWithKeyAlgorithm(name string, caLifetime time.Duration, keyAlgorithm KeyAlgorithm) (*TLSCertificateConfig, error) {
 	subject := pkix.Name{CommonName: name}
-	return makeSelfSignedCAConfigForSubjectAndDuration(subject, caLifetime)
+	return makeSelfSignedCAConfigForSubjectAndDuration(subject, caLifetime, keyAlgorithm)
 }
 
-func makeSelfSignedCAConfigForSubjectAndDuration(subject pkix.Name, caLifetime time.Duration) (*TLSCertificateConfig, error) {
+func makeSelfSignedCAConfigForSubjectAndDuration(subject pkix.Name, caLifetime time.Duration, keyAlgorithm KeyAlgorithm) (*TLSCertificateConfig, error) {
 	// Create CA cert
-	rootcaPublicKey, rootcaPrivateKey, publicKeyHash, err := newKeyPairWithHash()
+	rootcaPublicKey, rootcaPrivateKey, publicKeyHash, err := newKeyPairWithHash(keyAlgorithm)
 	if err != nil {
 		return nil, err
 	}
@@ -675,7 +684,7 @@ func makeSelfSignedCAConfigForSubjectAndDuration(subject pkix.Name, caLifetime t
 
 func MakeCAConfigForDuration(name string, caLifetime time.Duration, issuer *CA) (*TLSCertificateConfig, error) {
 	// Create CA cert
-	signerPublicKey, signerPrivateKey, publicKeyHash, err := newKeyPairWithHash()
+	signerPublicKey, signerPrivateKey, publicKeyHash, err := newKeyPairWithHash(issuer.KeyAlgorithm)
 	if err != nil {
 		return nil, err
 	}
@@ -738,6 +747,7 @@ func (ca *CA) MakeAndWriteSubCA(certFile, keyFile, serialFile, name string, expi
 	return &CA{
 		Config:          subCAConfig,
 		SerialGenerator: serialGenerator,
+		KeyAlgorithm:    ca.KeyAlgorithm,
 	}, nil
 }
 
@@ -789,7 +799,10 @@ func (ca *CA) MakeAndWriteServerCert(certFile, keyFile string, hostnames sets.St
 type CertificateExtensionFunc func(*x509.Certificate) error
 
 func (ca *CA) MakeServerCert(hostnames sets.String, expireDays int, fns ...CertificateExtensionFunc) (*TLSCertificateConfig, error) {
-	serverPublicKey, serverPrivateKey, publicKeyHash, _ := newKeyPairWithHash()
+	serverPublicKey, serverPrivateKey, publicKeyHash, err := newKeyPairWithHash(ca.KeyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
 	authorityKeyId := ca.Config.Certs[0].SubjectKeyId
 	subjectKeyId := publicKeyHash
 	serverTemplate := newServerCertificateTemplate(pkix.Name{CommonName: hostnames.List()[0]}, hostnames.List(), expireDays, time.Now, authorityKeyId, subjectKeyId)
@@ -810,7 +823,10 @@ func (ca *CA) MakeServerCert(hostnames sets.String, expireDays int, fns ...Certi
 }
 
 func (ca *CA) MakeServerCertForDuration(hostnames sets.String, lifetime time.Duration, fns ...CertificateExtensionFunc) (*TLSCertificateConfig, error) {
-	serverPublicKey, serverPrivateKey, publicKeyHash, _ := newKeyPairWithHash()
+	serverPublicKey, serverPrivateKey, publicKeyHash, err := newKeyPairWithHash(ca.KeyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
 	authorityKeyId := ca.Config.Certs[0].SubjectKeyId
 	subjectKeyId := publicKeyHash
 	serverTemplate := newServerCertificateTemplateForDuration(pkix.Name{CommonName: hostnames.List()[0]}, hostnames.List(), lifetime, time.Now, authorityKeyId, subjectKeyId)
@@ -872,7 +888,10 @@ func (ca *CA) MakeClientCertificate(certFile, keyFile string, u user.Info, expir
 		return nil, err
 	}
 
-	clientPublicKey, clientPrivateKey, _ := NewKeyPair()
+	clientPublicKey, clientPrivateKey, err := NewKeyPairForAlgorithm(ca.KeyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
 	clientTemplate := newClientCertificateTemplate(userToSubject(u), expireDays, time.Now)
 	clientCrt, err := ca.signCertificate(clientTemplate, clientPublicKey)
 	if err != nil {
@@ -899,7 +918,10 @@ func (ca *CA) MakeClientCertificate(certFile, keyFile string, u user.Info, expir
 }
 
 func (ca *CA) MakeClientCertificateForDuration(u user.Info, lifetime time.Duration) (*TLSCertificateConfig, error) {
-	clientPublicKey, clientPrivateKey, _ := NewKeyPair()
+	clientPublicKey, clientPrivateKey, err := NewKeyPairForAlgorithm(ca.KeyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
 	clientTemplate := newClientCertificateTemplateForDuration(userToSubject(u), lifetime, time.Now)
 	clientCrt, err := ca.signCertificate(clientTemplate, clientPublicKey)
 	if err != nil {
@@ -968,30 +990,20 @@ func (ca *CA) signCertificate(template *x509.Certificate, requestKey crypto.Publ
 		return nil, err
 	}
 	template.SerialNumber = big.NewInt(serial)
-	return signCertificate(template, requestKey, ca.Config.Certs[0], ca.Config.Key)
-}
-
-func NewKeyPair() (crypto.PublicKey, crypto.PrivateKey, error) {
-	return newRSAKeyPair()
-}
-
-func newKeyPairWithHash() (crypto.PublicKey, crypto.PrivateKey, []byte, error) {
-	publicKey, privateKey, err := newRSAKeyPair()
-	var publicKeyHash []byte
-	if err == nil {
-		hash := sha1.New()
-		hash.Write(publicKey.N.Bytes())
-		publicKeyHash = hash.Sum(nil)
+	cert, err := signCertificate(template, requestKey, ca.Config.Certs[0], ca.Config.Key)
+	if err != nil {
+		return nil, err
+	}
+	if ca.Index != nil {
+		if err := ca.Index.Add(cert); err != nil {
+			return nil, err
+		}
 	}
-	return publicKey, privateKey, publicKeyHash, err
+	return cert, nil
 }
 
-func newRSAKeyPair() (*rsa.PublicKey, *rsa.PrivateKey, error) {
-	privateKey, err := rsa.GenerateKey(rand.Reader, keyBits)
-	if err != nil {
-		return nil, nil, err
-	}
-	return &privateKey.PublicKey, privateKey, nil
+func NewKeyPair() (crypto.PublicKey, crypto.PrivateKey, error) {
+	return NewKeyPairForAlgorithm(DefaultKeyAlgorithm)
 }
 
 // Can be used for CA or intermediate signing certs
@@ -1009,7 +1021,7 @@ func newSigningCertificateTemplateForDuration(subject pkix.Name, caLifetime time
 		// signing certificate is ever rotated.
 		SerialNumber: big.NewInt(randomSerialNumber()),
 
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
 		BasicConstraintsValid: true,
 		IsCA:                  true,
 
@@ -1147,6 +1159,7 @@ func warnAboutCertificateLifeTime(name string, defaultLifetimeInDays int) {
 }
 
 func signCertificate(template *x509.Certificate, requestKey crypto.PublicKey, issuer *x509.Certificate, issuerKey crypto.PrivateKey) (*x509.Certificate, error) {
+	template = templateForKeys(template, requestKey, issuerKey)
 	derBytes, err := x509.CreateCertificate(rand.Reader, template, issuer, requestKey, issuerKey)
 	if err != nil {
 		return nil, err
@@ -1185,6 +1198,14 @@ func encodeKey(key crypto.PrivateKey) ([]byte, error) {
 		if err := pem.Encode(&b, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}); err != nil {
 			return []byte{}, err
 		}
+	case ed25519.PrivateKey:
+		keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return []byte{}, err
+		}
+		if err := pem.Encode(&b, &pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}); err != nil {
+			return []byte{}, err
+		}
 	default:
 		return []byte{}, errors.New("Unrecognized key type")
 
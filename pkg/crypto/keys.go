@@ -0,0 +1,166 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"fmt"
+)
+
+// KeyAlgorithm is the algorithm and size of a generated private key.
+type KeyAlgorithm string
+
+const (
+	RSA2048   KeyAlgorithm = "RSA-2048"
+	RSA3072   KeyAlgorithm = "RSA-3072"
+	RSA4096   KeyAlgorithm = "RSA-4096"
+	ECDSAP256 KeyAlgorithm = "ECDSA-P256"
+	ECDSAP384 KeyAlgorithm = "ECDSA-P384"
+	Ed25519   KeyAlgorithm = "Ed25519"
+
+	// DefaultKeyAlgorithm is used when no key algorithm is specified.
+	DefaultKeyAlgorithm = RSA2048
+)
+
+// SupportedKeyAlgorithms returns all key algorithms that keys can be generated for.
+func SupportedKeyAlgorithms() []KeyAlgorithm {
+	return []KeyAlgorithm{RSA2048, RSA3072, RSA4096, ECDSAP256, ECDSAP384, Ed25519}
+}
+
+// Validate returns an error if keys cannot be generated for the algorithm. The empty algorithm is valid, it stands
+// for DefaultKeyAlgorithm.
+func (a KeyAlgorithm) Validate() error {
+	if len(a) == 0 {
+		return nil
+	}
+	for _, supported := range SupportedKeyAlgorithms() {
+		if a == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported key algorithm %q, supported are %v", a, SupportedKeyAlgorithms())
+}
+
+// orDefault returns DefaultKeyAlgorithm for the empty algorithm.
+func (a KeyAlgorithm) orDefault() KeyAlgorithm {
+	if len(a) == 0 {
+		return DefaultKeyAlgorithm
+	}
+	return a
+}
+
+// KeyAlgorithmOf returns the algorithm of the given public or private key.
+func KeyAlgorithmOf(key interface{}) (KeyAlgorithm, error) {
+	if signer, ok := key.(crypto.Signer); ok {
+		key = signer.Public()
+	}
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		switch key.N.BitLen() {
+		case 2048:
+			return RSA2048, nil
+		case 3072:
+			return RSA3072, nil
+		case 4096:
+			return RSA4096, nil
+		}
+		return "", fmt.Errorf("unsupported RSA key size %d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return ECDSAP256, nil
+		case elliptic.P384():
+			return ECDSAP384, nil
+		}
+		return "", fmt.Errorf("unsupported ECDSA curve %s", key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return Ed25519, nil
+	default:
+		return "", fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// NewKeyPairForAlgorithm generates a new key pair of the given algorithm.
+func NewKeyPairForAlgorithm(algorithm KeyAlgorithm) (crypto.PublicKey, crypto.PrivateKey, error) {
+	switch algorithm.orDefault() {
+	case RSA2048:
+		return newRSAKeyPair(2048)
+	case RSA3072:
+		return newRSAKeyPair(3072)
+	case RSA4096:
+		return newRSAKeyPair(4096)
+	case ECDSAP256:
+		return newECDSAKeyPair(elliptic.P256())
+	case ECDSAP384:
+		return newECDSAKeyPair(elliptic.P384())
+	case Ed25519:
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return publicKey, privateKey, nil
+	default:
+		return nil, nil, algorithm.Validate()
+	}
+}
+
+func newKeyPairWithHash(algorithm KeyAlgorithm) (crypto.PublicKey, crypto.PrivateKey, []byte, error) {
+	publicKey, privateKey, err := NewKeyPairForAlgorithm(algorithm)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	publicKeyHash, err := publicKeyHashOf(publicKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return publicKey, privateKey, publicKeyHash, nil
+}
+
+// publicKeyHashOf returns the subject key id of the public key.
+func publicKeyHashOf(publicKey crypto.PublicKey) ([]byte, error) {
+	hash := sha1.New()
+	if rsaKey, ok := publicKey.(*rsa.PublicKey); ok {
+		// RSA keys keep the historical hash of the modulus
+		hash.Write(rsaKey.N.Bytes())
+		return hash.Sum(nil), nil
+	}
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	hash.Write(publicKeyBytes)
+	return hash.Sum(nil), nil
+}
+
+func newRSAKeyPair(bits int) (*rsa.PublicKey, *rsa.PrivateKey, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &privateKey.PublicKey, privateKey, nil
+}
+
+func newECDSAKeyPair(curve elliptic.Curve) (*ecdsa.PublicKey, *ecdsa.PrivateKey, error) {
+	privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &privateKey.PublicKey, privateKey, nil
+}
+
+// templateForKeys adjusts the templates, which are written for RSA keys, to the keys of the certificate and its issuer.
+// Only RSA keys can encipher keys, and the signature algorithm is picked from the issuer key unless that is an RSA key.
+func templateForKeys(template *x509.Certificate, requestKey crypto.PublicKey, issuerKey crypto.PrivateKey) *x509.Certificate {
+	if _, ok := requestKey.(*rsa.PublicKey); !ok {
+		template.KeyUsage &^= x509.KeyUsageKeyEncipherment
+	}
+	if _, ok := issuerKey.(*rsa.PrivateKey); !ok && template.SignatureAlgorithm == x509.SHA256WithRSA {
+		template.SignatureAlgorithm = x509.UnknownSignatureAlgorithm
+	}
+	return template
+}
@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func TestKeyAlgorithms(t *testing.T) {
+	for _, caAlgorithm := range []KeyAlgorithm{RSA2048, ECDSAP256, Ed25519} {
+		for _, algorithm := range SupportedKeyAlgorithms() {
+			t.Run(string(caAlgorithm)+"/"+string(algorithm), func(t *testing.T) {
+				caConfig, err := MakeSelfSignedCAConfigForDurationWithKeyAlgorithm("ca", time.Hour, caAlgorithm)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ca := &CA{Config: caConfig, SerialGenerator: &RandomSerialGenerator{}, KeyAlgorithm: algorithm}
+				assertKeyAlgorithm(t, caConfig, caAlgorithm, caConfig.Certs[0])
+
+				subCAConfig, err := MakeCAConfigForDuration("sub-ca", time.Hour, ca)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assertKeyAlgorithm(t, subCAConfig, algorithm, caConfig.Certs[0])
+
+				server, err := ca.MakeServerCertForDuration(sets.NewString("foo"), time.Hour)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assertKeyAlgorithm(t, server, algorithm, caConfig.Certs[0])
+
+				client, err := ca.MakeClientCertificateForDuration(&user.DefaultInfo{Name: "user"}, time.Hour)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assertKeyAlgorithm(t, client, algorithm, caConfig.Certs[0])
+			})
+		}
+	}
+}
+
+func assertKeyAlgorithm(t *testing.T, config *TLSCertificateConfig, expected KeyAlgorithm, issuer *x509.Certificate) {
+	t.Helper()
+
+	// the key must survive an encoding round trip
+	certBytes, keyBytes, err := config.GetPEMBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err = GetTLSCertificateConfigFromBytes(certBytes, keyBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert := config.Certs[0]
+	if algorithm, err := KeyAlgorithmOf(cert.PublicKey); err != nil || algorithm != expected {
+		t.Errorf("expected certificate key algorithm %s, got %s (%v)", expected, algorithm, err)
+	}
+	if algorithm, err := KeyAlgorithmOf(config.Key); err != nil || algorithm != expected {
+		t.Errorf("expected private key algorithm %s, got %s (%v)", expected, algorithm, err)
+	}
+	if err := cert.CheckSignatureFrom(issuer); err != nil {
+		t.Errorf("expected certificate to be signed by the issuer: %v", err)
+	}
+	if expected != RSA2048 && expected != RSA3072 && expected != RSA4096 && cert.KeyUsage&x509.KeyUsageKeyEncipherment != 0 {
+		t.Errorf("expected no key encipherment usage for %s keys", expected)
+	}
+}
+
+func TestKeyAlgorithmValidate(t *testing.T) {
+	for _, algorithm := range append(SupportedKeyAlgorithms(), "") {
+		if err := algorithm.Validate(); err != nil {
+			t.Errorf("expected %q to be valid, got %v", algorithm, err)
+		}
+	}
+	if err := KeyAlgorithm("DSA-1024").Validate(); err == nil {
+		t.Errorf("expected an error for an unsupported algorithm")
+	}
+	if _, _, err := NewKeyPairForAlgorithm("DSA-1024"); err == nil {
+		t.Errorf("expected an error for an unsupported algorithm")
+	}
+}
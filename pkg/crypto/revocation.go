@@ -0,0 +1,587 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+	"k8s.io/klog/v2"
+)
+
+// Revocation reasons as defined in RFC 5280, section 5.3.1.
+const (
+	RevocationReasonUnspecified          = ocsp.Unspecified
+	RevocationReasonKeyCompromise        = ocsp.KeyCompromise
+	RevocationReasonCACompromise         = ocsp.CACompromise
+	RevocationReasonAffiliationChanged   = ocsp.AffiliationChanged
+	RevocationReasonSuperseded           = ocsp.Superseded
+	RevocationReasonCessationOfOperation = ocsp.CessationOfOperation
+	RevocationReasonCertificateHold      = ocsp.CertificateHold
+)
+
+// revocationReasonNames are the names OpenSSL uses for the revocation reasons in index files.
+var revocationReasonNames = map[int]string{
+	ocsp.Unspecified:          "unspecified",
+	ocsp.KeyCompromise:        "keyCompromise",
+	ocsp.CACompromise:         "CACompromise",
+	ocsp.AffiliationChanged:   "affiliationChanged",
+	ocsp.Superseded:           "superseded",
+	ocsp.CessationOfOperation: "cessationOfOperation",
+	ocsp.CertificateHold:      "certificateHold",
+}
+
+// oidExtensionReasonCode is the CRL entry extension carrying the revocation reason.
+var oidExtensionReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+// IssuedCertificate is a certificate recorded in a CertificateIndex.
+type IssuedCertificate struct {
+	SerialNumber *big.Int
+	Subject      string
+	NotAfter     time.Time
+	// AuthorityKeyID is the subject key ID of the CA that issued the certificate. It is empty for certificates
+	// recorded before it was tracked, those are attributed to every CA sharing the index.
+	AuthorityKeyID []byte
+
+	Revoked          bool
+	RevokedAt        time.Time
+	RevocationReason int
+}
+
+// CertificateIndex records the certificates issued by a CA and their revocation. It MUST be thread-safe.
+// The index may outlive the CA and be shared with the CAs rotated after it, every certificate records its issuer.
+// Expired certificates are removed when new certificates are added.
+// Only certificates signed by the CA itself are recorded. Certificates issued through the CertificateSigningRequest
+// API, e.g. the client certificates of the csr package, are signed by the kube-controller-manager and cannot be
+// revoked this way.
+type CertificateIndex interface {
+	// Add records a newly issued certificate.
+	Add(cert *x509.Certificate) error
+	// Revoke marks the certificate with the given serial as revoked. Unknown serials are an error.
+	Revoke(serial *big.Int, reason int, revokedAt time.Time) error
+	// Get returns the certificate with the given serial, or nil if it was not issued by the CA.
+	Get(serial *big.Int) (*IssuedCertificate, error)
+	// List returns all recorded certificates ordered by serial.
+	List() ([]IssuedCertificate, error)
+}
+
+// InMemoryCertificateIndex is a CertificateIndex that is lost when the process exits.
+type InMemoryCertificateIndex struct {
+	lock    sync.Mutex
+	entries map[string]IssuedCertificate
+}
+
+var _ CertificateIndex = &InMemoryCertificateIndex{}
+
+func NewInMemoryCertificateIndex() *InMemoryCertificateIndex {
+	return &InMemoryCertificateIndex{entries: map[string]IssuedCertificate{}}
+}
+
+func (i *InMemoryCertificateIndex) Add(cert *x509.Certificate) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	now := time.Now()
+	for serial, entry := range i.entries {
+		if now.After(entry.NotAfter) {
+			delete(i.entries, serial)
+		}
+	}
+	i.entries[cert.SerialNumber.String()] = NewIssuedCertificate(cert)
+	return nil
+}
+
+func (i *InMemoryCertificateIndex) Revoke(serial *big.Int, reason int, revokedAt time.Time) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	entry, ok := i.entries[serial.String()]
+	if !ok {
+		return fmt.Errorf("certificate with serial %X was not issued by this CA", serial)
+	}
+	i.entries[serial.String()] = revoked(entry, reason, revokedAt)
+	return nil
+}
+
+func (i *InMemoryCertificateIndex) Get(serial *big.Int) (*IssuedCertificate, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	entry, ok := i.entries[serial.String()]
+	if !ok {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+func (i *InMemoryCertificateIndex) List() ([]IssuedCertificate, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	entries := make([]IssuedCertificate, 0, len(i.entries))
+	for _, entry := range i.entries {
+		entries = append(entries, entry)
+	}
+	sortIssuedCertificates(entries)
+	return entries, nil
+}
+
+// IndexFileCertificateIndex is a CertificateIndex stored in an OpenSSL compatible index file. It complements the
+// SerialFileGenerator, so that `openssl ca` can be used on the same CA directory.
+type IndexFileCertificateIndex struct {
+	IndexFile string
+
+	// lock guards access to the index file
+	lock sync.Mutex
+}
+
+var _ CertificateIndex = &IndexFileCertificateIndex{}
+
+// NewIndexFileCertificateIndex returns an index stored in indexFile. The file is created on the first issued
+// certificate if it does not exist yet.
+func NewIndexFileCertificateIndex(indexFile string) (*IndexFileCertificateIndex, error) {
+	index := &IndexFileCertificateIndex{IndexFile: indexFile}
+	// fail early on a corrupted file
+	if _, err := index.read(); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func (i *IndexFileCertificateIndex) Add(cert *x509.Certificate) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	entries, err := i.read()
+	if err != nil {
+		return err
+	}
+	entries = append(RemoveExpiredCertificates(entries, time.Now()), NewIssuedCertificate(cert))
+	return i.write(entries)
+}
+
+func (i *IndexFileCertificateIndex) Revoke(serial *big.Int, reason int, revokedAt time.Time) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	entries, err := i.read()
+	if err != nil {
+		return err
+	}
+	for j := range entries {
+		if entries[j].SerialNumber.Cmp(serial) == 0 {
+			entries[j] = revoked(entries[j], reason, revokedAt)
+			return i.write(entries)
+		}
+	}
+	return fmt.Errorf("certificate with serial %X was not issued by this CA", serial)
+}
+
+func (i *IndexFileCertificateIndex) Get(serial *big.Int) (*IssuedCertificate, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	entries, err := i.read()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.SerialNumber.Cmp(serial) == 0 {
+			return &entry, nil
+		}
+	}
+	return nil, nil
+}
+
+func (i *IndexFileCertificateIndex) List() ([]IssuedCertificate, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	entries, err := i.read()
+	if err != nil {
+		return nil, err
+	}
+	sortIssuedCertificates(entries)
+	return entries, nil
+}
+
+// read parses the index file.
+func (i *IndexFileCertificateIndex) read() ([]IssuedCertificate, error) {
+	data, err := os.ReadFile(i.IndexFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ParseCertificateIndex(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid index file %s: %v", i.IndexFile, err)
+	}
+	return entries, nil
+}
+
+// write replaces the index file atomically, so that a crash does not leave a truncated index behind.
+func (i *IndexFileCertificateIndex) write(entries []IssuedCertificate) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(i.IndexFile), filepath.Base(i.IndexFile)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(FormatCertificateIndex(entries)); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Chmod(os.FileMode(0640)); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), i.IndexFile)
+}
+
+// NewIssuedCertificate returns the index entry of a newly issued certificate.
+func NewIssuedCertificate(cert *x509.Certificate) IssuedCertificate {
+	return IssuedCertificate{
+		SerialNumber:   new(big.Int).Set(cert.SerialNumber),
+		Subject:        cert.Subject.String(),
+		NotAfter:       cert.NotAfter,
+		AuthorityKeyID: append([]byte(nil), cert.AuthorityKeyId...),
+	}
+}
+
+// RemoveExpiredCertificates returns the entries that have not expired at the given time.
+func RemoveExpiredCertificates(entries []IssuedCertificate, now time.Time) []IssuedCertificate {
+	valid := make([]IssuedCertificate, 0, len(entries))
+	for _, entry := range entries {
+		if !now.After(entry.NotAfter) {
+			valid = append(valid, entry)
+		}
+	}
+	return valid
+}
+
+// issuedBy returns whether the entry was issued by the given CA certificate. Entries without an authority key ID
+// are attributed to every CA.
+func (c IssuedCertificate) issuedBy(caCert *x509.Certificate) bool {
+	return len(c.AuthorityKeyID) == 0 || bytes.Equal(c.AuthorityKeyID, caCert.SubjectKeyId)
+}
+
+// ParseCertificateIndex parses an OpenSSL index file. Every line has the tab separated fields status (V, R or E),
+// expiration time, revocation time and reason, serial in hex, file name and subject. OpenSSL does not use the file
+// name and sets it to "unknown", this package stores the authority key ID of the certificate in hex there.
+func ParseCertificateIndex(data []byte) ([]IssuedCertificate, error) {
+	entries := []IssuedCertificate{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		entry, err := parseIndexLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid line %d: %v", lineNumber, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// FormatCertificateIndex formats the entries as an OpenSSL index file, see ParseCertificateIndex.
+func FormatCertificateIndex(entries []IssuedCertificate) []byte {
+	b := bytes.Buffer{}
+	for _, entry := range entries {
+		status, revocation := "V", ""
+		if entry.Revoked {
+			status = "R"
+			revocation = formatIndexTime(entry.RevokedAt)
+			if name, ok := revocationReasonNames[entry.RevocationReason]; ok && entry.RevocationReason != ocsp.Unspecified {
+				revocation += "," + name
+			}
+		}
+		fileName := "unknown"
+		if len(entry.AuthorityKeyID) > 0 {
+			fileName = strings.ToUpper(hex.EncodeToString(entry.AuthorityKeyID))
+		}
+		subject := strings.ReplaceAll(entry.Subject, "\t", " ")
+		fmt.Fprintf(&b, "%s\t%s\t%s\t%s\t%s\t%s\n", status, formatIndexTime(entry.NotAfter), revocation, serialToHex(entry.SerialNumber), fileName, subject)
+	}
+	return b.Bytes()
+}
+
+func parseIndexLine(line string) (IssuedCertificate, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != 6 {
+		return IssuedCertificate{}, fmt.Errorf("expected 6 fields, got %d", len(fields))
+	}
+	entry := IssuedCertificate{Subject: fields[5]}
+
+	var ok bool
+	if entry.SerialNumber, ok = new(big.Int).SetString(fields[3], 16); !ok {
+		return entry, fmt.Errorf("invalid serial %q", fields[3])
+	}
+	var err error
+	if entry.NotAfter, err = parseIndexTime(fields[1]); err != nil {
+		return entry, err
+	}
+	// the file name is "unknown" in files written by OpenSSL
+	if fields[4] != "unknown" {
+		if entry.AuthorityKeyID, err = hex.DecodeString(fields[4]); err != nil {
+			return entry, fmt.Errorf("invalid authority key ID %q", fields[4])
+		}
+	}
+
+	switch fields[0] {
+	case "V", "E":
+	case "R":
+		entry.Revoked = true
+		revokedAt, reasonName, _ := strings.Cut(fields[2], ",")
+		if entry.RevokedAt, err = parseIndexTime(revokedAt); err != nil {
+			return entry, err
+		}
+		entry.RevocationReason = ocsp.Unspecified
+		for reason, name := range revocationReasonNames {
+			if strings.EqualFold(name, reasonName) {
+				entry.RevocationReason = reason
+			}
+		}
+	default:
+		return entry, fmt.Errorf("unknown status %q", fields[0])
+	}
+	return entry, nil
+}
+
+// formatIndexTime formats the time as ASN.1 UTCTime like OpenSSL does, GeneralizedTime is used from 2050 on.
+func formatIndexTime(t time.Time) string {
+	if t.UTC().Year() >= 2050 {
+		return t.UTC().Format("20060102150405Z")
+	}
+	return t.UTC().Format("060102150405Z")
+}
+
+func parseIndexTime(value string) (time.Time, error) {
+	if len(value) == len("20060102150405Z") {
+		return time.Parse("20060102150405Z", value)
+	}
+	return time.Parse("060102150405Z", value)
+}
+
+// serialToHex formats the serial like SerialFileGenerator does, padded to multiples of two characters.
+func serialToHex(serial *big.Int) string {
+	serialText := fmt.Sprintf("%X", serial)
+	if len(serialText)%2 == 1 {
+		serialText = "0" + serialText
+	}
+	return serialText
+}
+
+func revoked(entry IssuedCertificate, reason int, revokedAt time.Time) IssuedCertificate {
+	entry.Revoked = true
+	entry.RevokedAt = revokedAt
+	entry.RevocationReason = reason
+	return entry
+}
+
+func sortIssuedCertificates(entries []IssuedCertificate) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].SerialNumber.Cmp(entries[j].SerialNumber) < 0
+	})
+}
+
+// Revoke marks the certificate with the given serial as revoked. It requires the CA to record issued certificates
+// in an Index.
+func (ca *CA) Revoke(serial *big.Int, reason int) error {
+	if ca.Index == nil {
+		return errors.New("the CA does not record issued certificates")
+	}
+	if _, ok := revocationReasonNames[reason]; !ok {
+		return fmt.Errorf("invalid revocation reason %d", reason)
+	}
+	return ca.Index.Revoke(serial, reason, time.Now())
+}
+
+// MakeCRLForDuration returns a PEM encoded certificate revocation list of all certificates in the Index that were
+// issued by this CA, are revoked and not expired yet. The CRL is valid for the given lifetime and must be regenerated
+// before, or whenever a certificate is revoked.
+//
+// The CA certificate must allow signing CRLs. CAs created by this package before KeyUsageCRLSign was added to
+// the signing template lack it and have to be rotated first.
+func (ca *CA) MakeCRLForDuration(lifetime time.Duration) ([]byte, error) {
+	if ca.Index == nil {
+		return nil, errors.New("the CA does not record issued certificates")
+	}
+	caCert := ca.Config.Certs[0]
+	if caCert.KeyUsage&x509.KeyUsageCRLSign == 0 {
+		return nil, fmt.Errorf("the CA certificate %q is not allowed to sign CRLs", caCert.Subject.CommonName)
+	}
+	signer, ok := ca.Config.Key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("the CA key of type %T cannot sign", ca.Config.Key)
+	}
+	issued, err := ca.Index.List()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	revokedCerts := []pkix.RevokedCertificate{}
+	for _, cert := range issued {
+		if !cert.Revoked || now.After(cert.NotAfter) || !cert.issuedBy(caCert) {
+			continue
+		}
+		reasonCode, err := asn1.Marshal(asn1.Enumerated(cert.RevocationReason))
+		if err != nil {
+			return nil, err
+		}
+		revokedCerts = append(revokedCerts, pkix.RevokedCertificate{
+			SerialNumber:   cert.SerialNumber,
+			RevocationTime: cert.RevokedAt.UTC(),
+			Extensions:     []pkix.Extension{{Id: oidExtensionReasonCode, Value: reasonCode}},
+		})
+	}
+
+	template := &x509.RevocationList{
+		// CRL numbers must increase monotonically, which the current time does without persisting any state
+		Number:              big.NewInt(now.UnixNano()),
+		ThisUpdate:          now.Add(-1 * time.Second),
+		NextUpdate:          now.Add(lifetime),
+		RevokedCertificates: revokedCerts,
+	}
+	derBytes, err := x509.CreateRevocationList(rand.Reader, template, caCert, signer)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: derBytes}), nil
+}
+
+// OCSPResponder returns an HTTP handler answering OCSP requests (RFC 6960) for the certificates in the Index, in both
+// the GET and POST form. The responses are signed with the CA key and are valid for the given lifetime. Serials not
+// in the Index are reported as unknown. The handler must be served at the root path, use http.StripPrefix otherwise.
+func (ca *CA) OCSPResponder(lifetime time.Duration) http.Handler {
+	return &ocspResponder{ca: ca, lifetime: lifetime}
+}
+
+type ocspResponder struct {
+	ca       *CA
+	lifetime time.Duration
+}
+
+func (r *ocspResponder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var requestBytes []byte
+	var err error
+	switch req.Method {
+	case http.MethodGet:
+		var encoded string
+		// the base64 encoded request may contain slashes, the whole path is the request
+		if encoded, err = url.PathUnescape(strings.TrimPrefix(req.URL.EscapedPath(), "/")); err == nil {
+			requestBytes, err = base64.StdEncoding.DecodeString(encoded)
+		}
+	case http.MethodPost:
+		requestBytes, err = io.ReadAll(io.LimitReader(req.Body, 10*1024))
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		writeOCSPResponse(w, ocsp.MalformedRequestErrorResponse)
+		return
+	}
+
+	response, err := r.respond(requestBytes)
+	if err != nil {
+		klog.V(2).Infof("Failed to answer OCSP request: %v", err)
+		writeOCSPResponse(w, ocsp.InternalErrorErrorResponse)
+		return
+	}
+	writeOCSPResponse(w, response)
+}
+
+func (r *ocspResponder) respond(requestBytes []byte) ([]byte, error) {
+	request, err := ocsp.ParseRequest(requestBytes)
+	if err != nil {
+		return ocsp.MalformedRequestErrorResponse, nil
+	}
+	if r.ca.Index == nil {
+		return ocsp.UnauthorizedErrorResponse, nil
+	}
+	caCert := r.ca.Config.Certs[0]
+	signer, ok := r.ca.Config.Key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("the CA key of type %T cannot sign", r.ca.Config.Key)
+	}
+
+	now := time.Now()
+	template := ocsp.Response{
+		SerialNumber: request.SerialNumber,
+		Status:       ocsp.Unknown,
+		ThisUpdate:   now.Add(-1 * time.Second),
+		NextUpdate:   now.Add(r.lifetime),
+		IssuerHash:   request.HashAlgorithm,
+	}
+	// the request must be for certificates issued by this CA
+	if issuerMatches, err := ocspIssuerMatches(request, caCert); err != nil || !issuerMatches {
+		return ocsp.UnauthorizedErrorResponse, nil
+	}
+
+	issued, err := r.ca.Index.Get(request.SerialNumber)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case issued == nil, !issued.issuedBy(caCert):
+		// serials of other CAs sharing the index are unknown to this one
+	case issued.Revoked:
+		template.Status = ocsp.Revoked
+		template.RevokedAt = issued.RevokedAt
+		template.RevocationReason = issued.RevocationReason
+	default:
+		template.Status = ocsp.Good
+	}
+	return ocsp.CreateResponse(caCert, caCert, template, signer)
+}
+
+// ocspIssuerMatches compares the issuer name and key hashes of the request with the given issuer.
+func ocspIssuerMatches(request *ocsp.Request, issuer *x509.Certificate) (bool, error) {
+	if !request.HashAlgorithm.Available() {
+		return false, fmt.Errorf("unsupported hash algorithm %v", request.HashAlgorithm)
+	}
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return false, err
+	}
+
+	hash := request.HashAlgorithm.New()
+	hash.Write(issuer.RawSubject)
+	nameHash := hash.Sum(nil)
+	hash.Reset()
+	hash.Write(publicKeyInfo.PublicKey.RightAlign())
+	keyHash := hash.Sum(nil)
+
+	return bytes.Equal(nameHash, request.IssuerNameHash) && bytes.Equal(keyHash, request.IssuerKeyHash), nil
+}
+
+func writeOCSPResponse(w http.ResponseWriter, response []byte) {
+	w.Header().Set("Content-Type", "application/ocsp-response")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
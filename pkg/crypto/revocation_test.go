@@ -0,0 +1,330 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func newTestRevocationCA(t *testing.T, index CertificateIndex) *CA {
+	t.Helper()
+	caConfig, err := MakeSelfSignedCAConfigForDurationWithKeyAlgorithm("ca", time.Hour, ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &CA{Config: caConfig, SerialGenerator: &RandomSerialGenerator{}, KeyAlgorithm: ECDSAP256, Index: index}
+}
+
+func TestCRL(t *testing.T) {
+	indexFile, err := NewIndexFileCertificateIndex(filepath.Join(t.TempDir(), "index.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, index := range map[string]CertificateIndex{
+		"in-memory":  NewInMemoryCertificateIndex(),
+		"index file": indexFile,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ca := newTestRevocationCA(t, index)
+			leaked, err := ca.MakeClientCertificateForDuration(&user.DefaultInfo{Name: "leaked"}, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ca.MakeServerCertForDuration(sets.NewString("foo"), time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			if err := ca.Revoke(leaked.Certs[0].SerialNumber, RevocationReasonKeyCompromise); err != nil {
+				t.Fatal(err)
+			}
+			if err := ca.Revoke(big.NewInt(42), RevocationReasonKeyCompromise); err == nil {
+				t.Errorf("expected an error revoking a certificate that was not issued by the CA")
+			}
+
+			issued, err := index.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(issued) != 2 {
+				t.Fatalf("expected 2 issued certificates, got %d", len(issued))
+			}
+
+			crlPEM, err := ca.MakeCRLForDuration(time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			block, _ := pem.Decode(crlPEM)
+			if block == nil || block.Type != "X509 CRL" {
+				t.Fatalf("expected a PEM encoded CRL, got %q", string(crlPEM))
+			}
+			crl, err := x509.ParseRevocationList(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := crl.CheckSignatureFrom(ca.Config.Certs[0]); err != nil {
+				t.Errorf("expected the CRL to be signed by the CA: %v", err)
+			}
+			if !crl.NextUpdate.After(time.Now().Add(59 * time.Minute)) {
+				t.Errorf("unexpected next update %v", crl.NextUpdate)
+			}
+			if len(crl.RevokedCertificates) != 1 || crl.RevokedCertificates[0].SerialNumber.Cmp(leaked.Certs[0].SerialNumber) != 0 {
+				t.Fatalf("expected only the leaked certificate to be revoked, got %v", crl.RevokedCertificates)
+			}
+		})
+	}
+}
+
+func TestIndexFileCertificateIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.txt")
+	index, err := NewIndexFileCertificateIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := newTestRevocationCA(t, index)
+	cert, err := ca.MakeClientCertificateForDuration(&user.DefaultInfo{Name: "client"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ca.Revoke(cert.Certs[0].SerialNumber, RevocationReasonSuperseded); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new index on the same file must see the revocation
+	reloaded, err := NewIndexFileCertificateIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued, err := reloaded.Get(cert.Certs[0].SerialNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued == nil || !issued.Revoked || issued.RevocationReason != RevocationReasonSuperseded || issued.Subject != "CN=client" {
+		t.Errorf("unexpected entry %#v", issued)
+	}
+	if !issued.NotAfter.Equal(cert.Certs[0].NotAfter) {
+		t.Errorf("expected not after %v, got %v", cert.Certs[0].NotAfter, issued.NotAfter)
+	}
+
+	// the index is replaced by a rename, no temporary files are left behind
+	files, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "index.txt" {
+		t.Errorf("expected only the index file, got %v", files)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0640 {
+		t.Errorf("expected mode 0640, got %v", info.Mode().Perm())
+	}
+
+	if _, err := NewIndexFileCertificateIndex(filepath.Join("testfiles", "does-not-exist")); err != nil {
+		t.Errorf("expected a missing index file to be empty, got %v", err)
+	}
+}
+
+func TestOCSPResponder(t *testing.T) {
+	ca := newTestRevocationCA(t, NewInMemoryCertificateIndex())
+	good, err := ca.MakeClientCertificateForDuration(&user.DefaultInfo{Name: "good"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := ca.MakeClientCertificateForDuration(&user.DefaultInfo{Name: "revoked"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ca.Revoke(revoked.Certs[0].SerialNumber, RevocationReasonKeyCompromise); err != nil {
+		t.Fatal(err)
+	}
+	otherCA := newTestRevocationCA(t, NewInMemoryCertificateIndex())
+	other, err := otherCA.MakeClientCertificateForDuration(&user.DefaultInfo{Name: "other"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(ca.OCSPResponder(time.Hour))
+	defer server.Close()
+
+	query := func(t *testing.T, method string, cert, issuer *x509.Certificate) []byte {
+		t.Helper()
+		request, err := ocsp.CreateRequest(cert, issuer, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp *http.Response
+		if method == http.MethodGet {
+			resp, err = http.Get(server.URL + "/" + url.PathEscape(base64.StdEncoding.EncodeToString(request)))
+		} else {
+			resp, err = http.Post(server.URL, "application/ocsp-request", bytes.NewReader(request))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		responseBytes := new(bytes.Buffer)
+		if _, err := responseBytes.ReadFrom(resp.Body); err != nil {
+			t.Fatal(err)
+		}
+		return responseBytes.Bytes()
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		cert           *x509.Certificate
+		issuer         *x509.Certificate
+		expectedStatus int
+		expectedError  error
+	}{
+		{name: "good", method: http.MethodPost, cert: good.Certs[0], issuer: ca.Config.Certs[0], expectedStatus: ocsp.Good},
+		{name: "good with GET", method: http.MethodGet, cert: good.Certs[0], issuer: ca.Config.Certs[0], expectedStatus: ocsp.Good},
+		{name: "revoked", method: http.MethodPost, cert: revoked.Certs[0], issuer: ca.Config.Certs[0], expectedStatus: ocsp.Revoked},
+		{name: "unknown serial", method: http.MethodPost, cert: other.Certs[0], issuer: ca.Config.Certs[0], expectedStatus: ocsp.Unknown},
+		{name: "other issuer", method: http.MethodPost, cert: other.Certs[0], issuer: otherCA.Config.Certs[0], expectedError: ocsp.ResponseError{Status: ocsp.Unauthorized}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			responseBytes := query(t, test.method, test.cert, test.issuer)
+			response, err := ocsp.ParseResponseForCert(responseBytes, test.cert, ca.Config.Certs[0])
+			if test.expectedError != nil {
+				if err != test.expectedError {
+					t.Fatalf("expected error %v, got %v", test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.Status != test.expectedStatus {
+				t.Errorf("expected status %d, got %d", test.expectedStatus, response.Status)
+			}
+			if test.expectedStatus == ocsp.Revoked && response.RevocationReason != RevocationReasonKeyCompromise {
+				t.Errorf("expected revocation reason %d, got %d", RevocationReasonKeyCompromise, response.RevocationReason)
+			}
+		})
+	}
+}
+
+func TestSharedCertificateIndex(t *testing.T) {
+	indexFile, err := NewIndexFileCertificateIndex(filepath.Join(t.TempDir(), "index.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, index := range map[string]CertificateIndex{
+		"in-memory":  NewInMemoryCertificateIndex(),
+		"index file": indexFile,
+	} {
+		t.Run(name, func(t *testing.T) {
+			// the index outlives a rotated CA
+			previousCA := newTestRevocationCA(t, index)
+			ca := newTestRevocationCA(t, index)
+			previous, err := previousCA.MakeClientCertificateForDuration(&user.DefaultInfo{Name: "previous"}, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			current, err := ca.MakeClientCertificateForDuration(&user.DefaultInfo{Name: "current"}, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, cert := range []*x509.Certificate{previous.Certs[0], current.Certs[0]} {
+				if err := index.Revoke(cert.SerialNumber, RevocationReasonSuperseded, time.Now()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			issued, err := index.Get(previous.Certs[0].SerialNumber)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if issued == nil || !bytes.Equal(issued.AuthorityKeyID, previousCA.Config.Certs[0].SubjectKeyId) {
+				t.Fatalf("expected the issuer of the certificate to be recorded, got %#v", issued)
+			}
+
+			crlPEM, err := ca.MakeCRLForDuration(time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			block, _ := pem.Decode(crlPEM)
+			crl, err := x509.ParseRevocationList(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(crl.RevokedCertificates) != 1 || crl.RevokedCertificates[0].SerialNumber.Cmp(current.Certs[0].SerialNumber) != 0 {
+				t.Errorf("expected only the certificate of the CA to be in its CRL, got %v", crl.RevokedCertificates)
+			}
+
+			// the serial of the previous CA is unknown to the current one, even when asked with the current CA as issuer
+			request, err := ocsp.CreateRequest(previous.Certs[0], ca.Config.Certs[0], nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			responseBytes, err := (&ocspResponder{ca: ca, lifetime: time.Hour}).respond(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := ocsp.ParseResponse(responseBytes, ca.Config.Certs[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.Status != ocsp.Unknown {
+				t.Errorf("expected status %d, got %d", ocsp.Unknown, response.Status)
+			}
+		})
+	}
+}
+
+func TestCertificateIndexRemovesExpiredCertificates(t *testing.T) {
+	indexFile, err := NewIndexFileCertificateIndex(filepath.Join(t.TempDir(), "index.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, index := range map[string]CertificateIndex{
+		"in-memory":  NewInMemoryCertificateIndex(),
+		"index file": indexFile,
+	} {
+		t.Run(name, func(t *testing.T) {
+			expired := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(-time.Minute)}
+			valid := &x509.Certificate{SerialNumber: big.NewInt(2), NotAfter: time.Now().Add(time.Hour)}
+			for _, cert := range []*x509.Certificate{expired, valid} {
+				if err := index.Add(cert); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			issued, err := index.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(issued) != 1 || issued[0].SerialNumber.Cmp(valid.SerialNumber) != 0 {
+				t.Errorf("expected only the valid certificate to be left, got %v", issued)
+			}
+		})
+	}
+}
+
+func TestMakeCRLRequiresCRLSign(t *testing.T) {
+	ca := newTestRevocationCA(t, NewInMemoryCertificateIndex())
+	caCert := *ca.Config.Certs[0]
+	caCert.KeyUsage &^= x509.KeyUsageCRLSign
+	ca.Config.Certs = []*x509.Certificate{&caCert}
+
+	if _, err := ca.MakeCRLForDuration(time.Hour); err == nil {
+		t.Errorf("expected an error for a CA that is not allowed to sign CRLs")
+	}
+}
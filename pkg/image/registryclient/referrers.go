@@ -0,0 +1,119 @@
+package registryclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/distribution/distribution/v3"
+	registryclient "github.com/distribution/distribution/v3/registry/client"
+	"github.com/opencontainers/go-digest"
+
+	imagereference "github.com/openshift/library-go/pkg/image/reference"
+)
+
+// ErrReferrersNotSupported is returned by Referrers if the registry does not implement the OCI referrers API.
+var ErrReferrersNotSupported = errors.New("the registry does not support the referrers API")
+
+// maxReferrersSize prevents unbounded reads of referrers responses.
+const maxReferrersSize = 4 * 1024 * 1024
+
+// maxReferrersPages prevents unbounded pagination of referrers responses.
+const maxReferrersPages = 10
+
+var errTooManyReferrersPages = fmt.Errorf("more than %d pages of referrers", maxReferrersPages)
+
+// Referrers returns the descriptors of the manifests referring to the manifest with the given digest in the
+// repository of ref, using the OCI distribution referrers API. If artifactType is not empty, only referrers of that
+// artifact type are returned. If insecure is true, HTTP connections are allowed and HTTPS certificate verification
+// errors will be ignored. ErrReferrersNotSupported is returned if the registry does not implement the referrers API,
+// in which case callers may fall back to the referrers tag schema.
+func (c *Context) Referrers(ctx context.Context, ref imagereference.DockerImageReference, dgst digest.Digest, artifactType string, insecure bool) ([]distribution.Descriptor, error) {
+	rt, src, err := c.Ping(ctx, ref.RegistryURL(), insecure)
+	if err != nil {
+		return nil, err
+	}
+	repoName := ref.RepositoryName()
+	rt = c.repositoryTransport(rt, src, repoName, ref)
+	client := &http.Client{Transport: rt}
+
+	u := *src
+	u.Path = path.Join(u.Path, "/v2", repoName, "referrers", dgst.String())
+	if len(artifactType) > 0 {
+		u.RawQuery = url.Values{"artifactType": []string{artifactType}}.Encode()
+	}
+
+	var referrers []distribution.Descriptor
+	for page := 0; ; page++ {
+		if page >= maxReferrersPages {
+			return nil, fmt.Errorf("unable to list the referrers of %s in %s: %w", dgst, repoName, errTooManyReferrersPages)
+		}
+		pageReferrers, next, err := referrersPage(ctx, client, &u, artifactType)
+		if err != nil {
+			return nil, err
+		}
+		referrers = append(referrers, pageReferrers...)
+		if next == nil {
+			return referrers, nil
+		}
+		u = *next
+	}
+}
+
+// referrersPage returns the referrers of the given artifact type listed at u and the URL of the next page, or nil
+// if this is the last page.
+func referrersPage(ctx context.Context, client *http.Client, u *url.URL, artifactType string) ([]distribution.Descriptor, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.oci.image.index.v1+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil, ErrReferrersNotSupported
+	}
+	if !registryclient.SuccessStatus(resp.StatusCode) {
+		return nil, nil, registryclient.HandleErrorResponse(resp)
+	}
+
+	var index struct {
+		Manifests []struct {
+			distribution.Descriptor
+			ArtifactType string `json:"artifactType,omitempty"`
+		} `json:"manifests"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxReferrersSize)).Decode(&index); err != nil {
+		return nil, nil, err
+	}
+
+	// registries may ignore the artifactType filter, so it is applied again
+	var referrers []distribution.Descriptor
+	for _, manifest := range index.Manifests {
+		if len(artifactType) == 0 || manifest.ArtifactType == artifactType {
+			referrers = append(referrers, manifest.Descriptor)
+		}
+	}
+
+	link := resp.Header.Get("Link")
+	if len(link) == 0 {
+		return referrers, nil, nil
+	}
+	firstLink, _, _ := strings.Cut(link, ";")
+	next, err := url.Parse(strings.Trim(strings.TrimSpace(firstLink), "<>"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid Link header %q: %w", link, err)
+	}
+	return referrers, u.ResolveReference(next), nil
+}
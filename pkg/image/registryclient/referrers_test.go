@@ -0,0 +1,118 @@
+package registryclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+
+	imagereference "github.com/openshift/library-go/pkg/image/reference"
+)
+
+func TestReferrers(t *testing.T) {
+	subject := digest.FromString("subject")
+	referrer := digest.FromString("referrer")
+	nextReferrer := digest.FromString("next-referrer")
+	const artifactType = "application/vnd.dev.cosign.artifact.sig.v1+json"
+
+	tests := []struct {
+		name              string
+		handler           http.HandlerFunc
+		artifactType      string
+		expectedReferrers []digest.Digest
+		expectedErr       error
+	}{
+		{
+			name: "referrers",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v2/ns/release/referrers/"+subject.String() {
+					t.Errorf("unexpected request to %s", r.URL.Path)
+				}
+				w.Header().Set("Content-Type", "application/vnd.oci.image.index.v1+json")
+				fmt.Fprintf(w, `{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":%q,"size":10}]}`, referrer)
+			},
+			expectedReferrers: []digest.Digest{referrer},
+		},
+		{
+			name: "referrers of an artifact type",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("artifactType") != artifactType {
+					t.Errorf("unexpected query %s", r.URL.RawQuery)
+				}
+				// the registry does not apply the filter
+				w.Header().Set("Content-Type", "application/vnd.oci.image.index.v1+json")
+				fmt.Fprintf(w, `{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","artifactType":"application/spdx+json","digest":%q,"size":10},{"mediaType":"application/vnd.oci.image.manifest.v1+json","artifactType":%q,"digest":%q,"size":10}]}`, nextReferrer, artifactType, referrer)
+			},
+			artifactType:      artifactType,
+			expectedReferrers: []digest.Digest{referrer},
+		},
+		{
+			name: "paginated referrers",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/vnd.oci.image.index.v1+json")
+				if r.URL.Query().Get("page") == "" {
+					w.Header().Set("Link", fmt.Sprintf(`</v2/ns/release/referrers/%s?page=2>; rel="next"`, subject))
+					fmt.Fprintf(w, `{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":%q,"size":10}]}`, referrer)
+					return
+				}
+				fmt.Fprintf(w, `{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":%q,"size":10}]}`, nextReferrer)
+			},
+			expectedReferrers: []digest.Digest{referrer, nextReferrer},
+		},
+		{
+			name: "too many pages",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/vnd.oci.image.index.v1+json")
+				w.Header().Set("Link", fmt.Sprintf(`</v2/ns/release/referrers/%s?page=next>; rel="next"`, subject))
+				fmt.Fprint(w, `{"schemaVersion":2,"manifests":[]}`)
+			},
+			expectedErr: errTooManyReferrersPages,
+		},
+		{
+			name: "no referrers",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/vnd.oci.image.index.v1+json")
+				fmt.Fprint(w, `{"schemaVersion":2,"manifests":[]}`)
+			},
+		},
+		{
+			name:        "not supported",
+			handler:     http.NotFound,
+			expectedErr: ErrReferrersNotSupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/v2/" {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				tt.handler(w, r)
+			}))
+			defer server.Close()
+
+			ref, err := imagereference.Parse(strings.TrimPrefix(server.URL, "http://") + "/ns/release")
+			if err != nil {
+				t.Fatal(err)
+			}
+			referrers, err := NewContext(http.DefaultTransport, http.DefaultTransport).Referrers(context.Background(), ref, subject, tt.artifactType, true)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if len(referrers) != len(tt.expectedReferrers) {
+				t.Fatalf("expected referrers %v, got %v", tt.expectedReferrers, referrers)
+			}
+			for i := range referrers {
+				if referrers[i].Digest != tt.expectedReferrers[i] {
+					t.Errorf("expected referrer %d to be %s, got %s", i, tt.expectedReferrers[i], referrers[i].Digest)
+				}
+			}
+		})
+	}
+}
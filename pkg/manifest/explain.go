@@ -0,0 +1,330 @@
+package manifest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	configv1 "github.com/openshift/api/config/v1"
+)
+
+// InclusionGate names an inclusion filter applied to manifests by the cluster version operator.
+type InclusionGate string
+
+const (
+	// AnnotationsGate excludes manifests without annotations.
+	AnnotationsGate InclusionGate = "Annotations"
+	// ExcludeGate excludes manifests with the exclude.release.openshift.io/<identifier>=true annotation.
+	ExcludeGate InclusionGate = "Exclude"
+	// FeatureSetGate excludes manifests whose release.openshift.io/feature-set annotation does not
+	// list the required feature set.
+	FeatureSetGate InclusionGate = "FeatureSet"
+	// ProfileGate excludes manifests without the include.release.openshift.io/<profile>=true annotation.
+	ProfileGate InclusionGate = "Profile"
+	// CapabilityGate excludes manifests with unknown or disabled capabilities.
+	CapabilityGate InclusionGate = "Capability"
+	// OverrideGate excludes manifests set unmanaged by a component override.
+	OverrideGate InclusionGate = "Override"
+)
+
+// GateDecision is the outcome of a single inclusion gate for a manifest.
+type GateDecision struct {
+	Gate InclusionGate
+	// Evaluated is false if the gate was skipped because its filter was not set.
+	Evaluated bool
+	// Err describes why the gate excludes the manifest, or is nil if the manifest passes the gate.
+	Err error
+}
+
+// InclusionDecision lists the outcome of every inclusion gate for a manifest, in the order the
+// gates are applied by Include.
+type InclusionDecision struct {
+	Gates []GateDecision
+}
+
+// Included returns true if the manifest passes all gates.
+func (d InclusionDecision) Included() bool {
+	return d.Err() == nil
+}
+
+// Err returns the reason the first failing gate excludes the manifest, which is the error returned
+// by Include, or nil if the manifest is included.
+func (d InclusionDecision) Err() error {
+	for _, gate := range d.Gates {
+		if gate.Err != nil {
+			return gate.Err
+		}
+	}
+	return nil
+}
+
+// Excluded returns the gates which exclude the manifest.
+func (d InclusionDecision) Excluded() []GateDecision {
+	var excluded []GateDecision
+	for _, gate := range d.Gates {
+		if gate.Err != nil {
+			excluded = append(excluded, gate)
+		}
+	}
+	return excluded
+}
+
+// inclusionFilters holds the filters of IncludeAllowUnknownCapabilities and ExplainInclusion.
+type inclusionFilters struct {
+	excludeIdentifier        *string
+	requiredFeatureSet       *string
+	profile                  *string
+	capabilities             *configv1.ClusterVersionCapabilitiesStatus
+	overrides                []configv1.ComponentOverride
+	allowUnknownCapabilities bool
+}
+
+// inclusionGate checks a manifest with the given annotations against an inclusion filter. It returns false if the
+// filter is not set, and an error if the manifest is excluded.
+type inclusionGate struct {
+	gate  InclusionGate
+	check func(m *Manifest, annotations map[string]string, filters inclusionFilters) (evaluated bool, err error)
+}
+
+// inclusionGates are the inclusion gates in the order they are applied.
+var inclusionGates = []inclusionGate{
+	{gate: AnnotationsGate, check: func(m *Manifest, annotations map[string]string, filters inclusionFilters) (bool, error) {
+		if annotations == nil {
+			return true, fmt.Errorf("no annotations")
+		}
+		return true, nil
+	}},
+	{gate: ExcludeGate, check: func(m *Manifest, annotations map[string]string, filters inclusionFilters) (bool, error) {
+		if filters.excludeIdentifier == nil {
+			return false, nil
+		}
+		return true, checkExclude(*filters.excludeIdentifier, annotations)
+	}},
+	{gate: FeatureSetGate, check: func(m *Manifest, annotations map[string]string, filters inclusionFilters) (bool, error) {
+		if filters.requiredFeatureSet == nil {
+			return false, nil
+		}
+		return true, checkFeatureSets(*filters.requiredFeatureSet, annotations)
+	}},
+	{gate: ProfileGate, check: func(m *Manifest, annotations map[string]string, filters inclusionFilters) (bool, error) {
+		if filters.profile == nil {
+			return false, nil
+		}
+		return true, checkProfile(*filters.profile, annotations)
+	}},
+	{gate: CapabilityGate, check: func(m *Manifest, annotations map[string]string, filters inclusionFilters) (bool, error) {
+		// If there is no capabilities defined in a release then we do not need to check presence of capabilities in the manifest
+		if filters.capabilities == nil {
+			return false, nil
+		}
+		return true, checkResourceEnablement(annotations, filters.capabilities, filters.allowUnknownCapabilities)
+	}},
+	{gate: OverrideGate, check: func(m *Manifest, annotations map[string]string, filters inclusionFilters) (bool, error) {
+		if override := m.getOverrideForManifest(filters.overrides); override != nil && override.Unmanaged {
+			return true, fmt.Errorf("overridden")
+		}
+		return len(filters.overrides) > 0, nil
+	}},
+}
+
+// ExplainInclusion evaluates all inclusion filters of IncludeAllowUnknownCapabilities instead of stopping
+// at the first one excluding the manifest. Pointer arguments can be set nil to skip the corresponding gate.
+func (m *Manifest) ExplainInclusion(excludeIdentifier *string, requiredFeatureSet *string, profile *string,
+	capabilities *configv1.ClusterVersionCapabilitiesStatus, overrides []configv1.ComponentOverride, allowUnknownCapabilities bool) InclusionDecision {
+
+	filters := inclusionFilters{
+		excludeIdentifier:        excludeIdentifier,
+		requiredFeatureSet:       requiredFeatureSet,
+		profile:                  profile,
+		capabilities:             capabilities,
+		overrides:                overrides,
+		allowUnknownCapabilities: allowUnknownCapabilities,
+	}
+	annotations := m.Obj.GetAnnotations()
+	decision := InclusionDecision{Gates: make([]GateDecision, 0, len(inclusionGates))}
+	for _, gate := range inclusionGates {
+		evaluated, err := gate.check(m, annotations, filters)
+		decision.Gates = append(decision.Gates, GateDecision{Gate: gate.gate, Evaluated: evaluated, Err: err})
+	}
+	return decision
+}
+
+func checkExclude(excludeIdentifier string, annotations map[string]string) error {
+	excludeAnnotation := fmt.Sprintf("exclude.release.openshift.io/%s", excludeIdentifier)
+	if v := annotations[excludeAnnotation]; v == "true" {
+		return fmt.Errorf("%s=%s", excludeAnnotation, v)
+	}
+	return nil
+}
+
+func checkProfile(profile string, annotations map[string]string) error {
+	profileAnnotation := fmt.Sprintf("include.release.openshift.io/%s", profile)
+	if val, ok := annotations[profileAnnotation]; ok && val != "true" {
+		return fmt.Errorf("unrecognized value %s=%s", profileAnnotation, val)
+	} else if !ok {
+		return fmt.Errorf("%s unset", profileAnnotation)
+	}
+	return nil
+}
+
+// InclusionTarget describes a cluster configuration manifests are filtered for. Pointer fields can be
+// set nil to skip the corresponding gate, as in Include.
+type InclusionTarget struct {
+	// Name identifies the target in the inclusion matrix.
+	Name string
+
+	ExcludeIdentifier  *string
+	RequiredFeatureSet *string
+	Profile            *string
+	Capabilities       *configv1.ClusterVersionCapabilitiesStatus
+}
+
+// InclusionTargets returns a target for every combination of the given cluster profiles, feature
+// sets and named capability sets. An empty list skips the corresponding gate. Targets are named
+// "<profile>/<feature set>/<capability set>", omitting skipped gates and using "Default" for the
+// empty feature set. Callers filtering on an exclude identifier set it on the returned targets.
+func InclusionTargets(profiles []string, featureSets []configv1.FeatureSet, capabilitySets map[string]*configv1.ClusterVersionCapabilitiesStatus) []InclusionTarget {
+	targets := []InclusionTarget{{}}
+	expand := func(names []string, set func(target *InclusionTarget, i int)) {
+		if len(names) == 0 {
+			return
+		}
+		expanded := make([]InclusionTarget, 0, len(targets)*len(names))
+		for _, target := range targets {
+			for i, name := range names {
+				expandedTarget := target
+				set(&expandedTarget, i)
+				if len(expandedTarget.Name) > 0 {
+					expandedTarget.Name += "/"
+				}
+				expandedTarget.Name += name
+				expanded = append(expanded, expandedTarget)
+			}
+		}
+		targets = expanded
+	}
+
+	expand(profiles, func(target *InclusionTarget, i int) {
+		profile := profiles[i]
+		target.Profile = &profile
+	})
+
+	featureSetNames := make([]string, 0, len(featureSets))
+	for _, featureSet := range featureSets {
+		if len(featureSet) == 0 {
+			featureSetNames = append(featureSetNames, "Default")
+		} else {
+			featureSetNames = append(featureSetNames, string(featureSet))
+		}
+	}
+	expand(featureSetNames, func(target *InclusionTarget, i int) {
+		featureSet := string(featureSets[i])
+		target.RequiredFeatureSet = &featureSet
+	})
+
+	capabilitySetNames := make([]string, 0, len(capabilitySets))
+	for name := range capabilitySets {
+		capabilitySetNames = append(capabilitySetNames, name)
+	}
+	sort.Strings(capabilitySetNames)
+	expand(capabilitySetNames, func(target *InclusionTarget, i int) {
+		target.Capabilities = capabilitySets[capabilitySetNames[i]]
+	})
+
+	return targets
+}
+
+// InclusionMatrix holds the inclusion decision of each manifest for each target.
+type InclusionMatrix struct {
+	Manifests []Manifest
+	Targets   []InclusionTarget
+	// Decisions holds the decision for Manifests[i] and Targets[j] at [i][j].
+	Decisions [][]InclusionDecision
+}
+
+// NewInclusionMatrix evaluates the inclusion of each manifest for each target.
+func NewInclusionMatrix(manifests []Manifest, targets []InclusionTarget) *InclusionMatrix {
+	matrix := &InclusionMatrix{
+		Manifests: manifests,
+		Targets:   targets,
+		Decisions: make([][]InclusionDecision, len(manifests)),
+	}
+	for i := range manifests {
+		matrix.Decisions[i] = make([]InclusionDecision, len(targets))
+		for j, target := range targets {
+			matrix.Decisions[i][j] = manifests[i].ExplainInclusion(target.ExcludeIdentifier, target.RequiredFeatureSet, target.Profile, target.Capabilities, nil, false)
+		}
+	}
+	return matrix
+}
+
+// InclusionMatrixFromDir loads the manifests of the .yaml, .yml and .json files directly in dir, as
+// the cluster version operator does for a release payload manifest directory, and evaluates their
+// inclusion for each target.
+func InclusionMatrixFromDir(dir string, targets []InclusionTarget) (*InclusionMatrix, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch filepath.Ext(entry.Name()) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	manifests, err := ManifestsFromFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	return NewInclusionMatrix(manifests, targets), nil
+}
+
+// WriteTable writes a table with a row per manifest and a column per target. Each cell is "yes" if the
+// manifest is included for the target, or lists the gates excluding it otherwise.
+func (m *InclusionMatrix) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	header := []string{"MANIFEST"}
+	for _, target := range m.Targets {
+		header = append(header, target.Name)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for i := range m.Manifests {
+		row := []string{manifestRowName(&m.Manifests[i])}
+		for _, decision := range m.Decisions[i] {
+			excluded := decision.Excluded()
+			if len(excluded) == 0 {
+				row = append(row, "yes")
+				continue
+			}
+			gates := make([]string, 0, len(excluded))
+			for _, gate := range excluded {
+				gates = append(gates, string(gate.Gate))
+			}
+			row = append(row, fmt.Sprintf("no (%s)", strings.Join(gates, ",")))
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// manifestRowName identifies a manifest in the inclusion matrix table.
+func manifestRowName(m *Manifest) string {
+	name := m.id.Name
+	if len(m.id.Namespace) > 0 {
+		name = m.id.Namespace + "/" + name
+	}
+	name = m.id.Kind + " " + name
+	if len(m.OriginalFilename) > 0 {
+		name = m.OriginalFilename + ": " + name
+	}
+	return name
+}
@@ -0,0 +1,193 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilpointer "k8s.io/utils/pointer"
+)
+
+func TestExplainInclusion(t *testing.T) {
+	tests := []struct {
+		name               string
+		exclude            *string
+		requiredFeatureSet *string
+		profile            *string
+		annotations        map[string]interface{}
+		caps               *configv1.ClusterVersionCapabilitiesStatus
+		overrides          []configv1.ComponentOverride
+
+		expected []GateDecision
+	}{
+		{
+			name: "no filters",
+			annotations: map[string]interface{}{
+				"include.release.openshift.io/self-managed-high-availability": "true",
+			},
+			expected: []GateDecision{
+				{Gate: AnnotationsGate, Evaluated: true},
+				{Gate: ExcludeGate},
+				{Gate: FeatureSetGate},
+				{Gate: ProfileGate},
+				{Gate: CapabilityGate},
+				{Gate: OverrideGate},
+			},
+		},
+		{
+			name: "no annotations",
+			expected: []GateDecision{
+				{Gate: AnnotationsGate, Evaluated: true, Err: fmt.Errorf("no annotations")},
+				{Gate: ExcludeGate},
+				{Gate: FeatureSetGate},
+				{Gate: ProfileGate},
+				{Gate: CapabilityGate},
+				{Gate: OverrideGate},
+			},
+		},
+		{
+			name:               "all gates excluding",
+			exclude:            utilpointer.String("identifier"),
+			requiredFeatureSet: utilpointer.String(""),
+			profile:            utilpointer.String("single-node"),
+			annotations: map[string]interface{}{
+				"exclude.release.openshift.io/identifier":                     "true",
+				"include.release.openshift.io/self-managed-high-availability": "true",
+				"release.openshift.io/feature-set":                            "TechPreviewNoUpgrade",
+				CapabilityAnnotation:                                          "cap1",
+			},
+			caps: &configv1.ClusterVersionCapabilitiesStatus{
+				KnownCapabilities: []configv1.ClusterVersionCapability{"cap1"},
+			},
+			overrides: []configv1.ComponentOverride{{Kind: "Deployment", Namespace: "ns", Name: "name", Unmanaged: true}},
+			expected: []GateDecision{
+				{Gate: AnnotationsGate, Evaluated: true},
+				{Gate: ExcludeGate, Evaluated: true, Err: fmt.Errorf("exclude.release.openshift.io/identifier=true")},
+				{Gate: FeatureSetGate, Evaluated: true, Err: fmt.Errorf("\"Default\" is required, and release.openshift.io/feature-set=TechPreviewNoUpgrade")},
+				{Gate: ProfileGate, Evaluated: true, Err: fmt.Errorf("include.release.openshift.io/single-node unset")},
+				{Gate: CapabilityGate, Evaluated: true, Err: fmt.Errorf("disabled capabilities: cap1")},
+				{Gate: OverrideGate, Evaluated: true, Err: fmt.Errorf("overridden")},
+			},
+		},
+		{
+			name:    "all gates including",
+			exclude: utilpointer.String("identifier"),
+			profile: utilpointer.String("self-managed-high-availability"),
+			annotations: map[string]interface{}{
+				"include.release.openshift.io/self-managed-high-availability": "true",
+				CapabilityAnnotation: "cap1",
+			},
+			caps: &configv1.ClusterVersionCapabilitiesStatus{
+				KnownCapabilities:   []configv1.ClusterVersionCapability{"cap1"},
+				EnabledCapabilities: []configv1.ClusterVersionCapability{"cap1"},
+			},
+			overrides: []configv1.ComponentOverride{{Kind: "Deployment", Namespace: "ns", Name: "other", Unmanaged: true}},
+			expected: []GateDecision{
+				{Gate: AnnotationsGate, Evaluated: true},
+				{Gate: ExcludeGate, Evaluated: true},
+				{Gate: FeatureSetGate},
+				{Gate: ProfileGate, Evaluated: true},
+				{Gate: CapabilityGate, Evaluated: true},
+				{Gate: OverrideGate, Evaluated: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := map[string]interface{}{}
+			if tt.annotations != nil {
+				metadata["annotations"] = tt.annotations
+			}
+			m := Manifest{
+				Obj: &unstructured.Unstructured{
+					Object: map[string]interface{}{
+						"metadata": metadata,
+					},
+				},
+				id: resourceId{Kind: "Deployment", Namespace: "ns", Name: "name"},
+			}
+			decision := m.ExplainInclusion(tt.exclude, tt.requiredFeatureSet, tt.profile, tt.caps, tt.overrides, false)
+			assert.Equal(t, tt.expected, decision.Gates)
+			assert.Equal(t, m.Include(tt.exclude, tt.requiredFeatureSet, tt.profile, tt.caps, tt.overrides), decision.Err())
+		})
+	}
+}
+
+func TestInclusionTargets(t *testing.T) {
+	caps := &configv1.ClusterVersionCapabilitiesStatus{}
+	targets := InclusionTargets(
+		[]string{"self-managed-high-availability", "single-node"},
+		[]configv1.FeatureSet{configv1.Default, configv1.TechPreviewNoUpgrade},
+		map[string]*configv1.ClusterVersionCapabilitiesStatus{"none": caps},
+	)
+	expected := []InclusionTarget{
+		{Name: "self-managed-high-availability/Default/none", Profile: utilpointer.String("self-managed-high-availability"), RequiredFeatureSet: utilpointer.String(""), Capabilities: caps},
+		{Name: "self-managed-high-availability/TechPreviewNoUpgrade/none", Profile: utilpointer.String("self-managed-high-availability"), RequiredFeatureSet: utilpointer.String("TechPreviewNoUpgrade"), Capabilities: caps},
+		{Name: "single-node/Default/none", Profile: utilpointer.String("single-node"), RequiredFeatureSet: utilpointer.String(""), Capabilities: caps},
+		{Name: "single-node/TechPreviewNoUpgrade/none", Profile: utilpointer.String("single-node"), RequiredFeatureSet: utilpointer.String("TechPreviewNoUpgrade"), Capabilities: caps},
+	}
+	assert.Equal(t, expected, targets)
+
+	assert.Equal(t, []InclusionTarget{{Name: "single-node", Profile: utilpointer.String("single-node")}}, InclusionTargets([]string{"single-node"}, nil, nil))
+}
+
+func TestInclusionMatrixFromDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"0000_10_default.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: default
+  namespace: ns
+  annotations:
+    include.release.openshift.io/self-managed-high-availability: "true"
+    include.release.openshift.io/single-node: "true"
+`,
+		"0000_20_techpreview.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: techpreview
+  namespace: ns
+  annotations:
+    include.release.openshift.io/self-managed-high-availability: "true"
+    release.openshift.io/feature-set: TechPreviewNoUpgrade
+`,
+		"0000_30_capability.json": `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"capability","namespace":"ns","annotations":{"include.release.openshift.io/single-node":"true","capability.openshift.io/name":"cap1"}}}`,
+		"README.md":               "not a manifest",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	targets := InclusionTargets(
+		[]string{"self-managed-high-availability", "single-node"},
+		[]configv1.FeatureSet{configv1.Default, configv1.TechPreviewNoUpgrade},
+		map[string]*configv1.ClusterVersionCapabilitiesStatus{
+			"cap1": {KnownCapabilities: []configv1.ClusterVersionCapability{"cap1"}, EnabledCapabilities: []configv1.ClusterVersionCapability{"cap1"}},
+		},
+	)
+	matrix, err := InclusionMatrixFromDir(dir, targets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fmt.Errorf("include.release.openshift.io/self-managed-high-availability unset"), matrix.Decisions[2][0].Err())
+
+	out := &bytes.Buffer{}
+	if err := matrix.WriteTable(out); err != nil {
+		t.Fatal(err)
+	}
+	expected := `MANIFEST                                            self-managed-high-availability/Default/cap1  self-managed-high-availability/TechPreviewNoUpgrade/cap1  single-node/Default/cap1  single-node/TechPreviewNoUpgrade/cap1
+0000_10_default.yaml: ConfigMap ns/default          yes                                          yes                                                       yes                       yes
+0000_20_techpreview.yaml: ConfigMap ns/techpreview  no (FeatureSet)                              yes                                                       no (FeatureSet,Profile)   no (Profile)
+0000_30_capability.json: ConfigMap ns/capability    no (Profile)                                 no (Profile)                                              yes                       yes
+`
+	assert.Equal(t, expected, out.String())
+}
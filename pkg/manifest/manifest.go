@@ -199,46 +199,20 @@ func (m *Manifest) Include(excludeIdentifier *string, requiredFeatureSet *string
 func (m *Manifest) IncludeAllowUnknownCapabilities(excludeIdentifier *string, requiredFeatureSet *string, profile *string,
 	capabilities *configv1.ClusterVersionCapabilitiesStatus, overrides []configv1.ComponentOverride, allowUnknownCapabilities bool) error {
 
-	annotations := m.Obj.GetAnnotations()
-	if annotations == nil {
-		return fmt.Errorf("no annotations")
-	}
-
-	if excludeIdentifier != nil {
-		excludeAnnotation := fmt.Sprintf("exclude.release.openshift.io/%s", *excludeIdentifier)
-		if v := annotations[excludeAnnotation]; v == "true" {
-			return fmt.Errorf("%s=%s", excludeAnnotation, v)
-		}
-	}
-
-	if requiredFeatureSet != nil {
-		err := checkFeatureSets(*requiredFeatureSet, annotations)
-		if err != nil {
-			return err
-		}
+	filters := inclusionFilters{
+		excludeIdentifier:        excludeIdentifier,
+		requiredFeatureSet:       requiredFeatureSet,
+		profile:                  profile,
+		capabilities:             capabilities,
+		overrides:                overrides,
+		allowUnknownCapabilities: allowUnknownCapabilities,
 	}
-
-	if profile != nil {
-		profileAnnotation := fmt.Sprintf("include.release.openshift.io/%s", *profile)
-		if val, ok := annotations[profileAnnotation]; ok && val != "true" {
-			return fmt.Errorf("unrecognized value %s=%s", profileAnnotation, val)
-		} else if !ok {
-			return fmt.Errorf("%s unset", profileAnnotation)
-		}
-	}
-
-	// If there is no capabilities defined in a release then we do not need to check presence of capabilities in the manifest
-	if capabilities != nil {
-		err := checkResourceEnablement(annotations, capabilities, allowUnknownCapabilities)
-		if err != nil {
+	annotations := m.Obj.GetAnnotations()
+	for _, gate := range inclusionGates {
+		if _, err := gate.check(m, annotations, filters); err != nil {
 			return err
 		}
 	}
-
-	if override := m.getOverrideForManifest(overrides); override != nil && override.Unmanaged {
-		return fmt.Errorf("overridden")
-	}
-
 	return nil
 }
 
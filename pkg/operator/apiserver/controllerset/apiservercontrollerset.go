@@ -20,6 +20,7 @@ import (
 	"github.com/openshift/library-go/pkg/operator/encryption"
 	"github.com/openshift/library-go/pkg/operator/encryption/controllers"
 	"github.com/openshift/library-go/pkg/operator/encryption/controllers/migrators"
+	"github.com/openshift/library-go/pkg/operator/encryption/secrets"
 	"github.com/openshift/library-go/pkg/operator/encryption/statemachine"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/loglevel"
@@ -388,6 +389,18 @@ func (cs *APIServerControllerSet) WithUnsupportedConfigPrefixForEncryptionContro
 	return cs
 }
 
+// WithKeyWrappersForEncryptionControllers wraps the keys in the encryption key secrets with the given wrappers.
+func (cs *APIServerControllerSet) WithKeyWrappersForEncryptionControllers(keyWrappers *secrets.KeyWrappers) *APIServerControllerSet {
+	cs.encryptionControllers.keyWrappers = keyWrappers
+	return cs
+}
+
+// WithKMSConfigProviderForEncryptionControllers enables the KMS mode of the encryption controllers.
+func (cs *APIServerControllerSet) WithKMSConfigProviderForEncryptionControllers(kmsConfigProvider controllers.KMSConfigProvider) *APIServerControllerSet {
+	cs.encryptionControllers.kmsConfigProvider = kmsConfigProvider
+	return cs
+}
+
 func (cs *APIServerControllerSet) WithoutEncryptionControllers() *APIServerControllerSet {
 	cs.encryptionControllers.controller = nil
 	cs.encryptionControllers.emptyAllowed = true
@@ -474,6 +487,8 @@ type encryptionControllerBuilder struct {
 	resourceSyncer             *resourcesynccontroller.ResourceSyncController
 
 	unsupportedConfigPrefix []string
+	keyWrappers             *secrets.KeyWrappers
+	kmsConfigProvider       controllers.KMSConfigProvider
 }
 
 func (e *encryptionControllerBuilder) build() controllerWrapper {
@@ -491,6 +506,8 @@ func (e *encryptionControllerBuilder) build() controllerWrapper {
 		e.apiServerInformer,
 		e.kubeInformersForNamespaces,
 		e.secretsClient,
+		e.keyWrappers,
+		e.kmsConfigProvider,
 		e.eventRecorder,
 		e.resourceSyncer,
 	)
@@ -0,0 +1,191 @@
+package certexpirycontroller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/certs/cert-inspection/certgraphanalysis"
+	"github.com/openshift/library-go/pkg/certs/cert-inspection/certgraphapi"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/management"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+)
+
+// ManagedCertificate is a certificate the operator is responsible for.
+type ManagedCertificate struct {
+	// Secret holds the certificate.
+	Secret certgraphapi.InClusterSecretLocation
+	// CABundle is the config map clients verify the certificate with. It must contain the signer of the certificate.
+	// It is optional.
+	CABundle *certgraphapi.InClusterConfigMapLocation
+}
+
+// CertExpiryController periodically inspects the certificates in the platform namespaces. It exposes the expiry of
+// every certificate as a metric, emits events for certificates expiring within the warning window, and sets the
+// <name>Degraded and <name>Upgradeable conditions when a managed certificate is missing, expired or its signer is
+// missing from its CA bundle.
+type CertExpiryController struct {
+	name                string
+	operatorClient      v1helpers.OperatorClient
+	managedCertificates []ManagedCertificate
+	warningWindow       time.Duration
+
+	gatherCerts func(ctx context.Context) (*certgraphapi.PKIList, error)
+	now         func() time.Time
+}
+
+// NewCertExpiryController creates a CertExpiryController that inspects the certificates every resyncInterval.
+func NewCertExpiryController(
+	name string,
+	operatorClient v1helpers.OperatorClient,
+	kubeClient kubernetes.Interface,
+	managedCertificates []ManagedCertificate,
+	warningWindow time.Duration,
+	resyncInterval time.Duration,
+	eventRecorder events.Recorder,
+) factory.Controller {
+	c := newCertExpiryController(name, operatorClient, kubeClient, managedCertificates, warningWindow)
+	return factory.New().
+		ResyncEvery(resyncInterval).
+		WithInformers(operatorClient.Informer()).
+		WithSync(c.sync).
+		ToController(name, eventRecorder.WithComponentSuffix("cert-expiry-controller"))
+}
+
+func newCertExpiryController(
+	name string,
+	operatorClient v1helpers.OperatorClient,
+	kubeClient kubernetes.Interface,
+	managedCertificates []ManagedCertificate,
+	warningWindow time.Duration,
+) *CertExpiryController {
+	return &CertExpiryController{
+		name:                name,
+		operatorClient:      operatorClient,
+		managedCertificates: managedCertificates,
+		warningWindow:       warningWindow,
+		gatherCerts: func(ctx context.Context) (*certgraphapi.PKIList, error) {
+			return certgraphanalysis.GatherCertsFromPlatformNamespaces(ctx, kubeClient)
+		},
+		now: time.Now,
+	}
+}
+
+func (c *CertExpiryController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	operatorSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if !management.IsOperatorManaged(operatorSpec.ManagementState) {
+		return nil
+	}
+
+	pkiList, err := c.gatherCerts(ctx)
+	if pkiList == nil {
+		return err
+	}
+	if err != nil {
+		// some secrets or config maps could not be inspected, the others are still checked
+		klog.V(2).Infof("%s: failed to inspect certificates: %v", c.name, err)
+	}
+
+	now := c.now()
+	notAfterSeconds := map[notAfterSeries]float64{}
+	for _, certKeyPair := range pkiList.CertKeyPairs.Items {
+		notAfter, err := time.Parse(time.RFC3339, certKeyPair.Spec.CertMetadata.NotAfter)
+		if err != nil {
+			continue
+		}
+		for _, location := range certKeyPair.Spec.SecretLocations {
+			notAfterSeconds[notAfterSeries{namespace: location.Namespace, secret: location.Name, logicalName: certKeyPair.LogicalName}] = float64(notAfter.Unix())
+
+			switch {
+			case !now.Before(notAfter):
+				syncCtx.Recorder().Warningf("CertificateExpired", "The certificate %q in secret %s/%s expired at %s",
+					certKeyPair.Spec.CertMetadata.CertIdentifier.CommonName, location.Namespace, location.Name, notAfter.Format(time.RFC3339))
+			case notAfter.Sub(now) < c.warningWindow:
+				syncCtx.Recorder().Warningf("CertificateExpiringSoon", "The certificate %q in secret %s/%s expires at %s",
+					certKeyPair.Spec.CertMetadata.CertIdentifier.CommonName, location.Namespace, location.Name, notAfter.Format(time.RFC3339))
+			}
+		}
+	}
+
+	metrics.ReplaceNotAfter(c.name, notAfterSeconds)
+
+	problems := c.managedCertificateProblems(pkiList, now)
+
+	degraded := operatorv1.OperatorCondition{
+		Type:   c.name + operatorv1.OperatorStatusTypeDegraded,
+		Status: operatorv1.ConditionFalse,
+		Reason: "AsExpected",
+	}
+	upgradeable := operatorv1.OperatorCondition{
+		Type:   c.name + operatorv1.OperatorStatusTypeUpgradeable,
+		Status: operatorv1.ConditionTrue,
+		Reason: "AsExpected",
+	}
+	if len(problems) > 0 {
+		degraded.Status = operatorv1.ConditionTrue
+		degraded.Reason = "InvalidCertificates"
+		degraded.Message = strings.Join(problems, "\n")
+		upgradeable.Status = operatorv1.ConditionFalse
+		upgradeable.Reason = "InvalidCertificates"
+		upgradeable.Message = strings.Join(problems, "\n")
+	}
+
+	if _, _, updateError := v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(degraded), v1helpers.UpdateConditionFn(upgradeable)); updateError != nil {
+		return updateError
+	}
+	return nil
+}
+
+// managedCertificateProblems returns a message for every managed certificate that is missing, expired or whose signer
+// is missing from its CA bundle.
+func (c *CertExpiryController) managedCertificateProblems(pkiList *certgraphapi.PKIList, now time.Time) []string {
+	graph := certgraphanalysis.NewPKIGraph(pkiList)
+	nodesBySecret := map[string]*certgraphanalysis.PKIGraphNode{}
+	for _, node := range graph.Nodes {
+		for _, location := range node.Locations {
+			nodesBySecret[location] = node
+		}
+	}
+
+	problems := []string{}
+	for _, managed := range c.managedCertificates {
+		secret := fmt.Sprintf("secrets/%s/%s", managed.Secret.Namespace, managed.Secret.Name)
+		node, ok := nodesBySecret[secret]
+		if !ok || node.Certificate == nil {
+			problems = append(problems, fmt.Sprintf("%s: no certificate found", secret))
+			continue
+		}
+		if notAfter, err := time.Parse(time.RFC3339, node.Certificate.NotAfter); err == nil && !now.Before(notAfter) {
+			problems = append(problems, fmt.Sprintf("%s: expired at %s", secret, node.Certificate.NotAfter))
+		}
+		if managed.CABundle == nil {
+			continue
+		}
+
+		caBundle := fmt.Sprintf("configmaps/%s/%s", managed.CABundle.Namespace, managed.CABundle.Name)
+		found := false
+		for _, issuer := range graph.Issuers(node.ID) {
+			for _, bundle := range graph.CABundles(issuer.ID) {
+				for _, location := range bundle.Locations {
+					found = found || location == caBundle
+				}
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: signer %q is missing from %s", secret, node.Certificate.CertIdentifier.Issuer.CommonName, caBundle))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
@@ -0,0 +1,175 @@
+package certexpirycontroller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8smetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
+
+	"github.com/openshift/library-go/pkg/certs/cert-inspection/certgraphapi"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+)
+
+func newTestCA(t *testing.T, name string) *crypto.CA {
+	t.Helper()
+	caConfig, err := crypto.MakeSelfSignedCAConfigForDuration(name, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &crypto.CA{Config: caConfig, SerialGenerator: &crypto.RandomSerialGenerator{}}
+}
+
+func newTestServingSecret(t *testing.T, signer *crypto.CA, lifetime time.Duration) *corev1.Secret {
+	t.Helper()
+	serving, err := signer.MakeServerCertForDuration(sets.NewString("localhost"), lifetime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, keyPEM, err := serving.GetPEMBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-test", Name: "serving"},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{"tls.crt": certPEM, "tls.key": keyPEM},
+	}
+}
+
+func newTestCABundle(t *testing.T, cas ...*crypto.CA) *corev1.ConfigMap {
+	t.Helper()
+	certs := []byte{}
+	for _, ca := range cas {
+		caPEM, err := crypto.EncodeCertificates(ca.Config.Certs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certs = append(certs, caPEM...)
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-test", Name: "ca-bundle"},
+		Data:       map[string]string{"ca-bundle.crt": string(certs)},
+	}
+}
+
+func TestCertExpiryController(t *testing.T) {
+	signer := newTestCA(t, "signer")
+	otherSigner := newTestCA(t, "other-signer")
+
+	tests := []struct {
+		name                string
+		objects             []runtime.Object
+		now                 time.Time
+		expectedEvents      []string
+		expectedDegraded    operatorv1.ConditionStatus
+		expectedMessagePart string
+	}{
+		{
+			name:             "valid",
+			objects:          []runtime.Object{newTestServingSecret(t, signer, 10*time.Hour), newTestCABundle(t, signer)},
+			now:              time.Now(),
+			expectedDegraded: operatorv1.ConditionFalse,
+		},
+		{
+			name:             "expiring within the warning window",
+			objects:          []runtime.Object{newTestServingSecret(t, signer, 10*time.Hour), newTestCABundle(t, signer)},
+			now:              time.Now().Add(9 * time.Hour),
+			expectedEvents:   []string{"CertificateExpiringSoon"},
+			expectedDegraded: operatorv1.ConditionFalse,
+		},
+		{
+			name:                "expired",
+			objects:             []runtime.Object{newTestServingSecret(t, signer, 10*time.Hour), newTestCABundle(t, signer)},
+			now:                 time.Now().Add(11 * time.Hour),
+			expectedEvents:      []string{"CertificateExpired"},
+			expectedDegraded:    operatorv1.ConditionTrue,
+			expectedMessagePart: "secrets/openshift-test/serving: expired at",
+		},
+		{
+			name:                "missing certificate",
+			objects:             []runtime.Object{newTestCABundle(t, signer)},
+			now:                 time.Now(),
+			expectedDegraded:    operatorv1.ConditionTrue,
+			expectedMessagePart: "secrets/openshift-test/serving: no certificate found",
+		},
+		{
+			name:                "signer missing from the CA bundle",
+			objects:             []runtime.Object{newTestServingSecret(t, signer, 10*time.Hour), newTestCABundle(t, otherSigner)},
+			now:                 time.Now(),
+			expectedDegraded:    operatorv1.ConditionTrue,
+			expectedMessagePart: `signer "signer" is missing from configmaps/openshift-test/ca-bundle`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{ManagementState: operatorv1.Managed}, &operatorv1.OperatorStatus{}, nil)
+			kubeClient := kubefake.NewSimpleClientset(test.objects...)
+			recorder := events.NewInMemoryRecorder("test")
+
+			c := newCertExpiryController("CertExpiry", operatorClient, kubeClient, []ManagedCertificate{{
+				Secret:   certgraphapi.InClusterSecretLocation{Namespace: "openshift-test", Name: "serving"},
+				CABundle: &certgraphapi.InClusterConfigMapLocation{Namespace: "openshift-test", Name: "ca-bundle"},
+			}}, 2*time.Hour)
+			c.now = func() time.Time { return test.now }
+
+			if err := c.sync(context.TODO(), factory.NewSyncContext("test", recorder)); err != nil {
+				t.Fatal(err)
+			}
+
+			actualEvents := []string{}
+			for _, event := range recorder.Events() {
+				actualEvents = append(actualEvents, event.Reason)
+			}
+			if strings.Join(actualEvents, ",") != strings.Join(test.expectedEvents, ",") {
+				t.Errorf("expected events %v, got %v", test.expectedEvents, actualEvents)
+			}
+
+			_, status, _, _ := operatorClient.GetOperatorState()
+			degraded := v1helpers.FindOperatorCondition(status.Conditions, "CertExpiryDegraded")
+			upgradeable := v1helpers.FindOperatorCondition(status.Conditions, "CertExpiryUpgradeable")
+			if degraded == nil || upgradeable == nil {
+				t.Fatalf("missing conditions in %#v", status.Conditions)
+			}
+			if degraded.Status != test.expectedDegraded || (upgradeable.Status == operatorv1.ConditionTrue) != (test.expectedDegraded == operatorv1.ConditionFalse) {
+				t.Errorf("unexpected conditions %#v", status.Conditions)
+			}
+			if !strings.Contains(degraded.Message, test.expectedMessagePart) {
+				t.Errorf("expected message containing %q, got %q", test.expectedMessagePart, degraded.Message)
+			}
+		})
+	}
+}
+
+func TestExpiryMetricsPerController(t *testing.T) {
+	registry := k8smetrics.NewKubeRegistry()
+	m := newExpiryMetrics(registry.Register)
+	serving := notAfterSeries{namespace: "ns", secret: "serving", logicalName: "serving"}
+	client := notAfterSeries{namespace: "ns", secret: "client", logicalName: "client"}
+
+	m.ReplaceNotAfter("first", map[notAfterSeries]float64{serving: 1, client: 2})
+	m.ReplaceNotAfter("second", map[notAfterSeries]float64{serving: 1})
+	// the first controller no longer observes the client certificate
+	m.ReplaceNotAfter("first", map[notAfterSeries]float64{serving: 3})
+
+	expected := `
+# HELP openshift_certificate_not_after_timestamp_seconds [ALPHA] The time the certificate in a secret expires in seconds since the epoch, labeled with the controller observing it, the namespace and name of the secret and the logical name of the certificate
+# TYPE openshift_certificate_not_after_timestamp_seconds gauge
+openshift_certificate_not_after_timestamp_seconds{controller="first",logical_name="serving",namespace="ns",secret="serving"} 3
+openshift_certificate_not_after_timestamp_seconds{controller="second",logical_name="serving",namespace="ns",secret="serving"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "openshift_certificate_not_after_timestamp_seconds"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,74 @@
+package certexpirycontroller
+
+import (
+	"sync"
+
+	k8smetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// metrics provides access to the certificate expiry metrics.
+var metrics *expiryMetrics
+
+func init() {
+	metrics = newExpiryMetrics(legacyregistry.Register)
+}
+
+// expiryMetrics exposes the expiry of the inspected certificates. The series are labeled with the name of the
+// controller observing them, so that multiple controllers in a process do not remove the series of each other.
+type expiryMetrics struct {
+	notAfter *k8smetrics.GaugeVec
+
+	// lock guards observed
+	lock sync.Mutex
+	// observed holds the series set by the last sync of every controller
+	observed map[string]map[notAfterSeries]bool
+}
+
+// notAfterSeries identifies the certificate of a not_after_timestamp_seconds series.
+type notAfterSeries struct {
+	namespace   string
+	secret      string
+	logicalName string
+}
+
+func newExpiryMetrics(registerFunc func(k8smetrics.Registerable) error) *expiryMetrics {
+	notAfter := k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Namespace: "openshift",
+			Subsystem: "certificate",
+			Name:      "not_after_timestamp_seconds",
+			Help:      "The time the certificate in a secret expires in seconds since the epoch, labeled with the controller observing it, the namespace and name of the secret and the logical name of the certificate",
+		}, []string{"controller", "namespace", "secret", "logical_name"})
+
+	registerFunc(notAfter)
+
+	return &expiryMetrics{
+		notAfter: notAfter,
+		observed: map[string]map[notAfterSeries]bool{},
+	}
+}
+
+// ReplaceNotAfter sets the expiry of the certificates observed by the given controller, in seconds since the epoch, and
+// deletes the series of certificates the controller no longer observes.
+func (m *expiryMetrics) ReplaceNotAfter(controller string, notAfterSeconds map[notAfterSeries]float64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	observed := map[notAfterSeries]bool{}
+	for series, value := range notAfterSeconds {
+		m.notAfter.WithLabelValues(controller, series.namespace, series.secret, series.logicalName).Set(value)
+		observed[series] = true
+	}
+	for series := range m.observed[controller] {
+		if !observed[series] {
+			m.notAfter.Delete(map[string]string{
+				"controller":   controller,
+				"namespace":    series.namespace,
+				"secret":       series.secret,
+				"logical_name": series.logicalName,
+			})
+		}
+	}
+	m.observed[controller] = observed
+}
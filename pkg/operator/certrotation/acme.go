@@ -0,0 +1,445 @@
+package certrotation
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	routev1 "github.com/openshift/api/route/v1"
+	routev1client "github.com/openshift/client-go/route/clientset/versioned/typed/route/v1"
+	"golang.org/x/crypto/acme"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/crypto"
+)
+
+const (
+	// ACMEChallengeHTTP01 proves the control of a domain by serving a token over HTTP.
+	ACMEChallengeHTTP01 = "http-01"
+	// ACMEChallengeDNS01 proves the control of a domain by publishing a token in a TXT record.
+	ACMEChallengeDNS01 = "dns-01"
+
+	defaultACMETimeout = 5 * time.Minute
+)
+
+// ACMEChallengeSolver fulfils the challenges of an ACME server for a domain.
+type ACMEChallengeSolver interface {
+	// Type is the challenge type the solver fulfils, ACMEChallengeHTTP01 or ACMEChallengeDNS01.
+	Type() string
+	// Present makes the response to the challenge available to the ACME server.
+	Present(ctx context.Context, client *acme.Client, domain string, challenge *acme.Challenge) error
+	// CleanUp removes the response to the challenge once it was validated.
+	CleanUp(ctx context.Context, client *acme.Client, domain string, challenge *acme.Challenge) error
+}
+
+// ErrCertificatePending is returned by a TargetCertContextCreator while the certificate is being obtained in the
+// background. The target is not rotated in the current sync, the creator triggers a new sync through its
+// RecheckChannel once the certificate is available.
+var ErrCertificatePending = errors.New("the certificate is being obtained")
+
+// ACMEServingRotation is a TargetCertCreator that obtains serving certificates for the hostnames from an ACME server
+// like Let's Encrypt, instead of signing them with the signing CA. The certificates are stored in the same secret format
+// with the same annotations as the ones of ServingRotation, but they are not issued by a CA in the CA bundle: clients
+// have to trust the CA of the ACME server. The lifetime of the certificates is decided by the ACME server.
+//
+// The certificates are obtained in the background, so that the ACME order does not block the controller.
+//
+// The signing CA of RotatedSelfSignedCertKeySecret is still required: it provides the key algorithm of the
+// certificates and its age gates the refresh like for signed certificates. The validity of the certificates is not
+// limited to the one of the signing CA.
+type ACMEServingRotation struct {
+	Hostnames        ServingHostnameFunc
+	HostnamesChanged <-chan struct{}
+
+	// Client talks to the ACME server. Its Key is the account key and its DirectoryURL the directory of the server,
+	// the account is registered on first use.
+	Client *acme.Client
+	// Contact are the contact URLs of the account, e.g. mailto:admin@example.com.
+	Contact []string
+	// Solver fulfils the challenges of the ACME server.
+	Solver ACMEChallengeSolver
+	// Timeout is the maximum duration of obtaining a certificate. It defaults to 5 minutes.
+	Timeout time.Duration
+
+	registerLock sync.Mutex
+	registered   bool
+
+	// lock guards the fields of the order in the background
+	lock sync.Mutex
+	// pending is set while a certificate is obtained for the hostnames of pendingFor
+	pending    bool
+	pendingFor string
+	// issued and issueErr hold the result of the last order for the hostnames of issuedFor. The error is returned once,
+	// the certificate is returned until it is observed in the target secret, so that it is not lost when storing it
+	// fails, or until the hostnames change.
+	issued    *crypto.TLSCertificateConfig
+	issueErr  error
+	issuedFor string
+
+	recheckOnce sync.Once
+	recheck     chan struct{}
+}
+
+var _ TargetCertContextCreator = &ACMEServingRotation{}
+var _ targetCertObserver = &ACMEServingRotation{}
+
+// ExternallyIssued marks the certificates as not issued by the signing CA.
+func (r *ACMEServingRotation) ExternallyIssued() bool {
+	return true
+}
+
+// NewCertificate starts obtaining a certificate in the background, see NewCertificateWithContext. The order is not
+// cancelled before its timeout.
+func (r *ACMEServingRotation) NewCertificate(signer *crypto.CA, validity time.Duration) (*crypto.TLSCertificateConfig, error) {
+	return r.NewCertificateWithContext(context.Background(), signer, validity)
+}
+
+// NewCertificateWithContext returns the certificate obtained for the current hostnames in the background. It starts a
+// new ACME order and returns ErrCertificatePending if there is none yet. The order is cancelled when ctx is done.
+func (r *ACMEServingRotation) NewCertificateWithContext(ctx context.Context, signer *crypto.CA, validity time.Duration) (*crypto.TLSCertificateConfig, error) {
+	hostnames := r.Hostnames()
+	if len(hostnames) == 0 {
+		return nil, fmt.Errorf("no hostnames set")
+	}
+	orderFor := strings.Join(hostnames, ",")
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.issuedFor != orderFor || (r.issued != nil && time.Now().After(r.issued.Certs[0].NotAfter)) {
+		// the certificate was obtained for other hostnames, or it expired before it could be stored
+		r.issued, r.issueErr, r.issuedFor = nil, nil, ""
+	}
+	if r.issueErr != nil {
+		err := r.issueErr
+		r.issueErr, r.issuedFor = nil, ""
+		return nil, err
+	}
+	if r.issued != nil {
+		return r.issued, nil
+	}
+	if r.pending {
+		// an order for other hostnames is superseded once it finished
+		return nil, ErrCertificatePending
+	}
+
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = defaultACMETimeout
+	}
+	r.pending, r.pendingFor = true, orderFor
+	keyAlgorithm := signer.KeyAlgorithm
+	go func() {
+		orderCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		cert, err := r.obtainCertificate(orderCtx, hostnames, keyAlgorithm)
+
+		r.lock.Lock()
+		r.pending = false
+		r.issued, r.issueErr, r.issuedFor = cert, err, r.pendingFor
+		r.lock.Unlock()
+		r.notify()
+	}()
+	return nil, ErrCertificatePending
+}
+
+// observeTargetCertificate forgets the obtained certificate once it is stored in the target secret.
+func (r *ACMEServingRotation) observeTargetCertificate(cert *x509.Certificate) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.issued != nil && r.issued.Certs[0].SerialNumber.Cmp(cert.SerialNumber) == 0 {
+		r.issued, r.issuedFor = nil, ""
+	}
+}
+
+// obtainCertificate runs an ACME order for the hostnames.
+func (r *ACMEServingRotation) obtainCertificate(ctx context.Context, hostnames []string, keyAlgorithm crypto.KeyAlgorithm) (*crypto.TLSCertificateConfig, error) {
+	if err := r.register(ctx); err != nil {
+		return nil, err
+	}
+
+	order, err := r.Client.AuthorizeOrder(ctx, acme.DomainIDs(hostnames...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the ACME order: %w", err)
+	}
+	for _, authzURL := range order.AuthzURLs {
+		if err := r.authorize(ctx, authzURL); err != nil {
+			return nil, err
+		}
+	}
+	orderURI := order.URI
+	if order, err = r.Client.WaitOrder(ctx, orderURI); err != nil {
+		return nil, fmt.Errorf("ACME order %s is not ready: %w", orderURI, err)
+	}
+
+	// the key is generated like the keys of certificates signed by the signer
+	_, key, err := crypto.NewKeyPairForAlgorithm(keyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: hostnames[0]},
+		DNSNames: hostnames,
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+	ders, _, err := r.Client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize the ACME order %s: %w", order.URI, err)
+	}
+
+	certs := []*x509.Certificate{}
+	for _, der := range ders {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return &crypto.TLSCertificateConfig{Certs: certs, Key: key}, nil
+}
+
+func (r *ACMEServingRotation) register(ctx context.Context) error {
+	r.registerLock.Lock()
+	defer r.registerLock.Unlock()
+	if r.registered {
+		return nil
+	}
+
+	_, err := r.Client.GetReg(ctx, "")
+	if errors.Is(err, acme.ErrNoAccount) {
+		_, err = r.Client.Register(ctx, &acme.Account{Contact: r.Contact}, acme.AcceptTOS)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to register the ACME account: %w", err)
+	}
+	r.registered = true
+	return nil
+}
+
+// authorize fulfils the challenge of the solver type for the authorization.
+func (r *ACMEServingRotation) authorize(ctx context.Context, authzURL string) error {
+	authz, err := r.Client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return err
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var challenge *acme.Challenge
+	for _, curr := range authz.Challenges {
+		if curr.Type == r.Solver.Type() {
+			challenge = curr
+			break
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("the ACME server offers no %s challenge for %q", r.Solver.Type(), authz.Identifier.Value)
+	}
+
+	if err := r.Solver.Present(ctx, r.Client, authz.Identifier.Value, challenge); err != nil {
+		return fmt.Errorf("failed to present the %s challenge for %q: %w", challenge.Type, authz.Identifier.Value, err)
+	}
+	defer func() {
+		if err := r.Solver.CleanUp(ctx, r.Client, authz.Identifier.Value, challenge); err != nil {
+			klog.Warningf("failed to clean up the %s challenge for %q: %v", challenge.Type, authz.Identifier.Value, err)
+		}
+	}()
+
+	if _, err := r.Client.Accept(ctx, challenge); err != nil {
+		return err
+	}
+	if _, err := r.Client.WaitAuthorization(ctx, authzURL); err != nil {
+		return fmt.Errorf("ACME authorization of %q failed: %w", authz.Identifier.Value, err)
+	}
+	return nil
+}
+
+// RecheckChannel signals changed hostnames and finished ACME orders.
+func (r *ACMEServingRotation) RecheckChannel() <-chan struct{} {
+	r.initRecheck()
+	return r.recheck
+}
+
+func (r *ACMEServingRotation) initRecheck() {
+	r.recheckOnce.Do(func() {
+		r.recheck = make(chan struct{}, 1)
+		if r.HostnamesChanged != nil {
+			go func() {
+				for range r.HostnamesChanged {
+					r.notify()
+				}
+			}()
+		}
+	})
+}
+
+// notify triggers a recheck unless one is pending already.
+func (r *ACMEServingRotation) notify() {
+	r.initRecheck()
+	select {
+	case r.recheck <- struct{}{}:
+	default:
+	}
+}
+
+// NeedNewTargetCertKeyPair checks the hostnames of the certificate. Unlike ServingRotation, the issuer does not have to
+// be in the CA bundle. The validity is checked by RotatedSelfSignedCertKeySecret with its Policy before.
+func (r *ACMEServingRotation) NeedNewTargetCertKeyPair(annotations map[string]string, signer *crypto.CA, caBundleCerts []*x509.Certificate, refresh time.Duration, refreshOnlyWhenExpired bool) string {
+	return (&ServingRotation{Hostnames: r.Hostnames}).missingHostnames(annotations)
+}
+
+func (r *ACMEServingRotation) SetAnnotations(cert *crypto.TLSCertificateConfig, annotations map[string]string) map[string]string {
+	return (&ServingRotation{}).SetAnnotations(cert, annotations)
+}
+
+// HTTP01Solver fulfils http-01 challenges by serving the responses on /.well-known/acme-challenge/<token>. The ACME
+// server must reach it on port 80 of the domains, see RouteHTTP01Solver.
+type HTTP01Solver struct {
+	lock      sync.RWMutex
+	responses map[string]string
+}
+
+var _ http.Handler = &HTTP01Solver{}
+
+func (s *HTTP01Solver) Type() string {
+	return ACMEChallengeHTTP01
+}
+
+func (s *HTTP01Solver) Present(ctx context.Context, client *acme.Client, domain string, challenge *acme.Challenge) error {
+	response, err := client.HTTP01ChallengeResponse(challenge.Token)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.responses == nil {
+		s.responses = map[string]string{}
+	}
+	s.responses[client.HTTP01ChallengePath(challenge.Token)] = response
+	return nil
+}
+
+func (s *HTTP01Solver) CleanUp(ctx context.Context, client *acme.Client, domain string, challenge *acme.Challenge) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.responses, client.HTTP01ChallengePath(challenge.Token))
+	return nil
+}
+
+// ServeHTTP serves the responses to the presented challenges.
+func (s *HTTP01Solver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.lock.RLock()
+	response, ok := s.responses[req.URL.Path]
+	s.lock.RUnlock()
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(response))
+}
+
+// RouteHTTP01Solver fulfils http-01 challenges by exposing the HTTP01Solver, served by the pods of a service, with a
+// route for every challenge.
+type RouteHTTP01Solver struct {
+	HTTP01Solver
+
+	// Namespace is the namespace of the service and the routes.
+	Namespace string
+	// ServiceName is the service whose pods serve the HTTP01Solver.
+	ServiceName string
+	// ServicePort is the port of the service.
+	ServicePort intstr.IntOrString
+	Client      routev1client.RoutesGetter
+}
+
+func (s *RouteHTTP01Solver) Present(ctx context.Context, client *acme.Client, domain string, challenge *acme.Challenge) error {
+	if err := s.HTTP01Solver.Present(ctx, client, domain, challenge); err != nil {
+		return err
+	}
+	route := &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: s.Namespace,
+			Name:      challengeRouteName(challenge),
+			Labels:    map[string]string{"acme.openshift.io/challenge": "true"},
+		},
+		Spec: routev1.RouteSpec{
+			Host: domain,
+			Path: client.HTTP01ChallengePath(challenge.Token),
+			To:   routev1.RouteTargetReference{Kind: "Service", Name: s.ServiceName},
+			Port: &routev1.RoutePort{TargetPort: s.ServicePort},
+		},
+	}
+	_, err := s.Client.Routes(s.Namespace).Create(ctx, route, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *RouteHTTP01Solver) CleanUp(ctx context.Context, client *acme.Client, domain string, challenge *acme.Challenge) error {
+	err := s.Client.Routes(s.Namespace).Delete(ctx, challengeRouteName(challenge), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return s.HTTP01Solver.CleanUp(ctx, client, domain, challenge)
+}
+
+func challengeRouteName(challenge *acme.Challenge) string {
+	// tokens are base64url encoded, route names must be DNS labels
+	name := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(challenge.Token))
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	return "acme-challenge-" + name
+}
+
+// DNS01Provider publishes TXT records for a DNS01Solver.
+type DNS01Provider interface {
+	// SetTXTRecord sets the TXT record of the given fully qualified name.
+	SetTXTRecord(ctx context.Context, fqdn, value string) error
+	// DeleteTXTRecord deletes the TXT record of the given fully qualified name.
+	DeleteTXTRecord(ctx context.Context, fqdn, value string) error
+}
+
+// DNS01Solver fulfils dns-01 challenges by publishing the responses as TXT records of _acme-challenge.<domain> with
+// a pluggable DNS provider.
+type DNS01Solver struct {
+	Provider DNS01Provider
+}
+
+func (s *DNS01Solver) Type() string {
+	return ACMEChallengeDNS01
+}
+
+func (s *DNS01Solver) Present(ctx context.Context, client *acme.Client, domain string, challenge *acme.Challenge) error {
+	record, err := client.DNS01ChallengeRecord(challenge.Token)
+	if err != nil {
+		return err
+	}
+	return s.Provider.SetTXTRecord(ctx, dns01RecordName(domain), record)
+}
+
+func (s *DNS01Solver) CleanUp(ctx context.Context, client *acme.Client, domain string, challenge *acme.Challenge) error {
+	record, err := client.DNS01ChallengeRecord(challenge.Token)
+	if err != nil {
+		return err
+	}
+	return s.Provider.DeleteTXTRecord(ctx, dns01RecordName(domain), record)
+}
+
+func dns01RecordName(domain string) string {
+	return "_acme-challenge." + strings.TrimPrefix(domain, "*.") + "."
+}
@@ -0,0 +1,567 @@
+package certrotation
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	routefake "github.com/openshift/client-go/route/clientset/versioned/fake"
+	"golang.org/x/crypto/acme"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/events"
+)
+
+func newTestACMEClient(t *testing.T) *acme.Client {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &acme.Client{Key: key}
+}
+
+func TestHTTP01Solver(t *testing.T) {
+	client := newTestACMEClient(t)
+	challenge := &acme.Challenge{Type: ACMEChallengeHTTP01, Token: "Token_with-chars"}
+	routeClient := routefake.NewSimpleClientset()
+	solver := &RouteHTTP01Solver{
+		Namespace:   "ns",
+		ServiceName: "acme-solver",
+		ServicePort: intstr.FromInt(8080),
+		Client:      routeClient.RouteV1(),
+	}
+	server := httptest.NewServer(solver)
+	defer server.Close()
+
+	get := func() (int, string) {
+		resp, err := http.Get(server.URL + client.HTTP01ChallengePath(challenge.Token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	if err := solver.Present(context.TODO(), client, "example.com", challenge); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := client.HTTP01ChallengeResponse(challenge.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, body := get(); status != http.StatusOK || body != expected {
+		t.Errorf("expected the challenge response %q, got %d %q", expected, status, body)
+	}
+	route, err := routeClient.RouteV1().Routes("ns").Get(context.TODO(), "acme-challenge-tokenwithchars", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if route.Spec.Host != "example.com" || route.Spec.Path != "/.well-known/acme-challenge/Token_with-chars" || route.Spec.To.Name != "acme-solver" {
+		t.Errorf("unexpected route %#v", route.Spec)
+	}
+
+	if err := solver.CleanUp(context.TODO(), client, "example.com", challenge); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := get(); status != http.StatusNotFound {
+		t.Errorf("expected no challenge response after clean up, got %d", status)
+	}
+	if routes, _ := routeClient.RouteV1().Routes("ns").List(context.TODO(), metav1.ListOptions{}); len(routes.Items) != 0 {
+		t.Errorf("expected the route to be deleted, got %v", routes.Items)
+	}
+}
+
+type fakeDNS01Provider struct {
+	records map[string]string
+}
+
+func (p *fakeDNS01Provider) SetTXTRecord(ctx context.Context, fqdn, value string) error {
+	p.records[fqdn] = value
+	return nil
+}
+
+func (p *fakeDNS01Provider) DeleteTXTRecord(ctx context.Context, fqdn, value string) error {
+	delete(p.records, fqdn)
+	return nil
+}
+
+func TestDNS01Solver(t *testing.T) {
+	client := newTestACMEClient(t)
+	challenge := &acme.Challenge{Type: ACMEChallengeDNS01, Token: "token"}
+	provider := &fakeDNS01Provider{records: map[string]string{}}
+	solver := &DNS01Solver{Provider: provider}
+
+	if err := solver.Present(context.TODO(), client, "*.apps.example.com", challenge); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := client.DNS01ChallengeRecord(challenge.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := provider.records["_acme-challenge.apps.example.com."]; actual != expected {
+		t.Errorf("expected the TXT record %q, got %v", expected, provider.records)
+	}
+	if err := solver.CleanUp(context.TODO(), client, "*.apps.example.com", challenge); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.records) != 0 {
+		t.Errorf("expected the TXT record to be deleted, got %v", provider.records)
+	}
+}
+
+func TestACMEServingRotationNeedNewTargetCertKeyPair(t *testing.T) {
+	signer, err := crypto.MakeSelfSignedCAConfigForDuration("signer", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotation := &ACMEServingRotation{Hostnames: func() []string { return []string{"example.com"} }}
+	now := time.Now()
+	annotations := map[string]string{
+		CertificateNotBeforeAnnotation: now.Add(-time.Hour).Format(time.RFC3339),
+		CertificateNotAfterAnnotation:  now.Add(24 * time.Hour).Format(time.RFC3339),
+		CertificateIssuer:              "ACME issuer",
+		CertificateHostnames:           "example.com",
+	}
+
+	if reason := rotation.NeedNewTargetCertKeyPair(annotations, &crypto.CA{Config: signer}, signer.Certs, 10*time.Hour, false); len(reason) > 0 {
+		t.Errorf("expected the ACME issuer not to be required in the CA bundle, got %q", reason)
+	}
+	annotations[CertificateHostnames] = "other.example.com"
+	if reason := rotation.NeedNewTargetCertKeyPair(annotations, &crypto.CA{Config: signer}, signer.Certs, 10*time.Hour, false); !strings.Contains(reason, "required and not existing") {
+		t.Errorf("expected changed hostnames to require a new certificate, got %q", reason)
+	}
+}
+
+// TestACMEServingRotationPebble obtains a certificate from a local ACME test server like pebble, e.g. started with
+//
+//	PEBBLE_VA_ALWAYS_VALID=1 pebble -config test/config/pebble-config.json
+//
+// and ACME_DIRECTORY_URL=https://localhost:14000/dir. With PEBBLE_VA_ALWAYS_VALID, the challenges are not validated.
+func TestACMEServingRotationPebble(t *testing.T) {
+	directoryURL := os.Getenv("ACME_DIRECTORY_URL")
+	if len(directoryURL) == 0 {
+		t.Skip("ACME_DIRECTORY_URL is not set")
+	}
+
+	client := newTestACMEClient(t)
+	client.DirectoryURL = directoryURL
+	client.HTTPClient = &http.Client{Transport: &http.Transport{
+		// pebble serves its directory with a test certificate
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	solver := &HTTP01Solver{}
+	listener, err := net.Listen("tcp", "127.0.0.1:5002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: solver}
+	go server.Serve(listener)
+	defer server.Close()
+
+	rotation := &ACMEServingRotation{
+		Hostnames: func() []string { return []string{"localhost"} },
+		Client:    client,
+		Solver:    solver,
+		Timeout:   time.Minute,
+	}
+	signer, err := crypto.MakeSelfSignedCAConfigForDuration("signer", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := obtainTestCertificate(rotation, &crypto.CA{Config: signer, KeyAlgorithm: crypto.ECDSAP256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Certs[0].DNSNames[0] != "localhost" {
+		t.Errorf("unexpected certificate %v", cert.Certs[0].DNSNames)
+	}
+	if algorithm, err := crypto.KeyAlgorithmOf(cert.Key); err != nil || algorithm != crypto.ECDSAP256 {
+		t.Errorf("expected a %s key, got %s: %v", crypto.ECDSAP256, algorithm, err)
+	}
+}
+
+// obtainTestCertificate calls NewCertificateWithContext until the certificate obtained in the background is available.
+func obtainTestCertificate(rotation *ACMEServingRotation, signer *crypto.CA) (*crypto.TLSCertificateConfig, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	recheck := rotation.RecheckChannel()
+	for {
+		cert, err := rotation.NewCertificateWithContext(ctx, signer, time.Hour)
+		if !errors.Is(err, ErrCertificatePending) {
+			return cert, err
+		}
+		select {
+		case <-recheck:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestACMEServingRotationFakeDirectory(t *testing.T) {
+	solver := &HTTP01Solver{}
+	server := newFakeACMEServer(t, solver)
+	defer server.Close()
+
+	client := newTestACMEClient(t)
+	client.DirectoryURL = server.URL + "/directory"
+	hostnames := []string{"example.com", "www.example.com"}
+	rotation := &ACMEServingRotation{
+		Hostnames: func() []string { return hostnames },
+		Client:    client,
+		Contact:   []string{"mailto:admin@example.com"},
+		Solver:    solver,
+		Timeout:   time.Minute,
+	}
+	signer, err := crypto.MakeSelfSignedCAConfigForDuration("signer", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the order runs in the background
+	if _, err := rotation.NewCertificateWithContext(context.TODO(), &crypto.CA{Config: signer, KeyAlgorithm: crypto.ECDSAP256}, time.Hour); !errors.Is(err, ErrCertificatePending) {
+		t.Fatalf("expected the certificate to be pending, got %v", err)
+	}
+	cert, err := obtainTestCertificate(rotation, &crypto.CA{Config: signer, KeyAlgorithm: crypto.ECDSAP256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(cert.Certs[0].DNSNames, ",") != "example.com,www.example.com" || cert.Certs[0].Issuer.CommonName != "fake-acme" {
+		t.Errorf("unexpected certificate for %v issued by %q", cert.Certs[0].DNSNames, cert.Certs[0].Issuer.CommonName)
+	}
+	if algorithm, err := crypto.KeyAlgorithmOf(cert.Key); err != nil || algorithm != crypto.ECDSAP256 {
+		t.Errorf("expected a %s key, got %s: %v", crypto.ECDSAP256, algorithm, err)
+	}
+	if len(solver.responses) != 0 {
+		t.Errorf("expected the challenges to be cleaned up, got %v", solver.responses)
+	}
+
+	// the certificate is returned again until it is stored in the target secret, e.g. when updating the secret failed
+	if again, err := rotation.NewCertificateWithContext(context.TODO(), &crypto.CA{Config: signer}, time.Hour); err != nil || again != cert {
+		t.Fatalf("expected the obtained certificate again, got %v", err)
+	}
+	rotation.observeTargetCertificate(&x509.Certificate{SerialNumber: new(big.Int).Add(cert.Certs[0].SerialNumber, big.NewInt(1))})
+	if again, err := rotation.NewCertificateWithContext(context.TODO(), &crypto.CA{Config: signer}, time.Hour); err != nil || again != cert {
+		t.Fatalf("expected the obtained certificate to be kept while another one is stored, got %v", err)
+	}
+	rotation.observeTargetCertificate(cert.Certs[0])
+
+	// the order is cancelled with the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := rotation.NewCertificateWithContext(ctx, &crypto.CA{Config: signer}, time.Hour); !errors.Is(err, ErrCertificatePending) {
+		t.Fatalf("expected a new order once the certificate was stored, got %v", err)
+	}
+	<-rotation.RecheckChannel()
+	if _, err := rotation.NewCertificateWithContext(context.TODO(), &crypto.CA{Config: signer}, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancelled order to fail, got %v", err)
+	}
+
+	// a certificate obtained for other hostnames is dropped
+	rotation.lock.Lock()
+	rotation.issued, rotation.issuedFor = cert, strings.Join(hostnames, ",")
+	rotation.lock.Unlock()
+	hostnames = []string{"example.com"}
+	if _, err := rotation.NewCertificateWithContext(ctx, &crypto.CA{Config: signer}, time.Hour); !errors.Is(err, ErrCertificatePending) {
+		t.Fatalf("expected a new order for the changed hostnames, got %v", err)
+	}
+	<-rotation.RecheckChannel()
+	if _, err := rotation.NewCertificateWithContext(context.TODO(), &crypto.CA{Config: signer}, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancelled order to fail, got %v", err)
+	}
+}
+
+// newFakeACMEServer serves the parts of RFC 8555 used by ACMEServingRotation. It validates http-01 challenges with the
+// solver and does not verify the signatures of the requests.
+func newFakeACMEServer(t *testing.T, solver *HTTP01Solver) *httptest.Server {
+	t.Helper()
+	caConfig, err := crypto.MakeSelfSignedCAConfigForDuration("fake-acme", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lock sync.Mutex
+	var server *httptest.Server
+	registered := false
+	identifiers := []map[string]string{}
+	authzValid := map[string]bool{}
+	var certPEM []byte
+
+	writeJSON := func(w http.ResponseWriter, status int, location string, obj interface{}) {
+		if len(location) > 0 {
+			w.Header().Set("Location", server.URL+location)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(obj)
+	}
+	order := func() map[string]interface{} {
+		status := "ready"
+		authorizations := []string{}
+		for i := range identifiers {
+			authorizations = append(authorizations, fmt.Sprintf("%s/authz/%d", server.URL, i))
+			if !authzValid[fmt.Sprint(i)] {
+				status = "pending"
+			}
+		}
+		obj := map[string]interface{}{"status": status, "identifiers": identifiers, "authorizations": authorizations, "finalize": server.URL + "/finalize"}
+		if certPEM != nil {
+			obj["status"] = "valid"
+			obj["certificate"] = server.URL + "/cert"
+		}
+		return obj
+	}
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", time.Now().UnixNano()))
+		if req.URL.Path == "/directory" {
+			writeJSON(w, http.StatusOK, "", map[string]string{
+				"newNonce":   server.URL + "/nonce",
+				"newAccount": server.URL + "/account",
+				"newOrder":   server.URL + "/order",
+				"revokeCert": server.URL + "/revoke",
+				"keyChange":  server.URL + "/key-change",
+			})
+			return
+		}
+		if req.Method == http.MethodHead || req.URL.Path == "/nonce" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		var jws struct {
+			Protected string `json:"protected"`
+			Payload   string `json:"payload"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&jws); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		request := map[string]interface{}{}
+		if len(payload) > 0 {
+			if err := json.Unmarshal(payload, &request); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		switch path := req.URL.Path; {
+		case path == "/account":
+			if request["onlyReturnExisting"] == true && !registered {
+				writeJSON(w, http.StatusBadRequest, "", map[string]string{"type": "urn:ietf:params:acme:error:accountDoesNotExist"})
+				return
+			}
+			registered = true
+			writeJSON(w, http.StatusCreated, "/accounts/1", map[string]interface{}{"status": "valid", "contact": request["contact"]})
+		case path == "/order":
+			identifiers = identifiers[:0]
+			for _, identifier := range request["identifiers"].([]interface{}) {
+				identifiers = append(identifiers, map[string]string{"type": "dns", "value": identifier.(map[string]interface{})["value"].(string)})
+			}
+			authzValid = map[string]bool{}
+			certPEM = nil
+			writeJSON(w, http.StatusCreated, "/orders/1", order())
+		case path == "/orders/1":
+			writeJSON(w, http.StatusOK, "/orders/1", order())
+		case strings.HasPrefix(path, "/authz/"):
+			i := strings.TrimPrefix(path, "/authz/")
+			status := "pending"
+			if authzValid[i] {
+				status = "valid"
+			}
+			var identifier map[string]string
+			for j, curr := range identifiers {
+				if fmt.Sprint(j) == i {
+					identifier = curr
+				}
+			}
+			writeJSON(w, http.StatusOK, "", map[string]interface{}{
+				"status":     status,
+				"identifier": identifier,
+				"challenges": []map[string]string{
+					{"type": ACMEChallengeDNS01, "url": server.URL + "/challenges/dns/" + i, "token": "dns-token-" + i, "status": "pending"},
+					{"type": ACMEChallengeHTTP01, "url": server.URL + "/challenges/http/" + i, "token": "http-token-" + i, "status": "pending"},
+				},
+			})
+		case strings.HasPrefix(path, "/challenges/http/"):
+			i := strings.TrimPrefix(path, "/challenges/http/")
+			token := "http-token-" + i
+			// validate the response served by the solver
+			recorder := httptest.NewRecorder()
+			solver.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/.well-known/acme-challenge/"+token, nil))
+			if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Body.String(), token+".") {
+				writeJSON(w, http.StatusForbidden, "", map[string]string{"type": "urn:ietf:params:acme:error:unauthorized", "detail": "invalid challenge response"})
+				return
+			}
+			authzValid[i] = true
+			writeJSON(w, http.StatusOK, "", map[string]string{"type": ACMEChallengeHTTP01, "url": server.URL + path, "token": token, "status": "valid"})
+		case path == "/finalize":
+			der, err := base64.RawURLEncoding.DecodeString(request["csr"].(string))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			csr, err := x509.ParseCertificateRequest(der)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			certDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+				SerialNumber: big.NewInt(time.Now().UnixNano()),
+				Subject:      csr.Subject,
+				DNSNames:     csr.DNSNames,
+				NotBefore:    time.Now().Add(-time.Minute),
+				NotAfter:     time.Now().Add(time.Hour),
+				KeyUsage:     x509.KeyUsageDigitalSignature,
+				ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			}, caConfig.Certs[0], csr.PublicKey, caConfig.Key)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			cert, err := x509.ParseCertificate(certDER)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if certPEM, err = crypto.EncodeCertificates(cert, caConfig.Certs[0]); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, "/orders/1", order())
+		case path == "/cert":
+			w.Header().Set("Content-Type", "application/pem-certificate-chain")
+			w.Write(certPEM)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	return server
+}
+
+func TestEnsureTargetCertKeyPairACME(t *testing.T) {
+	solver := &HTTP01Solver{}
+	server := newFakeACMEServer(t, solver)
+	defer server.Close()
+
+	client := newTestACMEClient(t)
+	client.DirectoryURL = server.URL + "/directory"
+	rotation := &ACMEServingRotation{
+		Hostnames: func() []string { return []string{"example.com"} },
+		Client:    client,
+		Solver:    solver,
+	}
+	caConfig, err := crypto.MakeSelfSignedCAConfigForDuration("signer", 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &crypto.CA{Config: caConfig, SerialGenerator: &crypto.RandomSerialGenerator{}}
+
+	kubeClient := kubefake.NewSimpleClientset()
+	c := &RotatedSelfSignedCertKeySecret{
+		Namespace:     "ns",
+		Name:          "target-secret",
+		Validity:      24 * time.Hour,
+		Refresh:       12 * time.Hour,
+		CertCreator:   rotation,
+		Client:        kubeClient.CoreV1(),
+		Lister:        corev1listers.NewSecretLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		EventRecorder: events.NewInMemoryRecorder("test"),
+	}
+
+	// the sync does not wait for the ACME order
+	if err := c.ensureTargetCertKeyPair(context.TODO(), ca, ca.Config.Certs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kubeClient.CoreV1().Secrets("ns").Get(context.TODO(), "target-secret", metav1.GetOptions{}); err == nil {
+		t.Fatal("expected no secret while the certificate is pending")
+	}
+
+	select {
+	case <-rotation.RecheckChannel():
+	case <-time.After(time.Minute):
+		t.Fatal("the ACME order did not finish")
+	}
+
+	// the obtained certificate is not lost when storing it fails
+	failCreate := true
+	kubeClient.PrependReactor("create", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if failCreate {
+			failCreate = false
+			return true, nil, fmt.Errorf("create failed")
+		}
+		return false, nil, nil
+	})
+	if err := c.ensureTargetCertKeyPair(context.TODO(), ca, ca.Config.Certs); err == nil {
+		t.Fatal("expected the secret creation to fail")
+	}
+	if err := c.ensureTargetCertKeyPair(context.TODO(), ca, ca.Config.Certs); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := kubeClient.CoreV1().Secrets("ns").Get(context.TODO(), "target-secret", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Annotations[CertificateIssuer] != "fake-acme" || secret.Annotations[CertificateHostnames] != "example.com" {
+		t.Errorf("unexpected annotations %v", secret.Annotations)
+	}
+
+	// the certificate is forgotten once it is seen in the secret
+	c.Lister = corev1listers.NewSecretLister(newSecretIndexer(t, secret))
+	if err := c.ensureTargetCertKeyPair(context.TODO(), ca, ca.Config.Certs); err != nil {
+		t.Fatal(err)
+	}
+	rotation.lock.Lock()
+	defer rotation.lock.Unlock()
+	if rotation.issued != nil {
+		t.Errorf("expected the stored certificate to be forgotten")
+	}
+}
+
+func newSecretIndexer(t *testing.T, secrets ...*corev1.Secret) cache.Indexer {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, secret := range secrets {
+		if err := indexer.Add(secret); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return indexer
+}
@@ -3,6 +3,7 @@ package certrotation
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	operatorv1 "github.com/openshift/api/operator/v1"
@@ -42,6 +43,8 @@ type CertRotationController struct {
 	CABundleConfigMap CABundleConfigMap
 	// RotatedSelfSignedCertKeySecret rotates a key and cert signed by a signing CA and stores it in a secret.
 	RotatedSelfSignedCertKeySecret RotatedSelfSignedCertKeySecret
+	// rotationPolicyConfigMap holds the rotation policies replacing the Policy of the secrets above, if set.
+	rotationPolicyConfigMap RotationPolicyConfigMap
 
 	// Plumbing:
 	OperatorClient v1helpers.StaticPodOperatorClient
@@ -54,22 +57,41 @@ func NewCertRotationController(
 	rotatedSelfSignedCertKeySecret RotatedSelfSignedCertKeySecret,
 	operatorClient v1helpers.StaticPodOperatorClient,
 	recorder events.Recorder,
+) factory.Controller {
+	return NewCertRotationControllerWithPolicyConfig(name, rotatedSigningCASecret, caBundleConfigMap, rotatedSelfSignedCertKeySecret, RotationPolicyConfigMap{}, operatorClient, recorder)
+}
+
+// NewCertRotationControllerWithPolicyConfig returns a CertRotationController which reads the rotation policies of the
+// signing CA, the CA bundle and the target certificate from the given RotationPolicyConfigMap on every sync.
+func NewCertRotationControllerWithPolicyConfig(
+	name string,
+	rotatedSigningCASecret RotatedSigningCASecret,
+	caBundleConfigMap CABundleConfigMap,
+	rotatedSelfSignedCertKeySecret RotatedSelfSignedCertKeySecret,
+	rotationPolicyConfigMap RotationPolicyConfigMap,
+	operatorClient v1helpers.StaticPodOperatorClient,
+	recorder events.Recorder,
 ) factory.Controller {
 	c := &CertRotationController{
 		name:                           name,
 		rotatedSigningCASecret:         rotatedSigningCASecret,
 		CABundleConfigMap:              caBundleConfigMap,
 		RotatedSelfSignedCertKeySecret: rotatedSelfSignedCertKeySecret,
+		rotationPolicyConfigMap:        rotationPolicyConfigMap,
 		OperatorClient:                 operatorClient,
 	}
+	informers := []factory.Informer{
+		rotatedSigningCASecret.Informer.Informer(),
+		caBundleConfigMap.Informer.Informer(),
+		rotatedSelfSignedCertKeySecret.Informer.Informer(),
+	}
+	if rotationPolicyConfigMap.Informer != nil {
+		informers = append(informers, rotationPolicyConfigMap.Informer.Informer())
+	}
 	return factory.New().
 		ResyncEvery(time.Minute).
 		WithSync(c.Sync).
-		WithInformers(
-			rotatedSigningCASecret.Informer.Informer(),
-			caBundleConfigMap.Informer.Informer(),
-			rotatedSelfSignedCertKeySecret.Informer.Informer(),
-		).
+		WithInformers(informers...).
 		WithPostStartHooks(
 			c.targetCertRecheckerPostRunHook,
 		).
@@ -77,7 +99,10 @@ func NewCertRotationController(
 }
 
 func (c CertRotationController) Sync(ctx context.Context, syncCtx factory.SyncContext) error {
-	syncErr := c.syncWorker(ctx)
+	syncErr := c.applyRotationPolicyConfig()
+	if syncErr == nil {
+		syncErr = c.syncWorker(ctx)
+	}
 
 	// running this function with RunOnceContextKey value context will make this "run-once" without updating status.
 	isRunOnce, ok := ctx.Value(RunOnceContextKey).(bool)
@@ -94,7 +119,10 @@ func (c CertRotationController) Sync(ctx context.Context, syncCtx factory.SyncCo
 		newCondition.Reason = "RotationError"
 		newCondition.Message = syncErr.Error()
 	}
-	_, updated, updateErr := v1helpers.UpdateStaticPodStatus(ctx, c.OperatorClient, v1helpers.UpdateStaticPodConditionFn(newCondition))
+	_, updated, updateErr := v1helpers.UpdateStaticPodStatus(ctx, c.OperatorClient,
+		v1helpers.UpdateStaticPodConditionFn(newCondition),
+		v1helpers.UpdateStaticPodConditionFn(c.rotationPlannedCondition()),
+	)
 	if updateErr != nil {
 		return updateErr
 	}
@@ -123,6 +151,46 @@ func (c CertRotationController) syncWorker(ctx context.Context) error {
 	return nil
 }
 
+// applyRotationPolicyConfig replaces the policies of the rotated secrets by those of the rotation policy ConfigMap.
+// The controller is passed by value to Sync, so the policies only apply to the current sync.
+func (c *CertRotationController) applyRotationPolicyConfig() error {
+	config, err := c.rotationPolicyConfigMap.getRotationPolicyConfig()
+	if err != nil {
+		return err
+	}
+	c.rotatedSigningCASecret.Policy = config.policyFor(c.rotatedSigningCASecret.Namespace, c.rotatedSigningCASecret.Name, c.rotatedSigningCASecret.Policy)
+	c.CABundleConfigMap.Policy = config.policyFor(c.CABundleConfigMap.Namespace, c.CABundleConfigMap.Name, c.CABundleConfigMap.Policy)
+	c.RotatedSelfSignedCertKeySecret.Policy = config.policyFor(c.RotatedSelfSignedCertKeySecret.Namespace, c.RotatedSelfSignedCertKeySecret.Name, c.RotatedSelfSignedCertKeySecret.Policy)
+	return nil
+}
+
+// rotationPlannedCondition returns the condition listing the planned rotations of the signing CA and the target
+// certificate.
+func (c CertRotationController) rotationPlannedCondition() operatorv1.OperatorCondition {
+	newCondition := operatorv1.OperatorCondition{
+		Type:   fmt.Sprintf(condition.CertRotationPlannedConditionTypeFmt, c.name),
+		Status: operatorv1.ConditionTrue,
+		Reason: "RotationPlanned",
+	}
+	statuses := []string{}
+	for _, statusFn := range []func() (RotationStatus, error){c.rotatedSigningCASecret.RotationStatus, c.RotatedSelfSignedCertKeySecret.RotationStatus} {
+		status, err := statusFn()
+		if err != nil {
+			newCondition.Status = operatorv1.ConditionUnknown
+			newCondition.Reason = "RotationStatusError"
+			statuses = append(statuses, err.Error())
+			continue
+		}
+		if len(status.Reason) > 0 && newCondition.Status == operatorv1.ConditionTrue {
+			newCondition.Status = operatorv1.ConditionFalse
+			newCondition.Reason = "RotationPending"
+		}
+		statuses = append(statuses, status.String())
+	}
+	newCondition.Message = strings.Join(statuses, "\n")
+	return newCondition
+}
+
 func (c CertRotationController) targetCertRecheckerPostRunHook(ctx context.Context, syncCtx factory.SyncContext) error {
 	// If we have a need to force rechecking the cert, use this channel to do it.
 	refresher, ok := c.RotatedSelfSignedCertKeySecret.CertCreator.(TargetCertRechecker)
@@ -0,0 +1,133 @@
+package certrotation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/condition"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+)
+
+func TestCertRotationControllerPolicyConfig(t *testing.T) {
+	policyConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rotation-policy"},
+		Data:       map[string]string{RotationPolicyConfigKey: "secrets:\n  ns/target:\n    renewAtPercent: 25\n"},
+	}
+	client := kubefake.NewSimpleClientset(policyConfigMap)
+	secrets := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	configMaps := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	if err := configMaps.Add(policyConfigMap); err != nil {
+		t.Fatal(err)
+	}
+	recorder := events.NewInMemoryRecorder("test")
+	operatorClient := v1helpers.NewFakeStaticPodOperatorClient(&operatorv1.StaticPodOperatorSpec{}, &operatorv1.StaticPodOperatorStatus{}, nil, nil)
+
+	c := CertRotationController{
+		name: "test",
+		rotatedSigningCASecret: RotatedSigningCASecret{
+			Namespace:     "ns",
+			Name:          "signer",
+			Validity:      48 * time.Hour,
+			Refresh:       24 * time.Hour,
+			Client:        client.CoreV1(),
+			Lister:        corev1listers.NewSecretLister(secrets),
+			EventRecorder: recorder,
+		},
+		CABundleConfigMap: CABundleConfigMap{
+			Namespace:     "ns",
+			Name:          "ca-bundle",
+			Client:        client.CoreV1(),
+			Lister:        corev1listers.NewConfigMapLister(configMaps),
+			EventRecorder: recorder,
+		},
+		RotatedSelfSignedCertKeySecret: RotatedSelfSignedCertKeySecret{
+			Namespace:     "ns",
+			Name:          "target",
+			Validity:      24 * time.Hour,
+			Refresh:       20 * time.Hour,
+			CertCreator:   &ClientRotation{UserInfo: &user.DefaultInfo{Name: "client"}},
+			Client:        client.CoreV1(),
+			Lister:        corev1listers.NewSecretLister(secrets),
+			EventRecorder: recorder,
+		},
+		rotationPolicyConfigMap: RotationPolicyConfigMap{
+			Namespace: "ns",
+			Name:      "rotation-policy",
+			Lister:    corev1listers.NewConfigMapLister(configMaps),
+		},
+		OperatorClient: operatorClient,
+	}
+	syncCtx := factory.NewSyncContext("test", recorder)
+
+	plannedCondition := func() *operatorv1.OperatorCondition {
+		_, status, _, err := operatorClient.GetStaticPodOperatorState()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v1helpers.FindOperatorCondition(status.Conditions, fmt.Sprintf(condition.CertRotationPlannedConditionTypeFmt, "test"))
+	}
+
+	// the listers do not see the created secrets yet
+	if err := c.Sync(context.TODO(), syncCtx); err != nil {
+		t.Fatal(err)
+	}
+	if cond := plannedCondition(); cond == nil || cond.Status != operatorv1.ConditionFalse || cond.Reason != "RotationPending" || !strings.Contains(cond.Message, "ns/target: rotation pending: missing") {
+		t.Fatalf("unexpected condition %#v", cond)
+	}
+
+	for _, name := range []string{"signer", "target"} {
+		secret, err := client.CoreV1().Secrets("ns").Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := secrets.Add(secret); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Sync(context.TODO(), syncCtx); err != nil {
+		t.Fatal(err)
+	}
+	cond := plannedCondition()
+	if cond == nil || cond.Status != operatorv1.ConditionTrue || cond.Reason != "RotationPlanned" {
+		t.Fatalf("unexpected condition %#v", cond)
+	}
+	target, err := client.CoreV1().Secrets("ns").Get(context.TODO(), "target", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notBefore, _, _ := getValidityFromAnnotations(target.Annotations)
+	expected := "next rotation at " + notBefore.Add(6*time.Hour).Format(time.RFC3339)
+	if !strings.Contains(cond.Message, expected) {
+		t.Errorf("expected the condition message to contain %q with the configured policy, got %q", expected, cond.Message)
+	}
+
+	// an invalid policy config degrades the controller
+	invalid := policyConfigMap.DeepCopy()
+	invalid.Data[RotationPolicyConfigKey] = "default:\n  renewAtPercent: 100\n"
+	if err := configMaps.Update(invalid); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Sync(context.TODO(), syncCtx); err == nil || !strings.Contains(err.Error(), "renewAtPercent must be between 1 and 99") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_, status, _, err := operatorClient.GetStaticPodOperatorState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v1helpers.IsOperatorConditionTrue(status.Conditions, fmt.Sprintf(condition.CertRotationDegradedConditionTypeFmt, "test")) {
+		t.Errorf("expected the controller to be degraded, got %#v", status.Conditions)
+	}
+}
@@ -0,0 +1,258 @@
+package certrotation
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// defaultRenewAtPercent is the percentage of the lifetime after which certificates are renewed by default.
+	defaultRenewAtPercent = 80
+
+	// RotationPolicyConfigKey is the key of the RotationPolicyConfig in the ConfigMap read by GetRotationPolicyConfig.
+	RotationPolicyConfigKey = "config.yaml"
+)
+
+// RotationPolicy declares when certificates are rotated. It applies on top of the Refresh and Validity durations of the
+// rotated secrets, its zero value keeps the default behaviour of renewing at 80% of the lifetime. It can be embedded in
+// an operator spec, or read from a ConfigMap with GetRotationPolicyConfig or a RotationPolicyConfigMap.
+type RotationPolicy struct {
+	// RenewAtPercent is the percentage of the certificate lifetime after which it is renewed, between 1 and 99.
+	// It defaults to 80.
+	RenewAtPercent int `json:"renewAtPercent,omitempty"`
+	// JitterPercent moves the renewal of every certificate earlier by a random percentage of its lifetime up to this
+	// value, so that certificates created at the same time are not renewed at the same time. The jitter is stable for a
+	// given certificate. It must be less than RenewAtPercent.
+	JitterPercent int `json:"jitterPercent,omitempty"`
+	// MinimumCABundleOverlap is the minimum time a signing CA must have been valid, and hence distributed in the CA
+	// bundle, before it is used to renew target certificates that are past their refresh time. It defaults to 10% of
+	// the refresh duration of the target.
+	MinimumCABundleOverlap metav1.Duration `json:"minimumCABundleOverlap,omitempty"`
+	// MaxClockSkew is the clock skew tolerated between this process and the consumers of the certificates.
+	// Certificates are considered expired this long before their notAfter, while CA certificates are kept in the CA
+	// bundle this long after their notAfter.
+	MaxClockSkew metav1.Duration `json:"maxClockSkew,omitempty"`
+}
+
+// Validate returns an error if the policy is invalid.
+func (p RotationPolicy) Validate() error {
+	var errs []error
+	if p.RenewAtPercent < 0 || p.RenewAtPercent > 99 {
+		errs = append(errs, fmt.Errorf("renewAtPercent must be between 1 and 99, got %d", p.RenewAtPercent))
+	}
+	if p.JitterPercent < 0 || p.JitterPercent >= p.renewAtPercent() {
+		errs = append(errs, fmt.Errorf("jitterPercent must be between 0 and %d, got %d", p.renewAtPercent()-1, p.JitterPercent))
+	}
+	if p.MinimumCABundleOverlap.Duration < 0 {
+		errs = append(errs, fmt.Errorf("minimumCABundleOverlap must not be negative, got %v", p.MinimumCABundleOverlap.Duration))
+	}
+	if p.MaxClockSkew.Duration < 0 {
+		errs = append(errs, fmt.Errorf("maxClockSkew must not be negative, got %v", p.MaxClockSkew.Duration))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (p RotationPolicy) renewAtPercent() int {
+	if p.RenewAtPercent == 0 {
+		return defaultRenewAtPercent
+	}
+	return p.RenewAtPercent
+}
+
+// renewalTime returns the time a certificate with the given validity is renewed at. The jitter is derived from the
+// jitterKey, which should identify the certificate.
+func (p RotationPolicy) renewalTime(notBefore, notAfter time.Time, jitterKey string) time.Time {
+	validity := notAfter.Sub(notBefore)
+	renewAt := notBefore.Add(validity / 100 * time.Duration(p.renewAtPercent()))
+	if p.JitterPercent > 0 {
+		hash := fnv.New64a()
+		hash.Write([]byte(jitterKey))
+		hash.Write([]byte(notBefore.UTC().Format(time.RFC3339)))
+		maxJitter := validity / 100 * time.Duration(p.JitterPercent)
+		renewAt = renewAt.Add(-time.Duration(hash.Sum64() % uint64(maxJitter+1)))
+	}
+	return renewAt
+}
+
+// expired returns true when a certificate with the given notAfter is expired for any consumer within the tolerated skew.
+func (p RotationPolicy) expired(notAfter, now time.Time) bool {
+	return now.Add(p.MaxClockSkew.Duration).After(notAfter)
+}
+
+// signerOverlapReached returns true when the signer has been valid long enough to be trusted by all consumers of the
+// CA bundle.
+func (p RotationPolicy) signerOverlapReached(signerNotBefore time.Time, refresh time.Duration, now time.Time) bool {
+	overlap := p.MinimumCABundleOverlap.Duration
+	if overlap == 0 {
+		overlap = refresh / 10
+	}
+	return now.After(signerNotBefore.Add(overlap))
+}
+
+// filterExpiredCerts removes the certificates that are expired, tolerating the clock skew.
+func (p RotationPolicy) filterExpiredCerts(now time.Time, certs ...*x509.Certificate) []*x509.Certificate {
+	valid := []*x509.Certificate{}
+	for _, cert := range certs {
+		if now.Add(-p.MaxClockSkew.Duration).Before(cert.NotAfter) {
+			valid = append(valid, cert)
+		}
+	}
+	return valid
+}
+
+// RotationPolicyConfig holds the rotation policies of the secrets managed by an operator.
+type RotationPolicyConfig struct {
+	// Default is the policy of all secrets that have no policy in Secrets.
+	Default RotationPolicy `json:"default,omitempty"`
+	// Secrets are the policies of individual secrets, keyed by namespace/name. They replace the Default policy.
+	Secrets map[string]RotationPolicy `json:"secrets,omitempty"`
+}
+
+// PolicyFor returns the policy of the given secret.
+func (c RotationPolicyConfig) PolicyFor(namespace, name string) RotationPolicy {
+	if policy, ok := c.Secrets[namespace+"/"+name]; ok {
+		return policy
+	}
+	return c.Default
+}
+
+// Validate returns an error if any of the policies is invalid.
+func (c RotationPolicyConfig) Validate() error {
+	var errs []error
+	if err := c.Default.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("default: %w", err))
+	}
+	for key, policy := range c.Secrets {
+		if err := policy.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("secrets[%s]: %w", key, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// GetRotationPolicyConfig reads the RotationPolicyConfig from the RotationPolicyConfigKey of the given ConfigMap.
+// A missing ConfigMap or key results in an empty config, so that the default policy applies.
+func GetRotationPolicyConfig(ctx context.Context, client corev1client.ConfigMapsGetter, namespace, name string) (*RotationPolicyConfig, error) {
+	configMap, err := client.ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return &RotationPolicyConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rotationPolicyConfigFromConfigMap(configMap)
+}
+
+func rotationPolicyConfigFromConfigMap(configMap *corev1.ConfigMap) (*RotationPolicyConfig, error) {
+	config := &RotationPolicyConfig{}
+	data, ok := configMap.Data[RotationPolicyConfigKey]
+	if !ok {
+		return config, nil
+	}
+	if err := yaml.UnmarshalStrict([]byte(data), config); err != nil {
+		return nil, fmt.Errorf("configmap/%s -n%s has an invalid %s: %w", configMap.Name, configMap.Namespace, RotationPolicyConfigKey, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("configmap/%s -n%s has an invalid %s: %w", configMap.Name, configMap.Namespace, RotationPolicyConfigKey, err)
+	}
+	return config, nil
+}
+
+// RotationPolicyConfigMap is a ConfigMap with a RotationPolicyConfig read by the CertRotationController on every sync.
+// Its policies replace the Policy of the rotated secrets, unless neither the secret has an entry nor a Default is set.
+type RotationPolicyConfigMap struct {
+	// Namespace is the namespace of the ConfigMap.
+	Namespace string
+	// Name is the name of the ConfigMap. It is optional, a missing ConfigMap keeps the Policy of the rotated secrets.
+	Name string
+
+	// Plumbing:
+	Informer corev1informers.ConfigMapInformer
+	Lister   corev1listers.ConfigMapLister
+}
+
+// getRotationPolicyConfig returns the RotationPolicyConfig, or nil if no ConfigMap is configured or it does not exist.
+func (c RotationPolicyConfigMap) getRotationPolicyConfig() (*RotationPolicyConfig, error) {
+	if len(c.Name) == 0 {
+		return nil, nil
+	}
+	configMap, err := c.Lister.ConfigMaps(c.Namespace).Get(c.Name)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rotationPolicyConfigFromConfigMap(configMap)
+}
+
+// policyFor returns the policy of the given secret from the config, falling back to the given policy when the config
+// has neither an entry for the secret nor a Default.
+func (c *RotationPolicyConfig) policyFor(namespace, name string, fallback RotationPolicy) RotationPolicy {
+	if c == nil {
+		return fallback
+	}
+	if policy, ok := c.Secrets[namespace+"/"+name]; ok {
+		return policy
+	}
+	if c.Default != (RotationPolicy{}) {
+		return c.Default
+	}
+	return fallback
+}
+
+// RotationStatus summarizes the certificate in a rotated secret and when it is rotated next.
+type RotationStatus struct {
+	Namespace string
+	Name      string
+
+	NotBefore time.Time
+	NotAfter  time.Time
+	// NextRotation is the time the certificate is planned to be rotated at. Target certificates past their refresh
+	// time are rotated only once the signing CA was distributed, see RotationPolicy.MinimumCABundleOverlap.
+	NextRotation time.Time
+	// Reason is set when the secret holds no valid certificate and it is rotated on the next sync.
+	Reason string
+}
+
+func (s RotationStatus) String() string {
+	if len(s.Reason) > 0 {
+		return fmt.Sprintf("%s/%s: rotation pending: %s", s.Namespace, s.Name, s.Reason)
+	}
+	return fmt.Sprintf("%s/%s: valid from %s until %s, next rotation at %s", s.Namespace, s.Name,
+		s.NotBefore.Format(time.RFC3339), s.NotAfter.Format(time.RFC3339), s.NextRotation.Format(time.RFC3339))
+}
+
+// plannedRotation returns the rotation status for the given annotations and the time of the refresh.
+func plannedRotation(namespace, name string, annotations map[string]string, policy RotationPolicy, refresh time.Duration, refreshOnlyWhenExpired bool) RotationStatus {
+	status := RotationStatus{Namespace: namespace, Name: name}
+	var reason string
+	status.NotBefore, status.NotAfter, reason = getValidityFromAnnotations(annotations)
+	if len(reason) > 0 {
+		status.Reason = reason
+		return status
+	}
+
+	status.NextRotation = status.NotAfter.Add(-policy.MaxClockSkew.Duration)
+	if !refreshOnlyWhenExpired {
+		if renewAt := policy.renewalTime(status.NotBefore, status.NotAfter, namespace+"/"+name); renewAt.Before(status.NextRotation) {
+			status.NextRotation = renewAt
+		}
+		if refreshAt := status.NotBefore.Add(refresh); refreshAt.Before(status.NextRotation) {
+			status.NextRotation = refreshAt
+		}
+	}
+	return status
+}
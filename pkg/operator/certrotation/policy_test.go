@@ -0,0 +1,198 @@
+package certrotation
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNeedNewSigningCertKeyPairForPolicy(t *testing.T) {
+	now := time.Now()
+	annotations := map[string]string{
+		CertificateNotBeforeAnnotation: now.Add(-60 * time.Minute).Format(time.RFC3339),
+		CertificateNotAfterAnnotation:  now.Add(40 * time.Minute).Format(time.RFC3339),
+	}
+
+	tests := []struct {
+		name     string
+		policy   RotationPolicy
+		expected string
+	}{
+		{
+			name:     "default renews at 80%",
+			expected: "",
+		},
+		{
+			name:     "renew at 50%",
+			policy:   RotationPolicy{RenewAtPercent: 50},
+			expected: "past its latest possible time",
+		},
+		{
+			name:     "jitter cannot move the renewal before the given percentage",
+			policy:   RotationPolicy{RenewAtPercent: 70, JitterPercent: 9},
+			expected: "",
+		},
+		{
+			name:     "expired within the clock skew",
+			policy:   RotationPolicy{RenewAtPercent: 99, MaxClockSkew: metav1.Duration{Duration: time.Hour}},
+			expected: "already expired",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			needed, reason := needNewSigningCertKeyPair(annotations, 24*time.Hour, false, test.policy, "ns/signer")
+			if needed != (len(test.expected) > 0) || !strings.HasPrefix(reason, test.expected) {
+				t.Errorf("expected %q, got %v %q", test.expected, needed, reason)
+			}
+		})
+	}
+}
+
+func TestRotationPolicyJitter(t *testing.T) {
+	notBefore := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := notBefore.Add(100 * time.Hour)
+	policy := RotationPolicy{RenewAtPercent: 80, JitterPercent: 10}
+
+	renewalTimes := map[time.Time]bool{}
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("ns/secret-%d", i)
+		renewAt := policy.renewalTime(notBefore, notAfter, key)
+		if renewAt.Before(notBefore.Add(70*time.Hour)) || renewAt.After(notBefore.Add(80*time.Hour)) {
+			t.Errorf("renewal time %v of %s out of the jitter range", renewAt, key)
+		}
+		if !renewAt.Equal(policy.renewalTime(notBefore, notAfter, key)) {
+			t.Errorf("expected the renewal time of %s to be stable", key)
+		}
+		renewalTimes[renewAt] = true
+	}
+	if len(renewalTimes) < 2 {
+		t.Errorf("expected the renewal times to be spread, got %v", renewalTimes)
+	}
+}
+
+func TestRotationPolicyFilterExpiredCerts(t *testing.T) {
+	now := time.Now()
+	expiredRecently := &x509.Certificate{NotAfter: now.Add(-time.Minute)}
+	expiredLongAgo := &x509.Certificate{NotAfter: now.Add(-time.Hour)}
+	valid := &x509.Certificate{NotAfter: now.Add(time.Hour)}
+
+	policy := RotationPolicy{MaxClockSkew: metav1.Duration{Duration: 5 * time.Minute}}
+	if actual := policy.filterExpiredCerts(now, expiredRecently, expiredLongAgo, valid); len(actual) != 2 || actual[0] != expiredRecently || actual[1] != valid {
+		t.Errorf("expected the recently expired certificate to be kept within the clock skew, got %v", actual)
+	}
+	if actual := (RotationPolicy{}).filterExpiredCerts(now, expiredRecently, expiredLongAgo, valid); len(actual) != 1 || actual[0] != valid {
+		t.Errorf("expected only the valid certificate, got %v", actual)
+	}
+}
+
+func TestGetRotationPolicyConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		configMap     *corev1.ConfigMap
+		expected      RotationPolicyConfig
+		expectedError string
+	}{
+		{
+			name: "missing config map",
+		},
+		{
+			name: "valid",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rotation-policy"},
+				Data: map[string]string{RotationPolicyConfigKey: `
+default:
+  renewAtPercent: 70
+  jitterPercent: 5
+secrets:
+  ns/target:
+    renewAtPercent: 50
+    minimumCABundleOverlap: 1h
+    maxClockSkew: 5m
+`},
+			},
+			expected: RotationPolicyConfig{
+				Default: RotationPolicy{RenewAtPercent: 70, JitterPercent: 5},
+				Secrets: map[string]RotationPolicy{
+					"ns/target": {RenewAtPercent: 50, MinimumCABundleOverlap: metav1.Duration{Duration: time.Hour}, MaxClockSkew: metav1.Duration{Duration: 5 * time.Minute}},
+				},
+			},
+		},
+		{
+			name: "unknown field",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rotation-policy"},
+				Data:       map[string]string{RotationPolicyConfigKey: "default:\n  renewAt: 70\n"},
+			},
+			expectedError: `unknown field "renewAt"`,
+		},
+		{
+			name: "invalid policy",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rotation-policy"},
+				Data:       map[string]string{RotationPolicyConfigKey: "secrets:\n  ns/target:\n    renewAtPercent: 50\n    jitterPercent: 60\n"},
+			},
+			expectedError: "secrets[ns/target]: jitterPercent must be between 0 and 49, got 60",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := kubefake.NewSimpleClientset()
+			if test.configMap != nil {
+				client = kubefake.NewSimpleClientset(test.configMap)
+			}
+			config, err := GetRotationPolicyConfig(context.TODO(), client.CoreV1(), "ns", "rotation-policy")
+			switch {
+			case err != nil && len(test.expectedError) == 0:
+				t.Fatal(err)
+			case err != nil && !strings.Contains(err.Error(), test.expectedError):
+				t.Fatalf("expected error %q, got %v", test.expectedError, err)
+			case err == nil && len(test.expectedError) != 0:
+				t.Fatalf("missing %q", test.expectedError)
+			case err != nil:
+				return
+			}
+
+			if config.Default != test.expected.Default || len(config.Secrets) != len(test.expected.Secrets) {
+				t.Fatalf("expected %#v, got %#v", test.expected, *config)
+			}
+			for key, policy := range test.expected.Secrets {
+				if config.PolicyFor(strings.Split(key, "/")[0], strings.Split(key, "/")[1]) != policy {
+					t.Errorf("expected policy %#v for %s, got %#v", policy, key, config.Secrets[key])
+				}
+			}
+			if config.PolicyFor("ns", "other") != test.expected.Default {
+				t.Errorf("expected the default policy for secrets without a policy")
+			}
+		})
+	}
+}
+
+func TestPlannedRotation(t *testing.T) {
+	notBefore := time.Now().Add(-time.Hour).Truncate(time.Second)
+	notAfter := notBefore.Add(100 * time.Hour)
+	annotations := map[string]string{
+		CertificateNotBeforeAnnotation: notBefore.Format(time.RFC3339),
+		CertificateNotAfterAnnotation:  notAfter.Format(time.RFC3339),
+	}
+	policy := RotationPolicy{RenewAtPercent: 50, MaxClockSkew: metav1.Duration{Duration: time.Hour}}
+
+	if status := plannedRotation("ns", "name", annotations, policy, 200*time.Hour, false); !status.NextRotation.Equal(notBefore.Add(50 * time.Hour)) {
+		t.Errorf("expected rotation at 50%%, got %v", status)
+	}
+	if status := plannedRotation("ns", "name", annotations, policy, 10*time.Hour, false); !status.NextRotation.Equal(notBefore.Add(10 * time.Hour)) {
+		t.Errorf("expected rotation at the refresh time, got %v", status)
+	}
+	if status := plannedRotation("ns", "name", annotations, policy, 10*time.Hour, true); !status.NextRotation.Equal(notAfter.Add(-time.Hour)) {
+		t.Errorf("expected rotation on expiry within the clock skew, got %v", status)
+	}
+	if status := plannedRotation("ns", "name", nil, policy, 10*time.Hour, false); status.Reason != "missing notAfter" {
+		t.Errorf("expected a pending rotation, got %v", status)
+	}
+}
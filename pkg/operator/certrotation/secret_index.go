@@ -0,0 +1,186 @@
+package certrotation
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/crypto"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// CertificateIndexSecretKey is the key of the OpenSSL compatible index in a certificate index secret.
+const CertificateIndexSecretKey = "index.txt"
+
+// secretCertificateIndexTimeout bounds the requests of a SecretCertificateIndex that is not bound to a context.
+const secretCertificateIndexTimeout = time.Minute
+
+// contextCertificateIndex is a crypto.CertificateIndex whose requests can be bound to a context.
+type contextCertificateIndex interface {
+	WithContext(ctx context.Context) crypto.CertificateIndex
+}
+
+// SecretCertificateIndex is a crypto.CertificateIndex stored in a secret, in the same format as
+// crypto.IndexFileCertificateIndex. It must be a different secret than the signing CA secret, because the signing CA
+// secret is replaced on rotation.
+//
+// RotatedSigningCASecret binds the index to the context of its sync with WithContext. Used directly, every request
+// is bounded by a timeout instead.
+type SecretCertificateIndex struct {
+	// Namespace is the namespace of the Secret.
+	Namespace string
+	// Name is the name of the Secret. It is created on the first issued certificate if it does not exist yet.
+	Name string
+
+	// Plumbing:
+	Client corev1client.SecretsGetter
+
+	// lock serializes updates of this process, conflicting updates of other processes are retried.
+	lock sync.Mutex
+}
+
+var _ crypto.CertificateIndex = &SecretCertificateIndex{}
+var _ contextCertificateIndex = &SecretCertificateIndex{}
+
+// WithContext returns the index with all requests bound to ctx.
+func (i *SecretCertificateIndex) WithContext(ctx context.Context) crypto.CertificateIndex {
+	return &boundSecretCertificateIndex{index: i, ctx: ctx}
+}
+
+func (i *SecretCertificateIndex) Add(cert *x509.Certificate) error {
+	ctx, cancel := context.WithTimeout(context.Background(), secretCertificateIndexTimeout)
+	defer cancel()
+	return i.WithContext(ctx).Add(cert)
+}
+
+func (i *SecretCertificateIndex) Revoke(serial *big.Int, reason int, revokedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), secretCertificateIndexTimeout)
+	defer cancel()
+	return i.WithContext(ctx).Revoke(serial, reason, revokedAt)
+}
+
+func (i *SecretCertificateIndex) Get(serial *big.Int) (*crypto.IssuedCertificate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretCertificateIndexTimeout)
+	defer cancel()
+	return i.WithContext(ctx).Get(serial)
+}
+
+func (i *SecretCertificateIndex) List() ([]crypto.IssuedCertificate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretCertificateIndexTimeout)
+	defer cancel()
+	return i.WithContext(ctx).List()
+}
+
+// boundSecretCertificateIndex is a SecretCertificateIndex with all requests bound to a context.
+type boundSecretCertificateIndex struct {
+	index *SecretCertificateIndex
+	ctx   context.Context
+}
+
+func (i *boundSecretCertificateIndex) Add(cert *x509.Certificate) error {
+	return i.update(func(entries []crypto.IssuedCertificate) ([]crypto.IssuedCertificate, error) {
+		// expired certificates are neither in the CRL nor answered by OCSP, drop them so that the secret stays small
+		return append(crypto.RemoveExpiredCertificates(entries, time.Now()), crypto.NewIssuedCertificate(cert)), nil
+	})
+}
+
+func (i *boundSecretCertificateIndex) Revoke(serial *big.Int, reason int, revokedAt time.Time) error {
+	return i.update(func(entries []crypto.IssuedCertificate) ([]crypto.IssuedCertificate, error) {
+		for j := range entries {
+			if entries[j].SerialNumber.Cmp(serial) == 0 {
+				entries[j].Revoked = true
+				entries[j].RevokedAt = revokedAt
+				entries[j].RevocationReason = reason
+				return entries, nil
+			}
+		}
+		return nil, fmt.Errorf("certificate with serial %X was not issued by this CA", serial)
+	})
+}
+
+func (i *boundSecretCertificateIndex) Get(serial *big.Int) (*crypto.IssuedCertificate, error) {
+	_, entries, err := i.read()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.SerialNumber.Cmp(serial) == 0 {
+			return &entry, nil
+		}
+	}
+	return nil, nil
+}
+
+func (i *boundSecretCertificateIndex) List() ([]crypto.IssuedCertificate, error) {
+	_, entries, err := i.read()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].SerialNumber.Cmp(entries[b].SerialNumber) < 0
+	})
+	return entries, nil
+}
+
+// read returns the index secret, or nil if it does not exist, and its entries.
+func (i *boundSecretCertificateIndex) read() (*corev1.Secret, []crypto.IssuedCertificate, error) {
+	secret, err := i.index.Client.Secrets(i.index.Namespace).Get(i.ctx, i.index.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, err := crypto.ParseCertificateIndex(secret.Data[CertificateIndexSecretKey])
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid certificate index in secret %s/%s: %v", i.index.Namespace, i.index.Name, err)
+	}
+	return secret, entries, nil
+}
+
+// update applies mutate to the current entries and writes them back, retrying on conflicts.
+func (i *boundSecretCertificateIndex) update(mutate func([]crypto.IssuedCertificate) ([]crypto.IssuedCertificate, error)) error {
+	i.index.lock.Lock()
+	defer i.index.lock.Unlock()
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		secret, entries, err := i.read()
+		if err != nil {
+			return err
+		}
+		entries, err = mutate(entries)
+		if err != nil {
+			return err
+		}
+		data := crypto.FormatCertificateIndex(entries)
+
+		if secret == nil {
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: i.index.Namespace, Name: i.index.Name},
+				Type:       corev1.SecretTypeOpaque,
+				Data:       map[string][]byte{CertificateIndexSecretKey: data},
+			}
+			_, err := i.index.Client.Secrets(i.index.Namespace).Create(i.ctx, secret, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// created concurrently, read it again
+				return apierrors.NewConflict(corev1.Resource("secrets"), i.index.Name, err)
+			}
+			return err
+		}
+
+		secret = secret.DeepCopy()
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[CertificateIndexSecretKey] = data
+		_, err = i.index.Client.Secrets(i.index.Namespace).Update(i.ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
+}
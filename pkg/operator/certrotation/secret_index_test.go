@@ -0,0 +1,95 @@
+package certrotation
+
+import (
+	"context"
+	"crypto/x509"
+	"math/big"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/events"
+)
+
+func TestSecretCertificateIndex(t *testing.T) {
+	client := kubefake.NewSimpleClientset()
+	index := &SecretCertificateIndex{Namespace: "ns", Name: "signer-index", Client: client.CoreV1()}
+
+	// certificates issued by a rotated signer are recorded in the index secret
+	c := &RotatedSigningCASecret{
+		Namespace:     "ns",
+		Name:          "signer",
+		Validity:      24 * time.Hour,
+		Refresh:       12 * time.Hour,
+		Index:         index,
+		Client:        client.CoreV1(),
+		Lister:        corev1listers.NewSecretLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		EventRecorder: events.NewInMemoryRecorder("test"),
+	}
+	signer, err := c.ensureSigningCertKeyPair(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := signer.MakeClientCertificateForDuration(&user.DefaultInfo{Name: "client"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial := cert.Certs[0].SerialNumber
+	if err := signer.Revoke(serial, crypto.RevocationReasonKeyCompromise); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new index on the same secret must see the revocation
+	reloaded := &SecretCertificateIndex{Namespace: "ns", Name: "signer-index", Client: client.CoreV1()}
+	issued, err := reloaded.Get(serial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued == nil || !issued.Revoked || issued.RevocationReason != crypto.RevocationReasonKeyCompromise || issued.Subject != "CN=client" {
+		t.Errorf("unexpected entry %#v", issued)
+	}
+
+	secret, err := client.CoreV1().Secrets("ns").Get(context.TODO(), "signer-index", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := crypto.ParseCertificateIndex(secret.Data[CertificateIndexSecretKey])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].SerialNumber.Cmp(serial) != 0 {
+		t.Errorf("unexpected index %q", secret.Data[CertificateIndexSecretKey])
+	}
+
+	if err := reloaded.Revoke(new(big.Int).Add(serial, big.NewInt(1)), crypto.RevocationReasonKeyCompromise, time.Now()); err == nil {
+		t.Errorf("expected an error revoking an unknown serial")
+	}
+}
+
+func TestSecretCertificateIndexRemovesExpiredCertificates(t *testing.T) {
+	client := kubefake.NewSimpleClientset()
+	index := &SecretCertificateIndex{Namespace: "ns", Name: "signer-index", Client: client.CoreV1()}
+
+	expired := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(-time.Minute)}
+	valid := &x509.Certificate{SerialNumber: big.NewInt(2), NotAfter: time.Now().Add(time.Hour)}
+	bound := index.WithContext(context.TODO())
+	for _, cert := range []*x509.Certificate{expired, valid} {
+		if err := bound.Add(cert); err != nil {
+			t.Fatal(err)
+		}
+	}
+	issued, err := index.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issued) != 1 || issued[0].SerialNumber.Cmp(valid.SerialNumber) != 0 {
+		t.Errorf("expected the expired certificate to be removed, got %v", issued)
+	}
+
+}
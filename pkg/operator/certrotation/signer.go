@@ -3,6 +3,7 @@ package certrotation
 import (
 	"bytes"
 	"context"
+	"crypto/x509"
 	"fmt"
 	"time"
 
@@ -36,6 +37,15 @@ type RotatedSigningCASecret struct {
 	// but only rotate when the signing CA expires. This is useful for auto-recovery when we want to enforce
 	// rotation on expiration only, but not interfere with the ordinary rotation controller.
 	RefreshOnlyWhenExpired bool
+	// Policy refines when the signing CA is rotated, on top of Refresh and Validity.
+	Policy RotationPolicy
+	// KeyAlgorithm is the algorithm of the signing CA key. It defaults to crypto.DefaultKeyAlgorithm.
+	// A signing CA with a key of another algorithm is rotated.
+	KeyAlgorithm crypto.KeyAlgorithm
+	// Index records the certificates issued by the signing CA, so that they can be revoked, e.g. a
+	// SecretCertificateIndex. Certificates are not recorded if it is nil. A signing CA that is not allowed to sign
+	// CRLs is rotated when an Index is set.
+	Index crypto.CertificateIndex
 
 	// Plumbing:
 	Informer      corev1informers.SecretInformer
@@ -56,9 +66,18 @@ func (c RotatedSigningCASecret) ensureSigningCertKeyPair(ctx context.Context) (*
 	}
 	signingCertKeyPairSecret.Type = corev1.SecretTypeTLS
 
-	if needed, reason := needNewSigningCertKeyPair(signingCertKeyPairSecret.Annotations, c.Refresh, c.RefreshOnlyWhenExpired); needed {
+	needed, reason := needNewSigningCertKeyPair(signingCertKeyPairSecret.Annotations, c.Refresh, c.RefreshOnlyWhenExpired, c.Policy, c.Namespace+"/"+c.Name)
+	if !needed {
+		reason = keyAlgorithmChanged(signingCertKeyPairSecret.Data["tls.crt"], c.KeyAlgorithm)
+		needed = len(reason) > 0
+	}
+	if !needed && c.Index != nil {
+		reason = crlSignMissing(signingCertKeyPairSecret.Data["tls.crt"])
+		needed = len(reason) > 0
+	}
+	if needed {
 		c.EventRecorder.Eventf("SignerUpdateRequired", "%q in %q requires a new signing cert/key pair: %v", c.Name, c.Namespace, reason)
-		if err := setSigningCertKeyPairSecret(signingCertKeyPairSecret, c.Validity); err != nil {
+		if err := setSigningCertKeyPairSecret(signingCertKeyPairSecret, c.Validity, c.KeyAlgorithm); err != nil {
 			return nil, err
 		}
 
@@ -75,17 +94,21 @@ func (c RotatedSigningCASecret) ensureSigningCertKeyPair(ctx context.Context) (*
 	if err != nil {
 		return nil, err
 	}
+	signingCertKeyPair.Index = c.Index
+	if index, ok := c.Index.(contextCertificateIndex); ok {
+		signingCertKeyPair.Index = index.WithContext(ctx)
+	}
 
 	return signingCertKeyPair, nil
 }
 
-func needNewSigningCertKeyPair(annotations map[string]string, refresh time.Duration, refreshOnlyWhenExpired bool) (bool, string) {
+func needNewSigningCertKeyPair(annotations map[string]string, refresh time.Duration, refreshOnlyWhenExpired bool, policy RotationPolicy, jitterKey string) (bool, string) {
 	notBefore, notAfter, reason := getValidityFromAnnotations(annotations)
 	if len(reason) > 0 {
 		return true, reason
 	}
 
-	if time.Now().After(notAfter) {
+	if policy.expired(notAfter, time.Now()) {
 		return true, "already expired"
 	}
 
@@ -93,10 +116,9 @@ func needNewSigningCertKeyPair(annotations map[string]string, refresh time.Durat
 		return false, ""
 	}
 
-	validity := notAfter.Sub(notBefore)
-	at80Percent := notAfter.Add(-validity / 5)
-	if time.Now().After(at80Percent) {
-		return true, fmt.Sprintf("past its latest possible time %v", at80Percent)
+	renewAt := policy.renewalTime(notBefore, notAfter, jitterKey)
+	if time.Now().After(renewAt) {
+		return true, fmt.Sprintf("past its latest possible time %v", renewAt)
 	}
 
 	developerSpecifiedRefresh := notBefore.Add(refresh)
@@ -129,9 +151,9 @@ func getValidityFromAnnotations(annotations map[string]string) (notBefore time.T
 }
 
 // setSigningCertKeyPairSecret creates a new signing cert/key pair and sets them in the secret
-func setSigningCertKeyPairSecret(signingCertKeyPairSecret *corev1.Secret, validity time.Duration) error {
+func setSigningCertKeyPairSecret(signingCertKeyPairSecret *corev1.Secret, validity time.Duration, keyAlgorithm crypto.KeyAlgorithm) error {
 	signerName := fmt.Sprintf("%s_%s@%d", signingCertKeyPairSecret.Namespace, signingCertKeyPairSecret.Name, time.Now().Unix())
-	ca, err := crypto.MakeSelfSignedCAConfigForDuration(signerName, validity)
+	ca, err := crypto.MakeSelfSignedCAConfigForDurationWithKeyAlgorithm(signerName, validity, keyAlgorithm)
 	if err != nil {
 		return err
 	}
@@ -156,3 +178,49 @@ func setSigningCertKeyPairSecret(signingCertKeyPairSecret *corev1.Secret, validi
 
 	return nil
 }
+
+// keyAlgorithmChanged returns a non-empty reason when the key of the first certificate in certPEM is not of the
+// required algorithm. Nothing is required for an empty algorithm, so existing certificates are not rotated when
+// the algorithm is left unset.
+func keyAlgorithmChanged(certPEM []byte, required crypto.KeyAlgorithm) string {
+	if len(required) == 0 || len(certPEM) == 0 {
+		return ""
+	}
+	certs, err := crypto.CertsFromPEM(certPEM)
+	if err != nil {
+		return fmt.Sprintf("bad certificate: %v", err)
+	}
+	current, err := crypto.KeyAlgorithmOf(certs[0].PublicKey)
+	if err != nil || current != required {
+		return fmt.Sprintf("key algorithm changed to %s", required)
+	}
+	return ""
+}
+
+// crlSignMissing returns a non-empty reason when the first certificate in certPEM is not allowed to sign CRLs.
+// Signing CAs created before KeyUsageCRLSign was added to the signing template lack it.
+func crlSignMissing(certPEM []byte) string {
+	if len(certPEM) == 0 {
+		return ""
+	}
+	certs, err := crypto.CertsFromPEM(certPEM)
+	if err != nil {
+		return fmt.Sprintf("bad certificate: %v", err)
+	}
+	if certs[0].KeyUsage&x509.KeyUsageCRLSign == 0 {
+		return "not allowed to sign certificate revocation lists"
+	}
+	return ""
+}
+
+// RotationStatus returns when the signing CA is rotated next.
+func (c RotatedSigningCASecret) RotationStatus() (RotationStatus, error) {
+	secret, err := c.Lister.Secrets(c.Namespace).Get(c.Name)
+	if apierrors.IsNotFound(err) {
+		return RotationStatus{Namespace: c.Namespace, Name: c.Name, Reason: "missing"}, nil
+	}
+	if err != nil {
+		return RotationStatus{}, err
+	}
+	return plannedRotation(c.Namespace, c.Name, secret.Annotations, c.Policy, c.Refresh, c.RefreshOnlyWhenExpired), nil
+}
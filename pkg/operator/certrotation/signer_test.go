@@ -2,6 +2,9 @@ package certrotation
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"strings"
 	"testing"
 	"time"
@@ -15,14 +18,26 @@ import (
 	clienttesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
 
+	"github.com/openshift/library-go/pkg/crypto"
 	"github.com/openshift/library-go/pkg/operator/events"
 )
 
 func TestEnsureSigningCertKeyPair(t *testing.T) {
+	rsaSigner := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "signer"},
+		Type:       corev1.SecretTypeTLS,
+	}
+	if err := setSigningCertKeyPairSecret(rsaSigner, 24*time.Hour, crypto.RSA2048); err != nil {
+		t.Fatal(err)
+	}
+	signerWithoutCRLSign := withoutCRLSign(t, rsaSigner)
+
 	tests := []struct {
 		name string
 
 		initialSecret *corev1.Secret
+		keyAlgorithm  crypto.KeyAlgorithm
+		index         crypto.CertificateIndex
 
 		verifyActions func(t *testing.T, client *kubefake.Clientset)
 		expectedError string
@@ -96,6 +111,61 @@ func TestEnsureSigningCertKeyPair(t *testing.T) {
 			},
 			expectedError: "certFile missing", // this means we tried to read the cert from the existing secret.  If we created one, we fail in the client check
 		},
+		{
+			name:          "initial create with key algorithm",
+			keyAlgorithm:  crypto.Ed25519,
+			verifyActions: verifySignerKeyAlgorithm(crypto.Ed25519),
+		},
+		{
+			name:          "update key algorithm changed",
+			initialSecret: rsaSigner,
+			keyAlgorithm:  crypto.ECDSAP256,
+			verifyActions: verifySignerKeyAlgorithm(crypto.ECDSAP256),
+		},
+		{
+			name:          "update no work with unchanged key algorithm",
+			initialSecret: rsaSigner,
+			keyAlgorithm:  crypto.RSA2048,
+			verifyActions: func(t *testing.T, client *kubefake.Clientset) {
+				t.Helper()
+				actions := client.Actions()
+				if len(actions) != 0 {
+					t.Fatal(spew.Sdump(actions))
+				}
+			},
+		},
+		{
+			name:          "update signer not allowed to sign CRLs with index",
+			initialSecret: signerWithoutCRLSign,
+			index:         crypto.NewInMemoryCertificateIndex(),
+			verifyActions: func(t *testing.T, client *kubefake.Clientset) {
+				t.Helper()
+				actions := client.Actions()
+				if len(actions) != 2 {
+					t.Fatal(spew.Sdump(actions))
+				}
+
+				actual := actions[1].(clienttesting.UpdateAction).GetObject().(*corev1.Secret)
+				ca, err := crypto.GetCAFromBytes(actual.Data["tls.crt"], actual.Data["tls.key"])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ca.Config.Certs[0].KeyUsage&x509.KeyUsageCRLSign == 0 {
+					t.Errorf("expected the new signer to be allowed to sign CRLs")
+				}
+			},
+		},
+		{
+			name:          "update no work with signer not allowed to sign CRLs without index",
+			initialSecret: signerWithoutCRLSign,
+			verifyActions: func(t *testing.T, client *kubefake.Clientset) {
+				t.Helper()
+				actions := client.Actions()
+				if len(actions) != 0 {
+					t.Fatal(spew.Sdump(actions))
+				}
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -113,6 +183,8 @@ func TestEnsureSigningCertKeyPair(t *testing.T) {
 				Name:          "signer",
 				Validity:      24 * time.Hour,
 				Refresh:       12 * time.Hour,
+				KeyAlgorithm:  test.keyAlgorithm,
+				Index:         test.index,
 				Client:        client.CoreV1(),
 				Lister:        corev1listers.NewSecretLister(indexer),
 				EventRecorder: events.NewInMemoryRecorder("test"),
@@ -132,3 +204,41 @@ func TestEnsureSigningCertKeyPair(t *testing.T) {
 		})
 	}
 }
+
+func verifySignerKeyAlgorithm(expected crypto.KeyAlgorithm) func(t *testing.T, client *kubefake.Clientset) {
+	return func(t *testing.T, client *kubefake.Clientset) {
+		t.Helper()
+		actions := client.Actions()
+		if len(actions) != 2 {
+			t.Fatal(spew.Sdump(actions))
+		}
+
+		actual := actions[1].(clienttesting.CreateAction).GetObject().(*corev1.Secret)
+		ca, err := crypto.GetCAFromBytes(actual.Data["tls.crt"], actual.Data["tls.key"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if algorithm, err := crypto.KeyAlgorithmOf(ca.Config.Certs[0].PublicKey); err != nil || algorithm != expected {
+			t.Errorf("expected key algorithm %s, got %s (%v)", expected, algorithm, err)
+		}
+	}
+}
+
+// withoutCRLSign returns a copy of the signer secret with the certificate reissued without KeyUsageCRLSign, like
+// signers created before it was added to the signing template.
+func withoutCRLSign(t *testing.T, signer *corev1.Secret) *corev1.Secret {
+	t.Helper()
+	ca, err := crypto.GetCAFromBytes(signer.Data["tls.crt"], signer.Data["tls.key"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := *ca.Config.Certs[0]
+	template.KeyUsage &^= x509.KeyUsageCRLSign
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, template.PublicKey, ca.Config.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := signer.DeepCopy()
+	secret.Data["tls.crt"] = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return secret
+}
@@ -3,6 +3,7 @@ package certrotation
 import (
 	"context"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -20,6 +21,7 @@ import (
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
 )
 
 // RotatedSelfSignedCertKeySecret rotates a key and cert signed by a signing CA and stores it in a secret.
@@ -50,6 +52,12 @@ type RotatedSelfSignedCertKeySecret struct {
 	// rotation on expiration only, but not interfere with the ordinary rotation controller.
 	RefreshOnlyWhenExpired bool
 
+	// Policy refines when the certificate is rotated, on top of Refresh and Validity.
+	Policy RotationPolicy
+	// KeyAlgorithm is the algorithm of the certificate key. It defaults to crypto.DefaultKeyAlgorithm.
+	// A certificate with a key of another algorithm is rotated.
+	KeyAlgorithm crypto.KeyAlgorithm
+
 	// CertCreator does the actual cert generation.
 	CertCreator TargetCertCreator
 
@@ -69,6 +77,25 @@ type TargetCertCreator interface {
 	SetAnnotations(cert *crypto.TLSCertificateConfig, annotations map[string]string) map[string]string
 }
 
+// TargetCertExternalIssuer is an optional interface to be implemented by the TargetCertCreator when the certificates
+// are not issued by the signing CA, e.g. by an ACME server. Their issuer is not required to be in the CA bundle, and the
+// TargetCertCreator decides on rotation after the validity checks.
+type TargetCertExternalIssuer interface {
+	ExternallyIssued() bool
+}
+
+// TargetCertContextCreator is an optional interface to be implemented by the TargetCertCreator to create certificates
+// with the context of the controller, e.g. when talking to external services. NewCertificateWithContext is called
+// instead of NewCertificate. It may return ErrCertificatePending to obtain the certificate in the background.
+type TargetCertContextCreator interface {
+	NewCertificateWithContext(ctx context.Context, signer *crypto.CA, validity time.Duration) (*crypto.TLSCertificateConfig, error)
+}
+
+// targetCertObserver is implemented by TargetCertCreators that need to know the certificate in the target secret.
+type targetCertObserver interface {
+	observeTargetCertificate(cert *x509.Certificate)
+}
+
 // TargetCertRechecker is an optional interface to be implemented by the TargetCertCreator to enforce
 // a controller run.
 type TargetCertRechecker interface {
@@ -91,9 +118,34 @@ func (c RotatedSelfSignedCertKeySecret) ensureTargetCertKeyPair(ctx context.Cont
 	}
 	targetCertKeyPairSecret.Type = corev1.SecretTypeTLS
 
-	if reason := needNewTargetCertKeyPair(targetCertKeyPairSecret.Annotations, signingCertKeyPair, caBundleCerts, c.Refresh, c.RefreshOnlyWhenExpired); len(reason) > 0 {
+	if observer, ok := c.CertCreator.(targetCertObserver); ok && len(targetCertKeyPairSecret.Data["tls.crt"]) > 0 {
+		if certs, err := crypto.CertsFromPEM(targetCertKeyPairSecret.Data["tls.crt"]); err == nil {
+			observer.observeTargetCertificate(certs[0])
+		}
+	}
+
+	var reason string
+	if externalIssuer, ok := c.CertCreator.(TargetCertExternalIssuer); ok && externalIssuer.ExternallyIssued() {
+		reason = needNewTargetCertKeyPairForTime(targetCertKeyPairSecret.Annotations, signingCertKeyPair, c.Refresh, c.RefreshOnlyWhenExpired, c.Policy, c.Namespace+"/"+c.Name)
+		if len(reason) == 0 {
+			reason = c.CertCreator.NeedNewTargetCertKeyPair(targetCertKeyPairSecret.Annotations, signingCertKeyPair, caBundleCerts, c.Refresh, c.RefreshOnlyWhenExpired)
+		}
+	} else {
+		reason = needNewTargetCertKeyPairForPolicy(targetCertKeyPairSecret.Annotations, signingCertKeyPair, caBundleCerts, c.Refresh, c.RefreshOnlyWhenExpired, c.Policy, c.Namespace+"/"+c.Name)
+	}
+	if len(reason) == 0 {
+		reason = keyAlgorithmChanged(targetCertKeyPairSecret.Data["tls.crt"], c.KeyAlgorithm)
+	}
+	if len(reason) > 0 {
 		c.EventRecorder.Eventf("TargetUpdateRequired", "%q in %q requires a new target cert/key pair: %v", c.Name, c.Namespace, reason)
-		if err := setTargetCertKeyPairSecret(targetCertKeyPairSecret, c.Validity, signingCertKeyPair, c.CertCreator); err != nil {
+		// the signer generates the key of the target, it must not be modified because it is shared with other targets
+		signer := *signingCertKeyPair
+		signer.KeyAlgorithm = c.KeyAlgorithm
+		if err := setTargetCertKeyPairSecret(ctx, targetCertKeyPairSecret, c.Validity, &signer, c.CertCreator); err != nil {
+			if errors.Is(err, ErrCertificatePending) {
+				klog.V(2).Infof("%s/%s: %v", c.Namespace, c.Name, err)
+				return nil
+			}
 			return err
 		}
 
@@ -110,7 +162,11 @@ func (c RotatedSelfSignedCertKeySecret) ensureTargetCertKeyPair(ctx context.Cont
 }
 
 func needNewTargetCertKeyPair(annotations map[string]string, signer *crypto.CA, caBundleCerts []*x509.Certificate, refresh time.Duration, refreshOnlyWhenExpired bool) string {
-	if reason := needNewTargetCertKeyPairForTime(annotations, signer, refresh, refreshOnlyWhenExpired); len(reason) > 0 {
+	return needNewTargetCertKeyPairForPolicy(annotations, signer, caBundleCerts, refresh, refreshOnlyWhenExpired, RotationPolicy{}, "")
+}
+
+func needNewTargetCertKeyPairForPolicy(annotations map[string]string, signer *crypto.CA, caBundleCerts []*x509.Certificate, refresh time.Duration, refreshOnlyWhenExpired bool, policy RotationPolicy, jitterKey string) string {
+	if reason := needNewTargetCertKeyPairForTime(annotations, signer, refresh, refreshOnlyWhenExpired, policy, jitterKey); len(reason) > 0 {
 		return reason
 	}
 
@@ -147,14 +203,14 @@ func needNewTargetCertKeyPair(annotations map[string]string, signer *crypto.CA,
 // Hence, if the CAs are rotated too fast (like CA percentage around 10% or smaller), we will not hit the time to make use of the CA. Or if the cert renewal percentage is at 90%, there is not much time either.
 //
 // So with a cert percentage of 75% and equally long CA and cert validities at the worst case we start at 85% of the cert to renew, trying again every minute.
-func needNewTargetCertKeyPairForTime(annotations map[string]string, signer *crypto.CA, refresh time.Duration, refreshOnlyWhenExpired bool) string {
+func needNewTargetCertKeyPairForTime(annotations map[string]string, signer *crypto.CA, refresh time.Duration, refreshOnlyWhenExpired bool, policy RotationPolicy, jitterKey string) string {
 	notBefore, notAfter, reason := getValidityFromAnnotations(annotations)
 	if len(reason) > 0 {
 		return reason
 	}
 
 	// Is cert expired?
-	if time.Now().After(notAfter) {
+	if policy.expired(notAfter, time.Now()) {
 		return "already expired"
 	}
 
@@ -162,19 +218,17 @@ func needNewTargetCertKeyPairForTime(annotations map[string]string, signer *cryp
 		return ""
 	}
 
-	// Are we at 80% of validity?
-	validity := notAfter.Sub(notBefore)
-	at80Percent := notAfter.Add(-validity / 5)
-	if time.Now().After(at80Percent) {
-		return fmt.Sprintf("past its latest possible time %v", at80Percent)
+	// Are we at the renewal percentage of validity, 80% by default?
+	renewAt := policy.renewalTime(notBefore, notAfter, jitterKey)
+	if time.Now().After(renewAt) {
+		return fmt.Sprintf("past its latest possible time %v", renewAt)
 	}
 
 	// If Certificate is past its refresh time, we may have action to take. We only do this if the signer is old enough.
 	refreshTime := notBefore.Add(refresh)
 	if time.Now().After(refreshTime) {
-		// make sure the signer has been valid for more than 10% of the target's refresh time.
-		timeToWaitForTrustRotation := refresh / 10
-		if time.Now().After(signer.Config.Certs[0].NotBefore.Add(time.Duration(timeToWaitForTrustRotation))) {
+		// make sure the signer has been valid for the minimum overlap, 10% of the target's refresh time by default.
+		if policy.signerOverlapReached(signer.Config.Certs[0].NotBefore, refresh, time.Now()) {
 			return fmt.Sprintf("past its refresh time %v", refreshTime)
 		}
 	}
@@ -184,7 +238,7 @@ func needNewTargetCertKeyPairForTime(annotations map[string]string, signer *cryp
 
 // setTargetCertKeyPairSecret creates a new cert/key pair and sets them in the secret.  Only one of client, serving, or signer rotation may be specified.
 // TODO refactor with an interface for actually signing and move the one-of check higher in the stack.
-func setTargetCertKeyPairSecret(targetCertKeyPairSecret *corev1.Secret, validity time.Duration, signer *crypto.CA, certCreator TargetCertCreator) error {
+func setTargetCertKeyPairSecret(ctx context.Context, targetCertKeyPairSecret *corev1.Secret, validity time.Duration, signer *crypto.CA, certCreator TargetCertCreator) error {
 	if targetCertKeyPairSecret.Annotations == nil {
 		targetCertKeyPairSecret.Annotations = map[string]string{}
 	}
@@ -192,14 +246,22 @@ func setTargetCertKeyPairSecret(targetCertKeyPairSecret *corev1.Secret, validity
 		targetCertKeyPairSecret.Data = map[string][]byte{}
 	}
 
-	// our annotation is based on our cert validity, so we want to make sure that we don't specify something past our signer
+	// our annotation is based on our cert validity, so we want to make sure that we don't specify something past our signer.
+	// Externally issued certificates do not depend on the signer.
 	targetValidity := validity
 	remainingSignerValidity := signer.Config.Certs[0].NotAfter.Sub(time.Now())
-	if remainingSignerValidity < validity {
+	externalIssuer, ok := certCreator.(TargetCertExternalIssuer)
+	if remainingSignerValidity < validity && !(ok && externalIssuer.ExternallyIssued()) {
 		targetValidity = remainingSignerValidity
 	}
 
-	certKeyPair, err := certCreator.NewCertificate(signer, targetValidity)
+	var certKeyPair *crypto.TLSCertificateConfig
+	var err error
+	if contextCreator, ok := certCreator.(TargetCertContextCreator); ok {
+		certKeyPair, err = contextCreator.NewCertificateWithContext(ctx, signer, targetValidity)
+	} else {
+		certKeyPair, err = certCreator.NewCertificate(signer, targetValidity)
+	}
 	if err != nil {
 		return err
 	}
@@ -216,6 +278,18 @@ func setTargetCertKeyPairSecret(targetCertKeyPairSecret *corev1.Secret, validity
 	return nil
 }
 
+// RotationStatus returns when the target certificate is rotated next.
+func (c RotatedSelfSignedCertKeySecret) RotationStatus() (RotationStatus, error) {
+	secret, err := c.Lister.Secrets(c.Namespace).Get(c.Name)
+	if apierrors.IsNotFound(err) {
+		return RotationStatus{Namespace: c.Namespace, Name: c.Name, Reason: "missing"}, nil
+	}
+	if err != nil {
+		return RotationStatus{}, err
+	}
+	return plannedRotation(c.Namespace, c.Name, secret.Annotations, c.Policy, c.Refresh, c.RefreshOnlyWhenExpired), nil
+}
+
 type ClientRotation struct {
 	UserInfo user.Info
 }
@@ -123,7 +123,7 @@ func TestNeedNewTargetCertKeyPairForTime(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			actual := needNewTargetCertKeyPairForTime(test.annotations, signer, test.refresh, test.refreshOnlyWhenExpired)
+			actual := needNewTargetCertKeyPairForTime(test.annotations, signer, test.refresh, test.refreshOnlyWhenExpired, RotationPolicy{}, "")
 			if !strings.HasPrefix(actual, test.expected) {
 				t.Errorf("expected %v, got %v", test.expected, actual)
 			}
@@ -437,3 +437,73 @@ func TestEnsureTargetSignerCertKeyPair(t *testing.T) {
 		})
 	}
 }
+
+func TestEnsureTargetCertKeyPairKeyAlgorithm(t *testing.T) {
+	caConfig, err := crypto.MakeSelfSignedCAConfigForDurationWithKeyAlgorithm("signer-tests", 24*time.Hour, crypto.ECDSAP384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &crypto.CA{Config: caConfig, SerialGenerator: &crypto.RandomSerialGenerator{}}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	client := kubefake.NewSimpleClientset()
+	c := &RotatedSelfSignedCertKeySecret{
+		Namespace:    "ns",
+		Validity:     24 * time.Hour,
+		Refresh:      12 * time.Hour,
+		Name:         "target-secret",
+		KeyAlgorithm: crypto.Ed25519,
+		CertCreator: &ServingRotation{
+			Hostnames: func() []string { return []string{"foo", "bar"} },
+		},
+
+		Client:        client.CoreV1(),
+		Lister:        corev1listers.NewSecretLister(indexer),
+		EventRecorder: events.NewInMemoryRecorder("test"),
+	}
+
+	ensure := func(expectedAction string) {
+		t.Helper()
+		client.ClearActions()
+		if err := c.ensureTargetCertKeyPair(context.TODO(), ca, ca.Config.Certs); err != nil {
+			t.Fatal(err)
+		}
+		var writes []clienttesting.Action
+		for _, action := range client.Actions() {
+			if !action.Matches("get", "secrets") {
+				writes = append(writes, action)
+			}
+		}
+		if len(expectedAction) == 0 {
+			if len(writes) != 0 {
+				t.Fatal(spew.Sdump(writes))
+			}
+			return
+		}
+		if len(writes) != 1 || !writes[0].Matches(expectedAction, "secrets") {
+			t.Fatal(spew.Sdump(writes))
+		}
+
+		actual := writes[0].(clienttesting.CreateAction).GetObject().(*corev1.Secret)
+		certConfig, err := crypto.GetTLSCertificateConfigFromBytes(actual.Data["tls.crt"], actual.Data["tls.key"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if algorithm, err := crypto.KeyAlgorithmOf(certConfig.Certs[0].PublicKey); err != nil || algorithm != c.KeyAlgorithm {
+			t.Errorf("expected key algorithm %s, got %s (%v)", c.KeyAlgorithm, algorithm, err)
+		}
+		if err := certConfig.Certs[0].CheckSignatureFrom(ca.Config.Certs[0]); err != nil {
+			t.Errorf("expected the certificate to be signed by the signer: %v", err)
+		}
+		indexer.Update(actual)
+	}
+
+	ensure("create")
+	if len(ca.KeyAlgorithm) != 0 {
+		t.Errorf("expected the signer not to be modified, got key algorithm %s", ca.KeyAlgorithm)
+	}
+	ensure("")
+
+	c.KeyAlgorithm = crypto.ECDSAP256
+	ensure("update")
+}
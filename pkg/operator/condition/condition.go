@@ -47,6 +47,11 @@ const (
 	// validity can expire and without rotating/renewing them manual recovery might be required to fix the cluster.
 	CertRotationDegradedConditionTypeFmt = "CertRotation_%s_Degraded"
 
+	// CertRotationPlannedConditionTypeFmt reports the planned rotations of the certificates managed by a certificate rotation controller.
+	// It is true when all certificates are valid, with a message listing their next rotation, and false with the RotationPending reason
+	// when a certificate is rotated on the next sync.
+	CertRotationPlannedConditionTypeFmt = "CertRotation_%s_Planned"
+
 	// InstallerControllerDegradedConditionType is true when the operator is not able to create new installer pods so the new revisions
 	// cannot be rolled out. This might happen when one or more required secrets or config maps does not exists.
 	// In case the missing secret or config map is available, this condition is automatically set to false.
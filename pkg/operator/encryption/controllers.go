@@ -37,6 +37,8 @@ func NewControllers(
 	apiServerInformer configv1informers.APIServerInformer,
 	kubeInformersForNamespaces operatorv1helpers.KubeInformersForNamespaces,
 	secretsClient corev1.SecretsGetter,
+	keyWrappers *secrets.KeyWrappers,
+	kmsConfigProvider controllers.KMSConfigProvider,
 	eventRecorder events.Recorder,
 	resourceSyncer *resourcesynccontroller.ResourceSyncController,
 ) (*Controllers, error) {
@@ -46,13 +48,14 @@ func NewControllers(
 	// TODO: update the eventHandlers used by the controllers to ignore components that do not match their own
 	encryptionSecretSelector := metav1.ListOptions{LabelSelector: secrets.EncryptionKeySecretsLabel + "=" + component}
 
-	encryptionEnabledChecker, err := newEncryptionEnabledPrecondition(apiServerInformer.Lister(), kubeInformersForNamespaces, encryptionSecretSelector.LabelSelector, component)
+	encryptionEnabledChecker, err := newEncryptionEnabledPrecondition(apiServerInformer.Lister(), kubeInformersForNamespaces, encryptionSecretSelector.LabelSelector, component, keyWrappers, kmsConfigProvider)
 	if err != nil {
 		return nil, err
 	}
 
-	// for testing resourceSyncer might be nil
-	if resourceSyncer != nil {
+	// for testing resourceSyncer might be nil. With wrapped keys the state controller writes the encryption
+	// config into the component namespace itself.
+	if resourceSyncer != nil && !keyWrappers.Enabled() {
 		if err := resourceSyncer.SyncSecretConditionally(
 			resourcesynccontroller.ResourceLocation{Namespace: component, Name: encryptionconfig.EncryptionConfSecretName},
 			resourcesynccontroller.ResourceLocation{Namespace: "openshift-config-managed", Name: fmt.Sprintf("%s-%s", encryptionconfig.EncryptionConfSecretName, component)},
@@ -76,6 +79,8 @@ func NewControllers(
 				kubeInformersForNamespaces,
 				secretsClient,
 				encryptionSecretSelector,
+				keyWrappers,
+				kmsConfigProvider,
 				eventRecorder,
 			),
 			controllers.NewStateController(
@@ -88,6 +93,7 @@ func NewControllers(
 				kubeInformersForNamespaces,
 				secretsClient,
 				encryptionSecretSelector,
+				keyWrappers,
 				eventRecorder,
 			),
 			controllers.NewPruneController(
@@ -99,6 +105,7 @@ func NewControllers(
 				kubeInformersForNamespaces,
 				secretsClient,
 				encryptionSecretSelector,
+				keyWrappers,
 				eventRecorder,
 			),
 			controllers.NewMigrationController(
@@ -112,6 +119,7 @@ func NewControllers(
 				kubeInformersForNamespaces,
 				secretsClient,
 				encryptionSecretSelector,
+				keyWrappers,
 				eventRecorder,
 			),
 			controllers.NewConditionController(
@@ -123,6 +131,7 @@ func NewControllers(
 				kubeInformersForNamespaces,
 				secretsClient,
 				encryptionSecretSelector,
+				keyWrappers,
 				eventRecorder,
 			),
 		},
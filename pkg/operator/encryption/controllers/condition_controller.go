@@ -15,6 +15,7 @@ import (
 	configv1informers "github.com/openshift/client-go/config/informers/externalversions/config/v1"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/encryption/encryptionconfig"
+	"github.com/openshift/library-go/pkg/operator/encryption/secrets"
 	"github.com/openshift/library-go/pkg/operator/encryption/state"
 	"github.com/openshift/library-go/pkg/operator/encryption/statemachine"
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -32,6 +33,7 @@ type conditionController struct {
 	provider                 Provider
 	preconditionsFulfilledFn preconditionsFulfilled
 	secretClient             corev1client.SecretsGetter
+	keyWrappers              *secrets.KeyWrappers
 }
 
 func NewConditionController(
@@ -43,6 +45,7 @@ func NewConditionController(
 	kubeInformersForNamespaces operatorv1helpers.KubeInformersForNamespaces,
 	secretClient corev1client.SecretsGetter,
 	encryptionSecretSelector metav1.ListOptions,
+	keyWrappers *secrets.KeyWrappers,
 	eventRecorder events.Recorder,
 ) factory.Controller {
 	c := &conditionController{
@@ -53,6 +56,7 @@ func NewConditionController(
 		provider:                 provider,
 		preconditionsFulfilledFn: preconditionsFulfilledFn,
 		secretClient:             secretClient,
+		keyWrappers:              keyWrappers,
 	}
 
 	return factory.New().WithInformers(
@@ -82,13 +86,13 @@ func (c *conditionController) sync(ctx context.Context, _ factory.SyncContext) (
 	}
 
 	encryptedGRs := c.provider.EncryptedGRs()
-	currentConfig, desiredState, foundSecrets, transitioningReason, err := statemachine.GetEncryptionConfigAndState(ctx, c.deployer, c.secretClient, c.encryptionSecretSelector, encryptedGRs)
+	currentConfig, desiredState, foundSecrets, transitioningReason, err := statemachine.GetEncryptionConfigAndState(ctx, c.deployer, c.secretClient, c.encryptionSecretSelector, encryptedGRs, c.keyWrappers)
 	if err != nil || len(transitioningReason) > 0 {
 		// do not update the encryption condition (cond). Note: progressing is set elsewhere.
 		cond = nil
 		return err
 	}
-	currentState, _ := encryptionconfig.ToEncryptionState(currentConfig, foundSecrets)
+	currentState, _ := encryptionconfig.ToEncryptionState(ctx, currentConfig, foundSecrets, c.keyWrappers)
 
 	cond.Status = operatorv1.ConditionTrue
 	cond.Reason = "EncryptionCompleted"
@@ -3,6 +3,7 @@ package controllers
 import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	"github.com/openshift/library-go/pkg/operator/encryption/state"
 	"github.com/openshift/library-go/pkg/operator/management"
 	operatorv1helpers "github.com/openshift/library-go/pkg/operator/v1helpers"
 )
@@ -19,6 +20,16 @@ type Provider interface {
 	ShouldRunEncryptionControllers() (bool, error)
 }
 
+// KMSConfigProvider enables the KMS mode. The encryption type of the APIServer resource has no KMS value, so the
+// operator decides about KMS encryption instead, e.g. based on a feature gate.
+type KMSConfigProvider interface {
+	// KMSKeyConfig returns the endpoint of the KMS v2 plugin and the key ID the plugin currently reports, or nil if
+	// KMS encryption is not enabled. The plugin socket is only reachable on the control-plane hosts, so the key ID must
+	// be reported by a component running there, e.g. by the API server pods, not dialed by the operator. It is called
+	// on every sync and should be backed by informers.
+	KMSKeyConfig() (*state.KMSKeyConfig, error)
+}
+
 func shouldRunEncryptionController(operatorClient operatorv1helpers.OperatorClient, preconditionsFulfilledFn preconditionsFulfilled, shouldRunFn func() (bool, error)) (bool, error) {
 	if shouldRun, err := shouldRunFn(); !shouldRun || err != nil {
 		return false, err
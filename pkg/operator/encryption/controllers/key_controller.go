@@ -50,7 +50,8 @@ const encryptionSecretMigrationInterval = time.Hour * 24 * 7 // one week
 //   - a new to-be-encrypted resource shows up or
 //   - the EncryptionType in the API does not match with the newest existing key or
 //   - based on time (once a week is the proposed rotation interval) or
-//   - an external reason given as a string in .encryption.reason of UnsupportedConfigOverrides.
+//   - an external reason given as a string in .encryption.reason of UnsupportedConfigOverrides or
+//   - in kms mode, the KMS plugin endpoint or the key ID given by the KMSConfigProvider changed.
 //     It then creates it.
 //
 // Note: the "based on time" reason for a new key is based on the annotation
@@ -70,8 +71,11 @@ type keyController struct {
 	secretClient             corev1client.SecretsGetter
 	provider                 Provider
 	preconditionsFulfilledFn preconditionsFulfilled
+	keyWrappers              *secrets.KeyWrappers
 
 	unsupportedConfigPrefix []string
+
+	kmsConfigProvider KMSConfigProvider
 }
 
 func NewKeyController(
@@ -86,6 +90,8 @@ func NewKeyController(
 	kubeInformersForNamespaces operatorv1helpers.KubeInformersForNamespaces,
 	secretClient corev1client.SecretsGetter,
 	encryptionSecretSelector metav1.ListOptions,
+	keyWrappers *secrets.KeyWrappers,
+	kmsConfigProvider KMSConfigProvider,
 	eventRecorder events.Recorder,
 ) factory.Controller {
 	c := &keyController{
@@ -100,7 +106,9 @@ func NewKeyController(
 		deployer:                 deployer,
 		provider:                 provider,
 		preconditionsFulfilledFn: preconditionsFulfilledFn,
+		keyWrappers:              keyWrappers,
 		secretClient:             secretClient,
+		kmsConfigProvider:        kmsConfigProvider,
 	}
 
 	return factory.New().
@@ -146,8 +154,15 @@ func (c *keyController) checkAndCreateKeys(ctx context.Context, syncContext fact
 	if err != nil {
 		return err
 	}
+	currentKMSConfig, err := c.getCurrentKMSKeyConfig()
+	if err != nil {
+		return err
+	}
+	if currentKMSConfig != nil {
+		currentMode = state.KMS
+	}
 
-	currentConfig, desiredEncryptionState, secrets, isProgressingReason, err := statemachine.GetEncryptionConfigAndState(ctx, c.deployer, c.secretClient, c.encryptionSecretSelector, encryptedGRs)
+	currentConfig, desiredEncryptionState, secrets, isProgressingReason, err := statemachine.GetEncryptionConfigAndState(ctx, c.deployer, c.secretClient, c.encryptionSecretSelector, encryptedGRs, c.keyWrappers)
 	if err != nil {
 		return err
 	}
@@ -174,7 +189,7 @@ func (c *keyController) checkAndCreateKeys(ctx context.Context, syncContext fact
 
 	var commonReason *string
 	for gr, grKeys := range desiredEncryptionState {
-		latestKeyID, internalReason, needed := needsNewKey(grKeys, currentMode, externalReason, encryptedGRs)
+		latestKeyID, internalReason, needed := needsNewKey(grKeys, currentMode, currentKMSConfig, externalReason, encryptedGRs)
 		if !needed {
 			continue
 		}
@@ -201,7 +216,7 @@ func (c *keyController) checkAndCreateKeys(ctx context.Context, syncContext fact
 
 	sort.Sort(sort.StringSlice(reasons))
 	internalReason := strings.Join(reasons, ", ")
-	keySecret, err := c.generateKeySecret(newKeyID, currentMode, internalReason, externalReason)
+	keySecret, err := c.generateKeySecret(ctx, newKeyID, currentMode, currentKMSConfig, internalReason, externalReason)
 	if err != nil {
 		return fmt.Errorf("failed to create key: %v", err)
 	}
@@ -231,25 +246,30 @@ func (c *keyController) validateExistingSecret(ctx context.Context, keySecret *c
 		return fmt.Errorf("secret %s has an invalid name, new keys cannot be created for encryption target", keySecret.Name)
 	}
 
-	if _, err := secrets.ToKeyState(actualKeySecret); err != nil {
+	if _, err := secrets.ToKeyState(ctx, actualKeySecret, c.keyWrappers); err != nil {
 		return fmt.Errorf("secret %s is invalid, new keys cannot be created for encryption target", keySecret.Name)
 	}
 
 	return nil // we made this key earlier
 }
 
-func (c *keyController) generateKeySecret(keyID uint64, currentMode state.Mode, internalReason, externalReason string) (*corev1.Secret, error) {
-	bs := crypto.ModeToNewKeyFunc[currentMode]()
+func (c *keyController) generateKeySecret(ctx context.Context, keyID uint64, currentMode state.Mode, currentKMSConfig *state.KMSKeyConfig, internalReason, externalReason string) (*corev1.Secret, error) {
+	var secret string
+	if currentMode == state.KMS {
+		secret = currentKMSConfig.ToKeySecret()
+	} else {
+		secret = base64.StdEncoding.EncodeToString(crypto.ModeToNewKeyFunc[currentMode]())
+	}
 	ks := state.KeyState{
 		Key: apiserverv1.Key{
 			Name:   fmt.Sprintf("%d", keyID),
-			Secret: base64.StdEncoding.EncodeToString(bs),
+			Secret: secret,
 		},
 		Mode:           currentMode,
 		InternalReason: internalReason,
 		ExternalReason: externalReason,
 	}
-	return secrets.FromKeyState(c.component, ks)
+	return secrets.FromKeyState(ctx, c.component, ks, c.keyWrappers)
 }
 
 func (c *keyController) getCurrentModeAndExternalReason(ctx context.Context) (state.Mode, string, error) {
@@ -270,7 +290,7 @@ func (c *keyController) getCurrentModeAndExternalReason(ctx context.Context) (st
 
 	reason := encryptionConfig.Encryption.Reason
 	switch currentMode := state.Mode(apiServer.Spec.Encryption.Type); currentMode {
-	case state.AESCBC, state.AESGCM, state.Identity: // secretbox is disabled for now
+	case state.AESCBC, state.AESGCM, state.Identity: // secretbox is disabled for now, kms is enabled by the KMSConfigProvider
 		return currentMode, reason, nil
 	case "": // unspecified means use the default (which can change over time)
 		return state.DefaultMode, reason, nil
@@ -279,9 +299,25 @@ func (c *keyController) getCurrentModeAndExternalReason(ctx context.Context) (st
 	}
 }
 
+// getCurrentKMSKeyConfig returns the KMS plugin endpoint and key ID of the KMSConfigProvider, or nil if KMS encryption
+// is not enabled.
+func (c *keyController) getCurrentKMSKeyConfig() (*state.KMSKeyConfig, error) {
+	if c.kmsConfigProvider == nil {
+		return nil, nil
+	}
+	kmsConfig, err := c.kmsConfigProvider.KMSKeyConfig()
+	if err != nil || kmsConfig == nil {
+		return nil, err
+	}
+	if len(kmsConfig.Endpoint) == 0 || len(kmsConfig.KeyID) == 0 {
+		return nil, fmt.Errorf("encryption mode %s requires a KMS plugin endpoint and key ID", state.KMS)
+	}
+	return kmsConfig, nil
+}
+
 // needsNewKey checks whether a new key must be created for the given resource. If true, it also returns the latest
 // used key ID and a reason string.
-func needsNewKey(grKeys state.GroupResourceState, currentMode state.Mode, externalReason string, encryptedGRs []schema.GroupResource) (uint64, string, bool) {
+func needsNewKey(grKeys state.GroupResourceState, currentMode state.Mode, currentKMSConfig *state.KMSKeyConfig, externalReason string, encryptedGRs []schema.GroupResource) (uint64, string, bool) {
 	// we always need to have some encryption keys unless we are turned off
 	if len(grKeys.ReadKeys) == 0 {
 		return 0, "key-does-not-exist", currentMode != state.Identity
@@ -329,6 +365,18 @@ func needsNewKey(grKeys state.GroupResourceState, currentMode state.Mode, extern
 		return latestKeyID, "external-reason-changed", true
 	}
 
+	// the KMS plugin rotates its key itself. A new key ID is handled like a new key to migrate the resources to it.
+	if currentMode == state.KMS {
+		latestKMSConfig, err := state.KMSKeyConfigFromKeySecret(latestKey.Key.Secret)
+		if err != nil {
+			return latestKeyID, fmt.Sprintf("key-secret-%d-is-invalid", latestKeyID), true
+		}
+		if latestKMSConfig.Endpoint != currentKMSConfig.Endpoint {
+			return latestKeyID, "kms-endpoint-changed", true
+		}
+		return latestKeyID, "kms-key-id-changed", latestKMSConfig.KeyID != currentKMSConfig.KeyID
+	}
+
 	// we check for encryptionSecretMigratedTimestamp set by migration controller to determine when migration completed
 	// this also generates back pressure for key rotation when migration takes a long time or was recently completed
 	return latestKeyID, "rotation-interval-has-passed", time.Since(latestKey.Migrated.Timestamp) > encryptionSecretMigrationInterval
@@ -25,6 +25,7 @@ import (
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	encryptiondeployer "github.com/openshift/library-go/pkg/operator/encryption/deployer"
+	"github.com/openshift/library-go/pkg/operator/encryption/state"
 	encryptiontesting "github.com/openshift/library-go/pkg/operator/encryption/testing"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
@@ -39,10 +40,38 @@ func TestKeyController(t *testing.T) {
 	apiServerWithAESGCM := simpleAPIServer.DeepCopy()
 	apiServerWithAESGCM.Spec.Encryption = configv1.APIServerEncryption{Type: "aesgcm"}
 
+	// the key ID is reported by a running plugin in the scenarios setting kmsPluginKeyID
+	kmsPlugin := encryptiontesting.NewFakeKMSPlugin(t, "1")
+	kmsEndpoint := kmsPlugin.Endpoint
+	migratedKMSKeySecret := func(keyID uint64, kmsKeyID string) *corev1.Secret {
+		secret := encryptiontesting.CreateEncryptionKeySecretWithKMSConfig("kms", []schema.GroupResource{{Group: "", Resource: "secrets"}}, keyID, kmsEndpoint, kmsKeyID)
+		secret.Annotations["encryption.apiserver.operator.openshift.io/migrated-timestamp"] = time.Now().Add(-30 * 24 * time.Hour).Format(time.RFC3339)
+		return secret
+	}
+	validateCreatedKMSKeySecret := func(keyID uint64, kmsKeyID, internalReason string) func(ts *testing.T, actions []clientgotesting.Action, targetNamespace string, targetGRs []schema.GroupResource) {
+		return func(ts *testing.T, actions []clientgotesting.Action, targetNamespace string, targetGRs []schema.GroupResource) {
+			for _, action := range actions {
+				if action.Matches("create", "secrets") {
+					actualSecret := action.(clientgotesting.CreateAction).GetObject().(*corev1.Secret)
+					expectedSecret := encryptiontesting.CreateEncryptionKeySecretWithKMSConfig(targetNamespace, nil, keyID, kmsEndpoint, kmsKeyID)
+					expectedSecret.Annotations["encryption.apiserver.operator.openshift.io/internal-reason"] = internalReason
+					if !equality.Semantic.DeepEqual(actualSecret, expectedSecret) {
+						ts.Errorf(diff.ObjectDiff(expectedSecret, actualSecret))
+					}
+					return
+				}
+			}
+			ts.Errorf("the secret wasn't created")
+		}
+	}
+
 	scenarios := []struct {
-		name                     string
-		initialObjects           []runtime.Object
-		apiServerObjects         []runtime.Object
+		name             string
+		initialObjects   []runtime.Object
+		apiServerObjects []runtime.Object
+		kmsConfig        *state.KMSKeyConfig
+		// kmsPluginKeyID is the key ID reported by the KMS plugin, the KMS config is read from the plugin when set
+		kmsPluginKeyID           string
 		encryptionSecretSelector metav1.ListOptions
 		targetNamespace          string
 		targetGRs                []schema.GroupResource
@@ -322,6 +351,83 @@ func TestKeyController(t *testing.T) {
 				}
 			},
 		},
+
+		{
+			name: "creates a KMS key secret with the key ID of the plugin",
+			targetGRs: []schema.GroupResource{
+				{Group: "", Resource: "secrets"},
+			},
+			initialObjects: []runtime.Object{
+				encryptiontesting.CreateDummyKubeAPIPod("kube-apiserver-1", "kms", "node-1"),
+			},
+			apiServerObjects: []runtime.Object{simpleAPIServer},
+			kmsPluginKeyID:   "1",
+			targetNamespace:  "kms",
+			expectedActions:  []string{"list:pods:kms", "get:secrets:kms", "list:secrets:openshift-config-managed", "create:secrets:openshift-config-managed", "create:events:kms"},
+			validateFunc:     validateCreatedKMSKeySecret(1, "1", "secrets-key-does-not-exist"),
+		},
+
+		{
+			name: "no-op when the key ID of the KMS plugin is unchanged, independent of the rotation interval",
+			targetGRs: []schema.GroupResource{
+				{Group: "", Resource: "secrets"},
+			},
+			initialObjects: []runtime.Object{
+				encryptiontesting.CreateDummyKubeAPIPod("kube-apiserver-1", "kms", "node-1"),
+				migratedKMSKeySecret(5, "1"),
+			},
+			apiServerObjects: []runtime.Object{simpleAPIServer},
+			kmsPluginKeyID:   "1",
+			targetNamespace:  "kms",
+			expectedActions:  []string{"list:pods:kms", "get:secrets:kms", "list:secrets:openshift-config-managed"},
+		},
+
+		{
+			name: "creates a new KMS key secret because the key ID of the plugin changed",
+			targetGRs: []schema.GroupResource{
+				{Group: "", Resource: "secrets"},
+			},
+			initialObjects: []runtime.Object{
+				encryptiontesting.CreateDummyKubeAPIPod("kube-apiserver-1", "kms", "node-1"),
+				migratedKMSKeySecret(5, "1"),
+			},
+			apiServerObjects: []runtime.Object{simpleAPIServer},
+			kmsPluginKeyID:   "2",
+			targetNamespace:  "kms",
+			expectedActions:  []string{"list:pods:kms", "get:secrets:kms", "list:secrets:openshift-config-managed", "create:secrets:openshift-config-managed", "create:events:kms"},
+			validateFunc:     validateCreatedKMSKeySecret(6, "2", "secrets-kms-key-id-changed"),
+		},
+
+		{
+			name: "creates a KMS key secret because the encryption mode changed",
+			targetGRs: []schema.GroupResource{
+				{Group: "", Resource: "secrets"},
+			},
+			initialObjects: []runtime.Object{
+				encryptiontesting.CreateDummyKubeAPIPod("kube-apiserver-1", "kms", "node-1"),
+				encryptiontesting.CreateEncryptionKeySecretWithRawKeyWithMode("kms", []schema.GroupResource{{Group: "", Resource: "secrets"}}, 5, []byte("61def964fb967f5d7c44a2af8dab6865"), "aescbc"),
+			},
+			apiServerObjects: []runtime.Object{simpleAPIServer},
+			kmsConfig:        &state.KMSKeyConfig{Endpoint: kmsEndpoint, KeyID: "1"},
+			targetNamespace:  "kms",
+			expectedActions:  []string{"list:pods:kms", "get:secrets:kms", "list:secrets:openshift-config-managed", "create:secrets:openshift-config-managed", "create:events:kms"},
+			validateFunc:     validateCreatedKMSKeySecret(6, "1", "secrets-encryption-mode-changed"),
+		},
+
+		{
+			name: "degraded when the KMS key ID is missing",
+			targetGRs: []schema.GroupResource{
+				{Group: "", Resource: "secrets"},
+			},
+			initialObjects: []runtime.Object{
+				encryptiontesting.CreateDummyKubeAPIPod("kube-apiserver-1", "kms", "node-1"),
+			},
+			apiServerObjects: []runtime.Object{simpleAPIServer},
+			kmsConfig:        &state.KMSKeyConfig{Endpoint: kmsEndpoint},
+			targetNamespace:  "kms",
+			expectedActions:  []string{},
+			expectedError:    errors.New("encryption mode kms requires a KMS plugin endpoint and key ID"),
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -371,7 +477,13 @@ func TestKeyController(t *testing.T) {
 			}
 			provider := newTestProvider(scenario.targetGRs)
 
-			target := NewKeyController(scenario.targetNamespace, nil, provider, deployer, alwaysFulfilledPreconditions, fakeOperatorClient, fakeApiServerClient, fakeApiServerInformer, kubeInformers, fakeSecretClient, scenario.encryptionSecretSelector, eventRecorder)
+			var kmsConfigProvider KMSConfigProvider = encryptiontesting.FakeKMSConfigProvider{Config: scenario.kmsConfig}
+			if len(scenario.kmsPluginKeyID) > 0 {
+				kmsPlugin.SetKeyID(scenario.kmsPluginKeyID)
+				kmsConfigProvider = encryptiontesting.KMSPluginConfigProvider{Endpoint: kmsEndpoint}
+			}
+
+			target := NewKeyController(scenario.targetNamespace, nil, provider, deployer, alwaysFulfilledPreconditions, fakeOperatorClient, fakeApiServerClient, fakeApiServerInformer, kubeInformers, fakeSecretClient, scenario.encryptionSecretSelector, nil, kmsConfigProvider, eventRecorder)
 
 			// act
 			err = target.Sync(context.TODO(), factory.NewSyncContext("test", eventRecorder))
@@ -16,6 +16,7 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
 
 	operatorv1 "github.com/openshift/api/operator/v1"
 
@@ -35,6 +36,9 @@ import (
 const (
 	// how long to wait until we retry a migration when it failed with unknown errors.
 	migrationRetryDuration = time.Minute * 5
+
+	// how often the progress of a running migration is recorded on the write key secret at most.
+	migrationProgressUpdateInterval = time.Second * 30
 )
 
 // The migrationController controller migrates resources to a new write key
@@ -68,6 +72,7 @@ type migrationController struct {
 	migrator                 migrators.Migrator
 	provider                 Provider
 	preconditionsFulfilledFn preconditionsFulfilled
+	keyWrappers              *secrets.KeyWrappers
 }
 
 func NewMigrationController(
@@ -81,6 +86,7 @@ func NewMigrationController(
 	kubeInformersForNamespaces operatorv1helpers.KubeInformersForNamespaces,
 	secretClient corev1client.SecretsGetter,
 	encryptionSecretSelector metav1.ListOptions,
+	keyWrappers *secrets.KeyWrappers,
 	eventRecorder events.Recorder,
 ) factory.Controller {
 	c := &migrationController{
@@ -94,6 +100,7 @@ func NewMigrationController(
 		migrator:                 migrator,
 		provider:                 provider,
 		preconditionsFulfilledFn: preconditionsFulfilledFn,
+		keyWrappers:              keyWrappers,
 	}
 
 	return factory.New().ResyncEvery(time.Minute).WithSync(c.sync).WithInformers(
@@ -138,7 +145,7 @@ func (c *migrationController) sync(ctx context.Context, syncCtx factory.SyncCont
 	if len(migratingResources) > 0 {
 		progressingCondition.Status = operatorv1.ConditionTrue
 		progressingCondition.Reason = "Migrating"
-		progressingCondition.Message = fmt.Sprintf("migrating resources to a new write key: %v", grsToHumanReadable(migratingResources))
+		progressingCondition.Message = fmt.Sprintf("migrating resources to a new write key: %v", c.migratingResourcesToHumanReadable(migratingResources))
 	}
 	return migrationError
 }
@@ -146,7 +153,7 @@ func (c *migrationController) sync(ctx context.Context, syncCtx factory.SyncCont
 // TODO doc
 func (c *migrationController) migrateKeysIfNeededAndRevisionStable(ctx context.Context, syncContext factory.SyncContext, encryptedGRs []schema.GroupResource) (migratingResources []schema.GroupResource, err error) {
 	// no storage migration during revision changes
-	currentEncryptionConfig, desiredEncryptionState, _, isTransitionalReason, err := statemachine.GetEncryptionConfigAndState(ctx, c.deployer, c.secretClient, c.encryptionSecretSelector, encryptedGRs)
+	currentEncryptionConfig, desiredEncryptionState, _, isTransitionalReason, err := statemachine.GetEncryptionConfigAndState(ctx, c.deployer, c.secretClient, c.encryptionSecretSelector, encryptedGRs, c.keyWrappers)
 	if err != nil {
 		return nil, err
 	}
@@ -159,7 +166,7 @@ func (c *migrationController) migrateKeysIfNeededAndRevisionStable(ctx context.C
 	if err != nil {
 		return nil, err
 	}
-	currentState, _ := encryptionconfig.ToEncryptionState(currentEncryptionConfig, encryptionSecrets)
+	currentState, _ := encryptionconfig.ToEncryptionState(ctx, currentEncryptionConfig, encryptionSecrets, c.keyWrappers)
 	desiredEncryptedConfig := encryptionconfig.FromEncryptionState(desiredEncryptionState)
 
 	// no storage migration until config is stable
@@ -223,19 +230,18 @@ func (c *migrationController) migrateKeysIfNeededAndRevisionStable(ctx context.C
 
 		if !finished {
 			migratingResources = append(migratingResources, gr)
+			if err := c.recordMigrationProgress(ctx, gr, grActualKeys.WriteKey); err != nil {
+				klog.Warningf("failed to record the migration progress of resource %s: %v", gr, err)
+			}
 			continue
 		}
 
 		// update secret annotations
-		oldWriteKey, err := secrets.FromKeyState(c.component, grActualKeys.WriteKey)
-		if err != nil {
-			errs = append(errs, result)
-			continue
-		}
+		oldWriteKeyName := secrets.KeySecretName(c.component, grActualKeys.WriteKey)
 		if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
-			s, err := c.secretClient.Secrets(oldWriteKey.Namespace).Get(ctx, oldWriteKey.Name, metav1.GetOptions{})
+			s, err := c.secretClient.Secrets("openshift-config-managed").Get(ctx, oldWriteKeyName, metav1.GetOptions{})
 			if err != nil {
-				return fmt.Errorf("failed to get key secret %s/%s: %v", oldWriteKey.Namespace, oldWriteKey.Name, err)
+				return fmt.Errorf("failed to get key secret openshift-config-managed/%s: %v", oldWriteKeyName, err)
 			}
 
 			changed, err := setResourceMigrated(gr, s)
@@ -257,6 +263,121 @@ func (c *migrationController) migrateKeysIfNeededAndRevisionStable(ctx context.C
 	return migratingResources, errors.NewAggregate(errs)
 }
 
+// recordMigrationProgress records the progress of the running migration of gr, if the migrator reports it, in the
+// migration progress annotation of the write key secret.
+func (c *migrationController) recordMigrationProgress(ctx context.Context, gr schema.GroupResource, writeKey state.KeyState) error {
+	reporter, ok := c.migrator.(migrators.MigrationProgressReporter)
+	if !ok {
+		return nil
+	}
+	progress, ok := reporter.MigrationProgress(gr)
+	if !ok {
+		return nil
+	}
+
+	writeKeyName := secrets.KeySecretName(c.component, writeKey)
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		s, err := c.secretClient.Secrets("openshift-config-managed").Get(ctx, writeKeyName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get key secret openshift-config-managed/%s: %v", writeKeyName, err)
+		}
+
+		// updated directly, not applied, to avoid an event per update
+		s = s.DeepCopy()
+		changed, err := setResourceMigrationProgress(gr, progress, s, time.Now())
+		if err != nil || !changed {
+			return err
+		}
+		_, err = c.secretClient.Secrets("openshift-config-managed").Update(ctx, s, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// setResourceMigrationProgress sets the progress of gr in the migration progress annotation of s, unless the recorded
+// progress is more recent than migrationProgressUpdateInterval. Every update triggers another sync through the secret
+// informer, which must not record the progress again.
+func setResourceMigrationProgress(gr schema.GroupResource, progress migrators.MigrationProgress, s *corev1.Secret, now time.Time) (bool, error) {
+	migrationProgress := secrets.MigrationProgress{}
+	if existing, found := s.Annotations[secrets.EncryptionSecretMigrationProgress]; found {
+		if err := json.Unmarshal([]byte(existing), &migrationProgress); err != nil {
+			// ignore error and just start fresh, the progress is recorded again for every running migration
+			migrationProgress = secrets.MigrationProgress{}
+		}
+	}
+
+	resourceProgress := secrets.ResourceMigrationProgress{
+		GroupResource:     gr,
+		Migrated:          progress.Migrated,
+		RemainingEstimate: progress.RemainingEstimate,
+		LastUpdateTime:    metav1.NewTime(now),
+	}
+	if percentage, ok := progress.Percentage(); ok {
+		resourceProgress.Percentage = pointer.Int64(int64(percentage))
+	}
+
+	found := false
+	for i, existing := range migrationProgress.Resources {
+		if existing.GroupResource != gr {
+			continue
+		}
+		if now.Sub(existing.LastUpdateTime.Time) < migrationProgressUpdateInterval {
+			return false, nil
+		}
+		migrationProgress.Resources[i] = resourceProgress
+		found = true
+		break
+	}
+	if !found {
+		migrationProgress.Resources = append(migrationProgress.Resources, resourceProgress)
+	}
+
+	bs, err := json.Marshal(migrationProgress)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal %s annotation value %#v for key secret %s/%s", secrets.EncryptionSecretMigrationProgress, migrationProgress, s.Namespace, s.Name)
+	}
+	if s.Annotations == nil {
+		s.Annotations = map[string]string{}
+	}
+	s.Annotations[secrets.EncryptionSecretMigrationProgress] = string(bs)
+	return true, nil
+}
+
+// removeResourceMigrationProgress removes the progress of gr from the migration progress annotation of s.
+func removeResourceMigrationProgress(gr schema.GroupResource, s *corev1.Secret) (bool, error) {
+	existing, found := s.Annotations[secrets.EncryptionSecretMigrationProgress]
+	if !found {
+		return false, nil
+	}
+	migrationProgress := secrets.MigrationProgress{}
+	if err := json.Unmarshal([]byte(existing), &migrationProgress); err != nil {
+		// drop the invalid annotation, the progress is recorded again for every running migration
+		delete(s.Annotations, secrets.EncryptionSecretMigrationProgress)
+		return true, nil
+	}
+
+	resources := migrationProgress.Resources[:0]
+	for _, resourceProgress := range migrationProgress.Resources {
+		if resourceProgress.GroupResource != gr {
+			resources = append(resources, resourceProgress)
+		}
+	}
+	if len(resources) == len(migrationProgress.Resources) {
+		return false, nil
+	}
+	if len(resources) == 0 {
+		delete(s.Annotations, secrets.EncryptionSecretMigrationProgress)
+		return true, nil
+	}
+
+	migrationProgress.Resources = resources
+	bs, err := json.Marshal(migrationProgress)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal %s annotation value %#v for key secret %s/%s", secrets.EncryptionSecretMigrationProgress, migrationProgress, s.Namespace, s.Name)
+	}
+	s.Annotations[secrets.EncryptionSecretMigrationProgress] = string(bs)
+	return true, nil
+}
+
 func setResourceMigrated(gr schema.GroupResource, s *corev1.Secret) (bool, error) {
 	migratedGRs := secrets.MigratedGroupResources{}
 	if existing, found := s.Annotations[secrets.EncryptionSecretMigratedResources]; found {
@@ -274,9 +395,14 @@ func setResourceMigrated(gr schema.GroupResource, s *corev1.Secret) (bool, error
 		}
 	}
 
+	progressRemoved, err := removeResourceMigrationProgress(gr, s)
+	if err != nil {
+		return false, err
+	}
+
 	// update timestamp, if missing or first migration of gr
 	if _, found := s.Annotations[secrets.EncryptionSecretMigratedTimestamp]; found && alreadyMigrated {
-		return false, nil
+		return progressRemoved, nil
 	}
 	if s.Annotations == nil {
 		s.Annotations = map[string]string{}
@@ -306,6 +432,28 @@ func groupToHumanReadable(gr schema.GroupResource) string {
 	return group
 }
 
+// migratingResourcesToHumanReadable returns the human readable migrating resources, with their progress if the
+// migrator reports it.
+func (c *migrationController) migratingResourcesToHumanReadable(grs []schema.GroupResource) []string {
+	ret := grsToHumanReadable(grs)
+	reporter, ok := c.migrator.(migrators.MigrationProgressReporter)
+	if !ok {
+		return ret
+	}
+	for i, gr := range grs {
+		progress, ok := reporter.MigrationProgress(gr)
+		if !ok {
+			continue
+		}
+		if percentage, ok := progress.Percentage(); ok {
+			ret[i] = fmt.Sprintf("%s (%.0f%%)", ret[i], percentage)
+		} else if progress.Migrated > 0 {
+			ret[i] = fmt.Sprintf("%s (%d objects)", ret[i], progress.Migrated)
+		}
+	}
+	return ret
+}
+
 func grsToHumanReadable(grs []schema.GroupResource) []string {
 	ret := make([]string, 0, len(grs))
 	for _, gr := range grs {
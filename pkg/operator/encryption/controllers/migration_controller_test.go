@@ -11,6 +11,7 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -19,12 +20,14 @@ import (
 	"k8s.io/client-go/kubernetes/fake"
 	clientgotesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/pointer"
 
 	operatorv1 "github.com/openshift/api/operator/v1"
 
 	configv1clientfake "github.com/openshift/client-go/config/clientset/versioned/fake"
 	configv1informers "github.com/openshift/client-go/config/informers/externalversions"
 	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/encryption/controllers/migrators"
 	encryptiondeployer "github.com/openshift/library-go/pkg/operator/encryption/deployer"
 	"github.com/openshift/library-go/pkg/operator/encryption/secrets"
 	encryptiontesting "github.com/openshift/library-go/pkg/operator/encryption/testing"
@@ -47,6 +50,7 @@ func TestMigrationController(t *testing.T) {
 		expectedMigratorCalls []string
 		migratorEnsureReplies map[schema.GroupResource]map[string]finishedResultErr
 		migratorPruneReplies  map[schema.GroupResource]error
+		migratorProgress      map[schema.GroupResource]migrators.MigrationProgress
 
 		validateFunc               func(ts *testing.T, actionsKube []clientgotesting.Action, initialSecrets []*corev1.Secret, targetGRs []schema.GroupResource, unstructuredObjs []runtime.Object)
 		validateOperatorClientFunc func(ts *testing.T, operatorClient v1helpers.OperatorClient)
@@ -146,18 +150,29 @@ func TestMigrationController(t *testing.T) {
 				{Group: "", Resource: "secrets"}:    {"1": {finished: false}},
 				{Group: "", Resource: "configmaps"}: {"1": {finished: false}},
 			},
+			migratorProgress: map[schema.GroupResource]migrators.MigrationProgress{
+				{Group: "", Resource: "secrets"}:    {WriteKey: "1", Migrated: 250, RemainingEstimate: pointer.Int64(750)},
+				{Group: "", Resource: "configmaps"}: {WriteKey: "1", Migrated: 10},
+			},
 			expectedActions: []string{
 				"list:pods:kms",
 				"get:secrets:kms",
 				"list:secrets:openshift-config-managed",
 				"list:secrets:openshift-config-managed",
+				"get:secrets:openshift-config-managed",
+				"update:secrets:openshift-config-managed",
+				"get:secrets:openshift-config-managed",
+				"update:secrets:openshift-config-managed",
 			},
 			expectedMigratorCalls: []string{
 				"ensure:configmaps:1",
 				"ensure:secrets:1",
 			},
 			validateFunc: func(ts *testing.T, actionsKube []clientgotesting.Action, initialSecrets []*corev1.Secret, targetGRs []schema.GroupResource, unstructuredObjs []runtime.Object) {
-				validateSecretsWereAnnotated(ts, []schema.GroupResource{}, actionsKube, nil, []*corev1.Secret{initialSecrets[0]})
+				validateMigrationProgress(ts, actionsKube, initialSecrets[0], []secrets.ResourceMigrationProgress{
+					{GroupResource: schema.GroupResource{Resource: "configmaps"}, Migrated: 10},
+					{GroupResource: schema.GroupResource{Resource: "secrets"}, Migrated: 250, RemainingEstimate: pointer.Int64(750), Percentage: pointer.Int64(25)},
+				})
 			},
 			validateOperatorClientFunc: func(ts *testing.T, operatorClient v1helpers.OperatorClient) {
 				expectedConditions := []operatorv1.OperatorCondition{
@@ -168,7 +183,7 @@ func TestMigrationController(t *testing.T) {
 					{
 						Type:    "EncryptionMigrationControllerProgressing",
 						Reason:  "Migrating",
-						Message: "migrating resources to a new write key: [core/configmaps core/secrets]",
+						Message: "migrating resources to a new write key: [core/configmaps (10 objects) core/secrets (25%)]",
 						Status:  "True",
 					},
 				}
@@ -644,6 +659,7 @@ func TestMigrationController(t *testing.T) {
 			migrator := &fakeMigrator{
 				ensureReplies: scenario.migratorEnsureReplies,
 				pruneReplies:  scenario.migratorPruneReplies,
+				progress:      scenario.migratorProgress,
 			}
 			provider := newTestProvider(scenario.targetGRs)
 
@@ -659,6 +675,7 @@ func TestMigrationController(t *testing.T) {
 				kubeInformers,
 				fakeSecretClient,
 				scenario.encryptionSecretSelector,
+				nil,
 				eventRecorder,
 			)
 			err = target.Sync(context.TODO(), factory.NewSyncContext("test", eventRecorder))
@@ -693,6 +710,135 @@ func TestMigrationController(t *testing.T) {
 	}
 }
 
+func TestSetResourceMigrationProgress(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	secretsGR := schema.GroupResource{Resource: "secrets"}
+	configMapsGR := schema.GroupResource{Resource: "configmaps"}
+	progress := migrators.MigrationProgress{WriteKey: "1", Migrated: 250, RemainingEstimate: pointer.Int64(750)}
+	secretsProgress := secrets.ResourceMigrationProgress{GroupResource: secretsGR, Migrated: 250, RemainingEstimate: pointer.Int64(750), Percentage: pointer.Int64(25), LastUpdateTime: metav1.NewTime(now)}
+	configMapsProgress := secrets.ResourceMigrationProgress{GroupResource: configMapsGR, Migrated: 10, LastUpdateTime: metav1.NewTime(now.Add(-time.Hour))}
+
+	tests := []struct {
+		name            string
+		existing        []secrets.ResourceMigrationProgress
+		expectedChanged bool
+		expected        []secrets.ResourceMigrationProgress
+	}{
+		{
+			name:            "first progress",
+			expectedChanged: true,
+			expected:        []secrets.ResourceMigrationProgress{secretsProgress},
+		},
+		{
+			name:            "progress of other resources is kept",
+			existing:        []secrets.ResourceMigrationProgress{configMapsProgress},
+			expectedChanged: true,
+			expected:        []secrets.ResourceMigrationProgress{configMapsProgress, secretsProgress},
+		},
+		{
+			name:            "outdated progress is updated",
+			existing:        []secrets.ResourceMigrationProgress{{GroupResource: secretsGR, Migrated: 100, LastUpdateTime: metav1.NewTime(now.Add(-time.Minute))}},
+			expectedChanged: true,
+			expected:        []secrets.ResourceMigrationProgress{secretsProgress},
+		},
+		{
+			name:     "recent progress is not updated",
+			existing: []secrets.ResourceMigrationProgress{{GroupResource: secretsGR, Migrated: 100, LastUpdateTime: metav1.NewTime(now.Add(-10 * time.Second))}},
+			expected: []secrets.ResourceMigrationProgress{{GroupResource: secretsGR, Migrated: 100, LastUpdateTime: metav1.NewTime(now.Add(-10 * time.Second))}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-config-managed", Name: "encryption-key-kms-1"}}
+			if tt.existing != nil {
+				bs, err := json.Marshal(secrets.MigrationProgress{Resources: tt.existing})
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.Annotations = map[string]string{secrets.EncryptionSecretMigrationProgress: string(bs)}
+			}
+
+			changed, err := setResourceMigrationProgress(secretsGR, progress, s, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != tt.expectedChanged {
+				t.Errorf("expected changed %v, got %v", tt.expectedChanged, changed)
+			}
+			actual := secrets.MigrationProgress{}
+			if err := json.Unmarshal([]byte(s.Annotations[secrets.EncryptionSecretMigrationProgress]), &actual); err != nil {
+				t.Fatal(err)
+			}
+			if !equality.Semantic.DeepEqual(actual.Resources, tt.expected) {
+				t.Errorf("expected migration progress %#v, got %#v", tt.expected, actual.Resources)
+			}
+		})
+	}
+}
+
+func TestSetResourceMigratedRemovesMigrationProgress(t *testing.T) {
+	secretsGR := schema.GroupResource{Resource: "secrets"}
+	configMapsGR := schema.GroupResource{Resource: "configmaps"}
+	s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-config-managed", Name: "encryption-key-kms-1"}}
+	for _, gr := range []schema.GroupResource{secretsGR, configMapsGR} {
+		if _, err := setResourceMigrationProgress(gr, migrators.MigrationProgress{WriteKey: "1", Migrated: 10}, s, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if changed, err := setResourceMigrated(secretsGR, s); err != nil || !changed {
+		t.Fatalf("expected the secret to change, got %v: %v", changed, err)
+	}
+	migrationProgress := secrets.MigrationProgress{}
+	if err := json.Unmarshal([]byte(s.Annotations[secrets.EncryptionSecretMigrationProgress]), &migrationProgress); err != nil {
+		t.Fatal(err)
+	}
+	if len(migrationProgress.Resources) != 1 || migrationProgress.Resources[0].GroupResource != configMapsGR {
+		t.Errorf("expected only the progress of %s, got %#v", configMapsGR, migrationProgress.Resources)
+	}
+
+	if changed, err := setResourceMigrated(configMapsGR, s); err != nil || !changed {
+		t.Fatalf("expected the secret to change, got %v: %v", changed, err)
+	}
+	if v, found := s.Annotations[secrets.EncryptionSecretMigrationProgress]; found {
+		t.Errorf("expected no %s annotation after all resources migrated, got %q", secrets.EncryptionSecretMigrationProgress, v)
+	}
+}
+
+// validateMigrationProgress checks the migration progress annotation of the last update of the given secret, ignoring
+// the update times.
+func validateMigrationProgress(ts *testing.T, actions []clientgotesting.Action, secret *corev1.Secret, expected []secrets.ResourceMigrationProgress) {
+	ts.Helper()
+
+	var lastSeen *corev1.Secret
+	for _, action := range actions {
+		if !action.Matches("update", "secrets") {
+			continue
+		}
+		actualSecret := action.(clientgotesting.UpdateAction).GetObject().(*corev1.Secret)
+		if actualSecret.Namespace == secret.Namespace && actualSecret.Name == secret.Name {
+			lastSeen = actualSecret
+		}
+	}
+	if lastSeen == nil {
+		ts.Fatalf("missing update on %s/%s", secret.Namespace, secret.Name)
+	}
+
+	migrationProgress := secrets.MigrationProgress{}
+	if err := json.Unmarshal([]byte(lastSeen.Annotations[secrets.EncryptionSecretMigrationProgress]), &migrationProgress); err != nil {
+		ts.Fatalf("failed to unmarshal %s annotation of secret %s/%s: %v", secrets.EncryptionSecretMigrationProgress, secret.Namespace, secret.Name, err)
+	}
+	for i := range migrationProgress.Resources {
+		if migrationProgress.Resources[i].LastUpdateTime.IsZero() {
+			ts.Errorf("missing update time of the progress of %s", migrationProgress.Resources[i].GroupResource)
+		}
+		migrationProgress.Resources[i].LastUpdateTime = metav1.Time{}
+	}
+	if !reflect.DeepEqual(migrationProgress.Resources, expected) {
+		ts.Errorf("expected migration progress %#v, got %#v", expected, migrationProgress.Resources)
+	}
+}
+
 func validateSecretsWereAnnotated(ts *testing.T, grs []schema.GroupResource, actions []clientgotesting.Action, expectedSecrets []*corev1.Secret, notExpectedSecrets []*corev1.Secret) {
 	ts.Helper()
 
@@ -753,6 +899,7 @@ type fakeMigrator struct {
 	calls         []string
 	ensureReplies map[schema.GroupResource]map[string]finishedResultErr
 	pruneReplies  map[schema.GroupResource]error
+	progress      map[schema.GroupResource]migrators.MigrationProgress
 }
 
 func (m *fakeMigrator) AddEventHandler(handler cache.ResourceEventHandler) (cache.ResourceEventHandlerRegistration, error) {
@@ -773,3 +920,8 @@ func (m *fakeMigrator) PruneMigration(gr schema.GroupResource) error {
 	m.calls = append(m.calls, fmt.Sprintf("prune:%s", gr))
 	return m.pruneReplies[gr]
 }
+
+func (m *fakeMigrator) MigrationProgress(gr schema.GroupResource) (migrators.MigrationProgress, bool) {
+	progress, ok := m.progress[gr]
+	return progress, ok
+}
@@ -25,10 +25,18 @@ func NewInProcessMigrator(dynamicClient dynamic.Interface, discoveryClient disco
 	}
 }
 
+// WithProgressStore makes the migrator persist the progress of running migrations in the given store, such that
+// migrations to the same write key resume from the last migrated page after a restart.
+func (m *InProcessMigrator) WithProgressStore(store MigrationProgressStore) *InProcessMigrator {
+	m.progressStore = store
+	return m
+}
+
 // InProcessMigrator runs migration in-process using paging.
 type InProcessMigrator struct {
 	dynamicClient   dynamic.Interface
 	discoveryClient discovery.ServerResourcesInterface
+	progressStore   MigrationProgressStore
 
 	lock    sync.Mutex
 	running map[schema.GroupResource]*inProcessMigration
@@ -44,6 +52,7 @@ type inProcessMigration struct {
 	stopCh   chan<- struct{}
 	doneCh   <-chan struct{}
 	writeKey string
+	progress MigrationProgress
 
 	// non-nil when finished. *result==nil means "no error"
 	result *error
@@ -52,6 +61,7 @@ type inProcessMigration struct {
 }
 
 var _ Migrator = &InProcessMigrator{}
+var _ MigrationProgressReporter = &InProcessMigrator{}
 
 func (m *InProcessMigrator) EnsureMigration(gr schema.GroupResource, writeKey string) (finished bool, result error, ts time.Time, err error) {
 	m.lock.Lock()
@@ -82,20 +92,33 @@ func (m *InProcessMigrator) EnsureMigration(gr schema.GroupResource, writeKey st
 		return false, nil, time.Time{}, err
 	}
 
+	progress := MigrationProgress{WriteKey: writeKey}
+	if m.progressStore != nil {
+		stored, err := m.progressStore.Get(context.TODO(), gr)
+		if err != nil {
+			return false, nil, time.Time{}, err
+		}
+		if stored != nil && stored.WriteKey == writeKey {
+			klog.V(2).Infof("Resuming migration for resource %v and write key %q after %d migrated objects", gr, writeKey, stored.Migrated)
+			progress = *stored
+		}
+	}
+
 	stopCh := make(chan struct{})
 	doneCh := make(chan struct{})
 	m.running[gr] = &inProcessMigration{
 		stopCh:   stopCh,
 		doneCh:   doneCh,
 		writeKey: writeKey,
+		progress: progress,
 	}
 
-	go m.runMigration(gr.WithVersion(v), writeKey, stopCh, doneCh)
+	go m.runMigration(gr.WithVersion(v), writeKey, progress.Continue, stopCh, doneCh)
 
 	return false, nil, time.Time{}, nil
 }
 
-func (m *InProcessMigrator) runMigration(gvr schema.GroupVersionResource, writeKey string, stopCh <-chan struct{}, doneCh chan<- struct{}) {
+func (m *InProcessMigrator) runMigration(gvr schema.GroupVersionResource, writeKey string, continueToken string, stopCh <-chan struct{}, doneCh chan<- struct{}) {
 	var result error
 
 	defer close(doneCh)
@@ -118,6 +141,16 @@ func (m *InProcessMigrator) runMigration(gvr schema.GroupVersionResource, writeK
 
 		migration.result = &result
 		migration.timestamp = time.Now()
+		metrics.ClearMigrationProgress(gvr.String())
+
+		// a finished migration starts over when it is retried, and so does a resumed migration that failed before
+		// migrating a page, e.g. because the stored continue token is invalid.
+		resumeFailed := result != nil && len(continueToken) > 0 && migration.progress.Continue == continueToken
+		if (result == nil || resumeFailed) && m.progressStore != nil {
+			if err := m.progressStore.Delete(context.TODO(), gvr.GroupResource()); err != nil {
+				klog.Warningf("Failed to delete the migration progress of %v: %v", gvr, err)
+			}
+		}
 
 		m.handler.OnAdd(&corev1.Secret{}, false) // fake secret to trigger event loop of controller
 	}()
@@ -147,9 +180,76 @@ func (m *InProcessMigrator) runMigration(gvr schema.GroupVersionResource, writeK
 			}
 		}
 	})
+	listProcessor.continueToken = continueToken
+	listProcessor.pageFn = func(processed int, continueToken string, remaining *int64) {
+		m.observeProgress(ctx, gvr, writeKey, processed, continueToken, remaining)
+	}
+	listProcessor.restartFn = func() {
+		m.resetProgress(ctx, gvr, writeKey)
+	}
 	result = listProcessor.run(ctx, gvr)
 }
 
+// observeProgress updates the progress of a running migration after a processed page.
+func (m *InProcessMigrator) observeProgress(ctx context.Context, gvr schema.GroupVersionResource, writeKey string, processed int, continueToken string, remaining *int64) {
+	if len(continueToken) == 0 {
+		// the last page has been processed
+		remaining = new(int64)
+	}
+
+	m.lock.Lock()
+	migration := m.running[gvr.GroupResource()]
+	if migration == nil || migration.writeKey != writeKey {
+		m.lock.Unlock()
+		return
+	}
+	migration.progress.Migrated += int64(processed)
+	migration.progress.Continue = continueToken
+	migration.progress.RemainingEstimate = remaining
+	progress := migration.progress
+	m.lock.Unlock()
+
+	if percentage, ok := progress.Percentage(); ok {
+		metrics.ObserveMigrationProgress(percentage, gvr.String())
+	}
+	if m.progressStore != nil {
+		if err := m.progressStore.Set(ctx, gvr.GroupResource(), progress); err != nil {
+			klog.Warningf("Failed to store the migration progress of %v: %v", gvr, err)
+		}
+	}
+}
+
+// resetProgress resets the progress of a running migration that starts over because its continue token expired.
+func (m *InProcessMigrator) resetProgress(ctx context.Context, gvr schema.GroupVersionResource, writeKey string) {
+	m.lock.Lock()
+	migration := m.running[gvr.GroupResource()]
+	if migration == nil || migration.writeKey != writeKey {
+		m.lock.Unlock()
+		return
+	}
+	migration.progress = MigrationProgress{WriteKey: writeKey}
+	m.lock.Unlock()
+
+	metrics.ClearMigrationProgress(gvr.String())
+	if m.progressStore != nil {
+		if err := m.progressStore.Delete(ctx, gvr.GroupResource()); err != nil {
+			klog.Warningf("Failed to delete the migration progress of %v: %v", gvr, err)
+		}
+	}
+}
+
+// MigrationProgress returns the progress of the running migration of the given resource.
+func (m *InProcessMigrator) MigrationProgress(gr schema.GroupResource) (MigrationProgress, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	migration := m.running[gr]
+	if migration == nil || migration.result != nil {
+		return MigrationProgress{}, false
+	}
+	return migration.progress, true
+}
+
 func (m *InProcessMigrator) PruneMigration(gr schema.GroupResource) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
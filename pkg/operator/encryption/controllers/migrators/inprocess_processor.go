@@ -26,6 +26,11 @@ const (
 // workerFunc function that is executed by workers to process a single item
 type workerFunc func(*unstructured.Unstructured) error
 
+// pageFunc function that is executed after all items of a page have been processed. continueToken is the continue
+// token of the next page, empty after the last page. remaining is the estimated number of items not processed yet,
+// nil if unknown.
+type pageFunc func(processed int, continueToken string, remaining *int64)
+
 // listProcessor represents a type that processes resources in parallel.
 // It retrieves resources from the server in batches and distributes among set of workers.
 type listProcessor struct {
@@ -33,6 +38,13 @@ type listProcessor struct {
 	workerFn      workerFunc
 	dynamicClient dynamic.Interface
 	ctx           context.Context
+
+	// continueToken is the continue token of the first page. If empty, processing starts with the first item.
+	continueToken string
+	// pageFn is called after every processed page if set.
+	pageFn pageFunc
+	// restartFn is called if set when processing starts over with the first item because continueToken expired.
+	restartFn func()
 }
 
 // newListProcessor creates a new instance of listProcessor
@@ -54,9 +66,20 @@ func (p *listProcessor) run(ctx context.Context, gvr schema.GroupVersionResource
 			if err != nil {
 				klog.Warningf("List of %v failed: %v", gvr, err)
 				if errors.IsResourceExpired(err) {
-					token, err := inconsistentContinueToken(err)
-					if err != nil {
-						return nil, err
+					token, tokenErr := inconsistentContinueToken(err)
+					if tokenErr != nil && len(p.continueToken) > 0 && opts.Continue == p.continueToken {
+						// Continue tokens expire with the etcd compaction, by default after 5 minutes. If processing was
+						// interrupted for longer and the server does not return a token to continue inconsistently
+						// with, the only way to process all items is to start over.
+						klog.Warningf("Continue token to resume processing %v with expired, starting over", gvr)
+						opts.Continue = ""
+						if p.restartFn != nil {
+							p.restartFn()
+						}
+						continue
+					}
+					if tokenErr != nil {
+						return nil, tokenErr
 					}
 					opts.Continue = token
 					klog.V(2).Infof("Relisting %v after handling expired token", gvr)
@@ -80,6 +103,10 @@ func (p *listProcessor) run(ctx context.Context, gvr schema.GroupVersionResource
 			}
 			klog.V(2).Infof("Migration of %d objects of %v finished in %v", len(allResource.Items), gvr, time.Now().Sub(migrationStarted))
 
+			if p.pageFn != nil {
+				p.pageFn(len(allResource.Items), allResource.GetContinue(), allResource.GetRemainingItemCount())
+			}
+
 			allResource.Items = nil // do not accumulate items, this fakes the visitor pattern
 			return allResource, nil // leave the rest of the list intact to preserve continue token
 		}
@@ -87,7 +114,7 @@ func (p *listProcessor) run(ctx context.Context, gvr schema.GroupVersionResource
 	listPager.FullListIfExpired = false // prevent memory explosion from full list
 
 	migrationStarted := time.Now()
-	if _, _, err := listPager.List(p.ctx, metav1.ListOptions{}); err != nil {
+	if _, _, err := listPager.List(p.ctx, metav1.ListOptions{Continue: p.continueToken}); err != nil {
 		metrics.ObserveFailedMigration(gvr.String())
 		return err
 	}
@@ -24,6 +24,7 @@ type migratorMetrics struct {
 	objectsMigrated   *k8smetrics.CounterVec
 	migration         *k8smetrics.CounterVec
 	migrationDuration *k8smetrics.HistogramVec
+	migrationProgress *k8smetrics.GaugeVec
 }
 
 // newMigratorMetrics create a new MigratorMetrics, configured with default metric names.
@@ -60,16 +61,29 @@ func newMigratorMetrics(registerFunc func(k8smetrics.Registerable) error) *migra
 		}, []string{"resource"})
 	registerFunc(migrationDuration)
 
+	// migrationProgress is not defined upstream but uses the same Namespace and Subsystem
+	// as the other metrics that are defined in kube-storave-version-migrator
+	migrationProgress := k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "migration_progress_percent",
+			Help:      "The estimated percentage of migrated objects of running migrations, labeled with the full resource name",
+		}, []string{"resource"})
+	registerFunc(migrationProgress)
+
 	return &migratorMetrics{
 		objectsMigrated:   objectsMigrated,
 		migration:         migration,
 		migrationDuration: migrationDuration,
+		migrationProgress: migrationProgress,
 	}
 }
 
 func (m *migratorMetrics) Reset() {
 	m.objectsMigrated.Reset()
 	m.migration.Reset()
+	m.migrationProgress.Reset()
 }
 
 // ObserveObjectsMigrated adds the number of migrated objects for a resource type
@@ -91,3 +105,13 @@ func (m *migratorMetrics) ObserveFailedMigration(resource string) {
 func (m *migratorMetrics) ObserveSucceededMigrationDuration(seconds float64, resource string) {
 	m.migrationDuration.WithLabelValues(resource).Observe(seconds)
 }
+
+// ObserveMigrationProgress records the estimated percentage of migrated objects for a resource type
+func (m *migratorMetrics) ObserveMigrationProgress(percent float64, resource string) {
+	m.migrationProgress.WithLabelValues(resource).Set(percent)
+}
+
+// ClearMigrationProgress removes the progress of a finished migration for a resource type
+func (m *migratorMetrics) ClearMigrationProgress(resource string) {
+	m.migrationProgress.DeleteLabelValues(resource)
+}
@@ -0,0 +1,183 @@
+package migrators
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/clock"
+)
+
+// MigrationProgress is the progress of the migration of a resource to a write key.
+type MigrationProgress struct {
+	// WriteKey is the write key the resource is migrated to.
+	WriteKey string `json:"writeKey"`
+	// Migrated is the number of objects migrated so far.
+	Migrated int64 `json:"migrated"`
+	// RemainingEstimate is the estimated number of objects not migrated yet. It is nil if the server does not
+	// provide an estimate.
+	RemainingEstimate *int64 `json:"remainingEstimate,omitempty"`
+	// Continue is the continue token of the next page to migrate. Continue tokens expire with the etcd compaction, by
+	// default after 5 minutes. A migration resumed with an expired token continues with the inconsistent continue token
+	// returned by the server, or starts over if there is none. Objects missed by an inconsistent list were written
+	// after the migration started, i.e. with the write key already.
+	Continue string `json:"continue,omitempty"`
+}
+
+// Percentage returns the estimated percentage of migrated objects, or false if there is no estimate yet.
+func (p MigrationProgress) Percentage() (float64, bool) {
+	if p.RemainingEstimate == nil {
+		return 0, false
+	}
+	total := p.Migrated + *p.RemainingEstimate
+	if total == 0 {
+		return 100, true
+	}
+	return 100 * float64(p.Migrated) / float64(total), true
+}
+
+// MigrationProgressReporter is implemented by migrators which report the progress of running migrations.
+type MigrationProgressReporter interface {
+	// MigrationProgress returns the progress of the running migration of the given resource, or false if there is
+	// no running migration.
+	MigrationProgress(gr schema.GroupResource) (MigrationProgress, bool)
+}
+
+// MigrationProgressStore persists the progress of migrations, such that they resume from the last migrated page
+// after a restart.
+type MigrationProgressStore interface {
+	// Get returns the stored progress of the given resource, or nil if there is none.
+	Get(ctx context.Context, gr schema.GroupResource) (*MigrationProgress, error)
+	// Set stores the progress of the given resource.
+	Set(ctx context.Context, gr schema.GroupResource, progress MigrationProgress) error
+	// Delete removes the progress of the given resource. If there is none, this must not return an error.
+	Delete(ctx context.Context, gr schema.GroupResource) error
+}
+
+// progressStoreInterval is the minimum interval between two writes of the progress of a resource. A migration
+// resumed from an older page migrates the objects of the pages in between again, which is cheaper than a config
+// map write per page.
+const progressStoreInterval = 30 * time.Second
+
+// configMapProgressStore stores the progress of every resource as JSON in a key of a config map.
+type configMapProgressStore struct {
+	client    corev1client.ConfigMapsGetter
+	namespace string
+	name      string
+	clock     clock.PassiveClock
+
+	lock   sync.Mutex
+	stored map[schema.GroupResource]storedProgress
+}
+
+// storedProgress is the last progress of a resource written to the config map.
+type storedProgress struct {
+	writeKey  string
+	timestamp time.Time
+}
+
+// NewConfigMapProgressStore returns a MigrationProgressStore storing the progress in the given config map. The config
+// map is created when the first progress is stored. The progress of a resource is written at most every 30 seconds,
+// unless the migration started or finished since the last write.
+func NewConfigMapProgressStore(client corev1client.ConfigMapsGetter, namespace, name string) MigrationProgressStore {
+	return &configMapProgressStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		clock:     clock.RealClock{},
+		stored:    map[schema.GroupResource]storedProgress{},
+	}
+}
+
+func (s *configMapProgressStore) Get(ctx context.Context, gr schema.GroupResource) (*MigrationProgress, error) {
+	cm, err := s.client.ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	raw, ok := cm.Data[gr.String()]
+	if !ok {
+		return nil, nil
+	}
+	progress := &MigrationProgress{}
+	if err := json.Unmarshal([]byte(raw), progress); err != nil {
+		return nil, fmt.Errorf("invalid migration progress of %s in config map %s/%s: %v", gr, s.namespace, s.name, err)
+	}
+	return progress, nil
+}
+
+func (s *configMapProgressStore) Set(ctx context.Context, gr schema.GroupResource, progress MigrationProgress) error {
+	now := s.clock.Now()
+	s.lock.Lock()
+	last, found := s.stored[gr]
+	s.lock.Unlock()
+	finished := len(progress.Continue) == 0
+	if found && last.writeKey == progress.WriteKey && !finished && now.Sub(last.timestamp) < progressStoreInterval {
+		return nil
+	}
+
+	raw, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	if err := s.update(ctx, func(data map[string]string) {
+		data[gr.String()] = string(raw)
+	}); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stored[gr] = storedProgress{writeKey: progress.WriteKey, timestamp: now}
+	return nil
+}
+
+func (s *configMapProgressStore) Delete(ctx context.Context, gr schema.GroupResource) error {
+	s.lock.Lock()
+	delete(s.stored, gr)
+	s.lock.Unlock()
+
+	return s.update(ctx, func(data map[string]string) {
+		delete(data, gr.String())
+	})
+}
+
+func (s *configMapProgressStore) update(ctx context.Context, updateFn func(data map[string]string)) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		cm, err := s.client.ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: s.namespace, Name: s.name},
+				Data:       map[string]string{},
+			}
+			updateFn(cm.Data)
+			_, err = s.client.ConfigMaps(s.namespace).Create(ctx, cm, metav1.CreateOptions{})
+			if errors.IsAlreadyExists(err) {
+				// retry with the existing config map
+				return errors.NewConflict(corev1.Resource("configmaps"), s.name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		cm = cm.DeepCopy()
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		updateFn(cm.Data)
+		_, err = s.client.ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
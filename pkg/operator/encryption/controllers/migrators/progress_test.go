@@ -0,0 +1,320 @@
+package migrators
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+)
+
+func TestMigrationProgressPercentage(t *testing.T) {
+	tests := []struct {
+		name            string
+		progress        MigrationProgress
+		expected        float64
+		expectedUnknown bool
+	}{
+		{name: "no estimate", progress: MigrationProgress{Migrated: 10}, expectedUnknown: true},
+		{name: "half", progress: MigrationProgress{Migrated: 10, RemainingEstimate: pointer.Int64(10)}, expected: 50},
+		{name: "nothing to migrate", progress: MigrationProgress{RemainingEstimate: pointer.Int64(0)}, expected: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			percentage, ok := tt.progress.Percentage()
+			if ok == tt.expectedUnknown || percentage != tt.expected {
+				t.Errorf("expected %v (unknown %v), got %v (known %v)", tt.expected, tt.expectedUnknown, percentage, ok)
+			}
+		})
+	}
+}
+
+func TestConfigMapProgressStore(t *testing.T) {
+	ctx := context.TODO()
+	store := NewConfigMapProgressStore(fake.NewSimpleClientset().CoreV1(), "ns", "migration-progress")
+	secrets := schema.GroupResource{Resource: "secrets"}
+	routes := schema.GroupResource{Group: "route.openshift.io", Resource: "routes"}
+
+	if progress, err := store.Get(ctx, secrets); err != nil || progress != nil {
+		t.Fatalf("expected no progress, got %v: %v", progress, err)
+	}
+	secretsProgress := MigrationProgress{WriteKey: "2", Migrated: 500, RemainingEstimate: pointer.Int64(100), Continue: "token"}
+	if err := store.Set(ctx, secrets, secretsProgress); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, routes, MigrationProgress{WriteKey: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if progress, err := store.Get(ctx, secrets); err != nil || !reflect.DeepEqual(progress, &secretsProgress) {
+		t.Fatalf("expected %v, got %v: %v", secretsProgress, progress, err)
+	}
+
+	if err := store.Delete(ctx, secrets); err != nil {
+		t.Fatal(err)
+	}
+	if progress, err := store.Get(ctx, secrets); err != nil || progress != nil {
+		t.Fatalf("expected no progress after delete, got %v: %v", progress, err)
+	}
+	if progress, err := store.Get(ctx, routes); err != nil || progress == nil {
+		t.Fatalf("expected the routes progress to be kept, got %v: %v", progress, err)
+	}
+}
+
+func TestConfigMapProgressStoreThrottlesWrites(t *testing.T) {
+	ctx := context.TODO()
+	fakeClock := clocktesting.NewFakePassiveClock(time.Now())
+	store := NewConfigMapProgressStore(fake.NewSimpleClientset().CoreV1(), "ns", "migration-progress").(*configMapProgressStore)
+	store.clock = fakeClock
+	secrets := schema.GroupResource{Resource: "secrets"}
+
+	steps := []struct {
+		name     string
+		elapsed  time.Duration
+		progress MigrationProgress
+		expected MigrationProgress
+	}{
+		{
+			name:     "first page is stored",
+			progress: MigrationProgress{WriteKey: "1", Migrated: 500, Continue: "500"},
+			expected: MigrationProgress{WriteKey: "1", Migrated: 500, Continue: "500"},
+		},
+		{
+			name:     "next page within the interval is not stored",
+			elapsed:  10 * time.Second,
+			progress: MigrationProgress{WriteKey: "1", Migrated: 1000, Continue: "1000"},
+			expected: MigrationProgress{WriteKey: "1", Migrated: 500, Continue: "500"},
+		},
+		{
+			name:     "page after the interval is stored",
+			elapsed:  30 * time.Second,
+			progress: MigrationProgress{WriteKey: "1", Migrated: 1500, Continue: "1500"},
+			expected: MigrationProgress{WriteKey: "1", Migrated: 1500, Continue: "1500"},
+		},
+		{
+			name:     "last page is stored within the interval",
+			elapsed:  time.Second,
+			progress: MigrationProgress{WriteKey: "1", Migrated: 1800},
+			expected: MigrationProgress{WriteKey: "1", Migrated: 1800},
+		},
+		{
+			name:     "first page of another write key is stored within the interval",
+			elapsed:  time.Second,
+			progress: MigrationProgress{WriteKey: "2", Migrated: 500, Continue: "500"},
+			expected: MigrationProgress{WriteKey: "2", Migrated: 500, Continue: "500"},
+		},
+	}
+	for _, step := range steps {
+		fakeClock.SetTime(fakeClock.Now().Add(step.elapsed))
+		if err := store.Set(ctx, secrets, step.progress); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if progress, err := store.Get(ctx, secrets); err != nil || !reflect.DeepEqual(progress, &step.expected) {
+			t.Fatalf("%s: expected %v, got %v: %v", step.name, step.expected, progress, err)
+		}
+	}
+
+	if err := store.Delete(ctx, secrets); err != nil {
+		t.Fatal(err)
+	}
+	progress := MigrationProgress{WriteKey: "2", Migrated: 500, Continue: "500"}
+	if err := store.Set(ctx, secrets, progress); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := store.Get(ctx, secrets); err != nil || !reflect.DeepEqual(stored, &progress) {
+		t.Fatalf("expected the first page after delete to be stored, got %v: %v", stored, err)
+	}
+}
+
+// pagingDynamicClient serves lists in pages of opts.Limit items with the offset as continue token, which the fake
+// dynamic client does not support.
+type pagingDynamicClient struct {
+	dynamic.Interface
+	// expiredContinueTokens are answered with an expired error, carrying the mapped inconsistent continue token if
+	// not empty.
+	expiredContinueTokens map[string]string
+
+	lock           sync.Mutex
+	continueTokens []string
+}
+
+func (c *pagingDynamicClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &pagingResourceClient{NamespaceableResourceInterface: c.Interface.Resource(gvr), client: c}
+}
+
+type pagingResourceClient struct {
+	dynamic.NamespaceableResourceInterface
+	client *pagingDynamicClient
+}
+
+func (r *pagingResourceClient) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	r.client.lock.Lock()
+	r.client.continueTokens = append(r.client.continueTokens, opts.Continue)
+	r.client.lock.Unlock()
+
+	if inconsistentToken, expired := r.client.expiredContinueTokens[opts.Continue]; expired {
+		return nil, &apierrors.StatusError{ErrStatus: metav1.Status{
+			Status:   metav1.StatusFailure,
+			Code:     http.StatusGone,
+			Reason:   metav1.StatusReasonExpired,
+			Message:  "The provided continue parameter is too old to display a consistent list result.",
+			ListMeta: metav1.ListMeta{Continue: inconsistentToken},
+		}}
+	}
+
+	all, err := r.NamespaceableResourceInterface.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(all.Items, func(i, j int) bool { return all.Items[i].GetName() < all.Items[j].GetName() })
+
+	start := 0
+	if len(opts.Continue) > 0 {
+		if start, err = strconv.Atoi(opts.Continue); err != nil {
+			return nil, err
+		}
+	}
+	total := len(all.Items)
+	end := total
+	if opts.Limit > 0 && start+int(opts.Limit) < end {
+		end = start + int(opts.Limit)
+	}
+	all.Items = all.Items[start:end]
+	if end < total {
+		all.SetContinue(strconv.Itoa(end))
+		all.SetRemainingItemCount(pointer.Int64(int64(total - end)))
+	}
+	return all, nil
+}
+
+func TestInProcessMigratorResume(t *testing.T) {
+	secretsGR := schema.GroupResource{Resource: "secrets"}
+	apiResources := []metav1.APIResource{{Name: "secrets", Namespaced: true, Version: "v1"}}
+
+	tests := []struct {
+		name                   string
+		storedProgress         *MigrationProgress
+		expiredContinueTokens  map[string]string
+		expectedContinueTokens []string
+		expectedUpdates        int
+	}{
+		{
+			name:                   "no stored progress",
+			expectedContinueTokens: []string{"", "500", "1000"},
+			expectedUpdates:        1200,
+		},
+		{
+			name:                   "resumes from the stored continue token",
+			storedProgress:         &MigrationProgress{WriteKey: "1", Migrated: 500, RemainingEstimate: pointer.Int64(700), Continue: "500"},
+			expectedContinueTokens: []string{"500", "1000"},
+			expectedUpdates:        700,
+		},
+		{
+			name:                   "continues with the inconsistent continue token if the stored continue token expired",
+			storedProgress:         &MigrationProgress{WriteKey: "1", Migrated: 500, RemainingEstimate: pointer.Int64(700), Continue: "expired"},
+			expiredContinueTokens:  map[string]string{"expired": "500"},
+			expectedContinueTokens: []string{"expired", "500", "1000"},
+			expectedUpdates:        700,
+		},
+		{
+			name:                   "starts over if the stored continue token expired without an inconsistent continue token",
+			storedProgress:         &MigrationProgress{WriteKey: "1", Migrated: 500, RemainingEstimate: pointer.Int64(700), Continue: "expired"},
+			expiredContinueTokens:  map[string]string{"expired": ""},
+			expectedContinueTokens: []string{"expired", "", "500", "1000"},
+			expectedUpdates:        1200,
+		},
+		{
+			name:                   "ignores the progress of another write key",
+			storedProgress:         &MigrationProgress{WriteKey: "0", Migrated: 500, Continue: "500"},
+			expectedContinueTokens: []string{"", "500", "1000"},
+			expectedUpdates:        1200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.TODO()
+			fakeKubeClient := fake.NewSimpleClientset()
+			store := NewConfigMapProgressStore(fakeKubeClient.CoreV1(), "ns", "migration-progress")
+			if tt.storedProgress != nil {
+				if err := store.Set(ctx, secretsGR, *tt.storedProgress); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			objs := []runtime.Object{}
+			for _, secret := range createSecrets(1200) {
+				raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(secret)
+				if err != nil {
+					t.Fatal(err)
+				}
+				u := &unstructured.Unstructured{Object: raw}
+				u.SetAPIVersion("v1")
+				u.SetKind("Secret")
+				u.SetName(fmt.Sprintf("secret%04d", len(objs))) // sortable names
+				objs = append(objs, u)
+			}
+			fakeDynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+				secretsGR.WithVersion("v1"): "SecretList",
+			}, objs...)
+			dynamicClient := &pagingDynamicClient{Interface: fakeDynamicClient, expiredContinueTokens: tt.expiredContinueTokens}
+			discoveryClient := &fakeDisco{
+				delegate:           fakeKubeClient.Discovery(),
+				serverPreferredRes: []*metav1.APIResourceList{{APIResources: apiResources}},
+			}
+
+			m := NewInProcessMigrator(dynamicClient, discoveryClient).WithProgressStore(store)
+			m.AddEventHandler(&fakeHandler{})
+
+			err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+				finished, result, _, err := m.EnsureMigration(secretsGR, "1")
+				if err != nil {
+					return false, err
+				}
+				if result != nil {
+					return false, result
+				}
+				return finished, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(dynamicClient.continueTokens, tt.expectedContinueTokens) {
+				t.Errorf("expected lists with continue tokens %q, got %q", tt.expectedContinueTokens, dynamicClient.continueTokens)
+			}
+			updates := 0
+			for _, action := range fakeDynamicClient.Actions() {
+				if action.GetVerb() == "update" {
+					updates++
+				}
+			}
+			if updates != tt.expectedUpdates {
+				t.Errorf("expected %d updates, got %d", tt.expectedUpdates, updates)
+			}
+			if progress, err := store.Get(ctx, secretsGR); err != nil || progress != nil {
+				t.Errorf("expected the progress to be deleted after the migration finished, got %v: %v", progress, err)
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(""), "storage_migrator_core_migrator_migration_progress_percent"); err != nil {
+				t.Errorf("expected the progress metric to be cleared after the migration finished: %v", err)
+			}
+		})
+	}
+}
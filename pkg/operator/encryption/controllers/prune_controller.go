@@ -44,6 +44,7 @@ type pruneController struct {
 	provider                 Provider
 	preconditionsFulfilledFn preconditionsFulfilled
 	secretClient             corev1client.SecretsGetter
+	keyWrappers              *secrets.KeyWrappers
 	name                     string
 }
 
@@ -56,6 +57,7 @@ func NewPruneController(
 	kubeInformersForNamespaces operatorv1helpers.KubeInformersForNamespaces,
 	secretClient corev1client.SecretsGetter,
 	encryptionSecretSelector metav1.ListOptions,
+	keyWrappers *secrets.KeyWrappers,
 	eventRecorder events.Recorder,
 ) factory.Controller {
 	c := &pruneController{
@@ -66,6 +68,7 @@ func NewPruneController(
 		provider:                 provider,
 		preconditionsFulfilledFn: preconditionsFulfilledFn,
 		secretClient:             secretClient,
+		keyWrappers:              keyWrappers,
 	}
 
 	return factory.New().ResyncEvery(time.Minute).WithSync(c.sync).WithInformers(
@@ -104,7 +107,7 @@ func (c *pruneController) sync(ctx context.Context, syncCtx factory.SyncContext)
 }
 
 func (c *pruneController) deleteOldMigratedSecrets(ctx context.Context, syncContext factory.SyncContext, encryptedGRs []schema.GroupResource) error {
-	_, desiredEncryptionConfig, _, isProgressingReason, err := statemachine.GetEncryptionConfigAndState(ctx, c.deployer, c.secretClient, c.encryptionSecretSelector, encryptedGRs)
+	_, desiredEncryptionConfig, _, isProgressingReason, err := statemachine.GetEncryptionConfigAndState(ctx, c.deployer, c.secretClient, c.encryptionSecretSelector, encryptedGRs, c.keyWrappers)
 	if err != nil {
 		return err
 	}
@@ -139,7 +142,7 @@ func (c *pruneController) deleteOldMigratedSecrets(ctx context.Context, syncCont
 	deletedKeys := 0
 NextEncryptionSecret:
 	for _, s := range encryptionSecrets {
-		k, err := secrets.ToKeyState(s)
+		k, err := secrets.ToKeyState(ctx, s, c.keyWrappers)
 		if err == nil {
 			// ignore invalid keys, check whether secret is used
 			for _, us := range allUsedKeys {
@@ -140,7 +140,7 @@ func TestPruneController(t *testing.T) {
 
 			initialKeys := []state.KeyState{}
 			for _, s := range scenario.initialSecrets {
-				km, err := secrets.ToKeyState(s)
+				km, err := secrets.ToKeyState(context.TODO(), s, nil)
 				if err != nil {
 					t.Fatal(err)
 				}
@@ -193,6 +193,7 @@ func TestPruneController(t *testing.T) {
 				kubeInformers,
 				fakeSecretClient,
 				scenario.encryptionSecretSelector,
+				nil,
 				eventRecorder,
 			)
 
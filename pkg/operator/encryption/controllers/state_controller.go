@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
 	apiserverconfigv1 "k8s.io/apiserver/pkg/apis/config/v1"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/util/workqueue"
@@ -16,6 +18,7 @@ import (
 	configv1informers "github.com/openshift/client-go/config/informers/externalversions/config/v1"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/encryption/encryptionconfig"
+	"github.com/openshift/library-go/pkg/operator/encryption/secrets"
 	"github.com/openshift/library-go/pkg/operator/encryption/state"
 	"github.com/openshift/library-go/pkg/operator/encryption/statemachine"
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -33,6 +36,9 @@ const stateWorkKey = "key"
 // that matches encryptionSecretSelector is included in this final secret.
 // This secret is synced into targetNamespace at a static location.  This
 // indirection allows the cluster to recover from the deletion of targetNamespace.
+// When keyWrappers wrap the keys, the secret is written directly into
+// targetNamespace instead, so that it does not expose the unwrapped keys in
+// openshift-config-managed (see encryptionconfig.SecretLocation).
 // See getResourceConfigs for details on how the raw state of all keys
 // is converted into a single encryption config.  The logic for determining
 // the current write key is of special interest.
@@ -46,6 +52,7 @@ type stateController struct {
 	deployer                 statemachine.Deployer
 	provider                 Provider
 	preconditionsFulfilledFn preconditionsFulfilled
+	keyWrappers              *secrets.KeyWrappers
 }
 
 func NewStateController(
@@ -58,6 +65,7 @@ func NewStateController(
 	kubeInformersForNamespaces operatorv1helpers.KubeInformersForNamespaces,
 	secretClient corev1client.SecretsGetter,
 	encryptionSecretSelector metav1.ListOptions,
+	keyWrappers *secrets.KeyWrappers,
 	eventRecorder events.Recorder,
 ) factory.Controller {
 	c := &stateController{
@@ -71,6 +79,7 @@ func NewStateController(
 		deployer:                 deployer,
 		provider:                 provider,
 		preconditionsFulfilledFn: preconditionsFulfilledFn,
+		keyWrappers:              keyWrappers,
 	}
 
 	return factory.New().ResyncEvery(time.Minute).WithSync(c.sync).WithInformers(
@@ -115,7 +124,7 @@ type eventWithReason struct {
 }
 
 func (c *stateController) generateAndApplyCurrentEncryptionConfigSecret(ctx context.Context, queue workqueue.RateLimitingInterface, recorder events.Recorder, encryptedGRs []schema.GroupResource) error {
-	currentConfig, desiredEncryptionState, encryptionSecrets, transitioningReason, err := statemachine.GetEncryptionConfigAndState(ctx, c.deployer, c.secretClient, c.encryptionSecretSelector, encryptedGRs)
+	currentConfig, desiredEncryptionState, encryptionSecrets, transitioningReason, err := statemachine.GetEncryptionConfigAndState(ctx, c.deployer, c.secretClient, c.encryptionSecretSelector, encryptedGRs, c.keyWrappers)
 	if err != nil {
 		return err
 	}
@@ -138,7 +147,7 @@ func (c *stateController) generateAndApplyCurrentEncryptionConfigSecret(ctx cont
 	}
 
 	if changed {
-		currentEncryptionConfig, _ := encryptionconfig.ToEncryptionState(currentConfig, encryptionSecrets)
+		currentEncryptionConfig, _ := encryptionconfig.ToEncryptionState(ctx, currentConfig, encryptionSecrets, c.keyWrappers)
 		if actionEvents := eventsFromEncryptionConfigChanges(currentEncryptionConfig, desiredEncryptionState); len(actionEvents) > 0 {
 			for _, event := range actionEvents {
 				recorder.Eventf(event.reason, event.message)
@@ -149,13 +158,45 @@ func (c *stateController) generateAndApplyCurrentEncryptionConfigSecret(ctx cont
 }
 
 func (c *stateController) applyEncryptionConfigSecret(ctx context.Context, encryptionConfig *apiserverconfigv1.EncryptionConfiguration, recorder events.Recorder) (bool, error) {
-	s, err := encryptionconfig.ToSecret("openshift-config-managed", fmt.Sprintf("%s-%s", encryptionconfig.EncryptionConfSecretName, c.component), encryptionConfig)
+	namespace, name := encryptionconfig.SecretLocation(c.component, c.keyWrappers)
+	s, err := encryptionconfig.ToSecret(namespace, name, encryptionConfig)
 	if err != nil {
 		return false, err
 	}
 
 	_, changed, applyErr := resourceapply.ApplySecret(ctx, c.secretClient, recorder, s)
-	return changed, applyErr
+	if applyErr != nil || !c.keyWrappers.Enabled() {
+		return changed, applyErr
+	}
+	// the encryption configuration written before key wrapping was enabled still exposes the keys
+	return changed, c.deleteManagedEncryptionConfigSecret(ctx, recorder)
+}
+
+// deleteManagedEncryptionConfigSecret removes the encryption configuration from openshift-config-managed, dropping
+// the finalizer that protects it from accidental deletion first.
+func (c *stateController) deleteManagedEncryptionConfigSecret(ctx context.Context, recorder events.Recorder) error {
+	name := fmt.Sprintf("%s-%s", encryptionconfig.EncryptionConfSecretName, c.component)
+	secret, err := c.secretClient.Secrets("openshift-config-managed").Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if finalizers := sets.NewString(secret.Finalizers...); finalizers.Has(secrets.EncryptionSecretFinalizer) {
+		secret = secret.DeepCopy()
+		delete(finalizers, secrets.EncryptionSecretFinalizer)
+		secret.Finalizers = finalizers.List()
+		if _, err := c.secretClient.Secrets("openshift-config-managed").Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	if err := c.secretClient.Secrets("openshift-config-managed").Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	recorder.Eventf("EncryptionConfigRelocated", "Removed secret openshift-config-managed/%s because encryption keys are wrapped, the encryption config is written to %s/%s", name, c.component, encryptionconfig.EncryptionConfSecretName)
+	return nil
 }
 
 // eventsFromEncryptionConfigChanges return slice of event reasons with messages corresponding to a difference between current and desired encryption state.
@@ -24,6 +24,7 @@ import (
 	"github.com/openshift/library-go/pkg/controller/factory"
 	encryptiondeployer "github.com/openshift/library-go/pkg/operator/encryption/deployer"
 	"github.com/openshift/library-go/pkg/operator/encryption/encryptionconfig"
+	"github.com/openshift/library-go/pkg/operator/encryption/secrets"
 	"github.com/openshift/library-go/pkg/operator/encryption/state"
 	encryptiontesting "github.com/openshift/library-go/pkg/operator/encryption/testing"
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -38,6 +39,7 @@ func TestStateController(t *testing.T) {
 		encryptionSecretSelector metav1.ListOptions
 		targetNamespace          string
 		targetGRs                []schema.GroupResource
+		keyWrappers              *secrets.KeyWrappers
 		// expectedActions holds actions to be verified in the form of "verb:resource:namespace"
 		expectedActions            []string
 		expectedEncryptionCfg      *apiserverconfigv1.EncryptionConfiguration
@@ -706,6 +708,66 @@ func TestStateController(t *testing.T) {
 				encryptiontesting.ValidateOperatorClientConditions(ts, operatorClient, []operatorv1.OperatorCondition{expectedCondition})
 			},
 		},
+
+		// scenario 12
+		{
+			name:                     "secret with EncryptionConfig is written into the target namespace with wrapped keys",
+			targetNamespace:          "kms",
+			encryptionSecretSelector: metav1.ListOptions{LabelSelector: "encryption.apiserver.operator.openshift.io/component=kms"},
+			targetGRs: []schema.GroupResource{
+				{Group: "", Resource: "secrets"},
+			},
+			keyWrappers: secrets.NewKeyWrappers(encryptiontesting.FakeKeyWrapper{WrapperName: "fake"}),
+			initialResources: []runtime.Object{
+				encryptiontesting.CreateDummyKubeAPIPod("kube-apiserver-1", "kms", "node-1"),
+				encryptiontesting.CreateEncryptionKeySecretWithRawKey("kms", []schema.GroupResource{{Group: "", Resource: "secrets"}}, 1, []byte("61def964fb967f5d7c44a2af8dab6865")),
+				func() *corev1.Secret { // the encryption config written before the keys were wrapped
+					ecs := createEncryptionCfgSecret(t, "openshift-config-managed", "kms", encryptiontesting.CreateEncryptionCfgNoWriteKey("1", "NjFkZWY5NjRmYjk2N2Y1ZDdjNDRhMmFmOGRhYjY4NjU=", "secrets"))
+					return ecs
+				}(),
+			},
+			expectedActions: []string{
+				"list:pods:kms",
+				"get:secrets:kms",
+				"list:secrets:openshift-config-managed",
+				"get:secrets:kms",
+				"create:secrets:kms",
+				"create:events:kms",
+				"get:secrets:openshift-config-managed",
+				"update:secrets:openshift-config-managed",
+				"delete:secrets:openshift-config-managed",
+				"create:events:kms",
+				"create:events:kms",
+			},
+			expectedEncryptionCfg: encryptiontesting.CreateEncryptionCfgNoWriteKey("1", "NjFkZWY5NjRmYjk2N2Y1ZDdjNDRhMmFmOGRhYjY4NjU=", "secrets"),
+			validateFunc: func(ts *testing.T, actions []clientgotesting.Action, _ string, expectedEncryptionCfg *apiserverconfigv1.EncryptionConfiguration) {
+				wasSecretValidated := false
+				for _, action := range actions {
+					if action.Matches("create", "secrets") {
+						actualSecret := action.(clientgotesting.CreateAction).GetObject().(*corev1.Secret)
+						if actualSecret.Namespace != "kms" || actualSecret.Name != encryptionconfig.EncryptionConfSecretName {
+							ts.Fatalf("expected the encryption config to be written to kms/%s, got %s/%s", encryptionconfig.EncryptionConfSecretName, actualSecret.Namespace, actualSecret.Name)
+						}
+						actualEncryptionCfg, err := encryptionconfig.FromSecret(actualSecret)
+						if err != nil {
+							ts.Fatal(err)
+						}
+						if !equality.Semantic.DeepEqual(expectedEncryptionCfg, actualEncryptionCfg) {
+							ts.Fatalf("unexpected encryption config: %s", diff.ObjectDiff(expectedEncryptionCfg, actualEncryptionCfg))
+						}
+						wasSecretValidated = true
+					}
+					if action.Matches("update", "secrets") {
+						if finalizers := action.(clientgotesting.UpdateAction).GetObject().(*corev1.Secret).Finalizers; len(finalizers) > 0 {
+							ts.Errorf("expected the finalizer to be removed before the deletion, got %v", finalizers)
+						}
+					}
+				}
+				if !wasSecretValidated {
+					ts.Errorf("the secret wasn't created and validated")
+				}
+			},
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -764,6 +826,7 @@ func TestStateController(t *testing.T) {
 				kubeInformers,
 				fakeSecretClient,
 				scenario.encryptionSecretSelector,
+				scenario.keyWrappers,
 				eventRecorder,
 			)
 
@@ -0,0 +1,36 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apiserver/pkg/storage/value/encrypt/envelope/kmsv2"
+)
+
+// kmsStatusTimeout is the timeout of the status call to a KMS plugin.
+const kmsStatusTimeout = 10 * time.Second
+
+// KMSKeyID returns the key ID reported by the KMS v2 plugin listening on the given endpoint. It fails if the plugin
+// is not healthy. The plugin socket is local to the control-plane host, so this is meant for components running next
+// to the plugin that report the key ID to the operator, see controllers.KMSConfigProvider.
+func KMSKeyID(ctx context.Context, endpoint string) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // closes the connection
+
+	service, err := kmsv2.NewGRPCService(ctx, endpoint, "encryption-key-controller", kmsStatusTimeout)
+	if err != nil {
+		return "", err
+	}
+	status, err := service.Status(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the status of KMS plugin %s: %v", endpoint, err)
+	}
+	if status.Healthz != "ok" {
+		return "", fmt.Errorf("KMS plugin %s is unhealthy: %s", endpoint, status.Healthz)
+	}
+	if len(status.KeyID) == 0 {
+		return "", fmt.Errorf("KMS plugin %s reported an empty key ID", endpoint)
+	}
+	return status.KeyID, nil
+}
@@ -0,0 +1,24 @@
+package crypto_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/encryption/crypto"
+	encryptiontesting "github.com/openshift/library-go/pkg/operator/encryption/testing"
+)
+
+func TestKMSKeyID(t *testing.T) {
+	plugin := encryptiontesting.NewFakeKMSPlugin(t, "1")
+
+	for _, expected := range []string{"1", "2"} {
+		plugin.SetKeyID(expected)
+		keyID, err := crypto.KMSKeyID(context.TODO(), plugin.Endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keyID != expected {
+			t.Errorf("expected key ID %q, got %q", expected, keyID)
+		}
+	}
+}
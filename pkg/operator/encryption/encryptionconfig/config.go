@@ -1,8 +1,11 @@
 package encryptionconfig
 
 import (
+	"context"
 	"encoding/base64"
+	"fmt"
 	"sort"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -25,7 +28,7 @@ func FromEncryptionState(encryptionState map[schema.GroupResource]state.GroupRes
 	for gr, grKeys := range encryptionState {
 		resourceConfigs = append(resourceConfigs, apiserverconfigv1.ResourceConfiguration{
 			Resources: []string{gr.String()}, // we are forced to lose data here because this API is broken
-			Providers: stateToProviders(grKeys),
+			Providers: stateToProviders(gr, grKeys),
 		})
 	}
 
@@ -48,10 +51,12 @@ func FromEncryptionState(encryptionState map[schema.GroupResource]state.GroupRes
 //   - each resource has a distinct configuration with zero or more key based providers and the identity provider.
 //   - the last providers might be of type aesgcm. Then it carries the names of identity keys, recent first.
 //     We never use aesgcm as a real key because it is unsafe.
-func ToEncryptionState(encryptionConfig *apiserverconfigv1.EncryptionConfiguration, keySecrets []*corev1.Secret) (map[schema.GroupResource]state.GroupResourceState, []state.KeyState) {
+//
+// Wrapped key secrets are unwrapped with keyWrappers, which can be nil if key secrets are not wrapped.
+func ToEncryptionState(ctx context.Context, encryptionConfig *apiserverconfigv1.EncryptionConfiguration, keySecrets []*corev1.Secret, keyWrappers *secrets.KeyWrappers) (map[schema.GroupResource]state.GroupResourceState, []state.KeyState) {
 	backedKeys := make([]state.KeyState, 0, len(keySecrets))
 	for _, s := range keySecrets {
-		km, err := secrets.ToKeyState(s)
+		km, err := secrets.ToKeyState(ctx, s, keyWrappers)
 		if err != nil {
 			klog.Warningf("skipping invalid secret: %v", err)
 			continue
@@ -91,6 +96,15 @@ func ToEncryptionState(encryptionConfig *apiserverconfigv1.EncryptionConfigurati
 					Mode: state.SecretBox,
 				}
 
+			case provider.KMS != nil && provider.KMS.APIVersion == "v2":
+				ks = state.KeyState{
+					Key: apiserverconfigv1.Key{
+						Name:   kmsKeyName(provider.KMS.Name),
+						Secret: state.KMSKeyConfig{Endpoint: provider.KMS.Endpoint}.ToKeySecret(),
+					},
+					Mode: state.KMS,
+				}
+
 			case provider.Identity != nil:
 				// skip fake provider. If this is write-key, wait for first aesgcm provider providing the write key.
 				continue
@@ -139,7 +153,7 @@ func ToEncryptionState(encryptionConfig *apiserverconfigv1.EncryptionConfigurati
 // it primarily handles the conversion of KeyState to the appropriate provider config.
 // the identity mode is transformed into a custom aesgcm provider that simply exists to
 // curry the associated null key secret through the encryption state machine.
-func stateToProviders(desired state.GroupResourceState) []apiserverconfigv1.ProviderConfiguration {
+func stateToProviders(gr schema.GroupResource, desired state.GroupResourceState) []apiserverconfigv1.ProviderConfiguration {
 	allKeys := desired.ReadKeys
 
 	providers := make([]apiserverconfigv1.ProviderConfiguration, 0, len(allKeys)+1) // one extra for identity
@@ -181,6 +195,20 @@ func stateToProviders(desired state.GroupResourceState) []apiserverconfigv1.Prov
 					Keys: []apiserverconfigv1.Key{key.Key},
 				},
 			})
+		case state.KMS:
+			kmsConfig, err := state.KMSKeyConfigFromKeySecret(key.Key.Secret)
+			if err != nil {
+				// this should never happen because ToKeyState validates the configuration
+				klog.Infof("skipping key %s as it has invalid KMS configuration: %v", key.Key.Name, err)
+				continue
+			}
+			providers = append(providers, apiserverconfigv1.ProviderConfiguration{
+				KMS: &apiserverconfigv1.KMSConfiguration{
+					APIVersion: "v2",
+					Name:       kmsProviderName(key.Key.Name, gr),
+					Endpoint:   kmsConfig.Endpoint,
+				},
+			})
 		case state.Identity:
 			if i == 0 {
 				providers = append(providers, apiserverconfigv1.ProviderConfiguration{
@@ -210,3 +238,17 @@ func stateToProviders(desired state.GroupResourceState) []apiserverconfigv1.Prov
 
 	return providers
 }
+
+// kmsProviderName returns the name of the KMS provider of the given key. The API server requires KMS v2 provider names
+// to be unique across all resources, hence the name of the group resource is appended to the key ID.
+func kmsProviderName(keyName string, gr schema.GroupResource) string {
+	return fmt.Sprintf("%s_%s", keyName, gr.String())
+}
+
+// kmsKeyName returns the key ID of a KMS provider name.
+func kmsKeyName(providerName string) string {
+	if i := strings.Index(providerName, "_"); i >= 0 {
+		return providerName[:i]
+	}
+	return providerName
+}
@@ -1,6 +1,7 @@
 package encryptionconfig
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"testing"
@@ -365,7 +366,7 @@ func TestToEncryptionState(t *testing.T) {
 
 	for _, scenario := range scenarios {
 		t.Run(scenario.name, func(t *testing.T) {
-			actualOutput, _ := ToEncryptionState(scenario.input, nil)
+			actualOutput, _ := ToEncryptionState(context.TODO(), scenario.input, nil, nil)
 
 			if len(actualOutput) != len(scenario.output) {
 				t.Fatalf("expected to get %d GR, got %d", len(scenario.output), len(actualOutput))
@@ -522,6 +523,35 @@ func TestFromEncryptionState(t *testing.T) {
 
 		// scenario 6
 		// TODO: encryption on after being off
+
+		// scenario 7
+		{
+			name:       "kms write key and aes-cbc read key get a provider per resource",
+			grs:        []schema.GroupResource{{Group: "", Resource: "secrets"}, {Group: "route.openshift.io", Resource: "routes"}},
+			targetNs:   "kms",
+			writeKeyIn: encryptiontesting.CreateEncryptionKeySecretWithKMSConfig("kms", nil, 3, "unix:///var/run/kms/plugin.sock", "1"),
+			readKeysIn: []*corev1.Secret{
+				encryptiontesting.CreateEncryptionKeySecretWithRawKey("kms", nil, 2, []byte("558bf68d6d8ab5dd819eec02901766c1")),
+			},
+			makeOutput: func(writeKey *corev1.Secret, readKeys []*corev1.Secret) []apiserverconfigv1.ResourceConfiguration {
+				rr := apiserverconfigv1.ResourceConfiguration{}
+				rr.Resources = []string{"routes.route.openshift.io"}
+				rr.Providers = []apiserverconfigv1.ProviderConfiguration{
+					{KMS: &apiserverconfigv1.KMSConfiguration{APIVersion: "v2", Name: "3_routes.route.openshift.io", Endpoint: "unix:///var/run/kms/plugin.sock"}},
+					{AESCBC: keyToAESConfiguration(readKeys[0])},
+					{Identity: &apiserverconfigv1.IdentityConfiguration{}},
+				}
+
+				rs := apiserverconfigv1.ResourceConfiguration{}
+				rs.Resources = []string{"secrets"}
+				rs.Providers = []apiserverconfigv1.ProviderConfiguration{
+					{KMS: &apiserverconfigv1.KMSConfiguration{APIVersion: "v2", Name: "3_secrets", Endpoint: "unix:///var/run/kms/plugin.sock"}},
+					{AESCBC: keyToAESConfiguration(readKeys[0])},
+					{Identity: &apiserverconfigv1.IdentityConfiguration{}},
+				}
+				return []apiserverconfigv1.ResourceConfiguration{rr, rs}
+			},
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -529,7 +559,7 @@ func TestFromEncryptionState(t *testing.T) {
 
 			readKeyStatesIn := make([]state.KeyState, 0, len(scenario.readKeysIn))
 			for _, s := range scenario.readKeysIn {
-				ks, err := secrets.ToKeyState(s)
+				ks, err := secrets.ToKeyState(context.TODO(), s, nil)
 				if err != nil {
 					t.Fatal(err)
 				}
@@ -539,7 +569,7 @@ func TestFromEncryptionState(t *testing.T) {
 			var writeKeyStateIn state.KeyState
 			if scenario.writeKeyIn != nil {
 				var err error
-				writeKeyStateIn, err = secrets.ToKeyState(scenario.writeKeyIn)
+				writeKeyStateIn, err = secrets.ToKeyState(context.TODO(), scenario.writeKeyIn, nil)
 				if err != nil {
 					t.Fatal(err)
 				}
@@ -563,6 +593,30 @@ func TestFromEncryptionState(t *testing.T) {
 	}
 }
 
+func TestKMSRoundtrip(t *testing.T) {
+	gr := schema.GroupResource{Group: "", Resource: "secrets"}
+	keySecrets := []*corev1.Secret{
+		encryptiontesting.CreateEncryptionKeySecretWithKMSConfig("kms", nil, 2, "unix:///var/run/kms/plugin.sock", "key-2"),
+		encryptiontesting.CreateEncryptionKeySecretWithKMSConfig("kms", []schema.GroupResource{gr}, 1, "unix:///var/run/kms/plugin.sock", "key-1"),
+	}
+	keys := []state.KeyState{}
+	for _, s := range keySecrets {
+		ks, err := secrets.ToKeyState(context.TODO(), s, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, ks)
+	}
+
+	desired := map[schema.GroupResource]state.GroupResourceState{gr: {WriteKey: keys[0], ReadKeys: keys}}
+	actual, _ := ToEncryptionState(context.TODO(), FromEncryptionState(desired), keySecrets, nil)
+
+	// the observed KMS key IDs are restored from the key secrets
+	if !cmp.Equal(desired, actual) {
+		t.Fatal(cmp.Diff(desired, actual))
+	}
+}
+
 func keyToAESConfiguration(key *corev1.Secret) *apiserverconfigv1.AESConfiguration {
 	id, ok := state.NameToKeyID(key.Name)
 	if !ok {
@@ -10,6 +10,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	apiserverconfigv1 "k8s.io/apiserver/pkg/apis/config/v1"
 
+	"github.com/openshift/library-go/pkg/operator/encryption/secrets"
 	"github.com/openshift/library-go/pkg/operator/encryption/state"
 )
 
@@ -25,6 +26,17 @@ func init() {
 // EncryptionConfSecretName is the name of the final encryption config secret that is revisioned per apiserver rollout.
 const EncryptionConfSecretName = "encryption-config"
 
+// SecretLocation returns the namespace and name of the secret the encryption configuration of component is written to.
+// It is written to openshift-config-managed and synced into the component namespace from there, unless keyWrappers
+// wrap new keys. The encryption configuration holds the unwrapped keys, so it is then written directly into the
+// component namespace, to not expose the keys in openshift-config-managed.
+func SecretLocation(component string, keyWrappers *secrets.KeyWrappers) (namespace, name string) {
+	if keyWrappers.Enabled() {
+		return component, EncryptionConfSecretName
+	}
+	return "openshift-config-managed", fmt.Sprintf("%s-%s", EncryptionConfSecretName, component)
+}
+
 // EncryptionConfSecretKey is the map data key used to store the raw bytes of the final encryption config.
 const EncryptionConfSecretKey = "encryption-config"
 
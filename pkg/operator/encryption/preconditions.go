@@ -1,10 +1,10 @@
 package encryption
 
 import (
-	"fmt"
-
 	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/library-go/pkg/operator/encryption/controllers"
 	"github.com/openshift/library-go/pkg/operator/encryption/encryptionconfig"
+	"github.com/openshift/library-go/pkg/operator/encryption/secrets"
 	"github.com/openshift/library-go/pkg/operator/encryption/state"
 	operatorv1helpers "github.com/openshift/library-go/pkg/operator/v1helpers"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -16,22 +16,29 @@ type preconditionChecker struct {
 	component                string
 	encryptionSecretSelector labels.Selector
 
-	secretLister          corev1listers.SecretNamespaceLister
-	apiServerConfigLister configv1listers.APIServerLister
+	secretLister           corev1listers.SecretNamespaceLister
+	encryptionConfigLister corev1listers.SecretNamespaceLister
+	encryptionConfigName   string
+	apiServerConfigLister  configv1listers.APIServerLister
+	kmsConfigProvider      controllers.KMSConfigProvider
 }
 
 // newEncryptionEnabledPrecondition determines if encryption controllers should synchronise.
 // It uses the cache for gathering data to avoid sending requests to the API servers.
-func newEncryptionEnabledPrecondition(apiServerConfigLister configv1listers.APIServerLister, kubeInformersForNamespaces operatorv1helpers.KubeInformersForNamespaces, encryptionSecretSelectorString, component string) (*preconditionChecker, error) {
+func newEncryptionEnabledPrecondition(apiServerConfigLister configv1listers.APIServerLister, kubeInformersForNamespaces operatorv1helpers.KubeInformersForNamespaces, encryptionSecretSelectorString, component string, keyWrappers *secrets.KeyWrappers, kmsConfigProvider controllers.KMSConfigProvider) (*preconditionChecker, error) {
 	encryptionSecretSelector, err := labels.Parse(encryptionSecretSelectorString)
 	if err != nil {
 		return nil, err
 	}
+	encryptionConfigNamespace, encryptionConfigName := encryptionconfig.SecretLocation(component, keyWrappers)
 	return &preconditionChecker{
 		component:                component,
 		encryptionSecretSelector: encryptionSecretSelector,
 		secretLister:             kubeInformersForNamespaces.SecretLister().Secrets("openshift-config-managed"),
+		encryptionConfigLister:   kubeInformersForNamespaces.SecretLister().Secrets(encryptionConfigNamespace),
+		encryptionConfigName:     encryptionConfigName,
 		apiServerConfigLister:    apiServerConfigLister,
+		kmsConfigProvider:        kmsConfigProvider,
 	}, nil
 }
 
@@ -59,7 +66,8 @@ func (pc *preconditionChecker) PreconditionFulfilled() (bool, error) {
 //
 //	a server configuration doesn't exist
 //	the current encryption mode is empty or set to identity mode and
-//	a secret with encryption configuration doesn't exist in the managed namespace and
+//	KMS encryption is not enabled by the KMS config provider and
+//	a secret with encryption configuration doesn't exist in the managed namespace, or in the component namespace with wrapped keys, and
 //	secrets with encryption keys don't exist in the managed namespace
 func (pc *preconditionChecker) encryptionWasEnabled() (bool, error) {
 	apiServerConfig, err := pc.apiServerConfigLister.Get("cluster")
@@ -72,8 +80,17 @@ func (pc *preconditionChecker) encryptionWasEnabled() (bool, error) {
 	if currentMode := state.Mode(apiServerConfig.Spec.Encryption.Type); len(currentMode) > 0 && currentMode != state.Identity {
 		return true, nil // encryption might be actually in progress
 	}
+	if pc.kmsConfigProvider != nil {
+		kmsConfig, err := pc.kmsConfigProvider.KMSKeyConfig()
+		if err != nil {
+			return false, err
+		}
+		if kmsConfig != nil {
+			return true, nil
+		}
+	}
 
-	encryptionConfiguration, err := pc.secretLister.Get(fmt.Sprintf("%s-%s", encryptionconfig.EncryptionConfSecretName, pc.component))
+	encryptionConfiguration, err := pc.encryptionConfigLister.Get(pc.encryptionConfigName)
 	if err != nil && !errors.IsNotFound(err) {
 		return false, err // unknown error
 	}
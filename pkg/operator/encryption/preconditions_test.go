@@ -16,6 +16,7 @@ import (
 	configlistersv1 "github.com/openshift/client-go/config/listers/config/v1"
 	"github.com/openshift/library-go/pkg/operator/encryption/encryptionconfig"
 	"github.com/openshift/library-go/pkg/operator/encryption/secrets"
+	"github.com/openshift/library-go/pkg/operator/encryption/state"
 	encryptiontesting "github.com/openshift/library-go/pkg/operator/encryption/testing"
 )
 
@@ -30,6 +31,8 @@ func TestEncryptionEnabledPrecondition(t *testing.T) {
 		name                           string
 		encryptionType                 configv1.EncryptionType
 		existingSecret                 runtime.Object
+		kmsConfig                      *state.KMSKeyConfig
+		keyWrappers                    *secrets.KeyWrappers
 		expectedPreconditionsToBeReady bool
 		expectError                    bool
 	}{
@@ -53,6 +56,13 @@ func TestEncryptionEnabledPrecondition(t *testing.T) {
 		},
 
 		// scenario 4
+		{
+			name:                           "encryption on, kms enabled by the KMS config provider",
+			kmsConfig:                      &state.KMSKeyConfig{Endpoint: "unix:///var/run/kmsplugin/kms.sock", KeyID: "1"},
+			expectedPreconditionsToBeReady: true,
+		},
+
+		// scenario 5
 		{
 			name:                           "encryption off on previously enabled cluster, with existing encryption key secret",
 			encryptionType:                 configv1.EncryptionTypeIdentity,
@@ -60,7 +70,7 @@ func TestEncryptionEnabledPrecondition(t *testing.T) {
 			expectedPreconditionsToBeReady: true,
 		},
 
-		// scenario 5
+		// scenario 6
 		{
 			name:           "encryption off on previously enabled cluster, with existing encryption configuration secret",
 			encryptionType: configv1.EncryptionTypeIdentity,
@@ -72,6 +82,33 @@ func TestEncryptionEnabledPrecondition(t *testing.T) {
 			},
 			expectedPreconditionsToBeReady: true,
 		},
+
+		// scenario 7
+		{
+			name:           "encryption off on previously enabled cluster with wrapped keys, with existing encryption configuration secret in the component namespace",
+			encryptionType: configv1.EncryptionTypeIdentity,
+			existingSecret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      encryptionconfig.EncryptionConfSecretName,
+					Namespace: component,
+				},
+			},
+			keyWrappers:                    secrets.NewKeyWrappers(encryptiontesting.FakeKeyWrapper{WrapperName: "fake"}),
+			expectedPreconditionsToBeReady: true,
+		},
+
+		// scenario 8
+		{
+			name:           "encryption off with wrapped keys, with existing encryption configuration secret in the managed namespace",
+			encryptionType: configv1.EncryptionTypeIdentity,
+			existingSecret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("%s-%s", encryptionconfig.EncryptionConfSecretName, component),
+					Namespace: "openshift-config-managed",
+				},
+			},
+			keyWrappers: secrets.NewKeyWrappers(encryptiontesting.FakeKeyWrapper{WrapperName: "fake"}),
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -86,9 +123,19 @@ func TestEncryptionEnabledPrecondition(t *testing.T) {
 				secretsIndexer.Add(scenario.existingSecret)
 			}
 			namespacedSecretLister := corev1listers.NewSecretLister(secretsIndexer).Secrets("openshift-config-managed")
+			encryptionConfigNamespace, encryptionConfigName := encryptionconfig.SecretLocation(component, scenario.keyWrappers)
+			encryptionConfigLister := corev1listers.NewSecretLister(secretsIndexer).Secrets(encryptionConfigNamespace)
 
 			// act
-			target := &preconditionChecker{component: component, encryptionSecretSelector: encryptionSecretSelector, secretLister: namespacedSecretLister, apiServerConfigLister: apiServerConfigLister}
+			target := &preconditionChecker{
+				component:                component,
+				encryptionSecretSelector: encryptionSecretSelector,
+				secretLister:             namespacedSecretLister,
+				encryptionConfigLister:   encryptionConfigLister,
+				encryptionConfigName:     encryptionConfigName,
+				apiServerConfigLister:    apiServerConfigLister,
+				kmsConfigProvider:        encryptiontesting.FakeKMSConfigProvider{Config: scenario.kmsConfig},
+			}
 			preconditionsReady, err := target.PreconditionFulfilled()
 
 			// validate
@@ -0,0 +1,179 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/apiserver/pkg/storage/value/encrypt/envelope/kmsv2"
+	kmsservice "k8s.io/kms/pkg/service"
+)
+
+// KeyWrapper encrypts the key material of key secrets with a key-encryption-key, so that reading a key secret
+// does not expose the encryption key. The encryption configuration consumed by the API server holds the unwrapped
+// keys, so while keys are wrapped it is kept out of openshift-config-managed as well.
+type KeyWrapper interface {
+	// Name identifies the key-encryption-key. It is stored in the key secrets wrapped by this wrapper.
+	Name() string
+	// Wrap encrypts the given key.
+	Wrap(ctx context.Context, key []byte) ([]byte, error)
+	// Unwrap decrypts a key returned by Wrap.
+	Unwrap(ctx context.Context, wrappedKey []byte) ([]byte, error)
+}
+
+// KeyWrappers wraps the keys of new key secrets with the Active wrapper and unwraps them with the Active or any of the
+// Previous wrappers. Previous wrappers are needed to read key secrets written before the key-encryption-key was
+// rotated. A nil KeyWrappers or Active wrapper stores new keys in plain.
+type KeyWrappers struct {
+	Active   KeyWrapper
+	Previous []KeyWrapper
+}
+
+// NewKeyWrappers returns KeyWrappers wrapping new keys with active and unwrapping keys with active or previous.
+func NewKeyWrappers(active KeyWrapper, previous ...KeyWrapper) *KeyWrappers {
+	return &KeyWrappers{Active: active, Previous: previous}
+}
+
+// Enabled returns true if new keys are wrapped. The encryption configuration, which holds the unwrapped keys, is then
+// no longer written to openshift-config-managed, see encryptionconfig.SecretLocation.
+func (w *KeyWrappers) Enabled() bool {
+	return w.active() != nil
+}
+
+func (w *KeyWrappers) active() KeyWrapper {
+	if w == nil {
+		return nil
+	}
+	return w.Active
+}
+
+func (w *KeyWrappers) get(name string) (KeyWrapper, bool) {
+	if w == nil {
+		return nil, false
+	}
+	for _, wrapper := range append([]KeyWrapper{w.Active}, w.Previous...) {
+		if wrapper != nil && wrapper.Name() == name {
+			return wrapper, true
+		}
+	}
+	return nil, false
+}
+
+// fileKeyWrapper wraps keys with AES-GCM using a key-encryption-key read from a file.
+type fileKeyWrapper struct {
+	name string
+	aead cipher.AEAD
+}
+
+// NewFileKeyWrapper returns a KeyWrapper using the 32 byte AES key-encryption-key in the given file, either raw or
+// base64 encoded. The name of the wrapper is derived from the hash of the key-encryption-key.
+func NewFileKeyWrapper(path string) (KeyWrapper, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kek := bs
+	if len(kek) != 32 {
+		kek, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(bs)))
+		if err != nil || len(kek) != 32 {
+			return nil, fmt.Errorf("key-encryption-key in %s must be 32 bytes, raw or base64 encoded", path)
+		}
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(kek)
+	return &fileKeyWrapper{
+		name: "file:" + hex.EncodeToString(hash[:8]),
+		aead: aead,
+	}, nil
+}
+
+func (w *fileKeyWrapper) Name() string {
+	return w.name
+}
+
+func (w *fileKeyWrapper) Wrap(_ context.Context, key []byte) ([]byte, error) {
+	nonce := make([]byte, w.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return w.aead.Seal(nonce, nonce, key, nil), nil
+}
+
+func (w *fileKeyWrapper) Unwrap(_ context.Context, wrappedKey []byte) ([]byte, error) {
+	nonceSize := w.aead.NonceSize()
+	if len(wrappedKey) < nonceSize {
+		return nil, fmt.Errorf("wrapped key is too short")
+	}
+	return w.aead.Open(nil, wrappedKey[:nonceSize], wrappedKey[nonceSize:], nil)
+}
+
+// kmsKeyWrapper wraps keys with a KMS v2 plugin.
+type kmsKeyWrapper struct {
+	name    string
+	service kmsservice.Service
+}
+
+// kmsWrappedKey is the data stored in a key secret wrapped by a KMS v2 plugin.
+type kmsWrappedKey struct {
+	Ciphertext  []byte            `json:"ciphertext"`
+	KeyID       string            `json:"keyID"`
+	Annotations map[string][]byte `json:"annotations,omitempty"`
+}
+
+// NewKMSKeyWrapper returns a KeyWrapper using the KMS v2 plugin listening on the given endpoint,
+// e.g. unix:///var/run/kms/plugin.sock. The connection is closed when ctx is done.
+func NewKMSKeyWrapper(ctx context.Context, providerName, endpoint string, callTimeout time.Duration) (KeyWrapper, error) {
+	service, err := kmsv2.NewGRPCService(ctx, endpoint, providerName, callTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &kmsKeyWrapper{
+		name:    "kms:" + providerName,
+		service: service,
+	}, nil
+}
+
+func (w *kmsKeyWrapper) Name() string {
+	return w.name
+}
+
+func (w *kmsKeyWrapper) Wrap(ctx context.Context, key []byte) ([]byte, error) {
+	resp, err := w.service.Encrypt(ctx, "", key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap key with KMS provider %s: %v", w.name, err)
+	}
+	return json.Marshal(kmsWrappedKey{Ciphertext: resp.Ciphertext, KeyID: resp.KeyID, Annotations: resp.Annotations})
+}
+
+func (w *kmsKeyWrapper) Unwrap(ctx context.Context, wrappedKey []byte) ([]byte, error) {
+	wrapped := kmsWrappedKey{}
+	if err := json.Unmarshal(wrappedKey, &wrapped); err != nil {
+		return nil, err
+	}
+	key, err := w.service.Decrypt(ctx, "", &kmsservice.DecryptRequest{
+		Ciphertext:  wrapped.Ciphertext,
+		KeyID:       wrapped.KeyID,
+		Annotations: wrapped.Annotations,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key with KMS provider %s: %v", w.name, err)
+	}
+	return key, nil
+}
@@ -0,0 +1,188 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/apiserver/pkg/apis/config/v1"
+	kmsservice "k8s.io/kms/pkg/service"
+
+	"github.com/openshift/library-go/pkg/operator/encryption/state"
+)
+
+func newTestFileKeyWrapper(t *testing.T, kek []byte) KeyWrapper {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "kek")
+	if err := os.WriteFile(path, kek, 0600); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewFileKeyWrapper(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestKeyWrapping(t *testing.T) {
+	oldWrapper := newTestFileKeyWrapper(t, bytes.Repeat([]byte{1}, 32))
+	newWrapper := newTestFileKeyWrapper(t, []byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))+"\n"))
+	if oldWrapper.Name() == newWrapper.Name() {
+		t.Fatalf("expected different names for different key-encryption-keys, got %q", oldWrapper.Name())
+	}
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	ks := state.KeyState{
+		Key:    v1.Key{Name: "3", Secret: base64.StdEncoding.EncodeToString(key)},
+		Backed: true,
+		Mode:   state.AESGCM,
+	}
+
+	s, err := FromKeyState(context.TODO(), "kms", ks, NewKeyWrappers(oldWrapper))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(s.Data[EncryptionSecretKeyDataKey], key) {
+		t.Errorf("expected the key to be wrapped, got %q", s.Data[EncryptionSecretKeyDataKey])
+	}
+	if s.Name != KeySecretName("kms", ks) {
+		t.Errorf("unexpected name %q", s.Name)
+	}
+	if s.Annotations[encryptionSecretKeyWrapper] != oldWrapper.Name() {
+		t.Errorf("expected the key-wrapper annotation %q, got %q", oldWrapper.Name(), s.Annotations[encryptionSecretKeyWrapper])
+	}
+
+	// the key-encryption-key was rotated, old secrets are still readable
+	got, err := ToKeyState(context.TODO(), s, NewKeyWrappers(newWrapper, oldWrapper))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, ks) {
+		t.Errorf("expected %#v, got %#v", ks, got)
+	}
+
+	if _, err := ToKeyState(context.TODO(), s, NewKeyWrappers(newWrapper)); err == nil || !strings.Contains(err.Error(), "unknown key-encryption-key") {
+		t.Errorf("expected an unknown key-encryption-key error, got %v", err)
+	}
+
+	// plain secrets are still readable with wrapping enabled
+	plain, err := FromKeyState(context.TODO(), "kms", ks, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := plain.Annotations[encryptionSecretKeyWrapper]; ok {
+		t.Errorf("expected no key-wrapper annotation without an active wrapper")
+	}
+	if got, err := ToKeyState(context.TODO(), plain, NewKeyWrappers(newWrapper)); err != nil || !reflect.DeepEqual(got, ks) {
+		t.Errorf("expected %#v, got %#v: %v", ks, got, err)
+	}
+}
+
+// deadlineKeyWrapper fails wrap and unwrap calls without a deadline.
+type deadlineKeyWrapper struct{}
+
+func (deadlineKeyWrapper) Name() string {
+	return "deadline"
+}
+
+func (deadlineKeyWrapper) Wrap(ctx context.Context, key []byte) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, fmt.Errorf("wrap called without a deadline")
+	}
+	return key, nil
+}
+
+func (deadlineKeyWrapper) Unwrap(ctx context.Context, wrappedKey []byte) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, fmt.Errorf("unwrap called without a deadline")
+	}
+	return wrappedKey, nil
+}
+
+func TestKeyWrappingDeadline(t *testing.T) {
+	ks := state.KeyState{
+		Key:    v1.Key{Name: "3", Secret: base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))},
+		Backed: true,
+		Mode:   state.AESGCM,
+	}
+	s, err := FromKeyState(context.Background(), "kms", ks, NewKeyWrappers(deadlineKeyWrapper{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ToKeyState(context.Background(), s, NewKeyWrappers(deadlineKeyWrapper{})); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewFileKeyWrapperInvalidKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kek")
+	if err := os.WriteFile(path, []byte("too short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileKeyWrapper(path); err == nil {
+		t.Errorf("expected an error for a short key-encryption-key")
+	}
+}
+
+// fakeKMSService "encrypts" by reversing the plaintext.
+type fakeKMSService struct{}
+
+func reversed(in []byte) []byte {
+	out := make([]byte, len(in))
+	for i := range in {
+		out[len(in)-1-i] = in[i]
+	}
+	return out
+}
+
+func (fakeKMSService) Decrypt(_ context.Context, _ string, req *kmsservice.DecryptRequest) ([]byte, error) {
+	return reversed(req.Ciphertext), nil
+}
+
+func (fakeKMSService) Encrypt(_ context.Context, _ string, data []byte) (*kmsservice.EncryptResponse, error) {
+	return &kmsservice.EncryptResponse{Ciphertext: reversed(data), KeyID: "1"}, nil
+}
+
+func (fakeKMSService) Status(_ context.Context) (*kmsservice.StatusResponse, error) {
+	return &kmsservice.StatusResponse{Version: "v2", Healthz: "ok", KeyID: "1"}, nil
+}
+
+func TestKMSKeyWrapper(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "kms.sock")
+	plugin := kmsservice.NewGRPCService(socket, time.Second, fakeKMSService{})
+	go plugin.ListenAndServe()
+	defer plugin.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w, err := NewKMSKeyWrapper(ctx, "test", "unix://"+socket, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Name() != "kms:test" {
+		t.Errorf("unexpected name %q", w.Name())
+	}
+
+	key := []byte("0123456789abcdef")
+	wrapped, err := w.Wrap(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(wrapped, key) {
+		t.Errorf("expected the key to be wrapped, got %q", wrapped)
+	}
+	unwrapped, err := w.Unwrap(ctx, wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unwrapped, key) {
+		t.Errorf("expected %q, got %q", key, unwrapped)
+	}
+}
@@ -17,8 +17,12 @@ import (
 	"github.com/openshift/library-go/pkg/operator/encryption/state"
 )
 
-// ToKeyState converts a key secret to a key state.
-func ToKeyState(s *corev1.Secret) (state.KeyState, error) {
+// keyWrapTimeout bounds a single wrap or unwrap call, which can be a request to a KMS plugin.
+const keyWrapTimeout = 10 * time.Second
+
+// ToKeyState converts a key secret to a key state. Wrapped keys are unwrapped with the given wrappers, which can be
+// nil if key secrets are not wrapped.
+func ToKeyState(ctx context.Context, s *corev1.Secret, keyWrappers *KeyWrappers) (state.KeyState, error) {
 	data := s.Data[EncryptionSecretKeyDataKey]
 
 	keyID, validKeyID := state.NameToKeyID(s.Name)
@@ -26,6 +30,20 @@ func ToKeyState(s *corev1.Secret) (state.KeyState, error) {
 		return state.KeyState{}, fmt.Errorf("secret %s/%s has an invalid name", s.Namespace, s.Name)
 	}
 
+	if wrapperName, ok := s.Annotations[encryptionSecretKeyWrapper]; ok && len(data) > 0 {
+		wrapper, ok := keyWrappers.get(wrapperName)
+		if !ok {
+			return state.KeyState{}, fmt.Errorf("secret %s/%s is wrapped with unknown key-encryption-key %q", s.Namespace, s.Name, wrapperName)
+		}
+		unwrapCtx, cancel := context.WithTimeout(ctx, keyWrapTimeout)
+		defer cancel()
+		unwrapped, err := wrapper.Unwrap(unwrapCtx, data)
+		if err != nil {
+			return state.KeyState{}, fmt.Errorf("secret %s/%s cannot be unwrapped with key-encryption-key %q: %v", s.Namespace, s.Name, wrapperName, err)
+		}
+		data = unwrapped
+	}
+
 	key := state.KeyState{
 		Key: apiserverconfigv1.Key{
 			// we use keyID as the name to limit the length of the field as it is used as a prefix for every value in etcd
@@ -62,6 +80,11 @@ func ToKeyState(s *corev1.Secret) (state.KeyState, error) {
 	switch keyMode {
 	case state.AESCBC, state.AESGCM, state.SecretBox, state.Identity:
 		key.Mode = keyMode
+	case state.KMS:
+		if _, err := state.KMSKeyConfigFromKeySecret(key.Key.Secret); err != nil {
+			return state.KeyState{}, fmt.Errorf("secret %s/%s has invalid KMS configuration: %v", s.Namespace, s.Name, err)
+		}
+		key.Mode = keyMode
 	default:
 		return state.KeyState{}, fmt.Errorf("secret %s/%s has invalid mode: %s", s.Namespace, s.Name, keyMode)
 	}
@@ -72,8 +95,14 @@ func ToKeyState(s *corev1.Secret) (state.KeyState, error) {
 	return key, nil
 }
 
-// ToKeyState converts a key state to a key secret.
-func FromKeyState(component string, ks state.KeyState) (*corev1.Secret, error) {
+// KeySecretName returns the name of the key secret of the given key state.
+func KeySecretName(component string, ks state.KeyState) string {
+	return fmt.Sprintf("encryption-key-%s-%s", component, ks.Key.Name)
+}
+
+// FromKeyState converts a key state to a key secret. The key is wrapped with the active wrapper of the given
+// wrappers, which can be nil to store the key in plain.
+func FromKeyState(ctx context.Context, component string, ks state.KeyState, keyWrappers *KeyWrappers) (*corev1.Secret, error) {
 	bs, err := base64.StdEncoding.DecodeString(ks.Key.Secret)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode key string")
@@ -81,7 +110,7 @@ func FromKeyState(component string, ks state.KeyState) (*corev1.Secret, error) {
 
 	s := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("encryption-key-%s-%s", component, ks.Key.Name),
+			Name:      KeySecretName(component, ks),
 			Namespace: "openshift-config-managed",
 			Labels: map[string]string{
 				EncryptionKeySecretsLabel: component,
@@ -101,6 +130,17 @@ func FromKeyState(component string, ks state.KeyState) (*corev1.Secret, error) {
 		Type: corev1.SecretTypeOpaque,
 	}
 
+	if wrapper := keyWrappers.active(); wrapper != nil && len(bs) > 0 {
+		wrapCtx, cancel := context.WithTimeout(ctx, keyWrapTimeout)
+		defer cancel()
+		wrapped, err := wrapper.Wrap(wrapCtx, bs)
+		if err != nil {
+			return nil, err
+		}
+		s.Data[EncryptionSecretKeyDataKey] = wrapped
+		s.Annotations[encryptionSecretKeyWrapper] = wrapper.Name()
+	}
+
 	if !ks.Migrated.Timestamp.IsZero() {
 		s.Annotations[EncryptionSecretMigratedTimestamp] = ks.Migrated.Timestamp.Format(time.RFC3339)
 	}
@@ -1,6 +1,7 @@
 package secrets
 
 import (
+	"context"
 	"encoding/base64"
 	"reflect"
 	"testing"
@@ -114,11 +115,11 @@ func TestRoundtrip(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s, err := FromKeyState(tt.component, tt.ks)
+			s, err := FromKeyState(context.TODO(), tt.component, tt.ks, nil)
 			if err != nil {
 				t.Fatalf("unexpected FromKeyState() error: %v", err)
 			}
-			got, err := ToKeyState(s)
+			got, err := ToKeyState(context.TODO(), s, nil)
 			if err != nil {
 				t.Fatalf("unexpected ToKeyState() error: %v", err)
 			}
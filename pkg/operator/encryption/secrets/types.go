@@ -1,6 +1,7 @@
 package secrets
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -24,6 +25,10 @@ const (
 	// The list of resources that were migrated when encryptionSecretMigratedTimestamp was set.
 	// See the MigratedGroupResources struct below to understand the JSON encoding used.
 	EncryptionSecretMigratedResources = "encryption.apiserver.operator.openshift.io/migrated-resources"
+	// The progress of the running migrations of resources to this write key.  A resource is removed from it
+	// when it is added to encryptionSecretMigratedResources.
+	// See the MigrationProgress struct below to understand the JSON encoding used.
+	EncryptionSecretMigrationProgress = "encryption.apiserver.operator.openshift.io/migration-progress"
 
 	// encryptionSecretMode is the annotation that determines how the provider associated with a given key is
 	// configured.  For example, a key could be used with AES-CBC or Secretbox.  This allows for algorithm
@@ -41,6 +46,10 @@ const (
 	// determine if a new key should be created even if encryptionSecretMigrationInterval has not been reached.
 	encryptionSecretExternalReason = "encryption.apiserver.operator.openshift.io/external-reason"
 
+	// encryptionSecretKeyWrapper is the annotation that names the key-encryption-key the key in the data field
+	// is wrapped with (see KeyWrapper).  Secrets without it hold the plain key.
+	encryptionSecretKeyWrapper = "encryption.apiserver.operator.openshift.io/key-wrapper"
+
 	// In the data field of the secret API object, this (map) key is used to hold the actual encryption key
 	// (i.e. for AES-CBC mode the value associated with this map key is 32 bytes of random noise).
 	EncryptionSecretKeyDataKey = "encryption.apiserver.operator.openshift.io-key"
@@ -57,3 +66,23 @@ const (
 type MigratedGroupResources struct {
 	Resources []schema.GroupResource `json:"resources"`
 }
+
+// MigrationProgress is the data structured stored in the
+// encryption.apiserver.operator.openshift.io/migration-progress
+// annotation of a key secret.
+type MigrationProgress struct {
+	Resources []ResourceMigrationProgress `json:"resources"`
+}
+
+// ResourceMigrationProgress is the progress of the migration of a single resource to the write key.
+type ResourceMigrationProgress struct {
+	GroupResource schema.GroupResource `json:"groupResource"`
+	// Migrated is the number of objects migrated so far.
+	Migrated int64 `json:"migrated"`
+	// RemainingEstimate is the estimated number of objects not migrated yet, if the server provides an estimate.
+	RemainingEstimate *int64 `json:"remainingEstimate,omitempty"`
+	// Percentage is the estimated percentage of migrated objects, if there is an estimate.
+	Percentage *int64 `json:"percentage,omitempty"`
+	// LastUpdateTime is the time the progress was observed.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
+}
@@ -74,7 +74,11 @@ func NameToKeyID(name string) (uint64, bool) {
 }
 
 func EqualKeyAndEqualID(s1, s2 *KeyState) bool {
-	if s1.Mode != s2.Mode || s1.Key.Secret != s2.Key.Secret {
+	if s1.Mode != s2.Mode {
+		return false
+	}
+	// the encryption config does not carry the observed KMS key ID, hence KMS keys are identified by their ID only
+	if s1.Mode != KMS && s1.Key.Secret != s2.Key.Secret {
 		return false
 	}
 
@@ -0,0 +1,42 @@
+package state
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// KMSKeyConfig is the key of a key state in KMS mode. Instead of key material it holds the configuration of the
+// KMS v2 plugin that encrypts the data encryption keys of the API server.
+type KMSKeyConfig struct {
+	// Endpoint is the gRPC endpoint of the plugin, e.g. unix:///var/run/kms/plugin.sock.
+	Endpoint string `json:"endpoint"`
+	// KeyID is the key ID the plugin reported when the key was created. A new key ID means that the plugin rotated
+	// its key-encryption-key and the resources have to be migrated.
+	KeyID string `json:"keyID,omitempty"`
+}
+
+// ToKeySecret encodes the config as the secret of a key.
+func (c KMSKeyConfig) ToKeySecret() string {
+	bs, err := json.Marshal(c)
+	if err != nil {
+		panic(err) // cannot happen for a struct of strings
+	}
+	return base64.StdEncoding.EncodeToString(bs)
+}
+
+// KMSKeyConfigFromKeySecret decodes the secret of a key in KMS mode.
+func KMSKeyConfigFromKeySecret(secret string) (KMSKeyConfig, error) {
+	bs, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return KMSKeyConfig{}, err
+	}
+	c := KMSKeyConfig{}
+	if err := json.Unmarshal(bs, &c); err != nil {
+		return KMSKeyConfig{}, err
+	}
+	if len(c.Endpoint) == 0 {
+		return KMSKeyConfig{}, fmt.Errorf("missing KMS plugin endpoint")
+	}
+	return c, nil
+}
@@ -1,6 +1,7 @@
 package statemachine
 
 import (
+	"context"
 	"encoding/base64"
 	"reflect"
 	"testing"
@@ -988,7 +989,7 @@ func TestGetDesiredEncryptionState(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := getDesiredEncryptionState(tt.args.oldEncryptionConfig, tt.args.encryptionSecrets, tt.args.toBeEncryptedGRs)
+			got := getDesiredEncryptionState(context.TODO(), tt.args.oldEncryptionConfig, tt.args.encryptionSecrets, tt.args.toBeEncryptedGRs, nil)
 			if tt.validate != nil {
 				tt.validate(t, &tt.args, got)
 			}
@@ -84,6 +84,16 @@ func CreateEncryptionKeySecretWithKeyFromExistingSecretWithMode(targetNS string,
 	return secret
 }
 
+func CreateEncryptionKeySecretWithKMSConfig(targetNS string, grs []schema.GroupResource, keyID uint64, endpoint, kmsKeyID string) *corev1.Secret {
+	secret := CreateEncryptionKeySecretNoDataWithMode(targetNS, grs, keyID, string(state.KMS))
+	rawConfig, err := json.Marshal(state.KMSKeyConfig{Endpoint: endpoint, KeyID: kmsKeyID})
+	if err != nil {
+		panic(err)
+	}
+	secret.Data[encryptionSecretKeyDataForTest] = rawConfig
+	return secret
+}
+
 func CreateMigratedEncryptionKeySecretWithRawKey(targetNS string, grs []schema.GroupResource, keyID uint64, rawKey []byte, ts time.Time) *corev1.Secret {
 	secret := CreateEncryptionKeySecretWithRawKey(targetNS, grs, keyID, rawKey)
 	secret.Annotations[encryptionSecretMigratedTimestampForTest] = ts.Format(time.RFC3339)
@@ -0,0 +1,20 @@
+package testing
+
+import "context"
+
+// FakeKeyWrapper is a KeyWrapper of the encryption key secrets that "wraps" keys by reversing them.
+type FakeKeyWrapper struct {
+	WrapperName string
+}
+
+func (w FakeKeyWrapper) Name() string {
+	return w.WrapperName
+}
+
+func (w FakeKeyWrapper) Wrap(_ context.Context, key []byte) ([]byte, error) {
+	return reverse(key), nil
+}
+
+func (w FakeKeyWrapper) Unwrap(_ context.Context, wrappedKey []byte) ([]byte, error) {
+	return reverse(wrappedKey), nil
+}
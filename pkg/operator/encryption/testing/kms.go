@@ -0,0 +1,101 @@
+package testing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	kmsservice "k8s.io/kms/pkg/service"
+
+	"github.com/openshift/library-go/pkg/operator/encryption/crypto"
+	"github.com/openshift/library-go/pkg/operator/encryption/state"
+)
+
+// FakeKMSConfigProvider is a KMS config provider of the encryption controllers returning a static config.
+type FakeKMSConfigProvider struct {
+	Config *state.KMSKeyConfig
+}
+
+func (p FakeKMSConfigProvider) KMSKeyConfig() (*state.KMSKeyConfig, error) {
+	return p.Config, nil
+}
+
+// KMSPluginConfigProvider is a KMS config provider of the encryption controllers that asks the KMS v2 plugin at
+// Endpoint for its key ID on every call, like a component running next to the plugin, so that a key rotation of a
+// FakeKMSPlugin is seen by the controllers.
+type KMSPluginConfigProvider struct {
+	Endpoint string
+}
+
+func (p KMSPluginConfigProvider) KMSKeyConfig() (*state.KMSKeyConfig, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	keyID, err := crypto.KMSKeyID(ctx, p.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &state.KMSKeyConfig{Endpoint: p.Endpoint, KeyID: keyID}, nil
+}
+
+// FakeKMSPlugin is a KMS v2 plugin listening on a unix socket. It "encrypts" by reversing the plaintext.
+type FakeKMSPlugin struct {
+	// Endpoint is the endpoint of the plugin, e.g. unix:///tmp/kms123/kms.sock.
+	Endpoint string
+
+	lock  sync.Mutex
+	keyID string
+}
+
+// NewFakeKMSPlugin starts a fake KMS v2 plugin reporting the given key ID. It is stopped when the test finishes.
+func NewFakeKMSPlugin(t *testing.T, keyID string) *FakeKMSPlugin {
+	t.Helper()
+	// unix socket paths are limited to about 100 characters, t.TempDir() can be longer
+	dir, err := os.MkdirTemp("", "kms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "kms.sock")
+	plugin := &FakeKMSPlugin{Endpoint: "unix://" + socket, keyID: keyID}
+	server := kmsservice.NewGRPCService(socket, time.Second, plugin)
+	go server.ListenAndServe()
+	t.Cleanup(server.Close)
+	return plugin
+}
+
+// SetKeyID changes the key ID reported by the plugin, as if the plugin rotated its key.
+func (p *FakeKMSPlugin) SetKeyID(keyID string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.keyID = keyID
+}
+
+func (p *FakeKMSPlugin) getKeyID() string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.keyID
+}
+
+func (p *FakeKMSPlugin) Decrypt(_ context.Context, _ string, req *kmsservice.DecryptRequest) ([]byte, error) {
+	return reverse(req.Ciphertext), nil
+}
+
+func (p *FakeKMSPlugin) Encrypt(_ context.Context, _ string, data []byte) (*kmsservice.EncryptResponse, error) {
+	return &kmsservice.EncryptResponse{Ciphertext: reverse(data), KeyID: p.getKeyID()}, nil
+}
+
+func (p *FakeKMSPlugin) Status(_ context.Context) (*kmsservice.StatusResponse, error) {
+	return &kmsservice.StatusResponse{Version: "v2", Healthz: "ok", KeyID: p.getKeyID()}, nil
+}
+
+func reverse(in []byte) []byte {
+	out := make([]byte, len(in))
+	for i := range in {
+		out[len(in)-1-i] = in[i]
+	}
+	return out
+}
@@ -49,3 +49,15 @@ func ApplyAPIService(ctx context.Context, client apiregistrationv1client.APIServ
 	reportUpdateEvent(recorder, required, err)
 	return actual, true, err
 }
+
+func DeleteAPIService(ctx context.Context, client apiregistrationv1client.APIServicesGetter, recorder events.Recorder, required *apiregistrationv1.APIService) (*apiregistrationv1.APIService, bool, error) {
+	err := client.APIServices().Delete(ctx, required.Name, metav1.DeleteOptions{})
+	if err != nil && apierrors.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	reportDeleteEvent(recorder, required, err)
+	return nil, true, nil
+}
@@ -0,0 +1,140 @@
+package resourceapply
+
+import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	batchclientv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
+)
+
+// ApplyJob merges objectmeta and requires spec. The pod template of a job is immutable, so when the spec hash
+// annotation of the existing job differs from the required one, the job is deleted together with its pods. The job
+// is removed only after its pods are gone, so it is created again by a later call once the deletion has finished.
+func ApplyJob(ctx context.Context, client batchclientv1.JobsGetter, recorder events.Recorder, requiredOriginal *batchv1.Job) (*batchv1.Job, bool, error) {
+	required := requiredOriginal.DeepCopy()
+	err := SetSpecHashAnnotation(&required.ObjectMeta, required.Spec)
+	if err != nil {
+		return nil, false, err
+	}
+
+	existing, err := client.Jobs(required.Namespace).Get(ctx, required.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		requiredCopy := required.DeepCopy()
+		actual, err := client.Jobs(required.Namespace).Create(
+			ctx, resourcemerge.WithCleanLabelsAndAnnotations(requiredCopy).(*batchv1.Job), metav1.CreateOptions{})
+		reportCreateEvent(recorder, required, err)
+		return actual, true, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if existing.DeletionTimestamp != nil {
+		klog.V(2).Infof("Job %q is being deleted, waiting for the deletion to finish", required.Namespace+"/"+required.Name)
+		return existing, false, nil
+	}
+
+	if existing.Annotations[specHashAnnotation] != required.Annotations[specHashAnnotation] {
+		klog.V(2).Infof("Job %q spec changed, deleting it to create it again", required.Namespace+"/"+required.Name)
+		propagation := metav1.DeletePropagationForeground
+		err := client.Jobs(required.Namespace).Delete(ctx, required.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if apierrors.IsNotFound(err) {
+			return nil, true, nil
+		}
+		reportDeleteEvent(recorder, required, err)
+		if err != nil {
+			return nil, false, err
+		}
+		return nil, true, nil
+	}
+
+	modified := resourcemerge.BoolPtr(false)
+	existingCopy := existing.DeepCopy()
+
+	resourcemerge.EnsureObjectMeta(modified, &existingCopy.ObjectMeta, required.ObjectMeta)
+	if !*modified {
+		return existingCopy, false, nil
+	}
+
+	if klog.V(4).Enabled() {
+		klog.Infof("Job %q changes: %v", required.Namespace+"/"+required.Name, JSONPatchNoError(existing, existingCopy))
+	}
+
+	actual, err := client.Jobs(required.Namespace).Update(ctx, existingCopy, metav1.UpdateOptions{})
+	reportUpdateEvent(recorder, required, err)
+	return actual, true, err
+}
+
+// DeleteJob deletes the job together with its pods.
+func DeleteJob(ctx context.Context, client batchclientv1.JobsGetter, recorder events.Recorder, required *batchv1.Job) (*batchv1.Job, bool, error) {
+	propagation := metav1.DeletePropagationBackground
+	err := client.Jobs(required.Namespace).Delete(ctx, required.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && apierrors.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	reportDeleteEvent(recorder, required, err)
+	return nil, true, nil
+}
+
+// ApplyCronJob merges objectmeta and requires spec. The spec is compared using the spec hash annotation,
+// because the API server defaults many fields of the job template.
+func ApplyCronJob(ctx context.Context, client batchclientv1.CronJobsGetter, recorder events.Recorder, requiredOriginal *batchv1.CronJob) (*batchv1.CronJob, bool, error) {
+	required := requiredOriginal.DeepCopy()
+	err := SetSpecHashAnnotation(&required.ObjectMeta, required.Spec)
+	if err != nil {
+		return nil, false, err
+	}
+
+	existing, err := client.CronJobs(required.Namespace).Get(ctx, required.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		requiredCopy := required.DeepCopy()
+		actual, err := client.CronJobs(required.Namespace).Create(
+			ctx, resourcemerge.WithCleanLabelsAndAnnotations(requiredCopy).(*batchv1.CronJob), metav1.CreateOptions{})
+		reportCreateEvent(recorder, required, err)
+		return actual, true, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	modified := resourcemerge.BoolPtr(false)
+	existingCopy := existing.DeepCopy()
+
+	// This will catch also changes between old `required.spec` and current `required.spec`, because
+	// the annotation from SetSpecHashAnnotation will be different.
+	resourcemerge.EnsureObjectMeta(modified, &existingCopy.ObjectMeta, required.ObjectMeta)
+	if !*modified {
+		return existingCopy, false, nil
+	}
+
+	existingCopy.Spec = required.Spec
+
+	if klog.V(4).Enabled() {
+		klog.Infof("CronJob %q changes: %v", required.Namespace+"/"+required.Name, JSONPatchNoError(existing, existingCopy))
+	}
+
+	actual, err := client.CronJobs(required.Namespace).Update(ctx, existingCopy, metav1.UpdateOptions{})
+	reportUpdateEvent(recorder, required, err)
+	return actual, true, err
+}
+
+func DeleteCronJob(ctx context.Context, client batchclientv1.CronJobsGetter, recorder events.Recorder, required *batchv1.CronJob) (*batchv1.CronJob, bool, error) {
+	propagation := metav1.DeletePropagationBackground
+	err := client.CronJobs(required.Namespace).Delete(ctx, required.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && apierrors.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	reportDeleteEvent(recorder, required, err)
+	return nil, true, nil
+}
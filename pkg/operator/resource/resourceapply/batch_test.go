@@ -0,0 +1,99 @@
+package resourceapply
+
+import (
+	"context"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+)
+
+func TestApplyJob(t *testing.T) {
+	required := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "operand-ns", Name: "migrate"},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "migrate", Image: "new"}}}},
+		},
+	}
+	withSpecHash := func(job *batchv1.Job) *batchv1.Job {
+		job = job.DeepCopy()
+		if err := SetSpecHashAnnotation(&job.ObjectMeta, job.Spec); err != nil {
+			t.Fatal(err)
+		}
+		return job
+	}
+	oldJob := required.DeepCopy()
+	oldJob.Spec.Template.Spec.Containers[0].Image = "old"
+	deletingJob := withSpecHash(oldJob)
+	deletingJob.DeletionTimestamp = &metav1.Time{}
+
+	tests := []struct {
+		name            string
+		existing        []runtime.Object
+		expectedChanged bool
+		expectedVerbs   []string
+	}{
+		{
+			name:            "create",
+			expectedChanged: true,
+			expectedVerbs:   []string{"get", "create"},
+		},
+		{
+			name:          "unchanged",
+			existing:      []runtime.Object{withSpecHash(required)},
+			expectedVerbs: []string{"get"},
+		},
+		{
+			name:            "spec changed is deleted",
+			existing:        []runtime.Object{withSpecHash(oldJob)},
+			expectedChanged: true,
+			expectedVerbs:   []string{"get", "delete"},
+		},
+		{
+			name:          "deletion in progress",
+			existing:      []runtime.Object{deletingJob},
+			expectedVerbs: []string{"get"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(test.existing...)
+			_, changed, err := ApplyJob(context.TODO(), client.BatchV1(), events.NewInMemoryRecorder(""), required)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != test.expectedChanged {
+				t.Errorf("expected changed %v, got %v", test.expectedChanged, changed)
+			}
+			actions := client.Actions()
+			if len(actions) != len(test.expectedVerbs) {
+				t.Fatal(spew.Sdump(actions))
+			}
+			for i, verb := range test.expectedVerbs {
+				if !actions[i].Matches(verb, "jobs") {
+					t.Errorf("expected %s, got %s", verb, spew.Sdump(actions[i]))
+				}
+			}
+			switch action := actions[len(actions)-1].(type) {
+			case clienttesting.CreateAction:
+				created := action.GetObject().(*batchv1.Job)
+				if created.Spec.Template.Spec.Containers[0].Image != "new" {
+					t.Errorf("expected the required job to be created, got %s", spew.Sdump(created))
+				}
+			case clienttesting.DeleteAction:
+				if propagation := action.GetDeleteOptions().PropagationPolicy; propagation == nil || *propagation != metav1.DeletePropagationForeground {
+					t.Errorf("expected foreground deletion, got %s", spew.Sdump(action))
+				}
+			}
+		})
+	}
+}
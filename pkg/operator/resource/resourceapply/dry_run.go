@@ -0,0 +1,386 @@
+package resourceapply
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apiextensionsv1client "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	admissionregistrationv1client "k8s.io/client-go/kubernetes/typed/admissionregistration/v1"
+	appsv1client "k8s.io/client-go/kubernetes/typed/apps/v1"
+	batchv1client "k8s.io/client-go/kubernetes/typed/batch/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	networkingv1client "k8s.io/client-go/kubernetes/typed/networking/v1"
+	policyv1client "k8s.io/client-go/kubernetes/typed/policy/v1"
+	rbacv1client "k8s.io/client-go/kubernetes/typed/rbac/v1"
+	schedulingv1client "k8s.io/client-go/kubernetes/typed/scheduling/v1"
+	storagev1client "k8s.io/client-go/kubernetes/typed/storage/v1"
+	"k8s.io/client-go/rest"
+	apiregistrationclient "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset"
+	apiregistrationv1client "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset/typed/apiregistration/v1"
+	migrationclient "sigs.k8s.io/kube-storage-version-migrator/pkg/clients/clientset"
+	migrationv1alpha1client "sigs.k8s.io/kube-storage-version-migrator/pkg/clients/clientset/typed/migration/v1alpha1"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcehelper"
+)
+
+// DryRunAction describes what applying or deleting a manifest would do to the object in the cluster.
+type DryRunAction string
+
+const (
+	DryRunNone   DryRunAction = "None"
+	DryRunCreate DryRunAction = "Create"
+	DryRunUpdate DryRunAction = "Update"
+	DryRunDelete DryRunAction = "Delete"
+)
+
+// DryRunNoteRecreate is reported for a DryRunUpdate that deletes the existing object and creates it again, like
+// applying a job with a changed pod template. The API server cannot dry-run such an update, so no diff is reported.
+const DryRunNoteRecreate = "recreate: the existing object would be deleted and created again"
+
+// WithDryRun makes ApplyDirectly and DeleteAll compute what they would change without writing to the API server.
+// The outcome for every manifest is reported in ApplyResult.DryRunAction and ApplyResult.DryRunDiff.
+//
+// The API server computes the outcome: in client-side mode the typed Apply* functions send their create and update
+// requests with dryRun=All, with WithServerSideApply the apply patch is sent with dryRun=All. The reported diff hence
+// matches what a real apply would write, including defaulting and admission. Reads are not dry-run, so the clients
+// need read access to the applied resources.
+func (c *ClientHolder) WithDryRun() *ClientHolder {
+	c.dryRun = true
+	return c
+}
+
+// dryRunApply returns the object that applying required, decoded from manifest, would produce, together with the
+// action, the diff against the existing object and a note about updates that cannot be described by a diff.
+func dryRunApply(ctx context.Context, clients *ClientHolder, required runtime.Object, manifest []byte) (runtime.Object, DryRunAction, string, string, error) {
+	existing, err := dryRunGetExisting(ctx, clients, required)
+	if err != nil {
+		return nil, "", "", "", err
+	}
+
+	var actual runtime.Object
+	var changed bool
+	if clients.serverSideApply != nil {
+		actual, err = serverSideDryRunApply(ctx, clients, required, manifest, *clients.serverSideApply)
+		if err != nil {
+			return nil, "", "", "", err
+		}
+		changed = existing == nil || dryRunDiff(existing, actual) != "{}"
+	} else {
+		recreate, err := dryRunRecreate(existing, required)
+		if err != nil {
+			return nil, "", "", "", err
+		}
+		if recreate {
+			// the dry-run deletion would be followed by a create of an object that still exists, which fails
+			return required, DryRunUpdate, "", DryRunNoteRecreate, nil
+		}
+		// the recorder is thrown away, events are writes too
+		actual, changed, err = applyObject(ctx, clients.dryRunClients(), events.NewInMemoryRecorder(""), noCache, required)
+		if err != nil {
+			return nil, "", "", "", err
+		}
+	}
+
+	switch {
+	case !changed:
+		return actual, DryRunNone, "", "", nil
+	case existing == nil:
+		return actual, DryRunCreate, "", "", nil
+	case actual == nil:
+		// the apply deleted the existing object to create it again later
+		return required, DryRunUpdate, "", DryRunNoteRecreate, nil
+	default:
+		return actual, DryRunUpdate, dryRunDiff(existing, actual), "", nil
+	}
+}
+
+// dryRunRecreate returns true if the client-side apply of required would delete the existing object to create it
+// again, which is the case for jobs whose pod template changed.
+func dryRunRecreate(existing, required runtime.Object) (bool, error) {
+	requiredJob, ok := required.(*batchv1.Job)
+	if !ok {
+		return false, nil
+	}
+	existingJob, ok := existing.(*batchv1.Job)
+	if !ok || existingJob.DeletionTimestamp != nil {
+		return false, nil
+	}
+	requiredCopy := requiredJob.DeepCopy()
+	if err := SetSpecHashAnnotation(&requiredCopy.ObjectMeta, requiredCopy.Spec); err != nil {
+		return false, err
+	}
+	return existingJob.Annotations[specHashAnnotation] != requiredCopy.Annotations[specHashAnnotation], nil
+}
+
+// dryRunDelete reports whether deleting required would remove an object from the cluster.
+func dryRunDelete(ctx context.Context, clients *ClientHolder, required runtime.Object) (runtime.Object, DryRunAction, error) {
+	existing, err := dryRunGetExisting(ctx, clients, required)
+	if err != nil {
+		return nil, "", err
+	}
+	if existing == nil {
+		return nil, DryRunNone, nil
+	}
+	return existing, DryRunDelete, nil
+}
+
+// dryRunGetExisting reads the current state of required from the API server. It returns nil when the object does not exist.
+func dryRunGetExisting(ctx context.Context, clients *ClientHolder, required runtime.Object) (runtime.Object, error) {
+	client, err := clients.objectClientFor(required)
+	if err != nil {
+		return nil, err
+	}
+	requiredMeta, err := meta.Accessor(required)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := client.Get(ctx, requiredMeta.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return existing, err
+}
+
+// serverSideDryRunApply sends the same apply patch as applyManifestServerSide with dryRun=All.
+func serverSideDryRunApply(ctx context.Context, clients *ClientHolder, required runtime.Object, manifest []byte, options ServerSideApplyOptions) (runtime.Object, error) {
+	if len(options.FieldManager) == 0 {
+		return nil, fmt.Errorf("missing field manager for server-side apply")
+	}
+	client, err := clients.objectClientFor(required)
+	if err != nil {
+		return nil, err
+	}
+	applyObj, err := toManifestApplyObject(manifest)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := applyObj.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	patchOptions := metav1.PatchOptions{FieldManager: options.FieldManager, Force: &options.Force, DryRun: []string{metav1.DryRunAll}}
+	return client.Patch(ctx, applyObj.GetName(), types.ApplyPatchType, patch, patchOptions)
+}
+
+// dryRunClients returns clients that send every create, update, patch and delete request with dryRun=All, so that the
+// API server runs validation and admission for them without persisting anything. Tracked generations are copied, so
+// that the dry run neither ignores nor updates them.
+func (c *ClientHolder) dryRunClients() *ClientHolder {
+	dryRunClients := NewClientHolder().WithKubernetesInformers(c.kubeInformers)
+	if c.generations != nil {
+		generations := append([]operatorv1.GenerationStatus{}, *c.generations...)
+		dryRunClients = dryRunClients.WithGenerations(&generations)
+	}
+	if c.kubeClient != nil {
+		dryRunClients = dryRunClients.WithKubernetes(dryRunKubeClient{c.kubeClient})
+	}
+	if c.apiExtensionsClient != nil {
+		dryRunClients = dryRunClients.WithAPIExtensionsClient(dryRunAPIExtensionsClient{c.apiExtensionsClient})
+	}
+	if c.apiRegistrationClient != nil {
+		dryRunClients = dryRunClients.WithAPIRegistrationClient(dryRunAPIRegistrationClient{c.apiRegistrationClient})
+	}
+	if c.migrationClient != nil {
+		dryRunClients = dryRunClients.WithMigrationClient(dryRunMigrationClient{c.migrationClient})
+	}
+	if c.dynamicClient != nil {
+		dryRunClients = dryRunClients.WithDynamicClient(dryRunDynamicClient{c.dynamicClient})
+	}
+	return dryRunClients
+}
+
+// dryRunRESTClient adds dryRun=All to all mutating requests.
+type dryRunRESTClient struct {
+	rest.Interface
+}
+
+func (c dryRunRESTClient) Verb(verb string) *rest.Request {
+	switch verb {
+	case "POST", "PUT", "PATCH", "DELETE":
+		return c.Interface.Verb(verb).Param("dryRun", metav1.DryRunAll)
+	}
+	return c.Interface.Verb(verb)
+}
+
+func (c dryRunRESTClient) Post() *rest.Request {
+	return c.Interface.Post().Param("dryRun", metav1.DryRunAll)
+}
+
+func (c dryRunRESTClient) Put() *rest.Request {
+	return c.Interface.Put().Param("dryRun", metav1.DryRunAll)
+}
+
+func (c dryRunRESTClient) Patch(pt types.PatchType) *rest.Request {
+	return c.Interface.Patch(pt).Param("dryRun", metav1.DryRunAll)
+}
+
+func (c dryRunRESTClient) Delete() *rest.Request {
+	return c.Interface.Delete().Param("dryRun", metav1.DryRunAll)
+}
+
+// dryRunKubeClient overrides the API groups ApplyDirectly writes to.
+type dryRunKubeClient struct {
+	kubernetes.Interface
+}
+
+func (c dryRunKubeClient) AdmissionregistrationV1() admissionregistrationv1client.AdmissionregistrationV1Interface {
+	return admissionregistrationv1client.New(dryRunRESTClient{c.Interface.AdmissionregistrationV1().RESTClient()})
+}
+
+func (c dryRunKubeClient) AppsV1() appsv1client.AppsV1Interface {
+	return appsv1client.New(dryRunRESTClient{c.Interface.AppsV1().RESTClient()})
+}
+
+func (c dryRunKubeClient) BatchV1() batchv1client.BatchV1Interface {
+	return batchv1client.New(dryRunRESTClient{c.Interface.BatchV1().RESTClient()})
+}
+
+func (c dryRunKubeClient) CoreV1() corev1client.CoreV1Interface {
+	return corev1client.New(dryRunRESTClient{c.Interface.CoreV1().RESTClient()})
+}
+
+func (c dryRunKubeClient) NetworkingV1() networkingv1client.NetworkingV1Interface {
+	return networkingv1client.New(dryRunRESTClient{c.Interface.NetworkingV1().RESTClient()})
+}
+
+func (c dryRunKubeClient) PolicyV1() policyv1client.PolicyV1Interface {
+	return policyv1client.New(dryRunRESTClient{c.Interface.PolicyV1().RESTClient()})
+}
+
+func (c dryRunKubeClient) RbacV1() rbacv1client.RbacV1Interface {
+	return rbacv1client.New(dryRunRESTClient{c.Interface.RbacV1().RESTClient()})
+}
+
+func (c dryRunKubeClient) SchedulingV1() schedulingv1client.SchedulingV1Interface {
+	return schedulingv1client.New(dryRunRESTClient{c.Interface.SchedulingV1().RESTClient()})
+}
+
+func (c dryRunKubeClient) StorageV1() storagev1client.StorageV1Interface {
+	return storagev1client.New(dryRunRESTClient{c.Interface.StorageV1().RESTClient()})
+}
+
+type dryRunAPIExtensionsClient struct {
+	apiextensionsclient.Interface
+}
+
+func (c dryRunAPIExtensionsClient) ApiextensionsV1() apiextensionsv1client.ApiextensionsV1Interface {
+	return apiextensionsv1client.New(dryRunRESTClient{c.Interface.ApiextensionsV1().RESTClient()})
+}
+
+type dryRunAPIRegistrationClient struct {
+	apiregistrationclient.Interface
+}
+
+func (c dryRunAPIRegistrationClient) ApiregistrationV1() apiregistrationv1client.ApiregistrationV1Interface {
+	return apiregistrationv1client.New(dryRunRESTClient{c.Interface.ApiregistrationV1().RESTClient()})
+}
+
+type dryRunMigrationClient struct {
+	migrationclient.Interface
+}
+
+func (c dryRunMigrationClient) MigrationV1alpha1() migrationv1alpha1client.MigrationV1alpha1Interface {
+	return migrationv1alpha1client.New(dryRunRESTClient{c.Interface.MigrationV1alpha1().RESTClient()})
+}
+
+// dryRunDynamicClient sets DryRun in the options of all mutating requests, the dynamic client does not expose its
+// REST client.
+type dryRunDynamicClient struct {
+	dynamic.Interface
+}
+
+func (c dryRunDynamicClient) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	client := c.Interface.Resource(resource)
+	return dryRunNamespaceableResource{dryRunResource: dryRunResource{client}, client: client}
+}
+
+type dryRunNamespaceableResource struct {
+	dryRunResource
+	client dynamic.NamespaceableResourceInterface
+}
+
+func (r dryRunNamespaceableResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return dryRunResource{r.client.Namespace(namespace)}
+}
+
+type dryRunResource struct {
+	dynamic.ResourceInterface
+}
+
+func (r dryRunResource) Create(ctx context.Context, obj *unstructured.Unstructured, options metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	options.DryRun = []string{metav1.DryRunAll}
+	return r.ResourceInterface.Create(ctx, obj, options, subresources...)
+}
+
+func (r dryRunResource) Update(ctx context.Context, obj *unstructured.Unstructured, options metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	options.DryRun = []string{metav1.DryRunAll}
+	return r.ResourceInterface.Update(ctx, obj, options, subresources...)
+}
+
+func (r dryRunResource) UpdateStatus(ctx context.Context, obj *unstructured.Unstructured, options metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	options.DryRun = []string{metav1.DryRunAll}
+	return r.ResourceInterface.UpdateStatus(ctx, obj, options)
+}
+
+func (r dryRunResource) Delete(ctx context.Context, name string, options metav1.DeleteOptions, subresources ...string) error {
+	options.DryRun = []string{metav1.DryRunAll}
+	return r.ResourceInterface.Delete(ctx, name, options, subresources...)
+}
+
+func (r dryRunResource) DeleteCollection(ctx context.Context, options metav1.DeleteOptions, listOptions metav1.ListOptions) error {
+	options.DryRun = []string{metav1.DryRunAll}
+	return r.ResourceInterface.DeleteCollection(ctx, options, listOptions)
+}
+
+func (r dryRunResource) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, options metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	options.DryRun = []string{metav1.DryRunAll}
+	return r.ResourceInterface.Patch(ctx, name, pt, data, options, subresources...)
+}
+
+func (r dryRunResource) Apply(ctx context.Context, name string, obj *unstructured.Unstructured, options metav1.ApplyOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	options.DryRun = []string{metav1.DryRunAll}
+	return r.ResourceInterface.Apply(ctx, name, obj, options, subresources...)
+}
+
+func (r dryRunResource) ApplyStatus(ctx context.Context, name string, obj *unstructured.Unstructured, options metav1.ApplyOptions) (*unstructured.Unstructured, error) {
+	options.DryRun = []string{metav1.DryRunAll}
+	return r.ResourceInterface.ApplyStatus(ctx, name, obj, options)
+}
+
+// dryRunDiff returns a JSON merge patch from existing to actual, ignoring type meta, managed fields and secret values.
+// It returns an empty string if either object is missing.
+func dryRunDiff(existing, actual runtime.Object) string {
+	if existing == nil || actual == nil {
+		return ""
+	}
+	existing = existing.DeepCopyObject()
+	actual = actual.DeepCopyObject()
+	for _, obj := range []runtime.Object{existing, actual} {
+		obj.GetObjectKind().SetGroupVersionKind(resourcehelper.GuessObjectGroupVersionKind(obj))
+		if accessor, err := meta.Accessor(obj); err == nil {
+			accessor.SetManagedFields(nil)
+		}
+	}
+
+	existingSecret, existingIsSecret := existing.(*corev1.Secret)
+	actualSecret, actualIsSecret := actual.(*corev1.Secret)
+	if existingIsSecret && actualIsSecret {
+		return JSONPatchSecretNoError(existingSecret, actualSecret)
+	}
+	return JSONPatchNoError(existing, actual)
+}
@@ -0,0 +1,279 @@
+package resourceapply
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+)
+
+// dryRunTestServer serves the given objects by path and answers writes with the written object. It records the
+// requests that would have persisted anything.
+type dryRunTestServer struct {
+	lock    sync.Mutex
+	objects map[string]runtime.Object
+	writes  []string
+	bodies  []string
+}
+
+func (s *dryRunTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodGet {
+		obj, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+			return
+		}
+		json.NewEncoder(w).Encode(obj)
+		return
+	}
+
+	if r.URL.Query().Get("dryRun") != metav1.DryRunAll {
+		s.writes = append(s.writes, r.Method+" "+r.URL.Path)
+	}
+	body, _ := io.ReadAll(r.Body)
+	s.bodies = append(s.bodies, string(body))
+	w.Write(body)
+}
+
+func newDryRunTestClient(t *testing.T, objects map[string]runtime.Object) (kubernetes.Interface, *dryRunTestServer) {
+	handler := &dryRunTestServer{objects: objects}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL}), handler
+}
+
+var configMapTypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}
+
+var jobTypeMeta = metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"}
+
+const dryRunTestJob = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: foo
+  namespace: one-ns
+spec:
+  template:
+    spec:
+      containers:
+      - name: foo
+        image: foo
+      restartPolicy: Never
+`
+
+// dryRunTestJobWithSpecHash returns dryRunTestJob as ApplyJob created it.
+func dryRunTestJobWithSpecHash(t *testing.T) *batchv1.Job {
+	job := resourceread.ReadGenericWithUnstructuredOrDie([]byte(dryRunTestJob)).(*batchv1.Job)
+	if err := SetSpecHashAnnotation(&job.ObjectMeta, job.Spec); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestApplyDirectlyDryRun(t *testing.T) {
+	manifests := func(name string) ([]byte, error) {
+		switch name {
+		case "cm.yaml":
+			return []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+  namespace: one-ns
+data:
+  key: new-value
+`), nil
+		case "job.yaml":
+			return []byte(dryRunTestJob), nil
+		default:
+			return []byte(`apiVersion: v1
+kind: Secret
+metadata:
+  name: foo
+  namespace: one-ns
+stringData:
+  key: new-value
+`), nil
+		}
+	}
+
+	tests := []struct {
+		name           string
+		existing       map[string]runtime.Object
+		file           string
+		expectedAction DryRunAction
+		expectedDiff   string
+		expectedNote   string
+	}{
+		{
+			name:           "create",
+			file:           "cm.yaml",
+			expectedAction: DryRunCreate,
+		},
+		{
+			name: "update",
+			existing: map[string]runtime.Object{
+				"/api/v1/namespaces/one-ns/configmaps/foo": &corev1.ConfigMap{TypeMeta: configMapTypeMeta, ObjectMeta: metav1.ObjectMeta{Namespace: "one-ns", Name: "foo"}, Data: map[string]string{"key": "old-value"}},
+			},
+			file:           "cm.yaml",
+			expectedAction: DryRunUpdate,
+			expectedDiff:   `{"data":{"key":"new-value"}}`,
+		},
+		{
+			name: "no change",
+			existing: map[string]runtime.Object{
+				"/api/v1/namespaces/one-ns/configmaps/foo": &corev1.ConfigMap{TypeMeta: configMapTypeMeta, ObjectMeta: metav1.ObjectMeta{Namespace: "one-ns", Name: "foo"}, Data: map[string]string{"key": "new-value"}},
+			},
+			file:           "cm.yaml",
+			expectedAction: DryRunNone,
+		},
+		{
+			name: "secret diff hides data",
+			existing: map[string]runtime.Object{
+				"/api/v1/namespaces/one-ns/secrets/foo": &corev1.Secret{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"}, ObjectMeta: metav1.ObjectMeta{Namespace: "one-ns", Name: "foo"}, Data: map[string][]byte{"key": []byte("old-value")}},
+			},
+			file:           "secret.yaml",
+			expectedAction: DryRunUpdate,
+			expectedDiff:   `{"data":{"key":"TU9ESUZJRUQ="},"type":"Opaque"}`,
+		},
+		{
+			name: "job with changed spec is recreated",
+			existing: map[string]runtime.Object{
+				"/apis/batch/v1/namespaces/one-ns/jobs/foo": &batchv1.Job{TypeMeta: jobTypeMeta, ObjectMeta: metav1.ObjectMeta{Namespace: "one-ns", Name: "foo", Annotations: map[string]string{specHashAnnotation: "old-hash"}}},
+			},
+			file:           "job.yaml",
+			expectedAction: DryRunUpdate,
+			expectedNote:   DryRunNoteRecreate,
+		},
+		{
+			name: "job with unchanged spec",
+			existing: map[string]runtime.Object{
+				"/apis/batch/v1/namespaces/one-ns/jobs/foo": dryRunTestJobWithSpecHash(t),
+			},
+			file:           "job.yaml",
+			expectedAction: DryRunNone,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, server := newDryRunTestClient(t, test.existing)
+			results := ApplyDirectly(context.TODO(), NewKubeClientHolder(client).WithDryRun(), events.NewInMemoryRecorder(""), nil, manifests, test.file)
+			if results[0].Error != nil {
+				t.Fatal(results[0].Error)
+			}
+			if results[0].DryRunAction != test.expectedAction {
+				t.Errorf("expected action %q, got %q", test.expectedAction, results[0].DryRunAction)
+			}
+			if results[0].Changed != (test.expectedAction != DryRunNone) {
+				t.Errorf("unexpected changed %v for action %q", results[0].Changed, results[0].DryRunAction)
+			}
+			if results[0].DryRunDiff != test.expectedDiff {
+				t.Errorf("expected diff %s, got %s", test.expectedDiff, results[0].DryRunDiff)
+			}
+			if results[0].DryRunNote != test.expectedNote {
+				t.Errorf("expected note %q, got %q", test.expectedNote, results[0].DryRunNote)
+			}
+			if len(server.writes) > 0 {
+				t.Errorf("unexpected writes in dry-run mode: %v", server.writes)
+			}
+		})
+	}
+}
+
+func TestApplyDirectlyServerSideDryRun(t *testing.T) {
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+  namespace: one-ns
+spec:
+  template:
+    spec:
+      containers:
+      - name: foo
+        image: foo
+`
+	manifests := func(name string) ([]byte, error) {
+		return []byte(manifest), nil
+	}
+	client, server := newDryRunTestClient(t, nil)
+	clients := NewKubeClientHolder(client).WithServerSideApply(ServerSideApplyOptions{FieldManager: "test-operator"}).WithDryRun()
+
+	results := ApplyDirectly(context.TODO(), clients, events.NewInMemoryRecorder(""), nil, manifests, "deployment.yaml")
+	if results[0].Error != nil {
+		t.Fatal(results[0].Error)
+	}
+	if results[0].DryRunAction != DryRunCreate {
+		t.Errorf("expected action %q, got %q", DryRunCreate, results[0].DryRunAction)
+	}
+	if len(server.writes) > 0 {
+		t.Errorf("unexpected writes in dry-run mode: %v", server.writes)
+	}
+
+	// the dry run sends the same apply patch as the real apply, which holds only the fields of the manifest
+	manifestJSON, err := kyaml.ToJSON([]byte(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(server.bodies) != 1 {
+		t.Fatalf("expected a single apply patch, got %v", server.bodies)
+	}
+	expected, patched := map[string]interface{}{}, map[string]interface{}{}
+	if err := json.Unmarshal(manifestJSON, &expected); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(server.bodies[0]), &patched); err != nil {
+		t.Fatal(err)
+	}
+	if !equality.Semantic.DeepEqual(expected, patched) {
+		t.Errorf("expected apply patch %s, got %s", manifestJSON, server.bodies[0])
+	}
+}
+
+func TestDeleteAllDryRun(t *testing.T) {
+	manifests := func(name string) ([]byte, error) {
+		return []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ` + strings.TrimSuffix(name, ".yaml") + `
+  namespace: one-ns
+`), nil
+	}
+	client, server := newDryRunTestClient(t, map[string]runtime.Object{
+		"/api/v1/namespaces/one-ns/configmaps/existing": &corev1.ConfigMap{TypeMeta: configMapTypeMeta, ObjectMeta: metav1.ObjectMeta{Namespace: "one-ns", Name: "existing"}},
+	})
+
+	results := DeleteAll(context.TODO(), NewKubeClientHolder(client).WithDryRun(), events.NewInMemoryRecorder(""), manifests, "existing.yaml", "missing.yaml")
+	if results[0].Error != nil || results[0].DryRunAction != DryRunDelete || !results[0].Changed {
+		t.Errorf("expected existing configmap to be deleted, got %s", spew.Sdump(results[0]))
+	}
+	if results[1].Error != nil || results[1].DryRunAction != DryRunNone || results[1].Changed {
+		t.Errorf("expected missing configmap to be left alone, got %s", spew.Sdump(results[1]))
+	}
+	if len(server.writes) > 0 {
+		t.Errorf("unexpected writes in dry-run mode: %v", server.writes)
+	}
+}
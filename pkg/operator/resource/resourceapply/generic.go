@@ -5,9 +5,13 @@ import (
 	"fmt"
 
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
@@ -16,10 +20,15 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+	apiregistrationclient "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset"
 	migrationv1alpha1 "sigs.k8s.io/kube-storage-version-migrator/pkg/apis/migration/v1alpha1"
 	migrationclient "sigs.k8s.io/kube-storage-version-migrator/pkg/clients/clientset"
 
+	operatorv1 "github.com/openshift/api/operator/v1"
+
 	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 )
@@ -32,6 +41,15 @@ type ApplyResult struct {
 	Result  runtime.Object
 	Changed bool
 	Error   error
+
+	// DryRunAction is what the manifest would do to the cluster. It is only set when the clients were configured
+	// WithDryRun, in which case Result is the object that would be written.
+	DryRunAction DryRunAction
+	// DryRunDiff is a JSON merge patch from the existing object to the object that would be written. It is only set
+	// for DryRunUpdate.
+	DryRunDiff string
+	// DryRunNote explains a DryRunUpdate that cannot be described by DryRunDiff, see DryRunNoteRecreate.
+	DryRunNote string
 }
 
 // ConditionalFunction provides needed dependency for a resource on another condition instead of blindly creating
@@ -39,11 +57,19 @@ type ApplyResult struct {
 type ConditionalFunction func() bool
 
 type ClientHolder struct {
-	kubeClient          kubernetes.Interface
-	apiExtensionsClient apiextensionsclient.Interface
-	kubeInformers       v1helpers.KubeInformersForNamespaces
-	dynamicClient       dynamic.Interface
-	migrationClient     migrationclient.Interface
+	kubeClient            kubernetes.Interface
+	apiExtensionsClient   apiextensionsclient.Interface
+	kubeInformers         v1helpers.KubeInformersForNamespaces
+	dynamicClient         dynamic.Interface
+	migrationClient       migrationclient.Interface
+	apiRegistrationClient apiregistrationclient.Interface
+
+	// generations tracks the last generation of the applied deployments and daemonsets, see WithGenerations.
+	generations *[]operatorv1.GenerationStatus
+	// serverSideApply, when set, makes ApplyDirectly use server-side apply instead of the typed Apply* functions.
+	serverSideApply *ServerSideApplyOptions
+	// dryRun makes ApplyDirectly and DeleteAll report what they would change instead of writing to the API server.
+	dryRun bool
 }
 
 func NewClientHolder() *ClientHolder {
@@ -79,6 +105,19 @@ func (c *ClientHolder) WithMigrationClient(client migrationclient.Interface) *Cl
 	return c
 }
 
+func (c *ClientHolder) WithAPIRegistrationClient(client apiregistrationclient.Interface) *ClientHolder {
+	c.apiRegistrationClient = client
+	return c
+}
+
+// WithGenerations makes ApplyDirectly compare deployments and daemonsets against the generations in the given list
+// and record the generations it observes after applying them, like resourcemerge.SetDeploymentGeneration does.
+// The list is usually the operator status generations. Without it, deployments and daemonsets are always updated.
+func (c *ClientHolder) WithGenerations(generations *[]operatorv1.GenerationStatus) *ClientHolder {
+	c.generations = generations
+	return c
+}
+
 // ApplyDirectly applies the given manifest files to API server.
 func ApplyDirectly(ctx context.Context, clients *ClientHolder, recorder events.Recorder, cache ResourceCache, manifests AssetFunc, files ...string) []ApplyResult {
 	ret := []ApplyResult{}
@@ -99,120 +138,14 @@ func ApplyDirectly(ctx context.Context, clients *ClientHolder, recorder events.R
 		}
 		result.Type = fmt.Sprintf("%T", requiredObj)
 
-		// NOTE: Do not add CR resources into this switch otherwise the protobuf client can cause problems.
-		switch t := requiredObj.(type) {
-		case *corev1.Namespace:
-			if clients.kubeClient == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyNamespaceImproved(ctx, clients.kubeClient.CoreV1(), recorder, t, cache)
-			}
-		case *corev1.Service:
-			if clients.kubeClient == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyServiceImproved(ctx, clients.kubeClient.CoreV1(), recorder, t, cache)
-			}
-		case *corev1.Pod:
-			if clients.kubeClient == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyPodImproved(ctx, clients.kubeClient.CoreV1(), recorder, t, cache)
-			}
-		case *corev1.ServiceAccount:
-			if clients.kubeClient == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyServiceAccountImproved(ctx, clients.kubeClient.CoreV1(), recorder, t, cache)
-			}
-		case *corev1.ConfigMap:
-			client := clients.configMapsGetter()
-			if client == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyConfigMapImproved(ctx, client, recorder, t, cache)
-			}
-		case *corev1.Secret:
-			client := clients.secretsGetter()
-			if client == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplySecretImproved(ctx, client, recorder, t, cache)
-			}
-		case *rbacv1.ClusterRole:
-			if clients.kubeClient == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyClusterRole(ctx, clients.kubeClient.RbacV1(), recorder, t)
-			}
-		case *rbacv1.ClusterRoleBinding:
-			if clients.kubeClient == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyClusterRoleBinding(ctx, clients.kubeClient.RbacV1(), recorder, t)
-			}
-		case *rbacv1.Role:
-			if clients.kubeClient == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyRole(ctx, clients.kubeClient.RbacV1(), recorder, t)
-			}
-		case *rbacv1.RoleBinding:
-			if clients.kubeClient == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyRoleBinding(ctx, clients.kubeClient.RbacV1(), recorder, t)
-			}
-		case *policyv1.PodDisruptionBudget:
-			if clients.kubeClient == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyPodDisruptionBudget(ctx, clients.kubeClient.PolicyV1(), recorder, t)
-			}
-		case *apiextensionsv1.CustomResourceDefinition:
-			if clients.apiExtensionsClient == nil {
-				result.Error = fmt.Errorf("missing apiExtensionsClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyCustomResourceDefinitionV1(ctx, clients.apiExtensionsClient.ApiextensionsV1(), recorder, t)
-			}
-		case *storagev1.StorageClass:
-			if clients.kubeClient == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyStorageClass(ctx, clients.kubeClient.StorageV1(), recorder, t)
-			}
-		case *admissionregistrationv1.ValidatingWebhookConfiguration:
-			if clients.kubeClient == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyValidatingWebhookConfigurationImproved(ctx, clients.kubeClient.AdmissionregistrationV1(), recorder, t, cache)
-			}
-		case *admissionregistrationv1.MutatingWebhookConfiguration:
-			if clients.kubeClient == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyMutatingWebhookConfigurationImproved(ctx, clients.kubeClient.AdmissionregistrationV1(), recorder, t, cache)
-			}
-		case *storagev1.CSIDriver:
-			if clients.kubeClient == nil {
-				result.Error = fmt.Errorf("missing kubeClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyCSIDriver(ctx, clients.kubeClient.StorageV1(), recorder, t)
-			}
-		case *migrationv1alpha1.StorageVersionMigration:
-			if clients.migrationClient == nil {
-				result.Error = fmt.Errorf("missing migrationClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyStorageVersionMigration(ctx, clients.migrationClient, recorder, t)
-			}
-		case *unstructured.Unstructured:
-			if clients.dynamicClient == nil {
-				result.Error = fmt.Errorf("missing dynamicClient")
-			} else {
-				result.Result, result.Changed, result.Error = ApplyKnownUnstructured(ctx, clients.dynamicClient, recorder, t)
-			}
+		switch {
+		case clients.dryRun:
+			result.Result, result.DryRunAction, result.DryRunDiff, result.DryRunNote, result.Error = dryRunApply(ctx, clients, requiredObj, objBytes)
+			result.Changed = result.Error == nil && result.DryRunAction != DryRunNone
+		case clients.serverSideApply != nil:
+			result.Result, result.Changed, result.Error = applyManifestServerSide(ctx, clients, recorder, cache, requiredObj, objBytes, *clients.serverSideApply)
 		default:
-			result.Error = fmt.Errorf("unhandled type %T", requiredObj)
+			result.Result, result.Changed, result.Error = applyObject(ctx, clients, recorder, cache, requiredObj)
 		}
 
 		ret = append(ret, result)
@@ -221,6 +154,156 @@ func ApplyDirectly(ctx context.Context, clients *ClientHolder, recorder events.R
 	return ret
 }
 
+// applyObject applies a single decoded manifest using the typed Apply* function for its type.
+func applyObject(ctx context.Context, clients *ClientHolder, recorder events.Recorder, cache ResourceCache, requiredObj runtime.Object) (runtime.Object, bool, error) {
+	// NOTE: Do not add CR resources into this switch otherwise the protobuf client can cause problems.
+	switch t := requiredObj.(type) {
+	case *corev1.Namespace:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyNamespaceImproved(ctx, clients.kubeClient.CoreV1(), recorder, t, cache)
+	case *corev1.Service:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyServiceImproved(ctx, clients.kubeClient.CoreV1(), recorder, t, cache)
+	case *corev1.Pod:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyPodImproved(ctx, clients.kubeClient.CoreV1(), recorder, t, cache)
+	case *corev1.ServiceAccount:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyServiceAccountImproved(ctx, clients.kubeClient.CoreV1(), recorder, t, cache)
+	case *corev1.ConfigMap:
+		client := clients.configMapsGetter()
+		if client == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyConfigMapImproved(ctx, client, recorder, t, cache)
+	case *corev1.Secret:
+		client := clients.secretsGetter()
+		if client == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplySecretImproved(ctx, client, recorder, t, cache)
+	case *rbacv1.ClusterRole:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyClusterRole(ctx, clients.kubeClient.RbacV1(), recorder, t)
+	case *rbacv1.ClusterRoleBinding:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyClusterRoleBinding(ctx, clients.kubeClient.RbacV1(), recorder, t)
+	case *rbacv1.Role:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyRole(ctx, clients.kubeClient.RbacV1(), recorder, t)
+	case *rbacv1.RoleBinding:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyRoleBinding(ctx, clients.kubeClient.RbacV1(), recorder, t)
+	case *policyv1.PodDisruptionBudget:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyPodDisruptionBudget(ctx, clients.kubeClient.PolicyV1(), recorder, t)
+	case *apiextensionsv1.CustomResourceDefinition:
+		if clients.apiExtensionsClient == nil {
+			return nil, false, fmt.Errorf("missing apiExtensionsClient")
+		}
+		return ApplyCustomResourceDefinitionV1(ctx, clients.apiExtensionsClient.ApiextensionsV1(), recorder, t)
+	case *storagev1.StorageClass:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyStorageClass(ctx, clients.kubeClient.StorageV1(), recorder, t)
+	case *admissionregistrationv1.ValidatingWebhookConfiguration:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyValidatingWebhookConfigurationImproved(ctx, clients.kubeClient.AdmissionregistrationV1(), recorder, t, cache)
+	case *admissionregistrationv1.MutatingWebhookConfiguration:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyMutatingWebhookConfigurationImproved(ctx, clients.kubeClient.AdmissionregistrationV1(), recorder, t, cache)
+	case *storagev1.CSIDriver:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyCSIDriver(ctx, clients.kubeClient.StorageV1(), recorder, t)
+	case *migrationv1alpha1.StorageVersionMigration:
+		if clients.migrationClient == nil {
+			return nil, false, fmt.Errorf("missing migrationClient")
+		}
+		return ApplyStorageVersionMigration(ctx, clients.migrationClient, recorder, t)
+	case *appsv1.Deployment:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		if clients.generations == nil {
+			return ApplyDeployment(ctx, clients.kubeClient.AppsV1(), recorder, t, -1)
+		}
+		actual, changed, err := ApplyDeployment(ctx, clients.kubeClient.AppsV1(), recorder, t, resourcemerge.ExpectedDeploymentGeneration(t, *clients.generations))
+		if err == nil {
+			resourcemerge.SetDeploymentGeneration(clients.generations, actual)
+		}
+		return actual, changed, err
+	case *appsv1.DaemonSet:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		if clients.generations == nil {
+			return ApplyDaemonSet(ctx, clients.kubeClient.AppsV1(), recorder, t, -1)
+		}
+		actual, changed, err := ApplyDaemonSet(ctx, clients.kubeClient.AppsV1(), recorder, t, resourcemerge.ExpectedDaemonSetGeneration(t, *clients.generations))
+		if err == nil {
+			resourcemerge.SetDaemonSetGeneration(clients.generations, actual)
+		}
+		return actual, changed, err
+	case *networkingv1.NetworkPolicy:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyNetworkPolicy(ctx, clients.kubeClient.NetworkingV1(), recorder, t)
+	case *batchv1.Job:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyJob(ctx, clients.kubeClient.BatchV1(), recorder, t)
+	case *batchv1.CronJob:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyCronJob(ctx, clients.kubeClient.BatchV1(), recorder, t)
+	case *schedulingv1.PriorityClass:
+		if clients.kubeClient == nil {
+			return nil, false, fmt.Errorf("missing kubeClient")
+		}
+		return ApplyPriorityClass(ctx, clients.kubeClient.SchedulingV1(), recorder, t)
+	case *apiregistrationv1.APIService:
+		if clients.apiRegistrationClient == nil {
+			return nil, false, fmt.Errorf("missing apiRegistrationClient")
+		}
+		return ApplyAPIService(ctx, clients.apiRegistrationClient.ApiregistrationV1(), recorder, t)
+	case *unstructured.Unstructured:
+		if clients.dynamicClient == nil {
+			return nil, false, fmt.Errorf("missing dynamicClient")
+		}
+		return ApplyKnownUnstructured(ctx, clients.dynamicClient, recorder, t)
+	default:
+		return nil, false, fmt.Errorf("unhandled type %T", requiredObj)
+	}
+}
+
 func DeleteAll(ctx context.Context, clients *ClientHolder, recorder events.Recorder, manifests AssetFunc,
 	files ...string) []ApplyResult {
 	ret := []ApplyResult{}
@@ -240,6 +323,14 @@ func DeleteAll(ctx context.Context, clients *ClientHolder, recorder events.Recor
 			continue
 		}
 		result.Type = fmt.Sprintf("%T", requiredObj)
+
+		if clients.dryRun {
+			result.Result, result.DryRunAction, result.Error = dryRunDelete(ctx, clients, requiredObj)
+			result.Changed = result.Error == nil && result.DryRunAction != DryRunNone
+			ret = append(ret, result)
+			continue
+		}
+
 		// NOTE: Do not add CR resources into this switch otherwise the protobuf client can cause problems.
 		switch t := requiredObj.(type) {
 		case *corev1.Namespace:
@@ -334,6 +425,48 @@ func DeleteAll(ctx context.Context, clients *ClientHolder, recorder events.Recor
 			} else {
 				_, result.Changed, result.Error = DeleteStorageVersionMigration(ctx, clients.migrationClient, recorder, t)
 			}
+		case *appsv1.Deployment:
+			if clients.kubeClient == nil {
+				result.Error = fmt.Errorf("missing kubeClient")
+			} else {
+				_, result.Changed, result.Error = DeleteDeployment(ctx, clients.kubeClient.AppsV1(), recorder, t)
+			}
+		case *appsv1.DaemonSet:
+			if clients.kubeClient == nil {
+				result.Error = fmt.Errorf("missing kubeClient")
+			} else {
+				_, result.Changed, result.Error = DeleteDaemonSet(ctx, clients.kubeClient.AppsV1(), recorder, t)
+			}
+		case *networkingv1.NetworkPolicy:
+			if clients.kubeClient == nil {
+				result.Error = fmt.Errorf("missing kubeClient")
+			} else {
+				_, result.Changed, result.Error = DeleteNetworkPolicy(ctx, clients.kubeClient.NetworkingV1(), recorder, t)
+			}
+		case *batchv1.Job:
+			if clients.kubeClient == nil {
+				result.Error = fmt.Errorf("missing kubeClient")
+			} else {
+				_, result.Changed, result.Error = DeleteJob(ctx, clients.kubeClient.BatchV1(), recorder, t)
+			}
+		case *batchv1.CronJob:
+			if clients.kubeClient == nil {
+				result.Error = fmt.Errorf("missing kubeClient")
+			} else {
+				_, result.Changed, result.Error = DeleteCronJob(ctx, clients.kubeClient.BatchV1(), recorder, t)
+			}
+		case *schedulingv1.PriorityClass:
+			if clients.kubeClient == nil {
+				result.Error = fmt.Errorf("missing kubeClient")
+			} else {
+				_, result.Changed, result.Error = DeletePriorityClass(ctx, clients.kubeClient.SchedulingV1(), recorder, t)
+			}
+		case *apiregistrationv1.APIService:
+			if clients.apiRegistrationClient == nil {
+				result.Error = fmt.Errorf("missing apiRegistrationClient")
+			} else {
+				_, result.Changed, result.Error = DeleteAPIService(ctx, clients.apiRegistrationClient.ApiregistrationV1(), recorder, t)
+			}
 		case *unstructured.Unstructured:
 			if clients.dynamicClient == nil {
 				result.Error = fmt.Errorf("missing dynamicClient")
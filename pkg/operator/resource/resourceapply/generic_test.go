@@ -4,9 +4,13 @@ import (
 	"context"
 	"testing"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes/fake"
 
+	operatorv1 "github.com/openshift/api/operator/v1"
+
 	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
 )
 
 func TestApplyDirectlyUnhandledType(t *testing.T) {
@@ -28,3 +32,92 @@ metadata:
 		t.Fatal(ret[0].Error)
 	}
 }
+
+func TestApplyDirectlyDeploymentGenerations(t *testing.T) {
+	content := func(name string) ([]byte, error) {
+		return []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: operand
+  namespace: operand-ns
+spec:
+  template:
+    spec:
+      containers:
+      - name: operand
+        image: quay.io/openshift/operand:latest
+`), nil
+	}
+
+	fakeClient := fake.NewSimpleClientset()
+	generations := []operatorv1.GenerationStatus{}
+	clients := NewKubeClientHolder(fakeClient).WithGenerations(&generations)
+
+	for i, expectedChanged := range []bool{true, false} {
+		ret := ApplyDirectly(context.TODO(), clients, events.NewInMemoryRecorder(""), nil, content, "deployment")
+		if ret[0].Error != nil {
+			t.Fatal(ret[0].Error)
+		}
+		if ret[0].Changed != expectedChanged {
+			t.Errorf("apply %d: expected changed %v, got %v", i, expectedChanged, ret[0].Changed)
+		}
+	}
+	if generation := resourcemerge.GenerationFor(generations, schema.GroupResource{Group: "apps", Resource: "deployments"}, "operand-ns", "operand"); generation == nil {
+		t.Errorf("expected deployment generation to be tracked, got %v", generations)
+	}
+}
+
+func TestApplyDirectlyAndDeleteAllNewTypes(t *testing.T) {
+	manifests := map[string]string{
+		"networkpolicy": `apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: deny-all
+  namespace: operand-ns
+spec:
+  podSelector: {}
+`,
+		"cronjob": `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: pruner
+  namespace: operand-ns
+spec:
+  schedule: "0 * * * *"
+`,
+		"priorityclass": `apiVersion: scheduling.k8s.io/v1
+kind: PriorityClass
+metadata:
+  name: operand-critical
+value: 1000
+`,
+		"daemonset": `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: operand
+  namespace: operand-ns
+spec:
+  template:
+    spec:
+      containers:
+      - name: operand
+        image: quay.io/openshift/operand:latest
+`,
+	}
+	content := func(name string) ([]byte, error) {
+		return []byte(manifests[name]), nil
+	}
+	files := []string{"networkpolicy", "cronjob", "priorityclass", "daemonset"}
+
+	fakeClient := fake.NewSimpleClientset()
+	for _, result := range ApplyDirectly(context.TODO(), NewKubeClientHolder(fakeClient), events.NewInMemoryRecorder(""), nil, content, files...) {
+		if result.Error != nil || !result.Changed {
+			t.Errorf("%s: expected to be created, got changed=%v error=%v", result.File, result.Changed, result.Error)
+		}
+	}
+	for _, result := range DeleteAll(context.TODO(), NewKubeClientHolder(fakeClient), events.NewInMemoryRecorder(""), content, files...) {
+		if result.Error != nil || !result.Changed {
+			t.Errorf("%s: expected to be deleted, got changed=%v error=%v", result.File, result.Changed, result.Error)
+		}
+	}
+}
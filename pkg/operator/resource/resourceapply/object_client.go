@@ -0,0 +1,162 @@
+package resourceapply
+
+import (
+	"context"
+	"fmt"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+	migrationv1alpha1 "sigs.k8s.io/kube-storage-version-migrator/pkg/apis/migration/v1alpha1"
+)
+
+// objectClientFor returns a client for the resource of the required object.
+func (c *ClientHolder) objectClientFor(required runtime.Object) (objectClient, error) {
+	// NOTE: Keep the supported types in sync with ApplyDirectly.
+	switch t := required.(type) {
+	case *unstructured.Unstructured:
+		if c.dynamicClient == nil {
+			return nil, fmt.Errorf("missing dynamicClient")
+		}
+		gvr, ok := knownUnstructuredGVRs[t.GroupVersionKind().GroupKind()]
+		if !ok {
+			return nil, fmt.Errorf("unsupported object type: %s", t.GetKind())
+		}
+		var client dynamic.ResourceInterface = c.dynamicClient.Resource(gvr)
+		if len(t.GetNamespace()) > 0 {
+			client = c.dynamicClient.Resource(gvr).Namespace(t.GetNamespace())
+		}
+		return newObjectClient[*unstructured.Unstructured](dynamicApplyClient{client}), nil
+	case *apiextensionsv1.CustomResourceDefinition:
+		if c.apiExtensionsClient == nil {
+			return nil, fmt.Errorf("missing apiExtensionsClient")
+		}
+		return newObjectClient[*apiextensionsv1.CustomResourceDefinition](c.apiExtensionsClient.ApiextensionsV1().CustomResourceDefinitions()), nil
+	case *apiregistrationv1.APIService:
+		if c.apiRegistrationClient == nil {
+			return nil, fmt.Errorf("missing apiRegistrationClient")
+		}
+		return newObjectClient[*apiregistrationv1.APIService](c.apiRegistrationClient.ApiregistrationV1().APIServices()), nil
+	case *migrationv1alpha1.StorageVersionMigration:
+		if c.migrationClient == nil {
+			return nil, fmt.Errorf("missing migrationClient")
+		}
+		return newObjectClient[*migrationv1alpha1.StorageVersionMigration](c.migrationClient.MigrationV1alpha1().StorageVersionMigrations()), nil
+	}
+
+	if c.kubeClient == nil {
+		return nil, fmt.Errorf("missing kubeClient")
+	}
+	switch t := required.(type) {
+	case *corev1.Namespace:
+		return newObjectClient[*corev1.Namespace](c.kubeClient.CoreV1().Namespaces()), nil
+	case *corev1.Service:
+		return newObjectClient[*corev1.Service](c.kubeClient.CoreV1().Services(t.Namespace)), nil
+	case *corev1.Pod:
+		return newObjectClient[*corev1.Pod](c.kubeClient.CoreV1().Pods(t.Namespace)), nil
+	case *corev1.ServiceAccount:
+		return newObjectClient[*corev1.ServiceAccount](c.kubeClient.CoreV1().ServiceAccounts(t.Namespace)), nil
+	case *corev1.ConfigMap:
+		return newObjectClient[*corev1.ConfigMap](c.kubeClient.CoreV1().ConfigMaps(t.Namespace)), nil
+	case *corev1.Secret:
+		return newObjectClient[*corev1.Secret](c.kubeClient.CoreV1().Secrets(t.Namespace)), nil
+	case *rbacv1.ClusterRole:
+		return newObjectClient[*rbacv1.ClusterRole](c.kubeClient.RbacV1().ClusterRoles()), nil
+	case *rbacv1.ClusterRoleBinding:
+		return newObjectClient[*rbacv1.ClusterRoleBinding](c.kubeClient.RbacV1().ClusterRoleBindings()), nil
+	case *rbacv1.Role:
+		return newObjectClient[*rbacv1.Role](c.kubeClient.RbacV1().Roles(t.Namespace)), nil
+	case *rbacv1.RoleBinding:
+		return newObjectClient[*rbacv1.RoleBinding](c.kubeClient.RbacV1().RoleBindings(t.Namespace)), nil
+	case *policyv1.PodDisruptionBudget:
+		return newObjectClient[*policyv1.PodDisruptionBudget](c.kubeClient.PolicyV1().PodDisruptionBudgets(t.Namespace)), nil
+	case *appsv1.Deployment:
+		return newObjectClient[*appsv1.Deployment](c.kubeClient.AppsV1().Deployments(t.Namespace)), nil
+	case *appsv1.DaemonSet:
+		return newObjectClient[*appsv1.DaemonSet](c.kubeClient.AppsV1().DaemonSets(t.Namespace)), nil
+	case *networkingv1.NetworkPolicy:
+		return newObjectClient[*networkingv1.NetworkPolicy](c.kubeClient.NetworkingV1().NetworkPolicies(t.Namespace)), nil
+	case *batchv1.Job:
+		return newObjectClient[*batchv1.Job](c.kubeClient.BatchV1().Jobs(t.Namespace)), nil
+	case *batchv1.CronJob:
+		return newObjectClient[*batchv1.CronJob](c.kubeClient.BatchV1().CronJobs(t.Namespace)), nil
+	case *schedulingv1.PriorityClass:
+		return newObjectClient[*schedulingv1.PriorityClass](c.kubeClient.SchedulingV1().PriorityClasses()), nil
+	case *storagev1.StorageClass:
+		return newObjectClient[*storagev1.StorageClass](c.kubeClient.StorageV1().StorageClasses()), nil
+	case *storagev1.CSIDriver:
+		return newObjectClient[*storagev1.CSIDriver](c.kubeClient.StorageV1().CSIDrivers()), nil
+	case *admissionregistrationv1.ValidatingWebhookConfiguration:
+		return newObjectClient[*admissionregistrationv1.ValidatingWebhookConfiguration](c.kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations()), nil
+	case *admissionregistrationv1.MutatingWebhookConfiguration:
+		return newObjectClient[*admissionregistrationv1.MutatingWebhookConfiguration](c.kubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations()), nil
+	}
+
+	return nil, fmt.Errorf("unhandled type %T", required)
+}
+
+// knownUnstructuredGVRs lists the unstructured kinds ApplyKnownUnstructured knows how to apply.
+var knownUnstructuredGVRs = map[schema.GroupKind]schema.GroupVersionResource{
+	{Group: "monitoring.coreos.com", Kind: "ServiceMonitor"}:        serviceMonitorGVR,
+	{Group: "monitoring.coreos.com", Kind: "PrometheusRule"}:        prometheusRuleGVR,
+	{Group: "snapshot.storage.k8s.io", Kind: "VolumeSnapshotClass"}: volumeSnapshotClassResourceGVR,
+}
+
+// typedClient is implemented by every typed client of a single resource.
+type typedClient[T runtime.Object] interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (T, error)
+}
+
+// objectClient gets and patches objects of a single resource without knowing their type.
+type objectClient interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (runtime.Object, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions) (runtime.Object, error)
+}
+
+type typedObjectClient[T runtime.Object] struct {
+	client typedClient[T]
+}
+
+func newObjectClient[T runtime.Object](client typedClient[T]) objectClient {
+	return typedObjectClient[T]{client: client}
+}
+
+func (c typedObjectClient[T]) Get(ctx context.Context, name string, opts metav1.GetOptions) (runtime.Object, error) {
+	obj, err := c.client.Get(ctx, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (c typedObjectClient[T]) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions) (runtime.Object, error) {
+	obj, err := c.client.Patch(ctx, name, pt, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// dynamicApplyClient adapts the dynamic client Get, which takes subresources, to typedClient.
+type dynamicApplyClient struct {
+	dynamic.ResourceInterface
+}
+
+func (c dynamicApplyClient) Get(ctx context.Context, name string, opts metav1.GetOptions) (*unstructured.Unstructured, error) {
+	return c.ResourceInterface.Get(ctx, name, opts)
+}
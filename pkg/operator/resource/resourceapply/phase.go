@@ -0,0 +1,99 @@
+package resourceapply
+
+import (
+	"context"
+	"fmt"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+)
+
+// ApplyPhase groups kinds that can be applied together. Phases only bucket objects by their kind, they do not model
+// dependencies between individual objects: all objects of a lower phase should be applied, and be ready, before any
+// object of a higher phase is applied. Deletion goes in the opposite order.
+type ApplyPhase int
+
+const (
+	// NamespacePhase creates the namespaces all other namespaced objects live in.
+	NamespacePhase ApplyPhase = iota
+	// CustomResourceDefinitionPhase creates the types custom resources need.
+	CustomResourceDefinitionPhase
+	// ServiceAccountPhase creates the identities roles are bound to.
+	ServiceAccountPhase
+	// RolePhase creates the roles and cluster roles bindings refer to.
+	RolePhase
+	// RoleBindingPhase grants the roles to their subjects.
+	RoleBindingPhase
+	// ConfigurationPhase creates objects consumed by workloads, such as config maps, secrets and storage classes.
+	ConfigurationPhase
+	// WorkloadPhase creates services, workloads and every other kind that is not explicitly ordered.
+	WorkloadPhase
+	// CustomResourcePhase creates instances of custom resources.
+	CustomResourcePhase
+	// WebhookPhase registers admission webhooks and aggregated APIs, which must not intercept requests before their
+	// backing workloads exist.
+	WebhookPhase
+)
+
+// ApplyPhaseFor returns the phase the object should be applied in.
+func ApplyPhaseFor(obj runtime.Object) ApplyPhase {
+	switch obj.(type) {
+	case *corev1.Namespace:
+		return NamespacePhase
+	case *apiextensionsv1.CustomResourceDefinition:
+		return CustomResourceDefinitionPhase
+	case *corev1.ServiceAccount:
+		return ServiceAccountPhase
+	case *rbacv1.ClusterRole, *rbacv1.Role:
+		return RolePhase
+	case *rbacv1.ClusterRoleBinding, *rbacv1.RoleBinding:
+		return RoleBindingPhase
+	case *corev1.ConfigMap, *corev1.Secret, *storagev1.StorageClass, *storagev1.CSIDriver, *schedulingv1.PriorityClass:
+		return ConfigurationPhase
+	case *unstructured.Unstructured:
+		return CustomResourcePhase
+	case *admissionregistrationv1.ValidatingWebhookConfiguration, *admissionregistrationv1.MutatingWebhookConfiguration, *apiregistrationv1.APIService:
+		return WebhookPhase
+	default:
+		return WorkloadPhase
+	}
+}
+
+// IsReady checks once whether an applied object can be depended on by objects of later phases. Only custom resource
+// definitions have a readiness signal, they are ready once they are Established. All other objects are always ready.
+func IsReady(ctx context.Context, clients *ClientHolder, obj runtime.Object) (bool, error) {
+	crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition)
+	if !ok {
+		return true, nil
+	}
+	if clients.apiExtensionsClient == nil {
+		return false, fmt.Errorf("missing apiExtensionsClient")
+	}
+
+	existing, err := clients.apiExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, crd.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return isCustomResourceDefinitionEstablished(existing), nil
+}
+
+func isCustomResourceDefinitionEstablished(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	for _, condition := range crd.Status.Conditions {
+		if condition.Type == apiextensionsv1.Established {
+			return condition.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+	return false
+}
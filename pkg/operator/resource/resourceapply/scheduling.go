@@ -0,0 +1,88 @@
+package resourceapply
+
+import (
+	"context"
+
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	schedulingclientv1 "k8s.io/client-go/kubernetes/typed/scheduling/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
+)
+
+// ApplyPriorityClass merges objectmeta and requires value, preemption policy, description and global default.
+// Value and preemption policy are immutable, a priority class which differs in those is deleted and created again.
+func ApplyPriorityClass(ctx context.Context, client schedulingclientv1.PriorityClassesGetter, recorder events.Recorder, required *schedulingv1.PriorityClass) (*schedulingv1.PriorityClass, bool, error) {
+	existing, err := client.PriorityClasses().Get(ctx, required.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		requiredCopy := required.DeepCopy()
+		actual, err := client.PriorityClasses().Create(
+			ctx, resourcemerge.WithCleanLabelsAndAnnotations(requiredCopy).(*schedulingv1.PriorityClass), metav1.CreateOptions{})
+		reportCreateEvent(recorder, required, err)
+		return actual, true, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if priorityClassNeedsRecreate(existing, required) {
+		klog.V(2).Infof("PriorityClass %q value or preemption policy changed, deleting it to create it again", required.Name)
+		err := client.PriorityClasses().Delete(ctx, required.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			reportDeleteEvent(recorder, required, err)
+			return nil, false, err
+		}
+		requiredCopy := required.DeepCopy()
+		actual, err := client.PriorityClasses().Create(
+			ctx, resourcemerge.WithCleanLabelsAndAnnotations(requiredCopy).(*schedulingv1.PriorityClass), metav1.CreateOptions{})
+		reportCreateEvent(recorder, required, err)
+		return actual, true, err
+	}
+
+	modified := resourcemerge.BoolPtr(false)
+	existingCopy := existing.DeepCopy()
+
+	resourcemerge.EnsureObjectMeta(modified, &existingCopy.ObjectMeta, required.ObjectMeta)
+	contentSame := existingCopy.Description == required.Description && existingCopy.GlobalDefault == required.GlobalDefault
+	if contentSame && !*modified {
+		return existingCopy, false, nil
+	}
+
+	existingCopy.Description = required.Description
+	existingCopy.GlobalDefault = required.GlobalDefault
+
+	if klog.V(4).Enabled() {
+		klog.Infof("PriorityClass %q changes: %v", required.Name, JSONPatchNoError(existing, existingCopy))
+	}
+
+	actual, err := client.PriorityClasses().Update(ctx, existingCopy, metav1.UpdateOptions{})
+	reportUpdateEvent(recorder, required, err)
+	return actual, true, err
+}
+
+func priorityClassNeedsRecreate(existing, required *schedulingv1.PriorityClass) bool {
+	if existing.Value != required.Value {
+		return true
+	}
+	// the preemption policy is defaulted by the API server, only an explicitly required one is compared
+	if required.PreemptionPolicy != nil && !equality.Semantic.DeepEqual(existing.PreemptionPolicy, required.PreemptionPolicy) {
+		return true
+	}
+	return false
+}
+
+func DeletePriorityClass(ctx context.Context, client schedulingclientv1.PriorityClassesGetter, recorder events.Recorder, required *schedulingv1.PriorityClass) (*schedulingv1.PriorityClass, bool, error) {
+	err := client.PriorityClasses().Delete(ctx, required.Name, metav1.DeleteOptions{})
+	if err != nil && apierrors.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	reportDeleteEvent(recorder, required, err)
+	return nil, true, nil
+}
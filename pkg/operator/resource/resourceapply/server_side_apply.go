@@ -0,0 +1,185 @@
+package resourceapply
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcehelper"
+)
+
+// ServerSideApplyOptions configures applying manifests with server-side apply patches instead of the
+// client-side get/merge/update logic of the typed Apply* functions.
+type ServerSideApplyOptions struct {
+	// FieldManager is recorded in the managedFields of every applied object. It is required.
+	FieldManager string
+	// Force takes over ownership of fields that are currently managed by a different field manager
+	// instead of failing the apply with a conflict.
+	Force bool
+}
+
+// WithServerSideApply makes ApplyDirectly apply every manifest using server-side apply with the given options.
+func (c *ClientHolder) WithServerSideApply(options ServerSideApplyOptions) *ClientHolder {
+	c.serverSideApply = &options
+	return c
+}
+
+// ApplyDirectlyServerSide applies the given manifest files to API server using server-side apply, regardless of
+// whether the clients were configured with WithServerSideApply.
+func ApplyDirectlyServerSide(ctx context.Context, clients *ClientHolder, recorder events.Recorder, cache ResourceCache, options ServerSideApplyOptions, manifests AssetFunc, files ...string) []ApplyResult {
+	clientsCopy := *clients
+	clientsCopy.serverSideApply = &options
+	return ApplyDirectly(ctx, &clientsCopy, recorder, cache, manifests, files...)
+}
+
+// ApplyObjectServerSide sends the required object as a server-side apply patch owned by options.FieldManager.
+// The field manager takes ownership of every field set in required, so typed objects, which carry zero values of
+// fields without omitempty, should only be used for types where that is intended. Prefer unstructured objects
+// holding only the fields the caller manages.
+//
+// It reports the same create and update events as the client-side Apply* functions. The object is read before the
+// apply: it is considered created when it did not exist and changed when the apply changed its resourceVersion. The
+// apply is skipped when cache recorded that the existing object is the result of the last apply of the same required
+// object.
+func ApplyObjectServerSide(ctx context.Context, clients *ClientHolder, recorder events.Recorder, cache ResourceCache, required runtime.Object, options ServerSideApplyOptions) (runtime.Object, bool, error) {
+	applyObj, err := toApplyObject(required)
+	if err != nil {
+		return nil, false, err
+	}
+	return applyObjectServerSide(ctx, clients, recorder, cache, required, applyObj, options)
+}
+
+// applyManifestServerSide applies the fields set in the manifest, rather than the fields of the decoded required
+// object, so that the field manager does not take ownership of zero values and defaults.
+func applyManifestServerSide(ctx context.Context, clients *ClientHolder, recorder events.Recorder, cache ResourceCache, required runtime.Object, manifest []byte, options ServerSideApplyOptions) (runtime.Object, bool, error) {
+	applyObj, err := toManifestApplyObject(manifest)
+	if err != nil {
+		return nil, false, err
+	}
+	return applyObjectServerSide(ctx, clients, recorder, cache, required, applyObj, options)
+}
+
+func applyObjectServerSide(ctx context.Context, clients *ClientHolder, recorder events.Recorder, cache ResourceCache, required runtime.Object, applyObj *unstructured.Unstructured, options ServerSideApplyOptions) (runtime.Object, bool, error) {
+	if len(options.FieldManager) == 0 {
+		return nil, false, fmt.Errorf("missing field manager for server-side apply")
+	}
+	client, err := clients.objectClientFor(required)
+	if err != nil {
+		return nil, false, err
+	}
+	return serverSideApply(ctx, client, recorder, cache, required, applyObj, options)
+}
+
+func serverSideApply(ctx context.Context, client objectClient, recorder events.Recorder, cache ResourceCache, required runtime.Object, applyObj *unstructured.Unstructured, options ServerSideApplyOptions) (runtime.Object, bool, error) {
+	if cache == nil {
+		cache = noCache
+	}
+	existing, err := client.Get(ctx, applyObj.GetName(), metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, false, err
+	}
+	if existing != nil && cache.SafeToSkipApply(required, existing) {
+		return existing, false, nil
+	}
+
+	patch, err := applyObj.MarshalJSON()
+	if err != nil {
+		return nil, false, err
+	}
+	patchOptions := metav1.PatchOptions{FieldManager: options.FieldManager, Force: &options.Force}
+	actual, err := client.Patch(ctx, applyObj.GetName(), types.ApplyPatchType, patch, patchOptions)
+	if existing == nil {
+		reportCreateEvent(recorder, required, err)
+		if err != nil {
+			return nil, false, err
+		}
+		cache.UpdateCachedResourceMetadata(required, actual)
+		return actual, true, nil
+	}
+	if err != nil {
+		reportUpdateEvent(recorder, required, err)
+		return nil, false, err
+	}
+	cache.UpdateCachedResourceMetadata(required, actual)
+
+	existingMeta, err := meta.Accessor(existing)
+	if err != nil {
+		return nil, false, err
+	}
+	actualMeta, err := meta.Accessor(actual)
+	if err != nil {
+		return nil, false, err
+	}
+	// the API server does not write the object if the apply does not change it
+	if existingMeta.GetResourceVersion() == actualMeta.GetResourceVersion() {
+		return actual, false, nil
+	}
+	reportUpdateEvent(recorder, required, nil)
+	return actual, true, nil
+}
+
+// toManifestApplyObject converts the manifest into the unstructured form sent as an apply patch.
+func toManifestApplyObject(manifest []byte) (*unstructured.Unstructured, error) {
+	manifestJSON, err := kyaml.ToJSON(manifest)
+	if err != nil {
+		return nil, err
+	}
+	applyObj := &unstructured.Unstructured{}
+	if err := applyObj.UnmarshalJSON(manifestJSON); err != nil {
+		return nil, err
+	}
+	return stripServerPopulatedFields(applyObj), nil
+}
+
+// toApplyObject converts required into the unstructured form sent as an apply patch.
+func toApplyObject(required runtime.Object) (*unstructured.Unstructured, error) {
+	gvk := resourcehelper.GuessObjectGroupVersionKind(required)
+	if gvk.Kind == "<unknown>" {
+		return nil, fmt.Errorf("cannot determine kind of %T", required)
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(required)
+	if err != nil {
+		return nil, err
+	}
+	applyObj := &unstructured.Unstructured{Object: removeNulls(content).(map[string]interface{})}
+	applyObj.SetGroupVersionKind(gvk)
+	return stripServerPopulatedFields(applyObj), nil
+}
+
+// stripServerPopulatedFields removes the status and the metadata set by the API server, which apply patches must
+// not claim.
+func stripServerPopulatedFields(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	delete(obj.Object, "status")
+	for _, field := range []string{"creationTimestamp", "resourceVersion", "uid", "generation", "managedFields", "selfLink"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	return obj
+}
+
+// removeNulls drops null values, like the creationTimestamp of pod templates, which would otherwise be applied
+// as owned fields.
+func removeNulls(value interface{}) interface{} {
+	switch t := value.(type) {
+	case map[string]interface{}:
+		for k, v := range t {
+			if v == nil {
+				delete(t, k)
+				continue
+			}
+			t[k] = removeNulls(v)
+		}
+	case []interface{}:
+		for i, v := range t {
+			t[i] = removeNulls(v)
+		}
+	}
+	return value
+}
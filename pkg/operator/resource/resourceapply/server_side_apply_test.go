@@ -0,0 +1,243 @@
+package resourceapply
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+)
+
+func TestApplyDirectlyServerSide(t *testing.T) {
+	manifestBytes := []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+  namespace: one-ns
+data:
+  key: value
+`)
+	manifest := func(name string) ([]byte, error) {
+		return manifestBytes, nil
+	}
+	created := metav1.NewTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	later := metav1.NewTime(created.Add(time.Hour))
+	existing := func(resourceVersion string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "one-ns", Name: "foo", ResourceVersion: resourceVersion, CreationTimestamp: created},
+			Data:       map[string]string{"key": "old-value"},
+		}
+	}
+
+	tests := []struct {
+		name              string
+		existing          *corev1.ConfigMap
+		cached            bool
+		patchedRV         string
+		applyTime         metav1.Time
+		expectedPatch     bool
+		expectedChanged   bool
+		expectedEventType string
+	}{
+		{
+			name:              "create",
+			patchedRV:         "1",
+			applyTime:         created,
+			expectedPatch:     true,
+			expectedChanged:   true,
+			expectedEventType: "ConfigMapCreated",
+		},
+		{
+			name:              "update",
+			existing:          existing("1"),
+			patchedRV:         "2",
+			applyTime:         later,
+			expectedPatch:     true,
+			expectedChanged:   true,
+			expectedEventType: "ConfigMapUpdated",
+		},
+		{
+			name:          "no change",
+			existing:      existing("1"),
+			patchedRV:     "1",
+			applyTime:     later,
+			expectedPatch: true,
+		},
+		{
+			// the managed fields of an object created by the field manager keep the creation time until its fields change
+			name:          "no change of an object created by the field manager",
+			existing:      existing("1"),
+			patchedRV:     "1",
+			applyTime:     created,
+			expectedPatch: true,
+		},
+		{
+			name:      "unchanged since the last apply",
+			existing:  existing("1"),
+			cached:    true,
+			patchedRV: "1",
+			applyTime: created,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			if test.existing != nil {
+				client = fake.NewSimpleClientset(test.existing)
+			}
+			client.PrependReactor("patch", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				patched := &corev1.ConfigMap{}
+				if err := json.Unmarshal(action.(clienttesting.PatchAction).GetPatch(), patched); err != nil {
+					return true, nil, err
+				}
+				patched.ResourceVersion = test.patchedRV
+				patched.CreationTimestamp = created
+				patched.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "test-operator", Operation: metav1.ManagedFieldsOperationApply, Time: &test.applyTime}}
+				return true, patched, nil
+			})
+			recorder := events.NewInMemoryRecorder("")
+			cache := NewResourceCache()
+			if test.cached {
+				// a previous apply of the same manifest produced the existing object
+				cache.UpdateCachedResourceMetadata(resourceread.ReadGenericWithUnstructuredOrDie(manifestBytes), test.existing)
+			}
+			clients := NewKubeClientHolder(client).WithServerSideApply(ServerSideApplyOptions{FieldManager: "test-operator", Force: true})
+
+			results := ApplyDirectly(context.TODO(), clients, recorder, cache, manifest, "cm.yaml")
+			if results[0].Error != nil {
+				t.Fatal(results[0].Error)
+			}
+			if results[0].Changed != test.expectedChanged {
+				t.Errorf("expected changed %v, got %v", test.expectedChanged, results[0].Changed)
+			}
+
+			actions := client.Actions()
+			if !test.expectedPatch {
+				if len(actions) != 1 || !actions[0].Matches("get", "configmaps") {
+					t.Fatal(spew.Sdump(actions))
+				}
+			} else {
+				if len(actions) != 2 || !actions[0].Matches("get", "configmaps") || !actions[1].Matches("patch", "configmaps") {
+					t.Fatal(spew.Sdump(actions))
+				}
+				patchAction := actions[1].(clienttesting.PatchActionImpl)
+				if patchAction.GetPatchType() != types.ApplyPatchType {
+					t.Errorf("expected apply patch, got %v", patchAction.GetPatchType())
+				}
+				patched := map[string]interface{}{}
+				if err := json.Unmarshal(patchAction.GetPatch(), &patched); err != nil {
+					t.Fatal(err)
+				}
+				expectedPatch := map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "ConfigMap",
+					"metadata":   map[string]interface{}{"name": "foo", "namespace": "one-ns"},
+					"data":       map[string]interface{}{"key": "value"},
+				}
+				if !equality.Semantic.DeepEqual(expectedPatch, patched) {
+					t.Errorf("expected apply patch to contain only the manifest fields, got %q", string(patchAction.GetPatch()))
+				}
+			}
+
+			recordedEvents := recorder.Events()
+			switch {
+			case len(test.expectedEventType) == 0 && len(recordedEvents) != 0:
+				t.Errorf("expected no events, got %v", spew.Sdump(recordedEvents))
+			case len(test.expectedEventType) > 0 && (len(recordedEvents) != 1 || recordedEvents[0].Reason != test.expectedEventType):
+				t.Errorf("expected %s event, got %v", test.expectedEventType, spew.Sdump(recordedEvents))
+			}
+		})
+	}
+}
+
+func TestApplyObjectServerSideOmitsServerPopulatedFields(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("patch", "deployments", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "one-ns", Name: "foo", ResourceVersion: "1"}}, nil
+	})
+	required := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "one-ns", Name: "foo"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "c", Image: "image"}}}},
+		},
+	}
+	_, _, err := ApplyObjectServerSide(context.TODO(), NewKubeClientHolder(client), events.NewInMemoryRecorder(""), nil, required, ServerSideApplyOptions{FieldManager: "test-operator"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patched := &unstructured.Unstructured{}
+	if err := patched.UnmarshalJSON(client.Actions()[1].(clienttesting.PatchActionImpl).GetPatch()); err != nil {
+		t.Fatal(err)
+	}
+	if patched.GetAPIVersion() != "apps/v1" || patched.GetKind() != "Deployment" {
+		t.Errorf("expected apply patch to carry apiVersion and kind, got %v", patched.Object)
+	}
+	for _, field := range [][]string{{"status"}, {"metadata", "creationTimestamp"}, {"spec", "template", "metadata", "creationTimestamp"}} {
+		if _, found, _ := unstructured.NestedFieldNoCopy(patched.Object, field...); found {
+			t.Errorf("expected apply patch not to contain %v, got %v", field, patched.Object)
+		}
+	}
+}
+
+func TestApplyObjectServerSideRequiresFieldManager(t *testing.T) {
+	_, _, err := ApplyObjectServerSide(context.TODO(), NewKubeClientHolder(fake.NewSimpleClientset()), events.NewInMemoryRecorder(""), nil, &corev1.ConfigMap{}, ServerSideApplyOptions{})
+	if err == nil {
+		t.Fatal("expected missing field manager error")
+	}
+}
+
+type recordingApplyClient struct {
+	existing     *corev1.ConfigMap
+	patchOptions metav1.PatchOptions
+}
+
+func (c *recordingApplyClient) Get(_ context.Context, name string, _ metav1.GetOptions) (*corev1.ConfigMap, error) {
+	if c.existing == nil {
+		return nil, apierrors.NewNotFound(corev1.Resource("configmaps"), name)
+	}
+	return c.existing, nil
+}
+
+func (c *recordingApplyClient) Patch(_ context.Context, _ string, _ types.PatchType, data []byte, opts metav1.PatchOptions, _ ...string) (*corev1.ConfigMap, error) {
+	c.patchOptions = opts
+	patched := &corev1.ConfigMap{}
+	return patched, json.Unmarshal(data, patched)
+}
+
+func TestServerSideApplyPatchOptions(t *testing.T) {
+	for _, force := range []bool{true, false} {
+		client := &recordingApplyClient{}
+		required := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "one-ns", Name: "foo"}}
+		applyObj, err := toApplyObject(required)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = serverSideApply(context.TODO(), newObjectClient[*corev1.ConfigMap](client), events.NewInMemoryRecorder(""), nil, required, applyObj, ServerSideApplyOptions{FieldManager: "test-operator", Force: force})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if client.patchOptions.FieldManager != "test-operator" {
+			t.Errorf("expected field manager test-operator, got %q", client.patchOptions.FieldManager)
+		}
+		if client.patchOptions.Force == nil || *client.patchOptions.Force != force {
+			t.Errorf("expected force %v, got %v", force, client.patchOptions.Force)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
 	migrationv1alpha1 "sigs.k8s.io/kube-storage-version-migrator/pkg/apis/migration/v1alpha1"
 )
 
@@ -26,6 +27,7 @@ func init() {
 	utilruntime.Must(apiextensionsv1.AddToScheme(genericScheme))
 	utilruntime.Must(migrationv1alpha1.AddToScheme(genericScheme))
 	utilruntime.Must(admissionregistrationv1.AddToScheme(genericScheme))
+	utilruntime.Must(apiregistrationv1.AddToScheme(genericScheme))
 }
 
 // ReadGenericWithUnstructured parses given yaml file using known scheme (see genericScheme above).
@@ -0,0 +1,108 @@
+package staticresourcecontroller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+)
+
+// phasedManifest is a single manifest file together with the phase it is applied in.
+type phasedManifest struct {
+	manifests resourceapply.AssetFunc
+	file      string
+	phase     resourceapply.ApplyPhase
+}
+
+func newPhasedManifests(manifests resourceapply.AssetFunc, files []string) []phasedManifest {
+	ret := []phasedManifest{}
+	for _, file := range files {
+		// manifests that cannot be read are applied with the workloads, resourceapply reports the error
+		var requiredObj runtime.Object
+		if objBytes, err := manifests(file); err == nil {
+			requiredObj, _ = resourceread.ReadGenericWithUnstructured(objBytes)
+		}
+		phase := resourceapply.WorkloadPhase
+		if requiredObj != nil {
+			phase = resourceapply.ApplyPhaseFor(requiredObj)
+		}
+		ret = append(ret, phasedManifest{manifests: manifests, file: file, phase: phase})
+	}
+	return ret
+}
+
+// applyManifestsInPhases deletes the manifests that should be deleted in the reverse phase order and then applies
+// the manifests that should be created phase by phase. When checkReadiness is set, objects of a phase must be ready
+// before the next phase is applied, otherwise the remaining phases are skipped and notReady is returned, so that the
+// caller can retry later. Readiness is checked only once, it is never waited for.
+func (c *StaticResourceController) applyManifestsInPhases(ctx context.Context, clients *resourceapply.ClientHolder, recorder events.Recorder, checkReadiness bool) (results []resourceapply.ApplyResult, errors []error, notReady bool) {
+	toCreate := []phasedManifest{}
+	toDelete := []phasedManifest{}
+	for _, conditionalManifest := range c.manifests {
+		shouldCreate := conditionalManifest.shouldCreateFn()
+		shouldDelete := conditionalManifest.shouldDeleteFn()
+
+		switch {
+		case !shouldCreate && !shouldDelete:
+			// no action required
+			continue
+		case shouldCreate && shouldDelete:
+			errors = append(errors, fmt.Errorf("cannot create and delete %v at the same time, skipping", strings.Join(conditionalManifest.files, ", ")))
+			continue
+
+		case shouldCreate:
+			toCreate = append(toCreate, newPhasedManifests(c.manifestsFor(conditionalManifest), conditionalManifest.files)...)
+		case shouldDelete:
+			toDelete = append(toDelete, newPhasedManifests(conditionalManifest.manifests, conditionalManifest.files)...)
+		}
+	}
+	sort.SliceStable(toCreate, func(i, j int) bool { return toCreate[i].phase < toCreate[j].phase })
+	sort.SliceStable(toDelete, func(i, j int) bool { return toDelete[i].phase > toDelete[j].phase })
+
+	for _, manifest := range toDelete {
+		results = append(results, resourceapply.DeleteAll(ctx, clients, recorder, manifest.manifests, manifest.file)...)
+	}
+
+	for start := 0; start < len(toCreate); {
+		end := start
+		for end < len(toCreate) && toCreate[end].phase == toCreate[start].phase {
+			end++
+		}
+
+		phaseResults := []resourceapply.ApplyResult{}
+		for _, manifest := range toCreate[start:end] {
+			phaseResults = append(phaseResults, resourceapply.ApplyDirectly(ctx, clients, recorder, c.performanceCache, manifest.manifests, manifest.file)...)
+		}
+		results = append(results, phaseResults...)
+
+		if checkReadiness && end < len(toCreate) {
+			for _, result := range phaseResults {
+				if result.Error != nil {
+					continue
+				}
+				ready, err := resourceapply.IsReady(ctx, clients, result.Result)
+				if err != nil {
+					errors = append(errors, fmt.Errorf("%q: %v", result.File, err))
+					continue
+				}
+				if !ready {
+					klog.V(2).Infof("%q is not ready yet, skipping %d manifests of later phases", result.File, len(toCreate)-end)
+					notReady = true
+				}
+			}
+			if notReady {
+				break
+			}
+		}
+		start = end
+	}
+
+	return results, errors, notReady
+}
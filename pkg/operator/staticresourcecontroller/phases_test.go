@@ -0,0 +1,144 @@
+package staticresourcecontroller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+)
+
+var phasesAssets = map[string]string{
+	"rolebinding": `apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: operand
+  namespace: operand-ns
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: operand
+subjects:
+- kind: ServiceAccount
+  name: operand
+  namespace: operand-ns
+`,
+	"role": `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: operand
+  namespace: operand-ns
+`,
+	"sa": `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: operand
+  namespace: operand-ns
+`,
+	"ns": `apiVersion: v1
+kind: Namespace
+metadata:
+  name: operand-ns
+`,
+	"crd": `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: operands.example.com
+spec:
+  group: example.com
+  names:
+    kind: Operand
+    plural: operands
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+`,
+	"cm": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: operand
+  namespace: operand-ns
+`,
+}
+
+func phasesAssetFunc(name string) ([]byte, error) {
+	return []byte(phasesAssets[name]), nil
+}
+
+func TestApplyManifestsInPhases(t *testing.T) {
+	established := &apiextensionsv1.CustomResourceDefinition{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1", Kind: "CustomResourceDefinition"},
+		ObjectMeta: metav1.ObjectMeta{Name: "operands.example.com"},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{
+			Conditions: []apiextensionsv1.CustomResourceDefinitionCondition{{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionTrue}},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		existingCRDs     map[string]runtime.Object
+		expectedFiles    []string
+		expectedNotReady bool
+	}{
+		{
+			name:          "established CRD",
+			existingCRDs:  map[string]runtime.Object{"/apis/apiextensions.k8s.io/v1/customresourcedefinitions/operands.example.com": established},
+			expectedFiles: []string{"ns", "crd", "sa", "role", "rolebinding", "cm"},
+		},
+		{
+			name:             "CRD not established",
+			expectedFiles:    []string{"ns", "crd"},
+			expectedNotReady: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, _ := newTestAPIServer(t, test.existingCRDs)
+			clients := resourceapply.NewKubeClientHolder(fake.NewSimpleClientset()).WithAPIExtensionsClient(apiextensionsclient.NewForConfigOrDie(config))
+			operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{ManagementState: operatorv1.Managed}, &operatorv1.OperatorStatus{}, nil)
+			c := NewStaticResourceController("test", phasesAssetFunc, []string{"cm", "rolebinding", "role"}, clients, operatorClient, events.NewInMemoryRecorder("")).
+				WithConditionalResources(phasesAssetFunc, []string{"sa", "crd", "ns"}, nil, nil).
+				WithPhasedApply()
+
+			results, errs, notReady := c.applyManifests(context.TODO(), clients, events.NewInMemoryRecorder(""), true)
+			files := []string{}
+			for _, result := range results {
+				if result.Error != nil {
+					t.Errorf("%s: %v", result.File, result.Error)
+				}
+				files = append(files, result.File)
+			}
+			assert.Equal(t, test.expectedFiles, files)
+			assert.Empty(t, errs)
+			assert.Equal(t, test.expectedNotReady, notReady)
+
+			// objects that are not ready yet requeue the sync without reporting Degraded
+			err := c.Sync(context.TODO(), factory.NewSyncContext("test", events.NewInMemoryRecorder("")))
+			if test.expectedNotReady {
+				assert.Equal(t, factory.SyntheticRequeueError, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			_, status, _, err := operatorClient.GetOperatorState()
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, v1helpers.IsOperatorConditionFalse(status.Conditions, "testDegraded"))
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package staticresourcecontroller
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcehelper"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+)
+
+const (
+	// OwnerLabel is set on every object applied by a StaticResourceController configured WithOwnershipLabel.
+	// Its value identifies the controller.
+	OwnerLabel = "operator.openshift.io/static-resource-owner"
+	// ManifestAnnotation is set on every object applied by a StaticResourceController configured
+	// WithOwnershipLabel. Its value is the name of the manifest file the object was created from.
+	ManifestAnnotation = "operator.openshift.io/static-resource-manifest"
+)
+
+// WithOwnershipLabel makes the controller stamp every object it applies with OwnerLabel set to owner and
+// ManifestAnnotation set to the name of the manifest file. The owner must be a valid label value and must be unique
+// among all static resource controllers in the cluster.
+func (c *StaticResourceController) WithOwnershipLabel(owner string) *StaticResourceController {
+	if errs := validation.IsValidLabelValue(owner); len(errs) > 0 {
+		panic(fmt.Sprintf("invalid static resource owner %q: %v", owner, errs))
+	}
+	c.owner = owner
+	return c
+}
+
+// WithPruning makes the controller delete objects it owns (see WithOwnershipLabel) that are no longer part of any of
+// its manifests, e.g. because a file was removed from the asset list in a new operator version.
+// Only objects of the given resources are ever pruned, the list serves as a safety allowlist. An event is emitted for
+// every deletion. Pruning only happens after a sync in which all manifests were applied successfully.
+func (c *StaticResourceController) WithPruning(dynamicClient dynamic.Interface, resources ...schema.GroupVersionResource) *StaticResourceController {
+	if len(c.owner) == 0 {
+		panic("pruning of static resources requires WithOwnershipLabel")
+	}
+	c.pruneClient = dynamicClient
+	c.prunableResources = resources
+	return c
+}
+
+// withOwnership returns manifests stamped with the owner label and the manifest annotation.
+func withOwnership(manifests resourceapply.AssetFunc, owner string) resourceapply.AssetFunc {
+	return func(name string) ([]byte, error) {
+		objBytes, err := manifests(name)
+		if err != nil {
+			return nil, err
+		}
+		objJSON, err := yaml.YAMLToJSON(objBytes)
+		if err != nil {
+			return nil, err
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(objJSON); err != nil {
+			return nil, err
+		}
+
+		objLabels := obj.GetLabels()
+		if objLabels == nil {
+			objLabels = map[string]string{}
+		}
+		objLabels[OwnerLabel] = owner
+		obj.SetLabels(objLabels)
+
+		objAnnotations := obj.GetAnnotations()
+		if objAnnotations == nil {
+			objAnnotations = map[string]string{}
+		}
+		objAnnotations[ManifestAnnotation] = name
+		obj.SetAnnotations(objAnnotations)
+
+		return obj.MarshalJSON()
+	}
+}
+
+// manifestsFor returns the manifests of the set as they should be applied.
+func (c *StaticResourceController) manifestsFor(conditionalManifest conditionalManifests) resourceapply.AssetFunc {
+	if len(c.owner) == 0 {
+		return conditionalManifest.manifests
+	}
+	return withOwnership(conditionalManifest.manifests, c.owner)
+}
+
+type prunedObjectKey struct {
+	groupKind schema.GroupKind
+	namespace string
+	name      string
+}
+
+// prunedObject is an object owned by this controller that is no longer part of any manifest.
+type prunedObject struct {
+	resource schema.GroupVersionResource
+	object   *unstructured.Unstructured
+}
+
+// pruneCandidates lists the objects owned by this controller which are not part of any manifest. Manifests of all
+// sets are considered, regardless of their conditions, conditional deletion is handled by the sets themselves.
+func (c *StaticResourceController) pruneCandidates(ctx context.Context) ([]prunedObject, error) {
+	if c.pruneClient == nil {
+		return nil, nil
+	}
+
+	known := map[prunedObjectKey]bool{}
+	for _, conditionalManifest := range c.manifests {
+		for _, file := range conditionalManifest.files {
+			objBytes, err := conditionalManifest.manifests(file)
+			if err != nil {
+				return nil, fmt.Errorf("missing %q: %v", file, err)
+			}
+			requiredObj, err := resourceread.ReadGenericWithUnstructured(objBytes)
+			if err != nil {
+				return nil, fmt.Errorf("cannot decode %q: %v", file, err)
+			}
+			metadata, err := meta.Accessor(requiredObj)
+			if err != nil {
+				return nil, fmt.Errorf("cannot get metadata %q: %v", file, err)
+			}
+			known[prunedObjectKey{
+				groupKind: resourcehelper.GuessObjectGroupVersionKind(requiredObj).GroupKind(),
+				namespace: metadata.GetNamespace(),
+				name:      metadata.GetName(),
+			}] = true
+		}
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{OwnerLabel: c.owner}).String()
+	candidates := []prunedObject{}
+	for _, resource := range c.prunableResources {
+		list, err := c.pruneClient.Resource(resource).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s owned by %q: %v", resource.String(), c.owner, err)
+		}
+		for i := range list.Items {
+			item := &list.Items[i]
+			key := prunedObjectKey{groupKind: item.GroupVersionKind().GroupKind(), namespace: item.GetNamespace(), name: item.GetName()}
+			if !known[key] {
+				candidates = append(candidates, prunedObject{resource: resource, object: item})
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// prune deletes the objects returned by pruneCandidates.
+func (c *StaticResourceController) prune(ctx context.Context, recorder events.Recorder) []error {
+	candidates, err := c.pruneCandidates(ctx)
+	if err != nil {
+		return []error{err}
+	}
+
+	errors := []error{}
+	propagation := metav1.DeletePropagationBackground
+	for _, candidate := range candidates {
+		obj := candidate.object
+		kind := obj.GetKind()
+		err := c.pruneClient.Resource(candidate.resource).Namespace(obj.GetNamespace()).Delete(ctx, obj.GetName(), metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			recorder.Warningf(fmt.Sprintf("%sDeleteFailed", kind), "Failed to prune %s: %v", resourcehelper.FormatResourceForCLIWithNamespace(obj), err)
+			errors = append(errors, fmt.Errorf("failed to prune %s: %v", resourcehelper.FormatResourceForCLIWithNamespace(obj), err))
+			continue
+		}
+		recorder.Eventf(fmt.Sprintf("%sDeleted", kind), "Deleted %s because manifest %q is no longer part of %s",
+			resourcehelper.FormatResourceForCLIWithNamespace(obj), obj.GetAnnotations()[ManifestAnnotation], c.owner)
+	}
+	return errors
+}
+
+// dryRunPrune reports the objects prune would delete.
+func (c *StaticResourceController) dryRunPrune(ctx context.Context) ([]resourceapply.ApplyResult, error) {
+	candidates, err := c.pruneCandidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := []resourceapply.ApplyResult{}
+	for _, candidate := range candidates {
+		results = append(results, resourceapply.ApplyResult{
+			File:         candidate.object.GetAnnotations()[ManifestAnnotation],
+			Type:         fmt.Sprintf("%T", candidate.object),
+			Result:       candidate.object,
+			Changed:      true,
+			DryRunAction: resourceapply.DryRunDelete,
+		})
+	}
+	return results, nil
+}
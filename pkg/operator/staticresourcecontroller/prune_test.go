@@ -0,0 +1,102 @@
+package staticresourcecontroller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+)
+
+func TestWithOwnershipLabel(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	clients := resourceapply.NewKubeClientHolder(kubeClient)
+	operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{}, &operatorv1.OperatorStatus{}, nil)
+	c := NewStaticResourceController("test", phasesAssetFunc, []string{"cm"}, clients, operatorClient, events.NewInMemoryRecorder("")).
+		WithOwnershipLabel("test-operator")
+
+	_, errs, _ := c.applyManifests(context.TODO(), clients, events.NewInMemoryRecorder(""), false)
+	assert.Empty(t, errs)
+
+	cm, err := kubeClient.CoreV1().ConfigMaps("operand-ns").Get(context.TODO(), "operand", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "test-operator", cm.Labels[OwnerLabel])
+	assert.Equal(t, "cm", cm.Annotations[ManifestAnnotation])
+}
+
+func TestPrune(t *testing.T) {
+	owned := func(obj metav1.Object, owner, file string) {
+		obj.SetLabels(map[string]string{OwnerLabel: owner})
+		obj.SetAnnotations(map[string]string{ManifestAnnotation: file})
+	}
+	current := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "operand-ns", Name: "operand"}}
+	owned(current, "test-operator", "cm")
+	removed := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "operand-ns", Name: "removed"}}
+	owned(removed, "test-operator", "removed-cm")
+	otherOwner := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "operand-ns", Name: "other"}}
+	owned(otherOwner, "other-operator", "cm")
+	unowned := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "operand-ns", Name: "unowned"}}
+	notAllowed := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "operand-ns", Name: "removed"}}
+	owned(notAllowed, "test-operator", "removed-secret")
+
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	newController := func(dynamicClient *dynamicfake.FakeDynamicClient) *StaticResourceController {
+		clients := resourceapply.NewKubeClientHolder(fake.NewSimpleClientset())
+		operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{}, &operatorv1.OperatorStatus{}, nil)
+		return NewStaticResourceController("test", phasesAssetFunc, []string{"cm"}, clients, operatorClient, events.NewInMemoryRecorder("")).
+			WithConditionalResources(phasesAssetFunc, []string{"sa"}, func() bool { return false }, func() bool { return false }).
+			WithOwnershipLabel("test-operator").
+			WithPruning(dynamicClient, corev1.SchemeGroupVersion.WithResource("configmaps"))
+	}
+
+	t.Run("prune", func(t *testing.T) {
+		dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme, current, removed, otherOwner, unowned, notAllowed)
+		recorder := events.NewInMemoryRecorder("")
+		errs := newController(dynamicClient).prune(context.TODO(), recorder)
+		assert.Empty(t, errs)
+
+		deleted := []string{}
+		for _, action := range dynamicClient.Actions() {
+			if action.GetVerb() == "delete" {
+				deleted = append(deleted, action.GetResource().Resource+"/"+action.(clienttesting.DeleteAction).GetName())
+			}
+		}
+		assert.Equal(t, []string{"configmaps/removed"}, deleted)
+		if assert.Len(t, recorder.Events(), 1) {
+			assert.Equal(t, "ConfigMapDeleted", recorder.Events()[0].Reason)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme, current, removed, otherOwner, unowned, notAllowed)
+		results, err := newController(dynamicClient).dryRunPrune(context.TODO())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if assert.Len(t, results, 1) {
+			assert.Equal(t, resourceapply.DryRunDelete, results[0].DryRunAction)
+			assert.Equal(t, "removed-cm", results[0].File)
+		}
+		for _, action := range dynamicClient.Actions() {
+			assert.Equal(t, "list", action.GetVerb())
+		}
+	})
+}
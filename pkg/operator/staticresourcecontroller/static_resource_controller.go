@@ -14,9 +14,13 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/restmapper"
 
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -24,6 +28,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
@@ -36,6 +41,7 @@ import (
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/management"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 )
 
@@ -64,6 +70,19 @@ type StaticResourceController struct {
 	ignoreNotFoundOnCreate bool
 	preconditions          []StaticResourcesPreconditionsFuncType
 
+	// trackGenerations records the generations of applied deployments and daemonsets in the operator status, see
+	// WithGenerationTracking.
+	trackGenerations bool
+
+	// phasedApply applies the manifests grouped by resourceapply.ApplyPhaseFor, see WithPhasedApply.
+	phasedApply bool
+
+	// owner is stamped on every applied object, see WithOwnershipLabel.
+	owner string
+	// pruneClient deletes owned objects of prunableResources that are no longer part of any manifest, see WithPruning.
+	pruneClient       dynamic.Interface
+	prunableResources []schema.GroupVersionResource
+
 	operatorClient v1helpers.OperatorClient
 	clients        *resourceapply.ClientHolder
 
@@ -129,6 +148,30 @@ func (c *StaticResourceController) WithIgnoreNotFoundOnCreate() *StaticResourceC
 	return c
 }
 
+// WithGenerationTracking makes the controller record the generations of the deployments and daemonsets it applies
+// in the operator status generations and compare against them on the next sync, so that they are only updated when
+// their manifest changes or somebody else modified them. Without it, deployments and daemonsets are updated on every
+// sync and the operator status generations are left alone, which is what operators that track the generations of
+// their operands themselves need.
+func (c *StaticResourceController) WithGenerationTracking() *StaticResourceController {
+	c.trackGenerations = true
+	return c
+}
+
+// WithPhasedApply makes the controller apply the manifests of all sets grouped in phases by their kind instead of in
+// the order they were added: namespaces first, then CRDs, service accounts, roles, role bindings, configuration,
+// workloads, custom resources and finally webhooks and API services (see resourceapply.ApplyPhase). Manifests that
+// should be deleted are deleted in the reverse order. Phases only group manifests by kind, manifests of the same
+// phase are applied in the order they were added and no other dependencies between them are considered.
+//
+// Before a phase is applied, the objects of the previous phase must be ready, e.g. CRDs must be Established before
+// any CR is created. Readiness is checked without waiting: when an object is not ready yet, the remaining phases are
+// skipped and the sync is retried with the rate limited backoff of the controller queue.
+func (c *StaticResourceController) WithPhasedApply() *StaticResourceController {
+	c.phasedApply = true
+	return c
+}
+
 // WithPrecondition adds a precondition, which blocks the sync method from being executed. Preconditions might be chained using:
 //
 //	WithPrecondition(a).WithPrecondition(b).WithPrecondition(c).
@@ -246,6 +289,18 @@ func (c *StaticResourceController) AddKubeInformers(kubeInformersByNamespace v1h
 				ret = ret.AddInformer(informer.Storage().V1().StorageClasses().Informer())
 			case *storagev1.CSIDriver:
 				ret = ret.AddInformer(informer.Storage().V1().CSIDrivers().Informer())
+			case *appsv1.Deployment:
+				ret = ret.AddInformer(informer.Apps().V1().Deployments().Informer())
+			case *appsv1.DaemonSet:
+				ret = ret.AddInformer(informer.Apps().V1().DaemonSets().Informer())
+			case *networkingv1.NetworkPolicy:
+				ret = ret.AddInformer(informer.Networking().V1().NetworkPolicies().Informer())
+			case *batchv1.Job:
+				ret = ret.AddInformer(informer.Batch().V1().Jobs().Informer())
+			case *batchv1.CronJob:
+				ret = ret.AddInformer(informer.Batch().V1().CronJobs().Informer())
+			case *schedulingv1.PriorityClass:
+				ret = ret.AddInformer(informer.Scheduling().V1().PriorityClasses().Informer())
 			default:
 				// if there's a missing case, the caller can add an informer or count on a time based trigger.
 				// if the controller doesn't handle it, then there will be failure from the underlying apply.
@@ -278,7 +333,7 @@ func (c *StaticResourceController) AddNamespaceInformer(informer cache.SharedInd
 }
 
 func (c *StaticResourceController) Sync(ctx context.Context, syncContext factory.SyncContext) error {
-	operatorSpec, _, _, err := c.operatorClient.GetOperatorState()
+	operatorSpec, operatorStatus, _, err := c.operatorClient.GetOperatorState()
 	if err != nil {
 		return err
 	}
@@ -308,38 +363,26 @@ func (c *StaticResourceController) Sync(ctx context.Context, syncContext factory
 		}
 	}
 
-	errors := []error{}
+	clients := *c.clients
+	var generations []operatorv1.GenerationStatus
+	if c.trackGenerations {
+		generations = append(generations, operatorStatus.Generations...)
+		clients.WithGenerations(&generations)
+	}
+	directResourceResults, errors, notReady := c.applyManifests(ctx, &clients, syncContext.Recorder(), true)
 	var notFoundErrorsCount int
-	for _, conditionalManifest := range c.manifests {
-		shouldCreate := conditionalManifest.shouldCreateFn()
-		shouldDelete := conditionalManifest.shouldDeleteFn()
-
-		var directResourceResults []resourceapply.ApplyResult
-
-		switch {
-		case !shouldCreate && !shouldDelete:
-			// no action required
-			continue
-		case shouldCreate && shouldDelete:
-			errors = append(errors, fmt.Errorf("cannot create and delete %v at the same time, skipping", strings.Join(conditionalManifest.files, ", ")))
-			continue
-
-		case shouldCreate:
-			directResourceResults = resourceapply.ApplyDirectly(ctx, c.clients, syncContext.Recorder(), c.performanceCache, conditionalManifest.manifests, conditionalManifest.files...)
-		case shouldDelete:
-			directResourceResults = resourceapply.DeleteAll(ctx, c.clients, syncContext.Recorder(), conditionalManifest.manifests, conditionalManifest.files...)
+	for _, currResult := range directResourceResults {
+		if apierrors.IsNotFound(currResult.Error) {
+			notFoundErrorsCount++
 		}
-
-		for _, currResult := range directResourceResults {
-			if apierrors.IsNotFound(currResult.Error) {
-				notFoundErrorsCount++
-			}
-			if currResult.Error != nil {
-				errors = append(errors, fmt.Errorf("%q (%T): %v", currResult.File, currResult.Type, currResult.Error))
-				continue
-			}
+		if currResult.Error != nil {
+			errors = append(errors, fmt.Errorf("%q (%T): %v", currResult.File, currResult.Type, currResult.Error))
 		}
 	}
+	if len(errors) == 0 && !notReady {
+		// only prune when the current manifests are known to be in place
+		errors = append(errors, c.prune(ctx, syncContext.Recorder())...)
+	}
 
 	cnd := operatorv1.OperatorCondition{
 		Type:    fmt.Sprintf("%sDegraded", c.name),
@@ -362,13 +405,67 @@ func (c *StaticResourceController) Sync(ctx context.Context, syncContext factory
 		}
 	}
 
-	_, _, err = v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(cnd))
+	_, _, err = v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(cnd), func(status *operatorv1.OperatorStatus) error {
+		for _, generation := range generations {
+			resourcemerge.SetGeneration(&status.Generations, generation)
+		}
+		return nil
+	})
 	if err != nil {
 		errors = append(errors, err)
 	}
+	if len(errors) == 0 && notReady {
+		// not an error, the skipped phases are applied once the objects they depend on are ready
+		return factory.SyntheticRequeueError
+	}
 	return utilerrors.NewAggregate(errors)
 }
 
+// DryRun computes what the controller would change in the cluster without writing to the API server or updating
+// the operator status. Management state and preconditions are not evaluated. Every manifest that would currently be
+// applied or deleted is returned with its resourceapply.DryRunAction and diff, followed by the objects that would be
+// pruned, see WithPruning.
+func (c *StaticResourceController) DryRun(ctx context.Context) ([]resourceapply.ApplyResult, error) {
+	clients := *c.clients
+	results, errors, _ := c.applyManifests(ctx, clients.WithDryRun(), c.eventRecorder, false)
+	pruneResults, err := c.dryRunPrune(ctx)
+	if err != nil {
+		errors = append(errors, err)
+	}
+	return append(results, pruneResults...), utilerrors.NewAggregate(errors)
+}
+
+// applyManifests applies or deletes every set of conditional manifests and returns the per-file results.
+// The returned errors describe manifests that could not be processed at all. notReady is set when manifests were
+// skipped because objects of an earlier phase are not ready yet, see WithPhasedApply.
+func (c *StaticResourceController) applyManifests(ctx context.Context, clients *resourceapply.ClientHolder, recorder events.Recorder, checkReadiness bool) ([]resourceapply.ApplyResult, []error, bool) {
+	if c.phasedApply {
+		return c.applyManifestsInPhases(ctx, clients, recorder, checkReadiness)
+	}
+
+	results := []resourceapply.ApplyResult{}
+	errors := []error{}
+	for _, conditionalManifest := range c.manifests {
+		shouldCreate := conditionalManifest.shouldCreateFn()
+		shouldDelete := conditionalManifest.shouldDeleteFn()
+
+		switch {
+		case !shouldCreate && !shouldDelete:
+			// no action required
+			continue
+		case shouldCreate && shouldDelete:
+			errors = append(errors, fmt.Errorf("cannot create and delete %v at the same time, skipping", strings.Join(conditionalManifest.files, ", ")))
+			continue
+
+		case shouldCreate:
+			results = append(results, resourceapply.ApplyDirectly(ctx, clients, recorder, c.performanceCache, c.manifestsFor(conditionalManifest), conditionalManifest.files...)...)
+		case shouldDelete:
+			results = append(results, resourceapply.DeleteAll(ctx, clients, recorder, conditionalManifest.manifests, conditionalManifest.files...)...)
+		}
+	}
+	return results, errors, false
+}
+
 func (c *StaticResourceController) Name() string {
 	return c.name
 }
@@ -1,17 +1,87 @@
 package staticresourcecontroller
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
 	configv1 "github.com/openshift/api/config/v1"
 	operatorv1 "github.com/openshift/api/operator/v1"
 	"github.com/openshift/library-go/pkg/client/openshiftrestmapper"
+	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/restmapper"
-	"testing"
 )
 
+// testAPIServer stores objects by path. Writes without dryRun=All are persisted and recorded.
+type testAPIServer struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+	writes  []string
+}
+
+func newTestAPIServer(t *testing.T, objects map[string]runtime.Object) (*rest.Config, *testAPIServer) {
+	s := &testAPIServer{objects: map[string][]byte{}}
+	for path, obj := range objects {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.objects[path] = data
+	}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return &rest.Config{Host: server.URL}, s
+}
+
+func (s *testAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodGet {
+		data, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+			return
+		}
+		w.Write(data)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	if r.URL.Query().Get("dryRun") != metav1.DryRunAll {
+		s.writes = append(s.writes, r.Method+" "+r.URL.Path)
+		path := r.URL.Path
+		if r.Method == http.MethodPost {
+			obj := &metav1.PartialObjectMetadata{}
+			if err := json.Unmarshal(body, obj); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			path += "/" + obj.Name
+		}
+		s.objects[path] = body
+	}
+	w.Write(body)
+}
+
 func TestRelatedObjects(t *testing.T) {
 	sa := `apiVersion: v1
 kind: ServiceAccount
@@ -57,3 +127,101 @@ metadata:
 	res, _ := src.RelatedObjects()
 	assert.ElementsMatch(t, expected, res)
 }
+
+func TestDryRun(t *testing.T) {
+	assets := map[string]string{
+		"sa": `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: operator
+  namespace: test
+`,
+		"cm": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: test
+data:
+  key: new-value
+`,
+		"obsolete": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: obsolete
+  namespace: test
+`,
+	}
+	readBytesFromString := func(filename string) ([]byte, error) {
+		return []byte(assets[filename]), nil
+	}
+	configMapTypeMeta := metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}
+	config, server := newTestAPIServer(t, map[string]runtime.Object{
+		"/api/v1/namespaces/test/configmaps/config":   &corev1.ConfigMap{TypeMeta: configMapTypeMeta, ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "config"}, Data: map[string]string{"key": "old-value"}},
+		"/api/v1/namespaces/test/configmaps/obsolete": &corev1.ConfigMap{TypeMeta: configMapTypeMeta, ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "obsolete"}},
+	})
+	kubeClient := kubernetes.NewForConfigOrDie(config)
+	operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{}, &operatorv1.OperatorStatus{}, nil)
+
+	src := NewStaticResourceController("", readBytesFromString, []string{"sa", "cm"}, resourceapply.NewKubeClientHolder(kubeClient), operatorClient, events.NewInMemoryRecorder(""))
+	src = src.WithConditionalResources(readBytesFromString, []string{"obsolete"}, func() bool { return false }, nil)
+
+	results, err := src.DryRun(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := map[string]resourceapply.DryRunAction{}
+	for _, result := range results {
+		if result.Error != nil {
+			t.Errorf("%s: %v", result.File, result.Error)
+		}
+		actions[result.File] = result.DryRunAction
+	}
+	assert.Equal(t, map[string]resourceapply.DryRunAction{
+		"sa":       resourceapply.DryRunCreate,
+		"cm":       resourceapply.DryRunUpdate,
+		"obsolete": resourceapply.DryRunDelete,
+	}, actions)
+
+	if len(server.writes) > 0 {
+		t.Errorf("unexpected writes in dry-run mode: %v", server.writes)
+	}
+}
+
+func TestSyncGenerationTracking(t *testing.T) {
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: operand
+  namespace: test
+spec:
+  template:
+    spec:
+      containers:
+      - name: operand
+        image: operand
+`
+	readBytesFromString := func(filename string) ([]byte, error) {
+		return []byte(deployment), nil
+	}
+
+	for _, trackGenerations := range []bool{false, true} {
+		t.Run(fmt.Sprintf("tracking %v", trackGenerations), func(t *testing.T) {
+			operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{ManagementState: operatorv1.Managed}, &operatorv1.OperatorStatus{}, nil)
+			src := NewStaticResourceController("test", readBytesFromString, []string{"deployment"}, resourceapply.NewKubeClientHolder(fake.NewSimpleClientset()), operatorClient, events.NewInMemoryRecorder(""))
+			if trackGenerations {
+				src = src.WithGenerationTracking()
+			}
+			if err := src.Sync(context.TODO(), factory.NewSyncContext("test", events.NewInMemoryRecorder(""))); err != nil {
+				t.Fatal(err)
+			}
+
+			_, status, _, err := operatorClient.GetOperatorState()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if trackGenerations != (len(status.Generations) == 1) {
+				t.Errorf("unexpected generations with tracking %v: %v", trackGenerations, status.Generations)
+			}
+		})
+	}
+}
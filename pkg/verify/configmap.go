@@ -2,6 +2,8 @@ package verify
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"strings"
@@ -14,6 +16,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/openshift/library-go/pkg/manifest"
+	"github.com/openshift/library-go/pkg/verify/cosign"
 	"github.com/openshift/library-go/pkg/verify/store"
 	"github.com/openshift/library-go/pkg/verify/store/configmap"
 	"github.com/openshift/library-go/pkg/verify/store/parallel"
@@ -33,6 +36,15 @@ const (
 	// must have signed the release image by digest.
 	verifierPublicKeyPrefix = "verifier-public-key-"
 
+	// verifierCosignPublicKeyPrefix is the unique portion of the key used within a config map
+	// identifying data field containing a PEM encoded ECDSA public key that must have signed
+	// the release image by digest with cosign.
+	verifierCosignPublicKeyPrefix = "verifier-cosign-public-key-"
+
+	// policyKey is the key used within a config map identifying the data field containing
+	// the verification policy as JSON.
+	policyKey = "verification-policy"
+
 	// storePrefix is the unique portion of the key used within a config map identifying
 	// data field containing a URL (scheme http://, or https://) location that contains
 	// signatures.
@@ -109,6 +121,17 @@ func NewFromManifests(manifests []manifest.Manifest, clientBuilder sigstore.HTTP
 //
 //	release image by digest.
 //
+// verifier-cosign-public-key-*: A PEM encoded ECDSA public key that must have signed the
+//
+//	release image by digest with cosign. The stores must provide these signatures in the
+//	format printed by "cosign download signature", see the cosign package.
+//
+// verification-policy: An optional JSON encoded Policy defining how many of the verifiers, which
+//
+//	are referred to by their key, must have signed a release, and when they expire. The policy
+//	must not scope verifiers to release versions, because the version of the release is not
+//	known when verifying it by digest.
+//
 // store-*: A URL (scheme file://, http://, or https://) location that contains signatures. These
 //
 //	signatures are in the atomic container signature format. The URL will have the digest
@@ -128,15 +151,30 @@ func NewFromManifests(manifests []manifest.Manifest, clientBuilder sigstore.HTTP
 // store and the lookup order is internally defined.
 func newFromConfigMapData(src string, data map[string]string, clientBuilder sigstore.HTTPClient) (Interface, error) {
 	verifiers := make(map[string]openpgp.EntityList)
+	cosignVerifiers := make(map[string]*ecdsa.PublicKey)
 	var stores []store.Store
+	var policy Policy
 	for k, v := range data {
 		switch {
+		case k == policyKey:
+			if err := json.Unmarshal([]byte(v), &policy); err != nil {
+				return nil, errors.Wrapf(err, "%s has an invalid %q that must be a JSON verification policy: %v", src, k, err)
+			}
+			if policy.scoped() {
+				return nil, fmt.Errorf("%s has an invalid %q: verifiers cannot be scoped to releases, because releases are verified by digest only", src, k)
+			}
 		case strings.HasPrefix(k, verifierPublicKeyPrefix):
-			keyring, err := loadArmoredOrUnarmoredGPGKeyRing([]byte(v))
+			keyring, err := LoadArmoredOrUnarmoredGPGKeyRing([]byte(v))
 			if err != nil {
 				return nil, errors.Wrapf(err, "%s has an invalid key %q that must be a GPG public key: %v", src, k, err)
 			}
 			verifiers[k] = keyring
+		case strings.HasPrefix(k, verifierCosignPublicKeyPrefix):
+			key, err := cosign.ParsePublicKey([]byte(v))
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s has an invalid key %q that must be a PEM encoded ECDSA public key: %v", src, k, err)
+			}
+			cosignVerifiers[k] = key
 		case strings.HasPrefix(k, storePrefix):
 			v = strings.TrimSpace(v)
 			u, err := url.Parse(v)
@@ -154,20 +192,25 @@ func newFromConfigMapData(src string, data map[string]string, clientBuilder sigs
 				})
 			}
 		default:
-			klog.Warningf("An unexpected key was found in %s and will be ignored (expected store-* or verifier-public-key-*): %s", src, k)
+			klog.Warningf("An unexpected key was found in %s and will be ignored (expected store-*, verifier-public-key-*, verifier-cosign-public-key-* or verification-policy): %s", src, k)
 		}
 	}
 	if len(stores) == 0 {
 		return nil, fmt.Errorf("%s did not provide any signature stores to read from and cannot be used", src)
 	}
-	if len(verifiers) == 0 {
-		return nil, fmt.Errorf("%s did not provide any GPG public keys to verify signatures from and cannot be used", src)
+	if len(verifiers) == 0 && len(cosignVerifiers) == 0 {
+		return nil, fmt.Errorf("%s did not provide any GPG or cosign public keys to verify signatures from and cannot be used", src)
 	}
 
-	return NewReleaseVerifier(verifiers, &parallel.Store{Stores: stores}), nil
+	verifier, err := NewPolicyReleaseVerifier(verifiers, cosignVerifiers, &parallel.Store{Stores: stores}, policy)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s is not valid: %v", src, err)
+	}
+	return verifier, nil
 }
 
-func loadArmoredOrUnarmoredGPGKeyRing(data []byte) (openpgp.EntityList, error) {
+// LoadArmoredOrUnarmoredGPGKeyRing reads a GPG keyring that may or may not be ASCII armored.
+func LoadArmoredOrUnarmoredGPGKeyRing(data []byte) (openpgp.EntityList, error) {
 	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
 	if err == nil {
 		return keyring, nil
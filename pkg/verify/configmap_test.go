@@ -57,6 +57,10 @@ func Test_newFromConfigMapData(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	cosignData, err := ioutil.ReadFile(filepath.Join("testdata", "keyrings", "cosign.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	tests := []struct {
 		name          string
@@ -93,6 +97,52 @@ func Test_newFromConfigMapData(t *testing.T) {
 			want:          true,
 			wantVerifiers: 1,
 		},
+		{
+			name: "loads cosign configuration",
+			data: map[string]string{
+				"verifier-cosign-public-key-redhat": string(cosignData),
+				"store-local":                       "file://../testdata/signatures",
+			},
+			want: true,
+		},
+		{
+			name: "loads verification policy",
+			data: map[string]string{
+				"verifier-public-key-redhat":        string(redhatData),
+				"verifier-cosign-public-key-redhat": string(cosignData),
+				"verification-policy":               `{"threshold": 1, "verifiers": {"verifier-cosign-public-key-redhat": {"notAfter": "2100-01-01T00:00:00Z"}}}`,
+				"store-local":                       "file://../testdata/signatures",
+			},
+			want:          true,
+			wantVerifiers: 1,
+		},
+		{
+			name: "rejects verification policy scoping verifiers to releases",
+			data: map[string]string{
+				"verifier-public-key-redhat":        string(redhatData),
+				"verifier-cosign-public-key-redhat": string(cosignData),
+				"verification-policy":               `{"threshold": 1, "verifiers": {"verifier-cosign-public-key-redhat": {"releases": ["4.14.*"]}}}`,
+				"store-local":                       "file://../testdata/signatures",
+			},
+			wantErr: true,
+		},
+		{
+			name: "requires valid verification policy",
+			data: map[string]string{
+				"verifier-public-key-redhat": string(redhatData),
+				"verification-policy":        `{"verifiers": {"verifier-public-key-other": {}}}`,
+				"store-local":                "file://../testdata/signatures",
+			},
+			wantErr: true,
+		},
+		{
+			name: "requires valid cosign keys",
+			data: map[string]string{
+				"verifier-cosign-public-key-redhat": string(redhatData),
+				"store-local":                       "file://../testdata/signatures",
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
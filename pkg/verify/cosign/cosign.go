@@ -0,0 +1,160 @@
+// Package cosign verifies cosign signatures of release images.
+//
+// A cosign signature is an ECDSA signature of the SHA-256 hash of a simple signing payload
+// [1]. In a registry, the payload is stored as a layer with media type SimpleSigningMediaType
+// of an OCI artifact, and the base64 encoded signature in the SignatureAnnotation of that
+// layer. Outside a registry, signatures are exchanged in the JSON format printed by
+// "cosign download signature", which is the format of the signatures passed to Verify.
+//
+// [1]: https://github.com/containers/image/blob/main/docs/containers-signature.5.md#json-data-format
+package cosign
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+)
+
+const (
+	// SignatureArtifactType is the artifact type of signature manifests referring to the signed image.
+	SignatureArtifactType = "application/vnd.dev.cosign.artifact.sig.v1+json"
+
+	// SimpleSigningMediaType is the media type of the layers holding simple signing payloads.
+	SimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+
+	// SignatureAnnotation is the layer annotation holding the base64 encoded signature of the payload.
+	SignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+	// signatureType is the critical type of cosign simple signing payloads.
+	signatureType = "cosign container image signature"
+)
+
+// Signature is a cosign signature in the format printed by "cosign download signature".
+// Certificates and transparency log bundles are not supported and ignored.
+type Signature struct {
+	// Base64Signature is the base64 encoded ASN.1 ECDSA signature of the payload.
+	Base64Signature string `json:"Base64Signature"`
+	// Payload is the signed simple signing payload.
+	Payload []byte `json:"Payload"`
+}
+
+// Marshal returns the JSON encoding of the signature, which is accepted by Verify.
+func (s Signature) Marshal() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// ParsePublicKey parses a PEM encoded ECDSA public key as written by "cosign generate-key-pair".
+func ParsePublicKey(data []byte) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded public key found")
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("unexpected PEM block type %q, expected PUBLIC KEY", block.Type)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported public key type %T, only ECDSA keys are supported", key)
+	}
+	return ecdsaKey, nil
+}
+
+// Fingerprint returns a human readable identifier of the public key, the SHA-256 hash of its
+// DER encoding.
+func Fingerprint(key *ecdsa.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "<invalid key>"
+	}
+	hash := sha256.Sum256(der)
+	return fmt.Sprintf("SHA256:%x", hash)
+}
+
+// Verify returns nil if data is a cosign signature by the given key of a payload attesting
+// the release digest. If error is returned the signature must be ignored.
+func Verify(data []byte, key *ecdsa.PublicKey, releaseDigest string) error {
+	_, err := VerifyIdentity(data, key, releaseDigest)
+	return err
+}
+
+// VerifyIdentity is like Verify and returns the docker reference the signature was made for.
+func VerifyIdentity(data []byte, key *ecdsa.PublicKey, releaseDigest string) (string, error) {
+	var sig Signature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return "", fmt.Errorf("the signature is not a valid cosign signature: %v", err)
+	}
+	rawSignature, err := base64.StdEncoding.DecodeString(sig.Base64Signature)
+	if err != nil {
+		return "", fmt.Errorf("the signature is not valid base64: %v", err)
+	}
+	hash := sha256.Sum256(sig.Payload)
+	if !ecdsa.VerifyASN1(key, hash[:], rawSignature) {
+		return "", fmt.Errorf("invalid signature")
+	}
+	return verifyPayload(sig.Payload, releaseDigest)
+}
+
+// A simple signing payload has the following schema:
+//
+//	{
+//		"critical": {
+//				"type": "cosign container image signature",
+//				"image": {
+//						"docker-manifest-digest": "sha256:817a12c32a39bbe394944ba49de563e085f1d3c5266eb8e9723256bc4448680e"
+//				},
+//				"identity": {
+//						"docker-reference": "quay.io/openshift-release-dev/ocp-release"
+//				}
+//		},
+//		"optional": null
+//	}
+//
+// Unlike the critical section, the optional section may contain arbitrary keys.
+type payload struct {
+	Critical criticalPayload        `json:"critical"`
+	Optional map[string]interface{} `json:"optional"`
+}
+
+type criticalPayload struct {
+	Type     string           `json:"type"`
+	Image    criticalImage    `json:"image"`
+	Identity criticalIdentity `json:"identity"`
+}
+
+type criticalImage struct {
+	DockerManifestDigest string `json:"docker-manifest-digest"`
+}
+
+type criticalIdentity struct {
+	DockerReference string `json:"docker-reference"`
+}
+
+// verifyPayload verifies that the provided payload authenticates the specified release digest
+// and returns its docker reference.
+func verifyPayload(data []byte, releaseDigest string) (string, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	var p payload
+	if err := d.Decode(&p); err != nil {
+		return "", fmt.Errorf("the signature payload is not valid JSON: %v", err)
+	}
+	if p.Critical.Type != signatureType {
+		return "", fmt.Errorf("signature is not the correct type")
+	}
+	if len(p.Critical.Identity.DockerReference) == 0 {
+		return "", fmt.Errorf("signature must have an identity")
+	}
+	if p.Critical.Image.DockerManifestDigest != releaseDigest {
+		return "", fmt.Errorf("signature digest does not match")
+	}
+	return p.Critical.Identity.DockerReference, nil
+}
@@ -0,0 +1,156 @@
+package cosign
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const releaseDigest = "sha256:e3f12513a4b22a2d7c0e7c9207f52128113758d9d68c7d06b11a0ac7672966f7"
+
+func newPayload(digest string) []byte {
+	return []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"quay.io/openshift-release-dev/ocp-release"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":{"creator":"test"}}`, digest))
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, payload []byte) Signature {
+	hash := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Signature{Base64Signature: base64.StdEncoding.EncodeToString(sig), Payload: payload}
+}
+
+func marshal(t *testing.T, sig Signature) []byte {
+	data, err := sig.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := sign(t, key, newPayload(releaseDigest))
+	tampered.Payload = newPayload("sha256:0000")
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{
+			name: "valid signature",
+			data: marshal(t, sign(t, key, newPayload(releaseDigest))),
+		},
+		{
+			name: "valid signature in cosign's field casing",
+			data: []byte(strings.Replace(string(marshal(t, sign(t, key, newPayload(releaseDigest)))), "Base64Signature", "base64Signature", 1)),
+		},
+		{
+			name:    "signed by another key",
+			data:    marshal(t, sign(t, otherKey, newPayload(releaseDigest))),
+			wantErr: "invalid signature",
+		},
+		{
+			name:    "tampered payload",
+			data:    marshal(t, tampered),
+			wantErr: "invalid signature",
+		},
+		{
+			name:    "another digest",
+			data:    marshal(t, sign(t, key, newPayload("sha256:0000"))),
+			wantErr: "signature digest does not match",
+		},
+		{
+			name:    "atomic container signature",
+			data:    marshal(t, sign(t, key, []byte(strings.Replace(string(newPayload(releaseDigest)), "cosign container image signature", "atomic container signature", 1)))),
+			wantErr: "signature is not the correct type",
+		},
+		{
+			name:    "unknown critical field",
+			data:    marshal(t, sign(t, key, []byte(`{"critical":{"type":"cosign container image signature","unknown":true}}`))),
+			wantErr: "the signature payload is not valid JSON",
+		},
+		{
+			name:    "invalid base64",
+			data:    []byte(`{"Base64Signature":"%%%","Payload":""}`),
+			wantErr: "the signature is not valid base64",
+		},
+		{
+			name:    "not JSON",
+			data:    []byte("-----BEGIN PGP MESSAGE-----"),
+			wantErr: "the signature is not a valid cosign signature",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.data, &key.PublicKey, releaseDigest)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func(key interface{}) []byte {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{name: "ECDSA key", data: encode(&ecdsaKey.PublicKey)},
+		{name: "RSA key", data: encode(&rsaKey.PublicKey), wantErr: true},
+		{name: "not PEM", data: []byte("not a key"), wantErr: true},
+		{name: "private key", data: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("secret")}), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePublicKey(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePublicKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !key.Equal(&ecdsaKey.PublicKey) {
+				t.Errorf("unexpected key %v", key)
+			}
+		})
+	}
+}
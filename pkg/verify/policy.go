@@ -0,0 +1,200 @@
+package verify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+	"github.com/openshift/library-go/pkg/verify/store"
+)
+
+// nowFn is used in unit test to freeze time.
+var nowFn = time.Now
+
+// Policy defines which verifiers apply to a release and how many of them must have signed it.
+// The zero policy requires signatures from all verifiers for every release.
+type Policy struct {
+	// Threshold is the number of distinct keys that must have signed a release, out of the keys
+	// of the verifiers applying to the release. If zero, all verifiers applying to the release
+	// must have signed it. A release to which fewer verifiers than the threshold apply fails
+	// verification with a policy error.
+	Threshold int `json:"threshold,omitempty"`
+
+	// Verifiers maps verifier names to their scope and validity. Verifiers without an entry apply
+	// to all releases and do not expire.
+	Verifiers map[string]VerifierPolicy `json:"verifiers,omitempty"`
+
+	// RevokedKeys are the fingerprints of keys whose signatures are rejected, in the format of
+	// Result.Accepted[].Signer: upper case hex for GPG keys and cosign.Fingerprint for cosign keys.
+	RevokedKeys []string `json:"revokedKeys,omitempty"`
+}
+
+// VerifierPolicy scopes a verifier to releases and limits its validity.
+type VerifierPolicy struct {
+	// Releases are patterns of the release versions the verifier applies to in path.Match
+	// syntax, e.g. "4.14.*". If empty, the verifier applies to all releases. Otherwise it does
+	// not apply to releases of unknown version, and it only accepts signatures whose signed
+	// docker reference names the release version in its tag, e.g.
+	// "quay.io/openshift-release-dev/ocp-release:4.14.1-x86_64" for 4.14.1. The release version
+	// passed by the caller is not signed, so this binds the scope to the signed content.
+	Releases []string `json:"releases,omitempty"`
+
+	// NotAfter is the time the verifier expires. Its signatures are rejected afterwards.
+	NotAfter *time.Time `json:"notAfter,omitempty"`
+}
+
+// appliesTo returns true if the verifier applies to the release with the given version.
+func (p VerifierPolicy) appliesTo(releaseVersion string) bool {
+	if len(p.Releases) == 0 {
+		return true
+	}
+	for _, pattern := range p.Releases {
+		if matched, _ := path.Match(pattern, releaseVersion); matched && len(releaseVersion) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySignedReleaseVersion returns an error unless the tag of the signed docker reference is
+// the release version, optionally followed by a "-" and a suffix like the architecture.
+func verifySignedReleaseVersion(dockerReference, releaseVersion string) error {
+	ref, err := reference.Parse(dockerReference)
+	if err != nil {
+		return fmt.Errorf("the signed docker reference %q is invalid: %v", dockerReference, err)
+	}
+	if len(releaseVersion) > 0 && (ref.Tag == releaseVersion || strings.HasPrefix(ref.Tag, releaseVersion+"-")) {
+		return nil
+	}
+	return fmt.Errorf("the signed docker reference %s does not name the release version %q", dockerReference, releaseVersion)
+}
+
+// validate returns an error if the policy refers to verifiers not in names or is otherwise invalid.
+func (p Policy) validate(names map[string]struct{}) error {
+	if p.Threshold < 0 {
+		return fmt.Errorf("the threshold must not be negative")
+	}
+	if p.Threshold > len(names) {
+		return fmt.Errorf("the threshold %d is larger than the number of verifiers %d", p.Threshold, len(names))
+	}
+	for name, verifierPolicy := range p.Verifiers {
+		if _, ok := names[name]; !ok {
+			return fmt.Errorf("the policy refers to the unknown verifier %q", name)
+		}
+		for _, pattern := range verifierPolicy.Releases {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("the verifier %q has an invalid release pattern %q: %v", name, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// scoped returns true if any verifier is limited to some releases.
+func (p Policy) scoped() bool {
+	for _, verifierPolicy := range p.Verifiers {
+		if len(verifierPolicy.Releases) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// expired returns true if any verifier expired before now.
+func (p Policy) expired(now time.Time) bool {
+	for _, verifierPolicy := range p.Verifiers {
+		if verifierPolicy.NotAfter != nil && now.After(*verifierPolicy.NotAfter) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRevoked returns true if the key with the given fingerprint is revoked.
+func (p Policy) isRevoked(signer string) bool {
+	for _, revoked := range p.RevokedKeys {
+		if revoked == signer {
+			return true
+		}
+	}
+	return false
+}
+
+// Result describes the outcome of verifying a release.
+type Result struct {
+	// ReleaseDigest is the digest of the verified release.
+	ReleaseDigest string
+	// ReleaseVersion is the version of the verified release, if known.
+	ReleaseVersion string
+	// Required is the number of verifiers, or distinct keys if the policy has a threshold, that
+	// must have signed the release.
+	Required int
+	// Accepted lists the verifiers which found a valid signature, sorted by name.
+	Accepted []VerifierResult
+	// Rejected lists the verifiers applying to the release which did not find a valid signature,
+	// sorted by name.
+	Rejected []VerifierResult
+	// NotApplicable lists the names of the verifiers not applying to the release, sorted.
+	NotApplicable []string
+}
+
+// VerifierResult describes the outcome of verifying a release with one verifier.
+type VerifierResult struct {
+	// Verifier is the name of the verifier.
+	Verifier string
+	// Signer is the fingerprint of the key which made the accepted signature.
+	Signer string
+	// Reasons describes why signatures were rejected.
+	Reasons []string
+}
+
+// PolicyVerifier is an Interface verifying releases according to a Policy.
+type PolicyVerifier interface {
+	Interface
+
+	// VerifyRelease verifies the release with the given version and digest and describes which
+	// verifiers accepted or rejected it. The version may be empty if it is unknown. Verifiers
+	// scoped to releases only accept signatures naming the version, see VerifierPolicy. A result is
+	// returned with the error if the release could not be verified, unless the inputs are invalid.
+	VerifyRelease(ctx context.Context, releaseVersion, releaseDigest string) (*Result, error)
+}
+
+// NewPolicyReleaseVerifier creates a release verifier for GPG and cosign signatures which
+// applies the given policy. The verifiers of both kinds must have distinct names.
+func NewPolicyReleaseVerifier(verifiers map[string]openpgp.EntityList, cosignVerifiers map[string]*ecdsa.PublicKey, store store.Store, policy Policy) (PolicyVerifier, error) {
+	names := make(map[string]struct{}, len(verifiers)+len(cosignVerifiers))
+	for name := range verifiers {
+		names[name] = struct{}{}
+	}
+	for name := range cosignVerifiers {
+		if _, ok := names[name]; ok {
+			return nil, fmt.Errorf("the verifier name %q is used for a GPG and a cosign key", name)
+		}
+		names[name] = struct{}{}
+	}
+	if err := policy.validate(names); err != nil {
+		return nil, fmt.Errorf("invalid verification policy: %v", err)
+	}
+	return &releaseVerifier{
+		verifiers:       verifiers,
+		cosignVerifiers: cosignVerifiers,
+		policy:          policy,
+		store:           store,
+
+		signatureCache: make(map[string][][]byte),
+	}, nil
+}
+
+// sortVerifierResults sorts the results by verifier name.
+func sortVerifierResults(results []VerifierResult) {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Verifier < results[j].Verifier
+	})
+}
@@ -0,0 +1,334 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+
+	"github.com/openshift/library-go/pkg/verify/cosign"
+	"github.com/openshift/library-go/pkg/verify/store"
+	"github.com/openshift/library-go/pkg/verify/store/bundle"
+	"github.com/openshift/library-go/pkg/verify/store/serial"
+)
+
+func Test_ReleaseVerifier_VerifyRelease(t *testing.T) {
+	const signedDigest = "sha256:e3f12513a4b22a2d7c0e7c9207f52128113758d9d68c7d06b11a0ac7672966f7"
+	const redhatSigner = "567E347AD0044ADE55BA8A5F199E2F91FD431D51"
+
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "keyrings", "redhat.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	redhatPublic, err := openpgp.ReadArmoredKeyRing(bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = ioutil.ReadFile(filepath.Join("testdata", "keyrings", "cosign.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cosignPublic, err := cosign.ParsePublicKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cosignSigner := cosign.Fingerprint(cosignPublic)
+
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	defer func(oldNowFn func() time.Time) { nowFn = oldNowFn }(nowFn)
+	nowFn = func() time.Time { return now }
+	yesterday := now.Add(-24 * time.Hour)
+
+	verifiers := map[string]openpgp.EntityList{"redhat": redhatPublic}
+	cosignVerifiers := map[string]*ecdsa.PublicKey{"cosign": cosignPublic, "missing": &missingKey.PublicKey}
+	signatures := &serial.Store{Stores: []store.Store{
+		&fileStore{directory: "testdata/signatures"},
+		&bundle.Store{Directory: "testdata/bundles"},
+	}}
+
+	tests := []struct {
+		name           string
+		policy         Policy
+		releaseVersion string
+		expected       Result
+		expectedErr    string
+	}{
+		{
+			name: "all verifiers are required",
+			expected: Result{
+				Required: 3,
+				Accepted: []VerifierResult{{Verifier: "cosign", Signer: cosignSigner}, {Verifier: "redhat", Signer: redhatSigner}},
+				Rejected: []VerifierResult{{Verifier: "missing", Reasons: []string{"invalid signature"}}},
+			},
+			expectedErr: "unable to verify " + signedDigest + " against keyrings: missing",
+		},
+		{
+			name:   "threshold",
+			policy: Policy{Threshold: 2},
+			expected: Result{
+				Required: 2,
+				Accepted: []VerifierResult{{Verifier: "cosign", Signer: cosignSigner}, {Verifier: "redhat", Signer: redhatSigner}},
+				Rejected: []VerifierResult{{Verifier: "missing", Reasons: []string{"invalid signature"}}},
+			},
+		},
+		{
+			name:   "threshold not reached",
+			policy: Policy{Threshold: 3},
+			expected: Result{
+				Required: 3,
+				Accepted: []VerifierResult{{Verifier: "cosign", Signer: cosignSigner}, {Verifier: "redhat", Signer: redhatSigner}},
+				Rejected: []VerifierResult{{Verifier: "missing", Reasons: []string{"invalid signature"}}},
+			},
+			expectedErr: "unable to verify " + signedDigest + ": found signatures from 2 distinct keys, 3 required",
+		},
+		{
+			name:           "verifier scoped to other releases",
+			policy:         Policy{Verifiers: map[string]VerifierPolicy{"missing": {Releases: []string{"4.13.*"}}}},
+			releaseVersion: "4.14.1",
+			expected: Result{
+				ReleaseVersion: "4.14.1",
+				Required:       2,
+				Accepted:       []VerifierResult{{Verifier: "cosign", Signer: cosignSigner}, {Verifier: "redhat", Signer: redhatSigner}},
+				NotApplicable:  []string{"missing"},
+			},
+		},
+		{
+			name:           "threshold larger than the number of applicable verifiers",
+			policy:         Policy{Threshold: 3, Verifiers: map[string]VerifierPolicy{"missing": {Releases: []string{"4.13.*"}}}},
+			releaseVersion: "4.14.1",
+			expected: Result{
+				ReleaseVersion: "4.14.1",
+				Required:       3,
+				NotApplicable:  []string{"missing"},
+			},
+			expectedErr: "invalid verification policy: the threshold 3 is larger than the number of verifiers 2 applying to the release",
+		},
+		{
+			name:           "verifier scoped to the release",
+			policy:         Policy{Verifiers: map[string]VerifierPolicy{"missing": {Releases: []string{"4.13.*", "4.14.*"}}}},
+			releaseVersion: "4.14.1",
+			expected: Result{
+				ReleaseVersion: "4.14.1",
+				Required:       3,
+				Accepted:       []VerifierResult{{Verifier: "cosign", Signer: cosignSigner}, {Verifier: "redhat", Signer: redhatSigner}},
+				Rejected:       []VerifierResult{{Verifier: "missing", Reasons: []string{"invalid signature"}}},
+			},
+			expectedErr: "against keyrings: missing",
+		},
+		{
+			name:           "scoped verifier accepts signatures naming the release version",
+			policy:         Policy{Threshold: 2, Verifiers: map[string]VerifierPolicy{"redhat": {Releases: []string{"7.*"}}}},
+			releaseVersion: "7.6",
+			expected: Result{
+				ReleaseVersion: "7.6",
+				Required:       2,
+				Accepted:       []VerifierResult{{Verifier: "cosign", Signer: cosignSigner}, {Verifier: "redhat", Signer: redhatSigner}},
+				Rejected:       []VerifierResult{{Verifier: "missing", Reasons: []string{"invalid signature"}}},
+			},
+		},
+		{
+			name: "scoped verifiers reject signatures not naming the release version",
+			policy: Policy{Threshold: 1, Verifiers: map[string]VerifierPolicy{
+				"cosign": {Releases: []string{"*"}},
+				"redhat": {Releases: []string{"7.*"}},
+			}},
+			releaseVersion: "7.7",
+			expected: Result{
+				ReleaseVersion: "7.7",
+				Required:       1,
+				Rejected: []VerifierResult{
+					{Verifier: "cosign", Reasons: []string{`the signed docker reference quay.io/openshift-release-dev/ocp-release does not name the release version "7.7"`}},
+					{Verifier: "missing", Reasons: []string{"invalid signature"}},
+					{Verifier: "redhat", Reasons: []string{`the signed docker reference registry.access.redhat.com/rhel7:7.6 does not name the release version "7.7"`}},
+				},
+			},
+			expectedErr: "found signatures from 0 distinct keys, 1 required",
+		},
+		{
+			name:   "scoped verifiers do not apply to releases of unknown version",
+			policy: Policy{Verifiers: map[string]VerifierPolicy{"missing": {Releases: []string{"*"}}}},
+			expected: Result{
+				Required:      2,
+				Accepted:      []VerifierResult{{Verifier: "cosign", Signer: cosignSigner}, {Verifier: "redhat", Signer: redhatSigner}},
+				NotApplicable: []string{"missing"},
+			},
+		},
+		{
+			name: "expired verifier",
+			policy: Policy{Threshold: 2, Verifiers: map[string]VerifierPolicy{
+				"cosign": {NotAfter: &yesterday},
+			}},
+			expected: Result{
+				Required: 2,
+				Accepted: []VerifierResult{{Verifier: "redhat", Signer: redhatSigner}},
+				Rejected: []VerifierResult{
+					{Verifier: "cosign", Reasons: []string{"the verifier expired on 2023-05-31T00:00:00Z"}},
+					{Verifier: "missing", Reasons: []string{"invalid signature"}},
+				},
+			},
+			expectedErr: "found signatures from 1 distinct keys, 2 required",
+		},
+		{
+			name:   "revoked key",
+			policy: Policy{Threshold: 1, RevokedKeys: []string{redhatSigner, cosignSigner}},
+			expected: Result{
+				Required: 1,
+				Rejected: []VerifierResult{
+					{Verifier: "cosign", Reasons: []string{"the signature was made with the revoked key " + cosignSigner}},
+					{Verifier: "missing", Reasons: []string{"invalid signature"}},
+					{Verifier: "redhat", Reasons: []string{"the signature was made with the revoked key " + redhatSigner}},
+				},
+			},
+			expectedErr: "found signatures from 0 distinct keys, 1 required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewPolicyReleaseVerifier(verifiers, cosignVerifiers, signatures, tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := v.VerifyRelease(context.Background(), tt.releaseVersion, signedDigest)
+			if len(tt.expectedErr) == 0 && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tt.expectedErr) > 0 && (err == nil || !strings.Contains(err.Error(), tt.expectedErr)) {
+				t.Fatalf("expected error containing %q, got %v", tt.expectedErr, err)
+			}
+
+			// a verifier rejects every signature it cannot verify, only check for the expected reason
+			for i, rejected := range result.Rejected {
+				if i >= len(tt.expected.Rejected) || len(tt.expected.Rejected[i].Reasons) == 0 {
+					break
+				}
+				expectedReason := tt.expected.Rejected[i].Reasons[0]
+				found := false
+				for _, reason := range rejected.Reasons {
+					found = found || strings.Contains(reason, expectedReason)
+				}
+				if !found {
+					t.Errorf("expected %s to be rejected because %q, got %q", rejected.Verifier, expectedReason, rejected.Reasons)
+				}
+				result.Rejected[i].Reasons = tt.expected.Rejected[i].Reasons
+			}
+			tt.expected.ReleaseDigest = signedDigest
+			if !reflect.DeepEqual(*result, tt.expected) {
+				t.Errorf("expected result\n%#v\ngot\n%#v", tt.expected, *result)
+			}
+		})
+	}
+}
+
+func Test_ReleaseVerifier_Verify_expiredVerifier(t *testing.T) {
+	const signedDigest = "sha256:e3f12513a4b22a2d7c0e7c9207f52128113758d9d68c7d06b11a0ac7672966f7"
+
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "keyrings", "cosign.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cosignPublic, err := cosign.ParsePublicKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	defer func(oldNowFn func() time.Time) { nowFn = oldNowFn }(nowFn)
+	nowFn = func() time.Time { return now }
+	tomorrow := now.Add(24 * time.Hour)
+
+	v, err := NewPolicyReleaseVerifier(nil, map[string]*ecdsa.PublicKey{"cosign": cosignPublic}, &bundle.Store{Directory: "testdata/bundles"}, Policy{
+		Verifiers: map[string]VerifierPolicy{"cosign": {NotAfter: &tomorrow}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Verify(context.Background(), signedDigest); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(v.Signatures()[signedDigest]) == 0 {
+		t.Fatalf("expected the verification to be cached")
+	}
+
+	// the cached verification must not outlive the verifier
+	now = tomorrow.Add(time.Hour)
+	if err := v.Verify(context.Background(), signedDigest); err == nil || !strings.Contains(err.Error(), "against keyrings: cosign") {
+		t.Fatalf("expected the expired verifier to reject the release, got %v", err)
+	}
+}
+
+func Test_NewPolicyReleaseVerifier(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifiers := map[string]openpgp.EntityList{"gpg": nil}
+	cosignVerifiers := map[string]*ecdsa.PublicKey{"cosign": &key.PublicKey}
+
+	tests := []struct {
+		name            string
+		cosignVerifiers map[string]*ecdsa.PublicKey
+		policy          Policy
+		wantErr         string
+	}{
+		{name: "empty policy"},
+		{
+			name:   "valid policy",
+			policy: Policy{Threshold: 2, Verifiers: map[string]VerifierPolicy{"gpg": {Releases: []string{"4.1[34].*"}}}},
+		},
+		{
+			name:    "threshold larger than the number of verifiers",
+			policy:  Policy{Threshold: 3},
+			wantErr: "the threshold 3 is larger than the number of verifiers 2",
+		},
+		{
+			name:    "negative threshold",
+			policy:  Policy{Threshold: -1},
+			wantErr: "the threshold must not be negative",
+		},
+		{
+			name:    "unknown verifier",
+			policy:  Policy{Verifiers: map[string]VerifierPolicy{"other": {}}},
+			wantErr: `the policy refers to the unknown verifier "other"`,
+		},
+		{
+			name:    "invalid pattern",
+			policy:  Policy{Verifiers: map[string]VerifierPolicy{"gpg": {Releases: []string{"4.[14"}}}},
+			wantErr: `the verifier "gpg" has an invalid release pattern "4.[14"`,
+		},
+		{
+			name:            "duplicate verifier name",
+			cosignVerifiers: map[string]*ecdsa.PublicKey{"gpg": &key.PublicKey},
+			wantErr:         `the verifier name "gpg" is used for a GPG and a cosign key`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cosignVerifiers == nil {
+				tt.cosignVerifiers = cosignVerifiers
+			}
+			_, err := NewPolicyReleaseVerifier(verifiers, tt.cosignVerifiers, &fileStore{}, tt.policy)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
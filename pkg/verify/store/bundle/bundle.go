@@ -0,0 +1,96 @@
+// Package bundle retrieves signatures from local signature bundles.
+//
+// A bundle is a file named "<ALGO>=<DIGEST>" in the store directory
+// with one signature per line, e.g. the output of
+// "cosign download signature <IMAGE>@<ALGO>:<DIGEST>". This allows
+// signatures to be mirrored into disconnected environments along with
+// the release images.
+package bundle
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/openshift/library-go/pkg/verify/store"
+	"github.com/openshift/library-go/pkg/verify/util"
+)
+
+// maxBundleSize prevents unbounded reads of malicious bundles.
+const maxBundleSize = 1024 * 1024
+
+// Store provides access to signatures stored in bundle files.
+type Store struct {
+	// Directory is the directory holding the bundles.
+	Directory string
+}
+
+// Signatures fetches signatures for the provided digest.
+func (s *Store) Signatures(ctx context.Context, name string, digest string, fn store.Callback) error {
+	equalDigest, err := util.DigestToKeyPrefix(digest, "=")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.Directory, equalDigest)
+
+	data, err := readBundle(path)
+	if os.IsNotExist(err) {
+		_, err = fn(ctx, nil, fmt.Errorf("%s: %w", path, store.ErrNotFound))
+		return err
+	}
+	if err != nil {
+		_, err = fn(ctx, nil, fmt.Errorf("unable to read signature bundle %s: %w", path, err))
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, maxBundleSize+1)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		signature := bytes.TrimSpace(scanner.Bytes())
+		if len(signature) == 0 {
+			continue
+		}
+		done, err := fn(ctx, append([]byte(nil), signature...), nil)
+		if done || err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		_, err = fn(ctx, nil, fmt.Errorf("unable to read signature bundle %s: %w", path, err))
+		return err
+	}
+
+	_, err = fn(ctx, nil, fmt.Errorf("%s: %w", path, store.ErrNotFound))
+	return err
+}
+
+// readBundle reads the bundle at path, being careful not to allow unbounded reads.
+func readBundle(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxBundleSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBundleSize {
+		return nil, fmt.Errorf("bundle is larger than %d bytes", maxBundleSize)
+	}
+	return data, nil
+}
+
+// String returns a description of where this store finds
+// signatures.
+func (s *Store) String() string {
+	return fmt.Sprintf("signature bundles in %s", s.Directory)
+}
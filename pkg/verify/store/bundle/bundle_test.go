@@ -0,0 +1,72 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/verify/store"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sha256=123"), []byte("signature-1\n\nsignature-2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &Store{Directory: dir}
+
+	tests := []struct {
+		name               string
+		digest             string
+		doneAfter          int
+		expectedSignatures []string
+		expectedNotFound   bool
+	}{
+		{
+			name:               "all signatures",
+			digest:             "sha256:123",
+			expectedSignatures: []string{"signature-1", "signature-2"},
+			expectedNotFound:   true,
+		},
+		{
+			name:               "stops when done",
+			digest:             "sha256:123",
+			doneAfter:          1,
+			expectedSignatures: []string{"signature-1"},
+		},
+		{
+			name:             "no bundle",
+			digest:           "sha256:456",
+			expectedNotFound: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var signatures []string
+			notFound := false
+			err := s.Signatures(context.Background(), "", tt.digest, func(ctx context.Context, signature []byte, errIn error) (bool, error) {
+				if errIn != nil {
+					if !errors.Is(errIn, store.ErrNotFound) {
+						t.Fatalf("unexpected error: %v", errIn)
+					}
+					notFound = true
+					return false, nil
+				}
+				signatures = append(signatures, string(signature))
+				return len(signatures) == tt.doneAfter, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(signatures, tt.expectedSignatures) {
+				t.Errorf("expected signatures %q, got %q", tt.expectedSignatures, signatures)
+			}
+			if notFound != tt.expectedNotFound {
+				t.Errorf("expected not found %v, got %v", tt.expectedNotFound, notFound)
+			}
+		})
+	}
+}
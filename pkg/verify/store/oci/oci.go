@@ -0,0 +1,217 @@
+// Package oci retrieves cosign signatures attached to release images in
+// a registry.
+//
+// Signatures are discovered with the OCI referrers API [1], which lists
+// the manifests with the cosign signature artifact type, and with the
+// cosign tag convention, where the signatures of the image with digest
+// "<ALGO>:<DIGEST>" are stored in the manifest tagged
+// "<ALGO>-<DIGEST>.sig" in the same repository. Each layer of a
+// signature manifest with the cosign simple signing media type is passed
+// to the callback in the format expected by cosign.Verify.
+//
+// [1]: https://github.com/opencontainers/distribution-spec/blob/main/spec.md#listing-referrers
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/distribution/distribution/v3"
+	"github.com/distribution/distribution/v3/manifest/ocischema"
+	"github.com/distribution/distribution/v3/manifest/schema2"
+	"github.com/distribution/distribution/v3/registry/api/errcode"
+	"github.com/distribution/distribution/v3/registry/client"
+	"github.com/opencontainers/go-digest"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+	"github.com/openshift/library-go/pkg/image/registryclient"
+	"github.com/openshift/library-go/pkg/verify/cosign"
+	"github.com/openshift/library-go/pkg/verify/store"
+	"github.com/openshift/library-go/pkg/verify/util"
+)
+
+// maxSignatureSearch prevents unbounded recursion on malicious signature stores (if
+// an attacker was able to take ownership of the store to perform DoS on clusters).
+const maxSignatureSearch = 10
+
+// maxPayloadSize prevents unbounded reads of malicious payload blobs.
+const maxPayloadSize = 50 * 1024
+
+// Store provides access to signatures stored next to release images in a registry.
+type Store struct {
+	// Repository is the repository holding the release images and their signatures,
+	// e.g. the repository the release images are mirrored to.
+	Repository reference.DockerImageReference
+
+	// RegistryClient is used to connect to the registry.
+	RegistryClient *registryclient.Context
+
+	// Insecure allows HTTP connections and ignores HTTPS certificate verification errors.
+	Insecure bool
+}
+
+// Signatures fetches signatures for the provided digest.
+func (s *Store) Signatures(ctx context.Context, name string, digestString string, fn store.Callback) error {
+	dgst, err := digest.Parse(digestString)
+	if err != nil {
+		return err
+	}
+	tag, err := util.DigestToKeyPrefix(digestString, "-")
+	if err != nil {
+		return err
+	}
+	tag += ".sig"
+
+	repo, err := s.RegistryClient.RepositoryForRef(ctx, s.Repository, s.Insecure)
+	if err != nil {
+		_, err = fn(ctx, nil, fmt.Errorf("unable to connect to %s: %w", s, err))
+		return err
+	}
+	manifests, err := repo.Manifests(ctx)
+	if err != nil {
+		_, err = fn(ctx, nil, fmt.Errorf("unable to access the manifests of %s: %w", s, err))
+		return err
+	}
+
+	var signatureManifests []digest.Digest
+	referrers, err := s.RegistryClient.Referrers(ctx, s.Repository, dgst, cosign.SignatureArtifactType, s.Insecure)
+	switch {
+	case errors.Is(err, registryclient.ErrReferrersNotSupported):
+	case err != nil:
+		done, err := fn(ctx, nil, fmt.Errorf("unable to list the referrers of %s in %s: %w", digestString, s, err))
+		if done || err != nil {
+			return err
+		}
+	default:
+		for _, referrer := range referrers {
+			signatureManifests = append(signatureManifests, referrer.Digest)
+		}
+	}
+
+	desc, err := repo.Tags(ctx).Get(ctx, tag)
+	switch {
+	case isNotFound(err):
+	case err != nil:
+		done, err := fn(ctx, nil, fmt.Errorf("unable to get the signature tag %s in %s: %w", tag, s, err))
+		if done || err != nil {
+			return err
+		}
+	default:
+		if !containsDigest(signatureManifests, desc.Digest) {
+			signatureManifests = append(signatureManifests, desc.Digest)
+		}
+	}
+
+	blobs := repo.Blobs(ctx)
+	checked := 0
+	for i, manifestDigest := range signatureManifests {
+		if i >= maxSignatureSearch {
+			break
+		}
+		manifest, err := manifests.Get(ctx, manifestDigest)
+		if err != nil {
+			done, err := fn(ctx, nil, fmt.Errorf("unable to get the signature manifest %s in %s: %w", manifestDigest, s, err))
+			if done || err != nil {
+				return err
+			}
+			continue
+		}
+		for _, layer := range signatureLayers(manifest) {
+			if checked >= maxSignatureSearch {
+				_, err := fn(ctx, nil, fmt.Errorf("%s has more than %d signatures for %s: %w", s, maxSignatureSearch, digestString, store.ErrNotFound))
+				return err
+			}
+			checked++
+
+			signature, err := s.signature(ctx, blobs, layer)
+			if err != nil {
+				done, err := fn(ctx, nil, err)
+				if done || err != nil {
+					return err
+				}
+				continue
+			}
+			done, err := fn(ctx, signature, nil)
+			if done || err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = fn(ctx, nil, fmt.Errorf("%s %s: %w", s, digestString, store.ErrNotFound))
+	return err
+}
+
+// isNotFound returns true if the registry reported that the requested content does not exist.
+func isNotFound(err error) bool {
+	var errs errcode.Errors
+	if errors.As(err, &errs) {
+		for _, err := range errs {
+			if isNotFound(err) {
+				return true
+			}
+		}
+	}
+	var codeErr errcode.Error
+	if errors.As(err, &codeErr) && codeErr.Code.Descriptor().HTTPStatusCode == http.StatusNotFound {
+		return true
+	}
+	var code errcode.ErrorCode
+	if errors.As(err, &code) && code.Descriptor().HTTPStatusCode == http.StatusNotFound {
+		return true
+	}
+	var responseErr *client.UnexpectedHTTPResponseError
+	return errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound
+}
+
+func containsDigest(digests []digest.Digest, dgst digest.Digest) bool {
+	for _, d := range digests {
+		if d == dgst {
+			return true
+		}
+	}
+	return false
+}
+
+// signatureLayers returns the layers of the manifest holding simple signing payloads.
+func signatureLayers(manifest distribution.Manifest) []distribution.Descriptor {
+	var layers []distribution.Descriptor
+	switch m := manifest.(type) {
+	case *ocischema.DeserializedManifest:
+		layers = m.Layers
+	case *schema2.DeserializedManifest:
+		layers = m.Layers
+	}
+
+	var signatureLayers []distribution.Descriptor
+	for _, layer := range layers {
+		if layer.MediaType == cosign.SimpleSigningMediaType {
+			signatureLayers = append(signatureLayers, layer)
+		}
+	}
+	return signatureLayers
+}
+
+// signature returns the cosign signature of the given signature layer.
+func (s *Store) signature(ctx context.Context, blobs distribution.BlobStore, layer distribution.Descriptor) ([]byte, error) {
+	base64Signature, ok := layer.Annotations[cosign.SignatureAnnotation]
+	if !ok {
+		return nil, fmt.Errorf("the signature layer %s in %s has no %s annotation", layer.Digest, s, cosign.SignatureAnnotation)
+	}
+	if layer.Size > maxPayloadSize {
+		return nil, fmt.Errorf("the signature layer %s in %s is larger than %d bytes", layer.Digest, s, maxPayloadSize)
+	}
+	payload, err := blobs.Get(ctx, layer.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the signature layer %s in %s: %w", layer.Digest, s, err)
+	}
+	return cosign.Signature{Base64Signature: base64Signature, Payload: payload}.Marshal()
+}
+
+// String returns a description of where this store finds
+// signatures.
+func (s *Store) String() string {
+	return fmt.Sprintf("cosign signatures in %s", s.Repository.Exact())
+}
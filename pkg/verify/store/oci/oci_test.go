@@ -0,0 +1,245 @@
+package oci
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+	"github.com/openshift/library-go/pkg/image/registryclient"
+	"github.com/openshift/library-go/pkg/verify/cosign"
+	"github.com/openshift/library-go/pkg/verify/store"
+)
+
+const releaseDigest = "sha256:e3f12513a4b22a2d7c0e7c9207f52128113758d9d68c7d06b11a0ac7672966f7"
+
+// fakeRegistry serves manifests and blobs by digest or tag and referrers of the release digest
+// from memory.
+type fakeRegistry struct {
+	// content maps digests and tags to their media type and content.
+	content map[string]fakeContent
+	// referrers are the descriptors served by the referrers API, or nil if the API is not supported.
+	referrers []map[string]interface{}
+}
+
+type fakeContent struct {
+	mediaType string
+	data      []byte
+}
+
+func (r *fakeRegistry) add(mediaType string, data []byte, tags ...string) digest.Digest {
+	dgst := digest.FromBytes(data)
+	r.content[dgst.String()] = fakeContent{mediaType: mediaType, data: data}
+	for _, tag := range tags {
+		r.content[tag] = fakeContent{mediaType: mediaType, data: data}
+	}
+	return dgst
+}
+
+// addSignature adds a signature manifest for the release digest with a layer per signature.
+func (r *fakeRegistry) addSignature(t *testing.T, tags []string, signatures ...cosign.Signature) digest.Digest {
+	config := []byte("{}")
+	layers := []map[string]interface{}{}
+	for _, sig := range signatures {
+		layers = append(layers, map[string]interface{}{
+			"mediaType":   cosign.SimpleSigningMediaType,
+			"size":        len(sig.Payload),
+			"digest":      r.add("application/octet-stream", sig.Payload),
+			"annotations": map[string]string{cosign.SignatureAnnotation: sig.Base64Signature},
+		})
+	}
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"config": map[string]interface{}{
+			"mediaType": "application/vnd.oci.image.config.v1+json",
+			"size":      len(config),
+			"digest":    r.add("application/octet-stream", config),
+		},
+		"layers": layers,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r.add("application/vnd.oci.image.manifest.v1+json", manifest, tags...)
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	const prefix = "/v2/release/"
+	switch {
+	case req.URL.Path == "/v2/":
+		w.WriteHeader(http.StatusOK)
+	case req.URL.Path == prefix+"referrers/"+releaseDigest:
+		if r.referrers == nil {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.oci.image.index.v1+json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"schemaVersion": 2, "manifests": r.referrers})
+	case strings.HasPrefix(req.URL.Path, prefix+"manifests/"), strings.HasPrefix(req.URL.Path, prefix+"blobs/"):
+		name := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+		content, ok := r.content[name]
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`)
+			return
+		}
+		w.Header().Set("Content-Type", content.mediaType)
+		w.Header().Set("Docker-Content-Digest", digest.FromBytes(content.data).String())
+		w.Header().Set("Content-Length", fmt.Sprint(len(content.data)))
+		if req.Method != http.MethodHead {
+			w.Write(content.data)
+		}
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, creator string) cosign.Signature {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"example.com/release"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":{"creator":%q}}`, releaseDigest, creator))
+	hash := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cosign.Signature{Base64Signature: base64.StdEncoding.EncodeToString(sig), Payload: payload}
+}
+
+func TestStore(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagSignature := sign(t, key, "tag")
+	referrerSignature := sign(t, key, "referrer")
+
+	tests := []struct {
+		name               string
+		setup              func(r *fakeRegistry)
+		expectedSignatures []cosign.Signature
+		expectedErrors     []string
+	}{
+		{
+			name: "cosign tag",
+			setup: func(r *fakeRegistry) {
+				r.addSignature(t, []string{"sha256-e3f12513a4b22a2d7c0e7c9207f52128113758d9d68c7d06b11a0ac7672966f7.sig"}, tagSignature)
+			},
+			expectedSignatures: []cosign.Signature{tagSignature},
+		},
+		{
+			name: "referrers",
+			setup: func(r *fakeRegistry) {
+				dgst := r.addSignature(t, nil, referrerSignature)
+				r.referrers = []map[string]interface{}{{"mediaType": "application/vnd.oci.image.manifest.v1+json", "artifactType": cosign.SignatureArtifactType, "digest": dgst, "size": 1}}
+			},
+			expectedSignatures: []cosign.Signature{referrerSignature},
+		},
+		{
+			name: "referrers and cosign tag",
+			setup: func(r *fakeRegistry) {
+				r.addSignature(t, []string{"sha256-e3f12513a4b22a2d7c0e7c9207f52128113758d9d68c7d06b11a0ac7672966f7.sig"}, tagSignature)
+				dgst := r.addSignature(t, nil, referrerSignature)
+				r.referrers = []map[string]interface{}{{"mediaType": "application/vnd.oci.image.manifest.v1+json", "artifactType": cosign.SignatureArtifactType, "digest": dgst, "size": 1}}
+			},
+			expectedSignatures: []cosign.Signature{referrerSignature, tagSignature},
+		},
+		{
+			name: "referrers of other artifact types do not count as signatures",
+			setup: func(r *fakeRegistry) {
+				for i := 0; i < maxSignatureSearch; i++ {
+					r.referrers = append(r.referrers, map[string]interface{}{"mediaType": "application/vnd.oci.image.manifest.v1+json", "artifactType": "application/spdx+json", "digest": digest.FromString(fmt.Sprintf("sbom-%d", i)), "size": 1})
+				}
+				dgst := r.addSignature(t, nil, referrerSignature)
+				r.referrers = append(r.referrers, map[string]interface{}{"mediaType": "application/vnd.oci.image.manifest.v1+json", "artifactType": cosign.SignatureArtifactType, "digest": dgst, "size": 1})
+			},
+			expectedSignatures: []cosign.Signature{referrerSignature},
+		},
+		{
+			name: "missing referrer manifest",
+			setup: func(r *fakeRegistry) {
+				r.referrers = []map[string]interface{}{{"mediaType": "application/vnd.oci.image.manifest.v1+json", "artifactType": cosign.SignatureArtifactType, "digest": digest.FromString("missing"), "size": 1}}
+			},
+			expectedErrors: []string{"unable to get the signature manifest " + digest.FromString("missing").String()},
+		},
+		{
+			name:  "no signatures",
+			setup: func(r *fakeRegistry) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := &fakeRegistry{content: map[string]fakeContent{}}
+			tt.setup(registry)
+			server := httptest.NewServer(registry)
+			defer server.Close()
+
+			ref, err := reference.Parse(strings.TrimPrefix(server.URL, "http://") + "/release")
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := &Store{
+				Repository:     ref,
+				RegistryClient: registryclient.NewContext(http.DefaultTransport, http.DefaultTransport),
+				Insecure:       true,
+			}
+
+			var signatures []cosign.Signature
+			var errs []string
+			notFound := false
+			err = s.Signatures(context.Background(), "", releaseDigest, func(ctx context.Context, signature []byte, errIn error) (bool, error) {
+				if errors.Is(errIn, store.ErrNotFound) {
+					notFound = true
+					return false, nil
+				}
+				if errIn != nil {
+					errs = append(errs, errIn.Error())
+					return false, nil
+				}
+				if err := cosign.Verify(signature, &key.PublicKey, releaseDigest); err != nil {
+					t.Errorf("unexpected invalid signature: %v", err)
+				}
+				var sig cosign.Signature
+				if err := json.Unmarshal(signature, &sig); err != nil {
+					t.Fatal(err)
+				}
+				signatures = append(signatures, sig)
+				return false, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !notFound {
+				t.Errorf("expected the store to report running out of signatures")
+			}
+			if len(signatures) != len(tt.expectedSignatures) {
+				t.Fatalf("expected %d signatures, got %d", len(tt.expectedSignatures), len(signatures))
+			}
+			for i := range signatures {
+				if signatures[i].Base64Signature != tt.expectedSignatures[i].Base64Signature {
+					t.Errorf("expected signature %d to be %s, got %s", i, tt.expectedSignatures[i].Payload, signatures[i].Payload)
+				}
+			}
+			if len(errs) != len(tt.expectedErrors) {
+				t.Fatalf("expected errors %q, got %q", tt.expectedErrors, errs)
+			}
+			for i := range errs {
+				if !strings.Contains(errs[i], tt.expectedErrors[i]) {
+					t.Errorf("expected error %d to contain %q, got %q", i, tt.expectedErrors[i], errs[i])
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package verify
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -20,6 +21,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/klog/v2"
 
+	"github.com/openshift/library-go/pkg/verify/cosign"
 	"github.com/openshift/library-go/pkg/verify/store"
 	"github.com/openshift/library-go/pkg/verify/store/serial"
 	"github.com/openshift/library-go/pkg/verify/util"
@@ -37,7 +39,7 @@ type Interface interface {
 	// so far. It may return no signatures.
 	Signatures() map[string][][]byte
 
-	// Verifiers returns a copy of the verifiers in this payload.
+	// Verifiers returns a copy of the GPG verifiers in this payload.
 	Verifiers() map[string]openpgp.EntityList
 
 	// AddStore adds additional stores for signature verification.
@@ -90,6 +92,12 @@ var validReleaseDigest = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-z
 type releaseVerifier struct {
 	verifiers map[string]openpgp.EntityList
 
+	// cosignVerifiers are the public keys of cosign signatures, see the cosign package.
+	cosignVerifiers map[string]*ecdsa.PublicKey
+
+	// policy scopes the verifiers and defines how many must have signed a release.
+	policy Policy
+
 	// store is the store from which release signatures are retrieved.
 	store store.Store
 
@@ -107,7 +115,18 @@ func NewReleaseVerifier(verifiers map[string]openpgp.EntityList, store store.Sto
 	}
 }
 
-// Verifiers returns a copy of the verifiers in this payload.
+// NewCosignReleaseVerifier creates a release verifier for cosign signatures by the provided
+// ECDSA public keys. The store must provide signatures in the format expected by cosign.Verify.
+func NewCosignReleaseVerifier(verifiers map[string]*ecdsa.PublicKey, store store.Store) Interface {
+	return &releaseVerifier{
+		cosignVerifiers: verifiers,
+		store:           store,
+
+		signatureCache: make(map[string][][]byte),
+	}
+}
+
+// Verifiers returns a copy of the GPG verifiers in this payload.
 func (v *releaseVerifier) Verifiers() map[string]openpgp.EntityList {
 	out := make(map[string]openpgp.EntityList, len(v.verifiers))
 	for k, v := range v.verifiers {
@@ -126,33 +145,55 @@ func (v *releaseVerifier) String() string {
 
 	var builder strings.Builder
 	builder.Grow(256)
-	fmt.Fprintf(&builder, "All release image digests must have GPG signatures from")
-	if len(keys) == 0 {
-		fmt.Fprint(&builder, " <ERROR: no verifiers>")
-	}
-	for _, name := range keys {
-		verifier := v.verifiers[name]
-		fmt.Fprintf(&builder, " %s (", name)
-		for i, entity := range verifier {
-			if i != 0 {
-				fmt.Fprint(&builder, ", ")
-			}
-			if entity.PrimaryKey != nil {
-				fmt.Fprintf(&builder, strings.ToUpper(fmt.Sprintf("%x", entity.PrimaryKey.Fingerprint)))
-				fmt.Fprint(&builder, ": ")
-			}
-			count := 0
-			for identityName := range entity.Identities {
-				if count != 0 {
+	fmt.Fprintf(&builder, "All release image digests must have")
+	format := "containers/image"
+	if len(keys) > 0 || len(v.cosignVerifiers) == 0 {
+		fmt.Fprint(&builder, " GPG signatures from")
+		if len(keys) == 0 {
+			fmt.Fprint(&builder, " <ERROR: no verifiers>")
+		}
+		for _, name := range keys {
+			verifier := v.verifiers[name]
+			fmt.Fprintf(&builder, " %s (", name)
+			for i, entity := range verifier {
+				if i != 0 {
 					fmt.Fprint(&builder, ", ")
 				}
-				fmt.Fprintf(&builder, "%s", identityName)
-				count++
+				if entity.PrimaryKey != nil {
+					fmt.Fprintf(&builder, strings.ToUpper(fmt.Sprintf("%x", entity.PrimaryKey.Fingerprint)))
+					fmt.Fprint(&builder, ": ")
+				}
+				count := 0
+				for identityName := range entity.Identities {
+					if count != 0 {
+						fmt.Fprint(&builder, ", ")
+					}
+					fmt.Fprintf(&builder, "%s", identityName)
+					count++
+				}
 			}
+			fmt.Fprint(&builder, ")")
+		}
+	}
+	if len(v.cosignVerifiers) > 0 {
+		if len(keys) > 0 {
+			fmt.Fprint(&builder, " and")
+			format = "containers/image or cosign"
+		} else {
+			format = "cosign"
+		}
+		var cosignKeys []string
+		for name := range v.cosignVerifiers {
+			cosignKeys = append(cosignKeys, name)
+		}
+		sort.Strings(cosignKeys)
+		fmt.Fprint(&builder, " cosign signatures from")
+		for _, name := range cosignKeys {
+			key := v.cosignVerifiers[name]
+			fmt.Fprintf(&builder, " %s (ECDSA %s %s)", name, key.Curve.Params().Name, cosign.Fingerprint(key))
 		}
-		fmt.Fprint(&builder, ")")
 	}
-	fmt.Fprintf(&builder, " - will check for signatures in containers/image format at")
+	fmt.Fprintf(&builder, " - will check for signatures in %s format at", format)
 	if v.store == nil {
 		fmt.Fprint(&builder, " <ERROR: no store>")
 	} else {
@@ -165,7 +206,32 @@ func (v *releaseVerifier) String() string {
 // matching release digest in any of the provided locations for all verifiers, or returns
 // an error.
 func (v *releaseVerifier) Verify(ctx context.Context, releaseDigest string) error {
-	if len(v.verifiers) == 0 || v.store == nil {
+	if err := v.validate(releaseDigest); err != nil {
+		return err
+	}
+
+	// the cached signatures may have been verified for another release version or by a verifier
+	// which expired since
+	if !v.policy.scoped() && !v.policy.expired(nowFn()) && v.hasVerified(releaseDigest) {
+		return nil
+	}
+
+	_, err := v.verifyRelease(ctx, "", releaseDigest)
+	return err
+}
+
+// VerifyRelease verifies the release with the given version and digest according to the
+// policy of the verifier and describes which verifiers accepted or rejected it.
+func (v *releaseVerifier) VerifyRelease(ctx context.Context, releaseVersion, releaseDigest string) (*Result, error) {
+	if err := v.validate(releaseDigest); err != nil {
+		return nil, err
+	}
+	return v.verifyRelease(ctx, releaseVersion, releaseDigest)
+}
+
+// validate returns an error if the verifier is not configured or the digest is invalid.
+func (v *releaseVerifier) validate(releaseDigest string) error {
+	if len(v.verifiers)+len(v.cosignVerifiers) == 0 || v.store == nil {
 		return fmt.Errorf("the release verifier is incorrectly configured, unable to verify digests")
 	}
 	if len(releaseDigest) == 0 {
@@ -174,14 +240,56 @@ func (v *releaseVerifier) Verify(ctx context.Context, releaseDigest string) erro
 	if !validReleaseDigest.MatchString(releaseDigest) {
 		return fmt.Errorf("the provided release image digest has an invalid format: %q", releaseDigest)
 	}
+	return nil
+}
 
-	if v.hasVerified(releaseDigest) {
-		return nil
+func (v *releaseVerifier) verifyRelease(ctx context.Context, releaseVersion, releaseDigest string) (*Result, error) {
+	result := &Result{
+		ReleaseDigest:  releaseDigest,
+		ReleaseVersion: releaseVersion,
 	}
 
-	remaining := make(map[string]openpgp.EntityList, len(v.verifiers))
-	for k, v := range v.verifiers {
-		remaining[k] = v
+	now := nowFn()
+	remaining := make(map[string]signatureVerifier, len(v.verifiers)+len(v.cosignVerifiers))
+	rejections := make(map[string][]string)
+	addVerifier := func(name string, verifier signatureVerifier) {
+		policy := v.policy.Verifiers[name]
+		switch {
+		case !policy.appliesTo(releaseVersion):
+			result.NotApplicable = append(result.NotApplicable, name)
+		case policy.NotAfter != nil && now.After(*policy.NotAfter):
+			rejections[name] = []string{fmt.Sprintf("the verifier expired on %s", policy.NotAfter.Format(time.RFC3339))}
+		default:
+			remaining[name] = verifier
+		}
+	}
+	for k, keyring := range v.verifiers {
+		addVerifier(k, keyringVerifier(keyring))
+	}
+	for k, key := range v.cosignVerifiers {
+		addVerifier(k, cosignVerifier(key))
+	}
+	sort.Strings(result.NotApplicable)
+
+	applicable := len(remaining) + len(rejections)
+	if applicable == 0 {
+		return result, fmt.Errorf("none of the verifiers apply to the release %s with version %q", releaseDigest, releaseVersion)
+	}
+	result.Required = v.policy.Threshold
+	if result.Required == 0 {
+		result.Required = applicable
+	}
+	if v.policy.Threshold > applicable {
+		// every verifier contributes at most one key, the threshold cannot be reached
+		return result, fmt.Errorf("invalid verification policy: the threshold %d is larger than the number of verifiers %d applying to the release %s with version %q", v.policy.Threshold, applicable, releaseDigest, releaseVersion)
+	}
+
+	signers := make(map[string]struct{})
+	satisfied := func() bool {
+		if v.policy.Threshold > 0 {
+			return len(signers) >= v.policy.Threshold
+		}
+		return len(result.Accepted) == applicable
 	}
 
 	var signedWith [][]byte
@@ -192,44 +300,90 @@ func (v *releaseVerifier) Verify(ctx context.Context, releaseDigest string) erro
 			errs = append(errs, fmt.Errorf("%s: %w", time.Now().Format(time.RFC3339), errIn))
 			return false, nil
 		}
-		for k, keyring := range remaining {
-			content, _, err := verifySignatureWithKeyring(bytes.NewReader(signature), keyring)
-			if err != nil {
-				klog.V(4).Infof("keyring %q could not verify signature for %s: %v", k, releaseDigest, err)
-				errs = append(errs, fmt.Errorf("%s: %w", time.Now().Format(time.RFC3339), err))
-				continue
+		for k, verifier := range remaining {
+			signer, dockerReference, err := verifier(signature, releaseDigest)
+			if err == nil && v.policy.isRevoked(signer) {
+				err = fmt.Errorf("the signature was made with the revoked key %s", signer)
 			}
-			if err := verifyAtomicContainerSignature(content, releaseDigest); err != nil {
-				klog.V(4).Infof("signature for %s is not valid: %v", releaseDigest, err)
+			if err == nil && len(v.policy.Verifiers[k].Releases) > 0 {
+				err = verifySignedReleaseVersion(dockerReference, releaseVersion)
+			}
+			if err != nil {
+				klog.V(4).Infof("verifier %q could not verify signature for %s: %v", k, releaseDigest, err)
 				errs = append(errs, fmt.Errorf("%s: %w", time.Now().Format(time.RFC3339), err))
+				rejections[k] = append(rejections[k], err.Error())
 				continue
 			}
 			delete(remaining, k)
+			delete(rejections, k)
+			result.Accepted = append(result.Accepted, VerifierResult{Verifier: k, Signer: signer})
+			signers[signer] = struct{}{}
 			signedWith = append(signedWith, signature)
 		}
-		return len(remaining) == 0, nil
+		return satisfied() || len(remaining) == 0, nil
 	})
 	if err != nil {
 		klog.V(4).Infof("Failed to retrieve signatures for %s: %v", releaseDigest, err)
 		errs = append(errs, fmt.Errorf("%s: %w", time.Now().Format(time.RFC3339), err))
 	}
 
-	if len(remaining) > 0 {
-		remainingKeyRings := make([]string, 0, len(remaining))
-		for k := range remaining {
-			remainingKeyRings = append(remainingKeyRings, k)
+	for k := range remaining {
+		if _, ok := rejections[k]; !ok {
+			rejections[k] = []string{"no valid signature found"}
+		}
+	}
+	for k, reasons := range rejections {
+		result.Rejected = append(result.Rejected, VerifierResult{Verifier: k, Reasons: reasons})
+	}
+	sortVerifierResults(result.Accepted)
+	sortVerifierResults(result.Rejected)
+
+	if !satisfied() {
+		var msg string
+		if v.policy.Threshold > 0 {
+			msg = fmt.Sprintf("unable to verify %s: found signatures from %d distinct keys, %d required", releaseDigest, len(signers), v.policy.Threshold)
+		} else {
+			rejectedKeyRings := make([]string, 0, len(result.Rejected))
+			for _, rejected := range result.Rejected {
+				rejectedKeyRings = append(rejectedKeyRings, rejected.Verifier)
+			}
+			msg = fmt.Sprintf("unable to verify %s against keyrings: %s", releaseDigest, strings.Join(rejectedKeyRings, ", "))
 		}
 		err := &wrapError{
-			msg: fmt.Sprintf("unable to verify %s against keyrings: %s", releaseDigest, strings.Join(remainingKeyRings, ", ")),
+			msg: msg,
 			err: errors.NewAggregate(errs),
 		}
 		klog.V(4).Info(err.Error())
-		return err
+		return result, err
 	}
 
 	v.cacheVerification(releaseDigest, signedWith)
 
-	return nil
+	return result, nil
+}
+
+// signatureVerifier returns the fingerprint of the signing key and the signed docker reference
+// if the signature is valid and attests the release digest.
+type signatureVerifier func(signature []byte, releaseDigest string) (signer string, dockerReference string, err error)
+
+// keyringVerifier verifies GPG signatures in the atomic container signature format.
+func keyringVerifier(keyring openpgp.EntityList) signatureVerifier {
+	return func(signature []byte, releaseDigest string) (string, string, error) {
+		content, signer, err := verifySignatureWithKeyring(bytes.NewReader(signature), keyring)
+		if err != nil {
+			return "", "", err
+		}
+		dockerReference, err := verifyAtomicContainerSignature(content, releaseDigest)
+		return signer, dockerReference, err
+	}
+}
+
+// cosignVerifier verifies cosign signatures.
+func cosignVerifier(key *ecdsa.PublicKey) signatureVerifier {
+	return func(signature []byte, releaseDigest string) (string, string, error) {
+		dockerReference, err := cosign.VerifyIdentity(signature, key, releaseDigest)
+		return cosign.Fingerprint(key), dockerReference, err
+	}
 }
 
 // Signatures returns a copy of any cached signatures that have been validated
@@ -283,6 +437,12 @@ type fileStore struct {
 	directory string
 }
 
+// NewFileStore returns a store reading signatures as "signature-1", "signature-2", etc. out of the
+// "<ALGO>=<DIGEST>" subdirectory of directory, the layout of the file:// stores of NewFromManifests.
+func NewFileStore(directory string) store.Store {
+	return &fileStore{directory: directory}
+}
+
 // Signatures reads signatures as "signature-1", "signature-2", etc. out of a digest-based subdirectory.
 func (s *fileStore) Signatures(ctx context.Context, name string, digest string, fn store.Callback) error {
 	digestPathSegment, err := util.DigestToKeyPrefix(digest, "=")
@@ -399,23 +559,23 @@ type optionalSignature struct {
 }
 
 // verifyAtomicContainerSignature verifiers that the provided data authenticates the
-// specified release digest. If error is returned the provided data does NOT authenticate
-// the release digest and the signature must be ignored.
-func verifyAtomicContainerSignature(data []byte, releaseDigest string) error {
+// specified release digest and returns its docker reference. If error is returned the provided
+// data does NOT authenticate the release digest and the signature must be ignored.
+func verifyAtomicContainerSignature(data []byte, releaseDigest string) (string, error) {
 	d := json.NewDecoder(bytes.NewReader(data))
 	d.DisallowUnknownFields()
 	var sig signature
 	if err := d.Decode(&sig); err != nil {
-		return fmt.Errorf("the signature is not valid JSON: %v", err)
+		return "", fmt.Errorf("the signature is not valid JSON: %v", err)
 	}
 	if sig.Critical.Type != "atomic container signature" {
-		return fmt.Errorf("signature is not the correct type")
+		return "", fmt.Errorf("signature is not the correct type")
 	}
 	if len(sig.Critical.Identity.DockerReference) == 0 {
-		return fmt.Errorf("signature must have an identity")
+		return "", fmt.Errorf("signature must have an identity")
 	}
 	if sig.Critical.Image.DockerManifestDigest != releaseDigest {
-		return fmt.Errorf("signature digest does not match")
+		return "", fmt.Errorf("signature digest does not match")
 	}
-	return nil
+	return sig.Critical.Identity.DockerReference, nil
 }
@@ -3,6 +3,9 @@ package verify
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -15,7 +18,9 @@ import (
 
 	"golang.org/x/crypto/openpgp"
 
+	"github.com/openshift/library-go/pkg/verify/cosign"
 	"github.com/openshift/library-go/pkg/verify/store"
+	"github.com/openshift/library-go/pkg/verify/store/bundle"
 	"github.com/openshift/library-go/pkg/verify/store/memory"
 	"github.com/openshift/library-go/pkg/verify/store/serial"
 	"github.com/openshift/library-go/pkg/verify/store/sigstore"
@@ -177,6 +182,88 @@ func Test_ReleaseVerifier_Verify(t *testing.T) {
 	}
 }
 
+func Test_ReleaseVerifier_VerifyCosign(t *testing.T) {
+	const signedDigest = "sha256:e3f12513a4b22a2d7c0e7c9207f52128113758d9d68c7d06b11a0ac7672966f7"
+
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "keyrings", "cosign.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cosignPublic, err := cosign.ParsePublicKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = ioutil.ReadFile(filepath.Join("testdata", "keyrings", "redhat.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	redhatPublic, err := openpgp.ReadArmoredKeyRing(bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		verifiers       map[string]openpgp.EntityList
+		cosignVerifiers map[string]*ecdsa.PublicKey
+		store           store.Store
+		releaseDigest   string
+		wantErr         bool
+	}{
+		{
+			name:            "valid signature from bundle",
+			releaseDigest:   signedDigest,
+			store:           &bundle.Store{Directory: "testdata/bundles"},
+			cosignVerifiers: map[string]*ecdsa.PublicKey{"cosign": cosignPublic},
+		},
+		{
+			name:            "valid GPG and cosign signatures",
+			releaseDigest:   signedDigest,
+			store:           &serial.Store{Stores: []store.Store{&fileStore{directory: "testdata/signatures"}, &bundle.Store{Directory: "testdata/bundles"}}},
+			verifiers:       map[string]openpgp.EntityList{"redhat": redhatPublic},
+			cosignVerifiers: map[string]*ecdsa.PublicKey{"cosign": cosignPublic},
+		},
+		{
+			name:            "GPG signatures are not cosign signatures",
+			releaseDigest:   signedDigest,
+			store:           &fileStore{directory: "testdata/signatures"},
+			cosignVerifiers: map[string]*ecdsa.PublicKey{"cosign": cosignPublic},
+			wantErr:         true,
+		},
+		{
+			name:            "signature by another key",
+			releaseDigest:   signedDigest,
+			store:           &bundle.Store{Directory: "testdata/bundles"},
+			cosignVerifiers: map[string]*ecdsa.PublicKey{"cosign": cosignPublic, "other": &otherKey.PublicKey},
+			wantErr:         true,
+		},
+		{
+			name:            "digest is not found",
+			releaseDigest:   "sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			store:           &bundle.Store{Directory: "testdata/bundles"},
+			cosignVerifiers: map[string]*ecdsa.PublicKey{"cosign": cosignPublic},
+			wantErr:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &releaseVerifier{
+				verifiers:       tt.verifiers,
+				cosignVerifiers: tt.cosignVerifiers,
+				store:           tt.store,
+				signatureCache:  make(map[string][][]byte),
+			}
+			if err := v.Verify(context.Background(), tt.releaseDigest); (err != nil) != tt.wantErr {
+				t.Errorf("releaseVerifier.Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func Test_ReleaseVerifier_String(t *testing.T) {
 	data, err := ioutil.ReadFile(filepath.Join("testdata", "keyrings", "redhat.txt"))
 	if err != nil {
@@ -186,12 +273,22 @@ func Test_ReleaseVerifier_String(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	data, err = ioutil.ReadFile(filepath.Join("testdata", "keyrings", "cosign.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cosignPublic, err := cosign.ParsePublicKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const cosignFingerprint = "ae28f422617b57000695206f233c8ab7fda17ce92909078e1efd9b0bd65d03c9"
 
 	tests := []struct {
-		name      string
-		verifiers map[string]openpgp.EntityList
-		store     store.Store
-		want      string
+		name            string
+		verifiers       map[string]openpgp.EntityList
+		cosignVerifiers map[string]*ecdsa.PublicKey
+		store           store.Store
+		want            string
 	}{
 		{
 			name: "none",
@@ -214,10 +311,24 @@ func Test_ReleaseVerifier_String(t *testing.T) {
 			},
 			want: "All release image digests must have GPG signatures from redhat (567E347AD0044ADE55BA8A5F199E2F91FD431D51: Red Hat, Inc. (release key 2) <security@redhat.com>) - will check for signatures in containers/image format at <ERROR: no store>",
 		},
+		{
+			name:            "cosign verifier",
+			cosignVerifiers: map[string]*ecdsa.PublicKey{"cosign": cosignPublic},
+			store:           &bundle.Store{Directory: "absolute/path"},
+			want:            "All release image digests must have cosign signatures from cosign (ECDSA P-256 SHA256:" + cosignFingerprint + ") - will check for signatures in cosign format at signature bundles in absolute/path",
+		},
+		{
+			name: "GPG and cosign verifiers",
+			verifiers: map[string]openpgp.EntityList{
+				"redhat": redhatPublic,
+			},
+			cosignVerifiers: map[string]*ecdsa.PublicKey{"cosign": cosignPublic},
+			want:            "All release image digests must have GPG signatures from redhat (567E347AD0044ADE55BA8A5F199E2F91FD431D51: Red Hat, Inc. (release key 2) <security@redhat.com>) and cosign signatures from cosign (ECDSA P-256 SHA256:" + cosignFingerprint + ") - will check for signatures in containers/image or cosign format at <ERROR: no store>",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			v := NewReleaseVerifier(tt.verifiers, tt.store)
+			v := &releaseVerifier{verifiers: tt.verifiers, cosignVerifiers: tt.cosignVerifiers, store: tt.store}
 			if got := fmt.Sprintf("%v", v); got != tt.want {
 				t.Errorf("releaseVerifier.String() = %v, want %v", got, tt.want)
 			}
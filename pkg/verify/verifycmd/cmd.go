@@ -0,0 +1,351 @@
+package verifycmd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/crypto/openpgp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/verify"
+	"github.com/openshift/library-go/pkg/verify/cosign"
+	"github.com/openshift/library-go/pkg/verify/store"
+	"github.com/openshift/library-go/pkg/verify/store/bundle"
+	"github.com/openshift/library-go/pkg/verify/store/serial"
+	"github.com/openshift/library-go/pkg/verify/store/sigstore"
+	"github.com/openshift/library-go/pkg/verify/util"
+)
+
+type VerifyOptions struct {
+	ReleaseDigest  string
+	ReleaseVersion string
+
+	KeyringDir string
+	PolicyFile string
+
+	StoreDirs      []string
+	BundleDirs     []string
+	ConfigMapFiles []string
+	StoreURLs      []string
+
+	Timeout time.Duration
+
+	Out io.Writer
+}
+
+func NewVerifyOptions() *VerifyOptions {
+	return &VerifyOptions{
+		Timeout: 30 * time.Second,
+		Out:     os.Stdout,
+	}
+}
+
+func NewVerify() *cobra.Command {
+	o := NewVerifyOptions()
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the signatures of a release image and report why they are accepted or rejected",
+		Run: func(cmd *cobra.Command, args []string) {
+			klog.V(1).Info(cmd.Flags())
+			klog.V(1).Info(spew.Sdump(o))
+
+			if err := o.Validate(); err != nil {
+				klog.Fatal(err)
+			}
+			if err := o.Run(); err != nil {
+				klog.Fatal(err)
+			}
+		},
+	}
+
+	o.AddFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *VerifyOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.ReleaseDigest, "release-digest", o.ReleaseDigest, "digest of the release image to verify, e.g. sha256:...")
+	fs.StringVar(&o.ReleaseVersion, "release-version", o.ReleaseVersion, "version of the release image, used to scope verifiers with the verification policy")
+	fs.StringVar(&o.KeyringDir, "keyring-dir", o.KeyringDir, "directory with one verifier per file, either a GPG keyring in ASCII or binary form or a PEM encoded cosign public key")
+	fs.StringVar(&o.PolicyFile, "policy", o.PolicyFile, "file with the JSON verification policy, as in the verification-policy key of the verification config map")
+	fs.StringSliceVar(&o.StoreDirs, "store-dir", o.StoreDirs, "directory with signatures as <ALGO>=<DIGEST>/signature-<NUMBER>, as in file:// signature stores")
+	fs.StringSliceVar(&o.BundleDirs, "bundle-dir", o.BundleDirs, "directory with cosign signature bundles as <ALGO>=<DIGEST>")
+	fs.StringSliceVar(&o.ConfigMapFiles, "configmap", o.ConfigMapFiles, "YAML or JSON file with a config map holding signatures in its binaryData, e.g. as returned by GetSignaturesAsConfigmap")
+	fs.StringSliceVar(&o.StoreURLs, "store-url", o.StoreURLs, "http:// or https:// signature store, as in the store-* keys of the verification config map")
+	fs.DurationVar(&o.Timeout, "timeout", o.Timeout, "maximum time to retrieve and verify signatures")
+}
+
+func (o *VerifyOptions) Validate() error {
+	if len(o.ReleaseDigest) == 0 {
+		return fmt.Errorf("--release-digest is required")
+	}
+	if len(o.KeyringDir) == 0 {
+		return fmt.Errorf("--keyring-dir is required")
+	}
+	if len(o.StoreDirs)+len(o.BundleDirs)+len(o.ConfigMapFiles)+len(o.StoreURLs) == 0 {
+		return fmt.Errorf("at least one of --store-dir, --bundle-dir, --configmap or --store-url is required")
+	}
+	for _, s := range o.StoreURLs {
+		u, err := url.Parse(s)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("--store-url %q must be a valid URL with scheme http:// or https://", s)
+		}
+	}
+	if o.Timeout <= 0 {
+		return fmt.Errorf("--timeout must be positive")
+	}
+
+	return nil
+}
+
+func (o *VerifyOptions) Run() error {
+	verifiers, cosignVerifiers, descriptions, err := loadVerifiers(o.KeyringDir)
+	if err != nil {
+		return err
+	}
+
+	var policy verify.Policy
+	if len(o.PolicyFile) > 0 {
+		data, err := ioutil.ReadFile(o.PolicyFile)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &policy); err != nil {
+			return fmt.Errorf("%s must be a JSON verification policy: %v", o.PolicyFile, err)
+		}
+	}
+
+	stores, err := o.stores()
+	if err != nil {
+		return err
+	}
+	wrapped := make([]store.Store, 0, len(stores))
+	for _, s := range stores {
+		wrapped = append(wrapped, s)
+	}
+
+	verifier, err := verify.NewPolicyReleaseVerifier(verifiers, cosignVerifiers, &serial.Store{Stores: wrapped}, policy)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.Timeout)
+	defer cancel()
+	result, verifyErr := verifier.VerifyRelease(ctx, o.ReleaseVersion, o.ReleaseDigest)
+	if result == nil {
+		return verifyErr
+	}
+
+	printReport(o.Out, result, verifyErr, descriptions, stores)
+	if verifyErr != nil {
+		return fmt.Errorf("the release %s could not be verified", o.ReleaseDigest)
+	}
+	return nil
+}
+
+// stores returns the signature sources in the order they are searched.
+func (o *VerifyOptions) stores() ([]*recordingStore, error) {
+	var stores []*recordingStore
+	for _, dir := range o.StoreDirs {
+		stores = append(stores, &recordingStore{Store: verify.NewFileStore(dir)})
+	}
+	for _, dir := range o.BundleDirs {
+		stores = append(stores, &recordingStore{Store: &bundle.Store{Directory: dir}})
+	}
+	for _, file := range o.ConfigMapFiles {
+		s, err := newConfigMapStore(file)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, &recordingStore{Store: s})
+	}
+	for _, s := range o.StoreURLs {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, &recordingStore{Store: &sigstore.Store{URI: u, HTTPClient: sigstore.DefaultClient}})
+	}
+	return stores, nil
+}
+
+// loadVerifiers loads a verifier from each file in dir, named after the file, and describes them.
+func loadVerifiers(dir string) (map[string]openpgp.EntityList, map[string]*ecdsa.PublicKey, map[string]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	verifiers := make(map[string]openpgp.EntityList)
+	cosignVerifiers := make(map[string]*ecdsa.PublicKey)
+	descriptions := make(map[string]string)
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if key, err := cosign.ParsePublicKey(data); err == nil {
+			cosignVerifiers[file.Name()] = key
+			descriptions[file.Name()] = fmt.Sprintf("cosign key %s", cosign.Fingerprint(key))
+			continue
+		}
+		keyring, err := verify.LoadArmoredOrUnarmoredGPGKeyRing(data)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s is neither a PEM encoded ECDSA public key nor a GPG keyring: %v", path, err)
+		}
+		var fingerprints []string
+		for _, entity := range keyring {
+			fingerprints = append(fingerprints, fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint))
+		}
+		verifiers[file.Name()] = keyring
+		descriptions[file.Name()] = fmt.Sprintf("GPG keyring with %s", strings.Join(fingerprints, ", "))
+	}
+	if len(verifiers)+len(cosignVerifiers) == 0 {
+		return nil, nil, nil, fmt.Errorf("%s does not contain any GPG or cosign public keys", dir)
+	}
+	return verifiers, cosignVerifiers, descriptions, nil
+}
+
+// recordingStore records what a store passed to the verifier for the report.
+type recordingStore struct {
+	store.Store
+
+	searched   bool
+	signatures int
+	errs       []error
+}
+
+// Signatures fetches signatures for the provided digest from the wrapped store.
+func (s *recordingStore) Signatures(ctx context.Context, name string, digest string, fn store.Callback) error {
+	s.searched = true
+	err := s.Store.Signatures(ctx, name, digest, func(ctx context.Context, signature []byte, errIn error) (bool, error) {
+		switch {
+		case errIn == nil:
+			s.signatures++
+		case !errors.Is(errIn, store.ErrNotFound):
+			s.errs = append(s.errs, errIn)
+		}
+		return fn(ctx, signature, errIn)
+	})
+	if err != nil {
+		s.errs = append(s.errs, err)
+	}
+	return err
+}
+
+// configMapStore provides access to signatures in the binaryData of a config map read from a file.
+type configMapStore struct {
+	path      string
+	configMap *corev1.ConfigMap
+}
+
+func newConfigMapStore(path string) (*configMapStore, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	configMap, err := util.ReadConfigMap(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid config map: %v", path, err)
+	}
+	if configMap == nil {
+		return nil, fmt.Errorf("%s is not a config map", path)
+	}
+	return &configMapStore{path: path, configMap: configMap}, nil
+}
+
+// Signatures fetches signatures for the provided digest out of the binaryData keys starting
+// with the digest, in lexical order.
+func (s *configMapStore) Signatures(ctx context.Context, name string, digest string, fn store.Callback) error {
+	prefix, err := util.DigestToKeyPrefix(digest, "-")
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(s.configMap.BinaryData))
+	for k := range s.configMap.BinaryData {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		done, err := fn(ctx, s.configMap.BinaryData[k], nil)
+		if err != nil || done {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	_, err = fn(ctx, nil, fmt.Errorf("%s %s: %w", s, digest, store.ErrNotFound))
+	return err
+}
+
+// String returns a description of where this store finds
+// signatures.
+func (s *configMapStore) String() string {
+	return fmt.Sprintf("config map %s/%s in %s", s.configMap.Namespace, s.configMap.Name, s.path)
+}
+
+// printReport describes the outcome of the verification and where the signatures came from.
+func printReport(out io.Writer, result *verify.Result, verifyErr error, descriptions map[string]string, stores []*recordingStore) {
+	fmt.Fprintf(out, "Release: %s\n", result.ReleaseDigest)
+	if len(result.ReleaseVersion) > 0 {
+		fmt.Fprintf(out, "Version: %s\n", result.ReleaseVersion)
+	}
+	if verifyErr == nil {
+		fmt.Fprintf(out, "Result: verified\n")
+	} else {
+		fmt.Fprintf(out, "Result: not verified: %s\n", verifyErr)
+	}
+	fmt.Fprintf(out, "Required: %d\n", result.Required)
+
+	fmt.Fprintf(out, "\nAccepted:\n")
+	for _, accepted := range result.Accepted {
+		fmt.Fprintf(out, "  %s (%s): signed by %s\n", accepted.Verifier, descriptions[accepted.Verifier], accepted.Signer)
+	}
+	fmt.Fprintf(out, "\nRejected:\n")
+	for _, rejected := range result.Rejected {
+		fmt.Fprintf(out, "  %s (%s):\n", rejected.Verifier, descriptions[rejected.Verifier])
+		for _, reason := range rejected.Reasons {
+			fmt.Fprintf(out, "    - %s\n", reason)
+		}
+	}
+	if len(result.NotApplicable) > 0 {
+		fmt.Fprintf(out, "\nNot applicable:\n")
+		for _, name := range result.NotApplicable {
+			fmt.Fprintf(out, "  %s (%s)\n", name, descriptions[name])
+		}
+	}
+
+	fmt.Fprintf(out, "\nSignature sources:\n")
+	for _, s := range stores {
+		if !s.searched {
+			fmt.Fprintf(out, "  %s: not searched\n", s.Store)
+			continue
+		}
+		fmt.Fprintf(out, "  %s: %d signatures\n", s.Store, s.signatures)
+		for _, err := range s.errs {
+			fmt.Fprintf(out, "    - %s\n", err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package verifycmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/verify"
+	"github.com/openshift/library-go/pkg/verify/util"
+)
+
+const (
+	signedDigest   = "sha256:e3f12513a4b22a2d7c0e7c9207f52128113758d9d68c7d06b11a0ac7672966f7"
+	unsignedDigest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		o       VerifyOptions
+		wantErr string
+	}{
+		{
+			name: "valid",
+			o:    VerifyOptions{ReleaseDigest: signedDigest, KeyringDir: "keys", StoreURLs: []string{"https://example.com/signatures"}, Timeout: 1},
+		},
+		{
+			name:    "missing digest",
+			o:       VerifyOptions{KeyringDir: "keys", StoreDirs: []string{"signatures"}, Timeout: 1},
+			wantErr: "--release-digest is required",
+		},
+		{
+			name:    "missing keyring directory",
+			o:       VerifyOptions{ReleaseDigest: signedDigest, StoreDirs: []string{"signatures"}, Timeout: 1},
+			wantErr: "--keyring-dir is required",
+		},
+		{
+			name:    "missing signature sources",
+			o:       VerifyOptions{ReleaseDigest: signedDigest, KeyringDir: "keys", Timeout: 1},
+			wantErr: "at least one of --store-dir, --bundle-dir, --configmap or --store-url is required",
+		},
+		{
+			name:    "file store URL",
+			o:       VerifyOptions{ReleaseDigest: signedDigest, KeyringDir: "keys", StoreURLs: []string{"file:///signatures"}, Timeout: 1},
+			wantErr: `--store-url "file:///signatures" must be a valid URL with scheme http:// or https://`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.o.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	keyringDir := t.TempDir()
+	for _, name := range []string{"redhat.txt", "cosign.pub"} {
+		data, err := ioutil.ReadFile(filepath.Join("..", "testdata", "keyrings", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(keyringDir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gpgSignature, err := ioutil.ReadFile(filepath.Join("..", "testdata", "signatures", "sha256="+strings.TrimPrefix(signedDigest, "sha256:"), "signature-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm, err := verify.GetSignaturesAsConfigmap(signedDigest, [][]byte{gpgSignature})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := util.ConfigMapAsBytes(cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configMapFile := filepath.Join(t.TempDir(), "signatures.yaml")
+	if err := ioutil.WriteFile(configMapFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.FileServer(http.Dir(filepath.Join("..", "testdata", "signatures"))))
+	defer server.Close()
+
+	policyFile := filepath.Join(t.TempDir(), "policy.json")
+	if err := ioutil.WriteFile(policyFile, []byte(`{"verifiers":{"cosign.pub":{"releases":["4.13.*"]}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		o        VerifyOptions
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name: "file store and bundles",
+			o: VerifyOptions{
+				ReleaseDigest: signedDigest,
+				StoreDirs:     []string{filepath.Join("..", "testdata", "signatures")},
+				BundleDirs:    []string{filepath.Join("..", "testdata", "bundles")},
+			},
+			expected: []string{
+				"Result: verified",
+				"Required: 2",
+				"  cosign.pub (cosign key SHA256:ae28f422617b57000695206f233c8ab7fda17ce92909078e1efd9b0bd65d03c9): signed by SHA256:ae28f422617b57000695206f233c8ab7fda17ce92909078e1efd9b0bd65d03c9",
+				"  redhat.txt (GPG keyring with 567E347AD0044ADE55BA8A5F199E2F91FD431D51): signed by 567E347AD0044ADE55BA8A5F199E2F91FD431D51",
+				"  file://../testdata/signatures: 1 signatures",
+				"  signature bundles in ../testdata/bundles: 1 signatures",
+			},
+		},
+		{
+			name: "config map without cosign signatures",
+			o: VerifyOptions{
+				ReleaseDigest:  signedDigest,
+				ConfigMapFiles: []string{configMapFile},
+			},
+			expected: []string{
+				"Result: not verified: unable to verify " + signedDigest + " against keyrings: cosign.pub",
+				"  redhat.txt (GPG keyring with 567E347AD0044ADE55BA8A5F199E2F91FD431D51): signed by 567E347AD0044ADE55BA8A5F199E2F91FD431D51",
+				"  cosign.pub (cosign key SHA256:ae28f422617b57000695206f233c8ab7fda17ce92909078e1efd9b0bd65d03c9):\n    - ",
+				"  config map openshift-config-managed/sha256-e3f12513a4b22a2d7c0e7c9207f52128113758d9d68c7d06b11a0ac7672966f7 in " + configMapFile + ": 1 signatures",
+			},
+			wantErr: true,
+		},
+		{
+			name: "policy scoping the cosign key and a signature server",
+			o: VerifyOptions{
+				ReleaseDigest:  signedDigest,
+				ReleaseVersion: "4.14.1",
+				PolicyFile:     policyFile,
+				StoreURLs:      []string{server.URL},
+			},
+			expected: []string{
+				"Version: 4.14.1",
+				"Result: verified",
+				"Required: 1",
+				"Not applicable:\n  cosign.pub (cosign key ",
+				"  containers/image signature store under " + server.URL + ": 1 signatures",
+			},
+		},
+		{
+			name: "no signatures",
+			o: VerifyOptions{
+				ReleaseDigest: unsignedDigest,
+				StoreDirs:     []string{filepath.Join("..", "testdata", "signatures")},
+				BundleDirs:    []string{filepath.Join("..", "testdata", "bundles")},
+			},
+			expected: []string{
+				"Result: not verified: unable to verify " + unsignedDigest + " against keyrings: cosign.pub, redhat.txt",
+				"  redhat.txt (GPG keyring with 567E347AD0044ADE55BA8A5F199E2F91FD431D51):\n    - no valid signature found",
+				"  file://../testdata/signatures: 0 signatures",
+				"  signature bundles in ../testdata/bundles: 0 signatures",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			o := NewVerifyOptions()
+			o.ReleaseDigest = tt.o.ReleaseDigest
+			o.ReleaseVersion = tt.o.ReleaseVersion
+			o.KeyringDir = keyringDir
+			o.PolicyFile = tt.o.PolicyFile
+			o.StoreDirs = tt.o.StoreDirs
+			o.BundleDirs = tt.o.BundleDirs
+			o.ConfigMapFiles = tt.o.ConfigMapFiles
+			o.StoreURLs = tt.o.StoreURLs
+			o.Out = out
+
+			if err := o.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			err := o.Run()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("expected error %t, got %v", tt.wantErr, err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("expected the report to contain %q, got:\n%s", expected, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestLoadVerifiers(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "invalid"), []byte("not a key"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, _, err := loadVerifiers(dir)
+	if err == nil || !strings.Contains(err.Error(), "is neither a PEM encoded ECDSA public key nor a GPG keyring") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "invalid")); err != nil {
+		t.Fatal(err)
+	}
+	_, _, _, err = loadVerifiers(dir)
+	if err == nil || !strings.Contains(err.Error(), "does not contain any GPG or cosign public keys") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -132,6 +132,8 @@ func TestEncryptionIntegration(tt *testing.T) {
 		fakeConfigInformer.Config().V1().APIServers(),
 		kubeInformers,
 		deployer, // secret client wrapping kubeClient with encryption-config revision counting
+		nil,
+		nil,
 		eventRecorder,
 		nil,
 	)
@@ -213,7 +215,7 @@ func TestEncryptionIntegration(tt *testing.T) {
 			s, err := kubeClient.CoreV1().Secrets("openshift-config-managed").Get(ctx, fmt.Sprintf("encryption-key-%s-%s", component, key), metav1.GetOptions{})
 			require.NoError(t, err)
 
-			ks, err := secrets.ToKeyState(s)
+			ks, err := secrets.ToKeyState(context.TODO(), s, nil)
 			require.NoError(t, err)
 			return len(ks.Migrated.Resources) == 2, nil
 		})